@@ -3,16 +3,23 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
 	"github.com/infracollect/infracollect/internal/runner"
+	"github.com/infracollect/infracollect/internal/runner/hclfuncs"
 	"github.com/samber/lo"
 	"github.com/urfave/cli/v3"
 	"go.uber.org/zap"
@@ -27,64 +34,134 @@ var collectCommand = &cli.Command{
 			Name:  "pass-env",
 			Usage: "Environment variables to pass through to job execution (can be repeated)",
 		},
+		&cli.StringSliceFlag{
+			Name:  "allow-path",
+			Usage: "Directory static steps with allow_absolute may read absolute paths from (can be repeated)",
+		},
+		jobVarFlag,
+		printVarsFlag,
 		&cli.BoolFlag{
 			Name:  "pass-all-env",
 			Usage: "Pass all environment variables through to job execution",
 		},
+		strictEnvFlag,
+		overlayFlag,
 		&cli.BoolFlag{
 			Name:  "trust-remote",
-			Usage: "Trust remote job file",
+			Usage: "Trust remote job files (http://, https:// and oci:// references)",
+		},
+		&cli.IntFlag{
+			Name:  "startup-concurrency",
+			Usage: "Maximum number of collectors started in parallel",
+			Value: 4,
+		},
+		&cli.IntFlag{
+			Name:  "step-concurrency",
+			Usage: "Maximum number of independent steps run in parallel",
+			Value: 1,
+		},
+		&cli.IntFlag{
+			Name:  "max-step-retries",
+			Usage: "Resolve a failing step again up to this many times before failing the job",
+		},
+		&cli.DurationFlag{
+			Name:  "step-retry-delay",
+			Usage: "How long to wait before each step retry",
+			Value: time.Second,
+		},
+		&cli.IntFlag{
+			Name:  "parallel-jobs",
+			Usage: "Maximum number of job files collected at the same time",
+			Value: 1,
+		},
+		&cli.BoolFlag{
+			Name:  "fail-fast",
+			Usage: "Stop at the first failing job instead of running the remaining ones",
+		},
+		&cli.BoolFlag{
+			Name:  "fail-on-empty",
+			Usage: "Fail a job when any step's result is null, an empty object or an empty array, after its output has been written",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "Abort the collection when it runs longer than this (e.g. 10m), per cycle with --watch; 0 disables the limit",
+		},
+		&cli.BoolFlag{
+			Name:  "flush-partial",
+			Usage: "When an archive job is interrupted (Ctrl-C, --timeout), write what was collected to <archive>.partial instead of nothing",
+		},
+		&cli.BoolFlag{
+			Name:  "resume",
+			Usage: "Skip steps whose output file already exists in the sink, e.g. to finish a job that failed partway",
+		},
+		&cli.StringFlag{
+			Name:    "cache-dir",
+			Usage:   "Reuse step results stored in this directory by earlier runs, and store new ones there",
+			Sources: cli.EnvVars("INFRACOLLECT_CACHE_DIR"),
+		},
+		&cli.DurationFlag{
+			Name:  "cache-ttl",
+			Usage: "How long a cached step result stays valid",
+			Value: time.Hour,
+		},
+		tfPluginCacheFlag,
+		&cli.BoolFlag{
+			Name:  "no-cache",
+			Usage: "Resolve every step, ignoring --cache-dir",
+		},
+		&cli.BoolFlag{
+			Name:  "inspect",
+			Usage: "Print each collector's and step's evaluated configuration as JSON, with secrets redacted, instead of running the jobs",
+		},
+		&cli.StringFlag{
+			Name:  "summary",
+			Usage: "Write a JSON summary of the run (per-job status, per-step timing and bytes) to this file",
+		},
+		&cli.DurationFlag{
+			Name:  "watch",
+			Usage: "Run the jobs again this long after each run finishes (e.g. 5m), until interrupted",
+		},
+		&cli.BoolFlag{
+			Name:  "watch-fail-fast",
+			Usage: "Stop watching when a run fails instead of logging the error and continuing",
+		},
+		remoteUserFlag,
+		remotePasswordFlag,
+		remoteHeaderFlag,
+		&cli.StringFlag{
+			Name:    "vault-addr",
+			Usage:   "Vault server address used by the vault() function",
+			Sources: cli.EnvVars("VAULT_ADDR"),
+		},
+		&cli.StringFlag{
+			Name:    "vault-token",
+			Usage:   "Vault token used by the vault() function",
+			Sources: cli.EnvVars("VAULT_TOKEN"),
 		},
 	},
 	Arguments: []cli.Argument{
-		&cli.StringArg{
-			Name:      "job",
-			UsageText: "The job file to collect data from",
+		&cli.StringArgs{
+			Name:      "jobs",
+			UsageText: "The job files to collect data from (glob patterns are expanded)",
+			Max:       -1,
 		},
 	},
 	Action: func(ctx context.Context, command *cli.Command) error {
 		logger := getLogger(ctx)
 
-		jobFilename := command.StringArg("job")
-		if jobFilename == "" {
-			return fmt.Errorf("no job file provided")
-		}
-
-		jobFile, isRemote, err := readJobFile(ctx, jobFilename)
+		jobFilenames, err := expandJobArgs(command.StringArgs("jobs"))
 		if err != nil {
-			return fmt.Errorf("failed to read job file '%s': %w", jobFilename, err)
-		}
-
-		if isRemote && !command.Bool("trust-remote") {
-			if !isInteractive(ctx) {
-				return fmt.Errorf("remote job file requires --trust-remote flag in non-interactive mode")
-			}
-
-			logger.Warn("remote job file is not trusted", zap.String("job_filename", jobFilename))
-			fmt.Println(string(jobFile))
-
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Print("Are you sure you want to trust this remote job file? (y/n): ")
-			response, err := reader.ReadString('\n')
-			if err != nil {
-				return fmt.Errorf("failed to read confirmation: %w", err)
-			}
-			if strings.TrimSpace(response) != "y" {
-				return fmt.Errorf("remote job file is not trusted")
-			}
+			return err
 		}
-
-		logger = logger.With(zap.String("job_filename", jobFilename))
-		logger.Info("parsing job file")
-
-		tmpl, diags := runner.ParseJobTemplate(jobFile, jobFilename)
-		if diags.HasErrors() {
-			writeDiags(diags)
-			return fmt.Errorf("failed to parse job file '%s'", jobFilename)
+		if len(jobFilenames) == 0 {
+			return fmt.Errorf("no job file provided")
 		}
 
 		var allowedEnv []string
 		if command.Bool("pass-all-env") {
+			if command.Bool("strict-env") {
+				return fmt.Errorf("--strict-env cannot be used with --pass-all-env")
+			}
 			logger.Warn("allowing all environment variables to be used in job configuration")
 			allowedEnv = lo.Map(os.Environ(), func(kv string, _ int) string {
 				name, _, ok := strings.Cut(kv, "=")
@@ -97,39 +174,462 @@ var collectCommand = &cli.Command{
 			allowedEnv = command.StringSlice("pass-env")
 		}
 
-		registry, err := buildRegistry(logger.Named("registry"), allowedEnv)
+		jobVars, err := parseJobVars(command.StringSlice("job-var"))
+		if err != nil {
+			return err
+		}
+
+		auth, err := remoteAuthFromCommand(command)
+		if err != nil {
+			return err
+		}
+		jobs := newJobLoader(logger, auth, command.Bool("trust-remote"))
+
+		if command.Bool("strict-env") {
+			if err := checkStrictEnv(ctx, command, jobs, jobFilenames, allowedEnv); err != nil {
+				return err
+			}
+		}
+
+		registry, err := buildRegistry(logger.Named("registry"), allowedEnv, command.StringSlice("allow-path"), command.String("tf-plugin-cache"))
 		if err != nil {
 			return fmt.Errorf("failed to build registry: %w", err)
 		}
 
-		r, diags := runner.New(
-			logger.WithOptions(zap.AddStacktrace(zapcore.ErrorLevel)).Named("runner"),
-			tmpl,
-			registry,
-			allowedEnv,
-		)
+		collectOnce := func(ctx context.Context) error {
+			if timeout := command.Duration("timeout"); timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeoutCause(ctx, timeout,
+					fmt.Errorf("collection exceeded the configured --timeout of %s", timeout))
+				defer cancel()
+			}
+
+			reports := make(map[string]*runner.RunReport, len(jobFilenames))
+			var reportsMu sync.Mutex
+			collect := func(ctx context.Context, jobFilename string) error {
+				report, err := collectJob(ctx, command, logger, registry, jobs, allowedEnv, jobVars, jobFilename)
+				reportsMu.Lock()
+				reports[jobFilename] = report
+				reportsMu.Unlock()
+				return err
+			}
+
+			if len(jobFilenames) == 1 {
+				start := time.Now()
+				err := withTimeoutCause(ctx, collect(ctx, jobFilenames[0]))
+				outcome := jobOutcome{filename: jobFilenames[0], err: err, duration: time.Since(start)}
+				return errors.Join(err, writeRunSummary(command.String("summary"), []jobOutcome{outcome}, reports))
+			}
+
+			outcomes := runJobs(ctx, logger, jobFilenames, command.Int("parallel-jobs"), command.Bool("fail-fast"), collect)
+			failed := lo.CountBy(outcomes, func(o jobOutcome) bool { return o.err != nil })
+
+			// Failed jobs are already logged as errors.
+			if !isQuiet(ctx) {
+				writeJobSummary(os.Stderr, newPalette(command, os.Stderr), outcomes)
+			}
+			if err := writeRunSummary(command.String("summary"), outcomes, reports); err != nil {
+				return err
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d jobs failed", failed, len(jobFilenames))
+			}
+			return nil
+		}
+
+		if interval := command.Duration("watch"); interval > 0 {
+			return watchJobs(ctx, logger, interval, command.Bool("watch-fail-fast"), collectOnce)
+		}
+		return collectOnce(ctx)
+	},
+}
+
+// watchJobs calls collect, waits interval after it returns, and repeats
+// until ctx is canceled (Ctrl-C). Every call builds fresh runners, so
+// collectors are reopened and timestamp() is re-evaluated each cycle. A
+// failed cycle is logged and the loop goes on, unless failFast is set.
+func watchJobs(
+	ctx context.Context,
+	logger *zap.Logger,
+	interval time.Duration,
+	failFast bool,
+	collect func(ctx context.Context) error,
+) error {
+	for cycle := 1; ; cycle++ {
+		logger.Info("starting watch cycle", zap.Int("cycle", cycle))
+		err := collect(ctx)
+		if ctx.Err() != nil {
+			logger.Info("watch stopped", zap.Int("cycle", cycle))
+			return nil
+		}
+		if err != nil {
+			if failFast {
+				return fmt.Errorf("watch cycle %d failed: %w", cycle, err)
+			}
+			logger.Error("watch cycle failed", zap.Int("cycle", cycle), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info("watch stopped", zap.Int("cycle", cycle))
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// withTimeoutCause prefixes err with the --timeout message when the run
+// context expired, so the user sees why the run stopped instead of a bare
+// "context deadline exceeded".
+func withTimeoutCause(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	cause := context.Cause(ctx)
+	if errors.Is(err, cause) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", cause, err)
+}
+
+// jobOutcome is one line of the summary printed after a multi-job run.
+type jobOutcome struct {
+	filename string
+	err      error
+	skipped  bool
+	duration time.Duration
+}
+
+// runJobs collects every job file, up to parallel at a time, and returns one
+// outcome per file in argument order. A failing job does not stop the others
+// unless failFast is set; then, as after the run context expires, jobs that
+// have not started yet are marked skipped while running ones finish.
+func runJobs(
+	ctx context.Context,
+	logger *zap.Logger,
+	jobFilenames []string,
+	parallel int,
+	failFast bool,
+	run func(ctx context.Context, jobFilename string) error,
+) []jobOutcome {
+	outcomes := make([]jobOutcome, len(jobFilenames))
+	slots := make(chan struct{}, max(parallel, 1))
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		halted bool
+	)
+	for i, jobFilename := range jobFilenames {
+		slots <- struct{}{}
+		mu.Lock()
+		stop := halted || ctx.Err() != nil
+		mu.Unlock()
+		if stop {
+			for j := i; j < len(jobFilenames); j++ {
+				outcomes[j] = jobOutcome{filename: jobFilenames[j], skipped: true}
+			}
+			break
+		}
+
+		wg.Go(func() {
+			defer func() { <-slots }()
+			start := time.Now()
+			err := withTimeoutCause(ctx, run(ctx, jobFilename))
+			outcomes[i] = jobOutcome{filename: jobFilename, err: err, duration: time.Since(start)}
+			if err == nil {
+				return
+			}
+			logger.Error("job failed", zap.String("job_filename", jobFilename), zap.Error(err))
+			if failFast {
+				mu.Lock()
+				halted = true
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	return outcomes
+}
+
+func writeJobSummary(w io.Writer, p palette, outcomes []jobOutcome) {
+	_, _ = fmt.Fprintln(w, "Summary:")
+	for _, o := range outcomes {
+		elapsed := o.duration.Round(time.Millisecond)
+		switch {
+		case o.skipped:
+			_, _ = fmt.Fprintf(w, "  SKIPPED %s\n", o.filename)
+		case o.err != nil:
+			_, _ = fmt.Fprintf(w, "  %s %s (%s): %s\n", p.failure("FAILED "), o.filename, elapsed, o.err)
+		default:
+			_, _ = fmt.Fprintf(w, "  %s %s (%s)\n", p.success("OK     "), o.filename, elapsed)
+		}
+	}
+}
+
+// expandJobArgs expands glob patterns among the job arguments, keeping the
+// order in which they were given. Remote URLs and plain paths pass through
+// unchanged (a missing plain path is reported when it is read); a pattern
+// that matches nothing is an error. Duplicates are dropped.
+func expandJobArgs(args []string) ([]string, error) {
+	var filenames []string
+	for _, arg := range args {
+		if isRemoteJob(arg) || !strings.ContainsAny(arg, "*?[") {
+			filenames = append(filenames, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid job file pattern '%s': %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("job file pattern '%s' matched no files", arg)
+		}
+		filenames = append(filenames, matches...)
+	}
+	return lo.Uniq(filenames), nil
+}
+
+// jobLoader reads the job files of a collect run. A remote job is fetched
+// and confirmed once and its content kept, so --strict-env, the run and
+// every --watch cycle use the bytes the user trusted instead of fetching
+// the job again.
+type jobLoader struct {
+	logger      *zap.Logger
+	auth        remoteAuth
+	trustRemote bool
+
+	mu     sync.Mutex
+	remote map[string][]byte
+}
+
+func newJobLoader(logger *zap.Logger, auth remoteAuth, trustRemote bool) *jobLoader {
+	return &jobLoader{
+		logger:      logger,
+		auth:        auth,
+		trustRemote: trustRemote,
+		remote:      make(map[string][]byte),
+	}
+}
+
+// load returns the content of jobFilename and whether it is remote. A
+// remote job without --trust-remote is refused before it is fetched when no
+// prompt can be shown.
+func (l *jobLoader) load(ctx context.Context, jobFilename string) ([]byte, bool, error) {
+	if !isRemoteJob(jobFilename) {
+		jobFile, _, err := readJobFile(ctx, jobFilename, l.auth)
+		return jobFile, false, err
+	}
+
+	l.mu.Lock()
+	jobFile, ok := l.remote[jobFilename]
+	l.mu.Unlock()
+	if ok {
+		return jobFile, true, nil
+	}
+
+	if !l.trustRemote && !isInteractive(ctx) {
+		return nil, true, fmt.Errorf("remote job file requires --trust-remote flag in non-interactive mode")
+	}
+	jobFile, _, err := readJobFile(ctx, jobFilename, l.auth)
+	if err != nil {
+		return nil, true, err
+	}
+	if !l.trustRemote {
+		if err := confirmRemoteJob(l.logger, jobFilename, jobFile); err != nil {
+			return nil, true, err
+		}
+	}
+
+	l.mu.Lock()
+	l.remote[jobFilename] = jobFile
+	l.mu.Unlock()
+	return jobFile, true, nil
+}
+
+var trustPromptMu sync.Mutex
+
+// confirmRemoteJob shows a remote job file and asks whether to trust it.
+// With --parallel-jobs several jobs may ask at once; one prompt at a time
+// keeps their output and answers apart. The lock is held for the prompt
+// only, so the jobs still run in parallel once trusted.
+func confirmRemoteJob(logger *zap.Logger, jobFilename string, jobFile []byte) error {
+	trustPromptMu.Lock()
+	defer trustPromptMu.Unlock()
+
+	logger.Warn("remote job file is not trusted", zap.String("job_filename", jobFilename))
+	fmt.Println(string(jobFile))
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print("Are you sure you want to trust this remote job file? (y/n): ")
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if strings.TrimSpace(response) != "y" {
+		return fmt.Errorf("remote job file is not trusted")
+	}
+	return nil
+}
+
+// checkStrictEnv parses every job file and fails when a variable of
+// allowedEnv is used by none of them. The jobs share --pass-env, so a
+// variable only one job needs is fine. Remote jobs are confirmed here, once,
+// before their content is parsed.
+func checkStrictEnv(ctx context.Context, command *cli.Command, jobs *jobLoader, jobFilenames []string, allowedEnv []string) error {
+	tmpls := make([]*runner.JobTemplate, 0, len(jobFilenames))
+	for _, jobFilename := range jobFilenames {
+		jobFile, isRemote, err := jobs.load(ctx, jobFilename)
+		if err != nil {
+			return fmt.Errorf("failed to read job file '%s': %w", jobFilename, err)
+		}
+		parseOpts, err := parseOptions(command, isRemote)
+		if err != nil {
+			return err
+		}
+		tmpl, diags := runner.ParseJobTemplate(jobFile, jobFilename, parseOpts...)
 		if diags.HasErrors() {
-			writeDiags(diags)
-			return fmt.Errorf("failed to create runner for job '%s'", jobFilename)
+			writeDiags(command, diags)
+			return fmt.Errorf("failed to parse job file '%s'", jobFilename)
 		}
+		tmpls = append(tmpls, tmpl)
+	}
+
+	if diags := runner.ValidateStrictEnv(tmpls, allowedEnv); diags.HasErrors() {
+		writeDiags(command, diags)
+		return fmt.Errorf("a variable passed with --pass-env is not used by any job")
+	}
+	return nil
+}
 
-		if _, err := r.Run(ctx); err != nil {
-			return fmt.Errorf("failed to run job: %w", err)
+// collectJob reads, parses, validates and runs a single job file. The run
+// report is returned whenever the job got as far as running, failed or not.
+func collectJob(
+	ctx context.Context,
+	command *cli.Command,
+	logger *zap.Logger,
+	registry *engine.Registry,
+	jobs *jobLoader,
+	allowedEnv []string,
+	jobVars map[string]string,
+	jobFilename string,
+) (*runner.RunReport, error) {
+	jobFile, isRemote, err := jobs.load(ctx, jobFilename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job file '%s': %w", jobFilename, err)
+	}
+
+	logger = logger.With(zap.String("job_filename", jobFilename))
+	logger.Info("parsing job file")
+
+	parseOpts, err := parseOptions(command, isRemote)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, diags := runner.ParseJobTemplate(jobFile, jobFilename, parseOpts...)
+	if diags.HasErrors() {
+		writeDiags(command, diags)
+		return nil, fmt.Errorf("failed to parse job file '%s'", jobFilename)
+	}
+
+	if command.Bool("print-vars") {
+		if err := printVars(tmpl, jobFilename, allowedEnv, jobVars); err != nil {
+			return nil, err
 		}
+	}
 
-		return nil
-	},
+	opts := []runner.Option{
+		runner.WithStartupConcurrency(command.Int("startup-concurrency")),
+		runner.WithStepConcurrency(command.Int("step-concurrency")),
+		runner.WithPartialFlush(command.Bool("flush-partial")),
+		runner.WithResume(command.Bool("resume")),
+		runner.WithJobVars(jobVars),
+		runner.WithStepRetries(command.Int("max-step-retries"), command.Duration("step-retry-delay")),
+	}
+	if dir := command.String("cache-dir"); dir != "" && !command.Bool("no-cache") {
+		ttl := command.Duration("cache-ttl")
+		if ttl <= 0 {
+			return nil, fmt.Errorf("--cache-ttl must be positive, got %s", ttl)
+		}
+		opts = append(opts, runner.WithResultCache(dir, ttl))
+	}
+	// Progress lines from concurrent jobs would interleave meaninglessly.
+	if command.Int("parallel-jobs") <= 1 && showProgress(ctx, logger) {
+		opts = append(opts, runner.WithProgress(progressReporter(os.Stderr)))
+	}
+	if addr := command.String("vault-addr"); addr != "" {
+		reader, err := hclfuncs.NewVaultSecretReader(addr, command.String("vault-token"))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, runner.WithSecretReader(reader))
+	}
+
+	r, diags := runner.New(
+		logger.WithOptions(zap.AddStacktrace(zapcore.ErrorLevel)).Named("runner"),
+		tmpl,
+		registry,
+		allowedEnv,
+		opts...,
+	)
+	if diags.HasErrors() {
+		writeDiags(command, diags)
+		return nil, fmt.Errorf("failed to create runner for job '%s'", jobFilename)
+	}
+
+	if command.Bool("inspect") {
+		return nil, printInspection(r)
+	}
+
+	results, err := r.Run(ctx)
+	if err != nil {
+		return r.Report(), fmt.Errorf("failed to run job: %w", err)
+	}
+
+	if command.Bool("fail-on-empty") {
+		if empty := runner.EmptyResults(results); len(empty) > 0 {
+			return r.Report(), fmt.Errorf("steps returned no data: %s", strings.Join(empty, ", "))
+		}
+	}
+
+	return r.Report(), nil
+}
+
+// printInspection writes the job's evaluated configuration to stdout in a
+// single write, so concurrent jobs do not interleave their output.
+func printInspection(r *runner.Runner) error {
+	inspection, err := r.Inspect()
+	if err != nil {
+		return fmt.Errorf("failed to inspect job: %w", err)
+	}
+	data, err := json.MarshalIndent(inspection, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode inspection: %w", err)
+	}
+	_, err = os.Stdout.Write(append(data, '\n'))
+	return err
 }
 
 // writeDiags renders hcl.Diagnostics to stderr with source ranges and
 // color when the terminal supports it. Falls back to plain text otherwise.
-func writeDiags(diags hcl.Diagnostics) {
-	w := hcl.NewDiagnosticTextWriter(os.Stderr, nil, 100, true)
+func writeDiags(command *cli.Command, diags hcl.Diagnostics) {
+	w := hcl.NewDiagnosticTextWriter(os.Stderr, nil, 100, newPalette(command, os.Stderr).enabled)
 	_ = w.WriteDiagnostics(diags)
 }
 
-func readJobFile(ctx context.Context, jobFilename string) ([]byte, bool, error) {
-	if strings.HasPrefix(jobFilename, "http://") || strings.HasPrefix(jobFilename, "https://") {
+// readJobFile reads a local job file, or fetches a remote one; auth is
+// applied to http(s) requests only. The returned bool reports whether the
+// job file is remote.
+func readJobFile(ctx context.Context, jobFilename string, auth remoteAuth) ([]byte, bool, error) {
+	if isOCIJob(jobFilename) {
+		body, err := readOCIJob(ctx, jobFilename)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to pull remote job file '%s': %w", jobFilename, err)
+		}
+		return body, true, nil
+	}
+
+	if isRemoteJob(jobFilename) {
 		parsedURL, err := url.Parse(jobFilename)
 		if err != nil {
 			return nil, false, fmt.Errorf("failed to parse URL '%s': %w", jobFilename, err)
@@ -139,6 +639,7 @@ func readJobFile(ctx context.Context, jobFilename string) ([]byte, bool, error)
 		if err != nil {
 			return nil, false, fmt.Errorf("failed to create request to remote job file '%s': %w", jobFilename, err)
 		}
+		auth.apply(req)
 
 		resp, err := cleanhttp.DefaultClient().Do(req)
 		if err != nil {
@@ -165,3 +666,31 @@ func readJobFile(ctx context.Context, jobFilename string) ([]byte, bool, error)
 
 	return jobFile, false, nil
 }
+
+var overlayFlag = &cli.StringSliceFlag{
+	Name:  "overlay",
+	Usage: "Merge this HCL file onto every job before validating it, e.g. per-environment overrides (can be repeated; later files win)",
+}
+
+// parseOptions returns the job parsing options for a job file. Only local
+// job files may include other files: a remote job, trusted or not, must not
+// read files from this machine. Overlays are local files chosen by the user,
+// so they apply to remote jobs too.
+func parseOptions(command *cli.Command, isRemote bool) ([]runner.ParseOption, error) {
+	var opts []runner.ParseOption
+	if !isRemote {
+		opts = append(opts, runner.WithIncludes())
+	}
+	for _, path := range command.StringSlice("overlay") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read overlay: %w", err)
+		}
+		opts = append(opts, runner.WithOverlay(data, path))
+	}
+	return opts, nil
+}
+
+func isRemoteJob(jobFilename string) bool {
+	return strings.HasPrefix(jobFilename, "http://") || strings.HasPrefix(jobFilename, "https://") || isOCIJob(jobFilename)
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+	"go.uber.org/zap"
+)
+
+// runCollect runs the collect command with args, with a no-op logger and
+// --quiet in place of what main's Before hook sets up.
+func runCollect(ctx context.Context, args ...string) error {
+	root := &cli.Command{
+		Name:     "infracollect",
+		Commands: []*cli.Command{collectCommand},
+		Before: func(ctx context.Context, _ *cli.Command) (context.Context, error) {
+			return withQuiet(withLogger(ctx, zap.NewNop()), true), nil
+		},
+	}
+	return root.Run(ctx, append([]string{"infracollect", "collect"}, args...))
+}
+
+// writeStaticJob writes a job with one static step writing to outDir and
+// returns its path. A failing job reads a file that does not exist.
+func writeStaticJob(t *testing.T, name, outDir string, failing bool) string {
+	t.Helper()
+	step := `value = "{\"job\": \"` + name + `\"}"
+  parse_as = "json"`
+	if failing {
+		step = `filepath = "does-not-exist.json"`
+	}
+	src := fmt.Sprintf(`
+job {
+  name = %q
+}
+
+step "static" "s" {
+  %s
+}
+
+output {
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, name, step, outDir)
+	path := filepath.Join(t.TempDir(), name+".hcl")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o600))
+	return path
+}
+
+// serveJob serves the job at path and counts the requests made for it.
+func serveJob(t *testing.T, path string) (string, *atomic.Int32) {
+	t.Helper()
+	src, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write(src)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/job.hcl", &requests
+}
+
+func staticOutput(outDir string) string {
+	return filepath.Join(outDir, "static", "s.json")
+}
+
+func TestCollect_ParallelJobs(t *testing.T) {
+	var args []string
+	var outDirs []string
+	for i := range 4 {
+		outDir := t.TempDir()
+		outDirs = append(outDirs, outDir)
+		args = append(args, writeStaticJob(t, fmt.Sprintf("job%d", i), outDir, false))
+	}
+
+	require.NoError(t, runCollect(t.Context(), append([]string{"--parallel-jobs", "2"}, args...)...))
+	for i, outDir := range outDirs {
+		data, err := os.ReadFile(staticOutput(outDir))
+		require.NoError(t, err)
+		assert.JSONEq(t, fmt.Sprintf(`{"job": "job%d"}`, i), string(data))
+	}
+}
+
+func TestCollect_FailingJob(t *testing.T) {
+	tests := []struct {
+		name        string
+		failFast    bool
+		wantSkipped bool
+	}{
+		{name: "later jobs still run", failFast: false},
+		{name: "fail-fast skips later jobs", failFast: true, wantSkipped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			okDir := t.TempDir()
+			args := []string{"--parallel-jobs", "1"}
+			if tt.failFast {
+				args = append(args, "--fail-fast")
+			}
+			args = append(args,
+				writeStaticJob(t, "broken", t.TempDir(), true),
+				writeStaticJob(t, "fine", okDir, false),
+			)
+
+			err := runCollect(t.Context(), args...)
+			assert.ErrorContains(t, err, "1 of 2 jobs failed")
+			_, statErr := os.Stat(staticOutput(okDir))
+			if tt.wantSkipped {
+				assert.ErrorIs(t, statErr, os.ErrNotExist, "the job after the failure is skipped")
+			} else {
+				assert.NoError(t, statErr, "the job after the failure still runs")
+			}
+		})
+	}
+}
+
+func TestCollect_Timeout(t *testing.T) {
+	job := writeStaticJob(t, "slow", t.TempDir(), false)
+
+	err := runCollect(t.Context(), "--timeout", "1ns", job)
+	assert.ErrorContains(t, err, "collection exceeded the configured --timeout of 1ns")
+}
+
+func TestCollect_Watch(t *testing.T) {
+	outDir := t.TempDir()
+	job := writeStaticJob(t, "watched", outDir, false)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() { errc <- runCollect(ctx, "--watch", "10ms", job) }()
+
+	// Remove the first cycle's output and wait for a later cycle to write it
+	// again.
+	output := staticOutput(outDir)
+	require.Eventually(t, func() bool { return os.Remove(output) == nil }, 5*time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(output)
+		return err == nil
+	}, 5*time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-errc:
+		assert.NoError(t, err, "interrupting a watch is not an error")
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop after the context was canceled")
+	}
+}
+
+func TestCollect_WatchFailFast(t *testing.T) {
+	job := writeStaticJob(t, "broken", t.TempDir(), true)
+
+	err := runCollect(t.Context(), "--watch", "10ms", "--watch-fail-fast", job)
+	assert.ErrorContains(t, err, "watch cycle 1 failed")
+}
+
+func TestCollect_RemoteJob(t *testing.T) {
+	t.Run("strict-env and the run share one fetch", func(t *testing.T) {
+		outDir := t.TempDir()
+		url, requests := serveJob(t, writeStaticJob(t, "remote", outDir, false))
+
+		require.NoError(t, runCollect(t.Context(), "--trust-remote", "--strict-env", url))
+		assert.FileExists(t, staticOutput(outDir))
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("every watch cycle reuses the trusted job", func(t *testing.T) {
+		outDir := t.TempDir()
+		url, requests := serveJob(t, writeStaticJob(t, "remote", outDir, false))
+
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		errc := make(chan error, 1)
+		go func() { errc <- runCollect(ctx, "--trust-remote", "--watch", "10ms", url) }()
+
+		output := staticOutput(outDir)
+		require.Eventually(t, func() bool { return os.Remove(output) == nil }, 5*time.Second, 5*time.Millisecond)
+		require.Eventually(t, func() bool {
+			_, err := os.Stat(output)
+			return err == nil
+		}, 5*time.Second, 5*time.Millisecond)
+		cancel()
+		require.NoError(t, <-errc)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("untrusted job is not fetched without a prompt", func(t *testing.T) {
+		url, requests := serveJob(t, writeStaticJob(t, "remote", t.TempDir(), false))
+
+		err := runCollect(t.Context(), "--strict-env", url)
+		assert.ErrorContains(t, err, "remote job file requires --trust-remote flag in non-interactive mode")
+		assert.Zero(t, requests.Load())
+	})
+}
+
+func TestWatchJobs(t *testing.T) {
+	t.Run("a failed cycle does not stop the watch", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		cycles := 0
+		err := watchJobs(ctx, zap.NewNop(), time.Millisecond, false, func(context.Context) error {
+			cycles++
+			if cycles == 3 {
+				cancel()
+			}
+			return assert.AnError
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, cycles)
+	})
+
+	t.Run("fail-fast stops at the first failure", func(t *testing.T) {
+		cycles := 0
+		err := watchJobs(t.Context(), zap.NewNop(), time.Millisecond, true, func(context.Context) error {
+			cycles++
+			return assert.AnError
+		})
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.ErrorContains(t, err, "watch cycle 1 failed")
+		assert.Equal(t, 1, cycles)
+	})
+}
+
+func TestRunJobs(t *testing.T) {
+	t.Run("runs at most parallel jobs at a time", func(t *testing.T) {
+		var (
+			mu      sync.Mutex
+			running int
+			peak    int
+		)
+		release := make(chan struct{})
+		jobs := []string{"a", "b", "c", "d", "e"}
+		done := make(chan []jobOutcome, 1)
+		go func() {
+			done <- runJobs(t.Context(), zap.NewNop(), jobs, 2, false, func(context.Context, string) error {
+				mu.Lock()
+				running++
+				peak = max(peak, running)
+				mu.Unlock()
+				<-release
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil
+			})
+		}()
+
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return running == 2
+		}, 5*time.Second, time.Millisecond)
+		close(release)
+		outcomes := <-done
+
+		assert.Equal(t, 2, peak)
+		for i, o := range outcomes {
+			assert.Equal(t, jobs[i], o.filename, "outcomes keep argument order")
+			assert.NoError(t, o.err)
+			assert.False(t, o.skipped)
+		}
+	})
+
+	t.Run("fail-fast skips jobs not started yet", func(t *testing.T) {
+		outcomes := runJobs(t.Context(), zap.NewNop(), []string{"a", "b", "c"}, 1, true, func(_ context.Context, job string) error {
+			if job == "a" {
+				return assert.AnError
+			}
+			return nil
+		})
+		require.Len(t, outcomes, 3)
+		assert.ErrorIs(t, outcomes[0].err, assert.AnError)
+		assert.True(t, outcomes[1].skipped)
+		assert.True(t, outcomes[2].skipped)
+	})
+
+	t.Run("an expired context skips jobs not started yet", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		outcomes := runJobs(ctx, zap.NewNop(), []string{"a", "b"}, 1, false, func(context.Context, string) error {
+			t.Error("no job runs once the context is done")
+			return nil
+		})
+		assert.True(t, outcomes[0].skipped)
+		assert.True(t, outcomes[1].skipped)
+	})
+}
+
+func TestWithTimeoutCause(t *testing.T) {
+	cause := errors.New("collection exceeded the configured --timeout of 1s")
+	expired, cancel := context.WithDeadlineCause(t.Context(), time.Now().Add(-time.Second), cause)
+	defer cancel()
+	canceled, cancelNow := context.WithCancel(t.Context())
+	cancelNow()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want string
+	}{
+		{name: "no error", ctx: expired, err: nil},
+		{name: "context still running", ctx: t.Context(), err: assert.AnError, want: assert.AnError.Error()},
+		{name: "canceled, not expired", ctx: canceled, err: assert.AnError, want: assert.AnError.Error()},
+		{name: "expired", ctx: expired, err: assert.AnError, want: cause.Error() + ": " + assert.AnError.Error()},
+		{name: "already mentions the cause", ctx: expired, err: fmt.Errorf("run: %w", cause), want: "run: " + cause.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := withTimeoutCause(tt.ctx, tt.err)
+			if tt.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.want)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+)
+
+// palette colors status text written to one output stream. It is a no-op
+// unless that stream is a terminal, --no-color is unset and NO_COLOR is empty
+// (https://no-color.org).
+type palette struct {
+	enabled bool
+}
+
+func newPalette(command *cli.Command, f *os.File) palette {
+	return palette{
+		enabled: !command.Bool("no-color") &&
+			os.Getenv("NO_COLOR") == "" &&
+			term.IsTerminal(int(f.Fd())),
+	}
+}
+
+// success colors s green.
+func (p palette) success(s string) string {
+	return p.wrap(ansiGreen, s)
+}
+
+// failure colors s red; used for errors and warnings alike.
+func (p palette) failure(s string) string {
+	return p.wrap(ansiRed, s)
+}
+
+func (p palette) wrap(code, s string) string {
+	if !p.enabled {
+		return s
+	}
+	return code + s + ansiReset
+}
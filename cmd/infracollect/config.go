@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileName is the defaults file looked up under the user config
+// directory ($XDG_CONFIG_HOME or ~/.config on Linux) when --config is not
+// given.
+const configFileName = "infracollect/config.yaml"
+
+var configFlag = &cli.StringFlag{
+	Name:    "config",
+	Usage:   "Read flag defaults from this YAML file (default: ~/.config/infracollect/config.yaml when it exists)",
+	Sources: cli.EnvVars("INFRACOLLECT_CONFIG"),
+}
+
+// loadConfigFile reads the flag defaults file: path when set, otherwise
+// configFileName under the user config directory. It returns the path it
+// read along with the defaults. Only a missing default file is ignored,
+// returning nil.
+//
+// Top-level keys are global flag names; a key named after a command holds
+// the defaults for that command's flags:
+//
+//	log-level: debug
+//	collect:
+//	  pass-env: [AWS_PROFILE, AWS_REGION]
+//	  cache-dir: /var/cache/infracollect
+func loadConfigFile(path string) (map[string]any, string, error) {
+	explicit := path != ""
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, "", nil
+		}
+		path = filepath.Join(dir, configFileName)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg map[string]any
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, "", fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return cfg, path, nil
+}
+
+// applyConfigFile uses cfg as the defaults of the global flags and of the
+// flags of the command being run. Flags given on the command line or through
+// their environment variables keep their value. Every key is checked, so a
+// typo in the section of another command is reported too.
+func applyConfigFile(root *cli.Command, cfg map[string]any) error {
+	running := root.Command(root.Args().First())
+
+	for _, key := range slices.Sorted(maps.Keys(cfg)) {
+		value := cfg[key]
+		if cmd := root.Command(key); cmd != nil {
+			section, ok := value.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s: must be a mapping of %s flags", key, key)
+			}
+			for _, name := range slices.Sorted(maps.Keys(section)) {
+				value := section[name]
+				flag := findFlag(cmd, name)
+				if flag == nil {
+					return fmt.Errorf("%s.%s: unknown flag", key, name)
+				}
+				if cmd != running {
+					continue
+				}
+				if err := setFlagDefault(cmd, flag, value); err != nil {
+					return fmt.Errorf("%s.%s: %w", key, name, err)
+				}
+			}
+			continue
+		}
+
+		flag := findFlag(root, key)
+		if flag == nil {
+			return fmt.Errorf("%s: unknown flag or command", key)
+		}
+		if err := setFlagDefault(root, flag, value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// findFlag returns the flag of cmd itself, not of its parents, named name.
+func findFlag(cmd *cli.Command, name string) cli.Flag {
+	for _, flag := range cmd.Flags {
+		if slices.Contains(flag.Names(), name) {
+			return flag
+		}
+	}
+	return nil
+}
+
+// setFlagDefault sets flag to value unless it is already set. A list sets
+// each element in turn, like repeating the flag, and is only accepted by
+// flags that can be repeated.
+func setFlagDefault(cmd *cli.Command, flag cli.Flag, value any) error {
+	if flag.IsSet() {
+		return nil
+	}
+
+	values, ok := value.([]any)
+	if ok {
+		if multi, ok := flag.(cli.DocGenerationMultiValueFlag); !ok || !multi.IsMultiValueFlag() {
+			return fmt.Errorf("must be a single value, not a list")
+		}
+	} else {
+		values = []any{value}
+	}
+	for _, v := range values {
+		switch v.(type) {
+		case map[string]any, []any, nil:
+			return fmt.Errorf("must be a value or a list of values")
+		}
+		if err := cmd.Set(flag.Names()[0], fmt.Sprint(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+// runWithConfig runs a small command tree with cfg applied the way main's
+// Before hook does, and returns the flag values the running command saw.
+func runWithConfig(t *testing.T, cfg map[string]any, args ...string) (map[string]any, error) {
+	t.Helper()
+	got := map[string]any{}
+	record := func(_ context.Context, cmd *cli.Command) error {
+		got["log-level"] = cmd.String("log-level")
+		for _, flag := range cmd.Flags {
+			if name := flag.Names()[0]; name != "help" {
+				got[name] = cmd.Value(name)
+			}
+		}
+		return nil
+	}
+
+	root := &cli.Command{
+		Name: "infracollect",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "log-level", Value: "info"},
+		},
+		Commands: []*cli.Command{
+			{
+				Name: "collect",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "cache-dir", Sources: cli.EnvVars("TEST_INFRACOLLECT_CACHE_DIR")},
+					&cli.StringSliceFlag{Name: "pass-env"},
+				},
+				Action: record,
+			},
+			{
+				Name: "validate",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "format", Value: "text"},
+				},
+				Action: record,
+			},
+		},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			return ctx, applyConfigFile(cmd, cfg)
+		},
+	}
+	err := root.Run(t.Context(), append([]string{"infracollect"}, args...))
+	return got, err
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     map[string]any
+		args    []string
+		env     map[string]string
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name: "global and command defaults",
+			cfg: map[string]any{
+				"log-level": "debug",
+				"collect": map[string]any{
+					"cache-dir": "/var/cache/infracollect",
+					"pass-env":  []any{"AWS_PROFILE", "AWS_REGION"},
+				},
+			},
+			args: []string{"collect"},
+			want: map[string]any{
+				"log-level": "debug",
+				"cache-dir": "/var/cache/infracollect",
+				"pass-env":  []string{"AWS_PROFILE", "AWS_REGION"},
+			},
+		},
+		{
+			name: "command line wins over the config file",
+			cfg: map[string]any{
+				"log-level": "debug",
+				"collect": map[string]any{
+					"cache-dir": "/var/cache/infracollect",
+					"pass-env":  []any{"AWS_PROFILE"},
+				},
+			},
+			args: []string{"--log-level", "warn", "collect", "--cache-dir", "/tmp/cache", "--pass-env", "HOME"},
+			want: map[string]any{
+				"log-level": "warn",
+				"cache-dir": "/tmp/cache",
+				"pass-env":  []string{"HOME"},
+			},
+		},
+		{
+			name: "environment wins over the config file",
+			cfg: map[string]any{
+				"collect": map[string]any{"cache-dir": "/var/cache/infracollect"},
+			},
+			env:  map[string]string{"TEST_INFRACOLLECT_CACHE_DIR": "/from/env"},
+			args: []string{"collect"},
+			want: map[string]any{
+				"log-level": "info",
+				"cache-dir": "/from/env",
+				"pass-env":  []string{},
+			},
+		},
+		{
+			name: "a single value for a repeatable flag",
+			cfg: map[string]any{
+				"collect": map[string]any{"pass-env": "AWS_PROFILE"},
+			},
+			args: []string{"collect"},
+			want: map[string]any{
+				"log-level": "info",
+				"cache-dir": "",
+				"pass-env":  []string{"AWS_PROFILE"},
+			},
+		},
+		{
+			name: "other commands' sections are not applied",
+			cfg: map[string]any{
+				"collect": map[string]any{"cache-dir": "/var/cache/infracollect"},
+				"validate": map[string]any{
+					"format": "json",
+				},
+			},
+			args: []string{"collect"},
+			want: map[string]any{
+				"log-level": "info",
+				"cache-dir": "/var/cache/infracollect",
+				"pass-env":  []string{},
+			},
+		},
+		{
+			name: "the running command's section only",
+			cfg: map[string]any{
+				"collect":  map[string]any{"cache-dir": "/var/cache/infracollect"},
+				"validate": map[string]any{"format": "json"},
+			},
+			args: []string{"validate"},
+			want: map[string]any{
+				"log-level": "info",
+				"format":    "json",
+			},
+		},
+		{
+			name:    "unknown global flag",
+			cfg:     map[string]any{"log-levle": "debug"},
+			args:    []string{"collect"},
+			wantErr: "log-levle: unknown flag or command",
+		},
+		{
+			name: "unknown flag in the running command's section",
+			cfg: map[string]any{
+				"collect": map[string]any{"cache_dir": "/tmp"},
+			},
+			args:    []string{"collect"},
+			wantErr: "collect.cache_dir: unknown flag",
+		},
+		{
+			name: "unknown flag in another command's section",
+			cfg: map[string]any{
+				"validate": map[string]any{"formt": "json"},
+			},
+			args:    []string{"collect"},
+			wantErr: "validate.formt: unknown flag",
+		},
+		{
+			name:    "section that is not a mapping",
+			cfg:     map[string]any{"collect": "yes"},
+			args:    []string{"collect"},
+			wantErr: "collect: must be a mapping of collect flags",
+		},
+		{
+			name:    "list for a single-value flag",
+			cfg:     map[string]any{"log-level": []any{"debug", "info"}},
+			args:    []string{"collect"},
+			wantErr: "log-level: must be a single value, not a list",
+		},
+		{
+			name: "nested list",
+			cfg: map[string]any{
+				"collect": map[string]any{"pass-env": []any{[]any{"HOME"}}},
+			},
+			args:    []string{"collect"},
+			wantErr: "collect.pass-env: must be a value or a list of values",
+		},
+		{
+			name: "mapping for a flag",
+			cfg: map[string]any{
+				"collect": map[string]any{"cache-dir": map[string]any{"path": "/tmp"}},
+			},
+			args:    []string{"collect"},
+			wantErr: "collect.cache-dir: must be a value or a list of values",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			got, err := runWithConfig(t, tt.cfg, tt.args...)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// jsonDiagnostic is one entry of the array printed by validate --format json.
+type jsonDiagnostic struct {
+	Severity string     `json:"severity"`
+	Summary  string     `json:"summary"`
+	Detail   string     `json:"detail,omitempty"`
+	Range    *jsonRange `json:"range,omitempty"`
+}
+
+type jsonRange struct {
+	Filename string  `json:"filename"`
+	Start    jsonPos `json:"start"`
+	End      jsonPos `json:"end"`
+}
+
+type jsonPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// writeJSONDiags writes diags to w as a JSON array, which is empty when there
+// is nothing to report.
+func writeJSONDiags(w io.Writer, diags hcl.Diagnostics) error {
+	out := make([]jsonDiagnostic, 0, len(diags))
+	for _, diag := range diags {
+		d := jsonDiagnostic{
+			Severity: "error",
+			Summary:  diag.Summary,
+			Detail:   diag.Detail,
+		}
+		if diag.Severity == hcl.DiagWarning {
+			d.Severity = "warning"
+		}
+		if diag.Subject != nil {
+			d.Range = &jsonRange{
+				Filename: diag.Subject.Filename,
+				Start:    jsonPos{Line: diag.Subject.Start.Line, Column: diag.Subject.Start.Column},
+				End:      jsonPos{Line: diag.Subject.End.Line, Column: diag.Subject.End.Column},
+			}
+		}
+		out = append(out, d)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+	"go.uber.org/zap"
+)
+
+func TestWriteJSONDiags(t *testing.T) {
+	tests := []struct {
+		name  string
+		diags hcl.Diagnostics
+		want  string
+	}{
+		{name: "nothing to report", diags: nil, want: `[]`},
+		{
+			name: "error with a range",
+			diags: hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Unsupported argument",
+				Detail:   `An argument named "foo" is not expected here.`,
+				Subject: &hcl.Range{
+					Filename: "job.hcl",
+					Start:    hcl.Pos{Line: 3, Column: 3},
+					End:      hcl.Pos{Line: 3, Column: 6},
+				},
+			}},
+			want: `[{
+				"severity": "error",
+				"summary": "Unsupported argument",
+				"detail": "An argument named \"foo\" is not expected here.",
+				"range": {"filename": "job.hcl", "start": {"line": 3, "column": 3}, "end": {"line": 3, "column": 6}}
+			}]`,
+		},
+		{
+			name:  "warning without a range or detail",
+			diags: hcl.Diagnostics{{Severity: hcl.DiagWarning, Summary: "Deprecated"}},
+			want:  `[{"severity": "warning", "summary": "Deprecated"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, writeJSONDiags(&buf, tt.diags))
+			assert.JSONEq(t, tt.want, buf.String())
+		})
+	}
+}
+
+// captureStdout returns what fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	fn()
+	require.NoError(t, w.Close())
+	return string(<-done)
+}
+
+func TestValidate_JSONFormat(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.hcl")
+	require.NoError(t, os.WriteFile(valid, []byte(`step "static" "s" { value = "hi" }`), 0o600))
+	invalid := filepath.Join(dir, "invalid.hcl")
+	require.NoError(t, os.WriteFile(invalid, []byte(`nope = 1`), 0o600))
+
+	tests := []struct {
+		name        string
+		job         string
+		wantErr     string
+		wantSummary string
+	}{
+		{name: "valid job", job: valid},
+		{name: "invalid job", job: invalid, wantErr: "is invalid", wantSummary: "Unsupported argument"},
+		{name: "unreadable job", job: filepath.Join(dir, "missing.hcl"), wantErr: "failed to read job file", wantSummary: "Failed to read job file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := &cli.Command{
+				Name:     "infracollect",
+				Commands: []*cli.Command{validateCommand},
+				Before: func(ctx context.Context, _ *cli.Command) (context.Context, error) {
+					return withQuiet(withLogger(ctx, zap.NewNop()), true), nil
+				},
+			}
+			var err error
+			out := captureStdout(t, func() {
+				err = root.Run(t.Context(), []string{"infracollect", "validate", "--format", "json", tt.job})
+			})
+
+			var diags []jsonDiagnostic
+			require.NoError(t, json.Unmarshal([]byte(out), &diags), "stdout is a JSON array: %s", out)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				assert.Empty(t, diags)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+			require.NotEmpty(t, diags)
+			assert.Equal(t, "error", diags[0].Severity)
+			assert.Equal(t, tt.wantSummary, diags[0].Summary)
+		})
+	}
+}
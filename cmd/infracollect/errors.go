@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/infracollect/infracollect/internal/runner"
+)
+
+// jsonError is the shape printed to stderr by --error-format json.
+type jsonError struct {
+	Error string `json:"error"`
+	Step  string `json:"step,omitempty"`
+	Kind  string `json:"kind,omitempty"`
+}
+
+// writeJSONError writes err to w as a single JSON object, naming the failing
+// step when err came from one.
+func writeJSONError(w io.Writer, err error) error {
+	out := jsonError{Error: err.Error()}
+	var stepErr *runner.StepError
+	if errors.As(err, &stepErr) {
+		out.Step = stepErr.ID
+		out.Kind = stepErr.Kind
+	}
+	return json.NewEncoder(w).Encode(out)
+}
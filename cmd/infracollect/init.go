@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	validation "github.com/urfave/cli-validation"
+	"github.com/urfave/cli/v3"
+)
+
+// defaultJobFilename is the file init writes when no path, or a directory,
+// is given.
+const defaultJobFilename = "job.hcl"
+
+// jobTemplates holds one starter job per --type, named <type>.hcl.
+//
+//go:embed templates/*.hcl
+var jobTemplates embed.FS
+
+var initCommand = &cli.Command{
+	Name:  "init",
+	Usage: "Write a commented starter job file",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:      "type",
+			Value:     "static",
+			Usage:     "Kind of starter job (static, http, terraform)",
+			Validator: validation.Enum("static", "http", "terraform"),
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "Overwrite the file if it already exists",
+		},
+	},
+	Arguments: []cli.Argument{
+		&cli.StringArg{
+			Name:      "path",
+			UsageText: "The job file (or directory) to write, defaults to " + defaultJobFilename,
+		},
+	},
+	Action: func(ctx context.Context, command *cli.Command) error {
+		content, err := jobTemplates.ReadFile("templates/" + command.String("type") + ".hcl")
+		if err != nil {
+			return fmt.Errorf("failed to read %s template: %w", command.String("type"), err)
+		}
+
+		path := command.StringArg("path")
+		if path == "" {
+			path = defaultJobFilename
+		} else if info, err := os.Stat(path); err == nil && info.IsDir() {
+			path = filepath.Join(path, defaultJobFilename)
+		}
+
+		if err := writeNewFile(path, content, command.Bool("force")); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(os.Stdout, "%s %s\n", newPalette(command, os.Stdout).success("Wrote"), path)
+		_, _ = fmt.Fprintf(os.Stdout, "Next: infracollect validate %s && infracollect collect %s\n", path, path)
+		return nil
+	},
+}
+
+// writeNewFile writes content to path, failing if the file exists unless
+// overwrite is set.
+func writeNewFile(path string, content []byte, overwrite bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+
+	f, err := os.OpenFile(path, flags, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	if _, err := f.Write(content); err != nil {
+		return errors.Join(fmt.Errorf("failed to write %s: %w", path, err), f.Close())
+	}
+	return f.Close()
+}
@@ -12,6 +12,10 @@ type loggerCtxKeyType struct{}
 
 var loggerCtxKey = loggerCtxKeyType{}
 
+type quietCtxKeyType struct{}
+
+var quietCtxKey = quietCtxKeyType{}
+
 func createLogger(debug bool, logLevel string, logFormat string) (logger *zap.Logger, level zap.AtomicLevel, err error) {
 	level, err = zap.ParseAtomicLevel(logLevel)
 	if err != nil {
@@ -61,3 +65,14 @@ func getLogger(ctx context.Context) *zap.Logger {
 	}
 	return logger
 }
+
+// withQuiet records --quiet: commands then print nothing on success, leaving
+// errors to the logger and the exit status.
+func withQuiet(ctx context.Context, quiet bool) context.Context {
+	return context.WithValue(ctx, quietCtxKey, quiet)
+}
+
+func isQuiet(ctx context.Context) bool {
+	quiet, ok := ctx.Value(quietCtxKey).(bool)
+	return ok && quiet
+}
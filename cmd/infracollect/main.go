@@ -11,6 +11,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/infracollect/infracollect/internal/buildinfo"
 	validation "github.com/urfave/cli-validation"
 	"github.com/urfave/cli/v3"
 )
@@ -21,13 +22,18 @@ func main() {
 	app := &cli.Command{
 		Name:    "infracollect",
 		Usage:   "Infracollect is a tool to collect infrastructure data",
-		Version: Version,
+		Version: buildinfo.Version,
 		Flags: []cli.Flag{
 			&cli.BoolFlag{
 				Name:    "debug",
 				Aliases: []string{"d"},
 				Usage:   "Enable debug logging",
 			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Usage:   "Log errors only and print no progress, summary or success lines; --debug takes precedence",
+			},
 			&cli.StringFlag{
 				Name:    "log-level",
 				Aliases: []string{"l"},
@@ -47,19 +53,53 @@ func main() {
 				Usage:     "Log format (json, console)",
 				Validator: validation.Enum("json", "console"),
 			},
+			&cli.StringFlag{
+				Name:      "error-format",
+				Value:     "text",
+				Usage:     "Format of the error printed when a command fails (text, json)",
+				Validator: validation.Enum("text", "json"),
+			},
+			&cli.BoolFlag{
+				Name:  "no-color",
+				Usage: "Disable colored output (also disabled by NO_COLOR or when not writing to a terminal)",
+			},
+			configFlag,
 		},
 		Commands: []*cli.Command{
+			initCommand,
 			collectCommand,
 			validateCommand,
+			schemaCommand,
+			terraformCommand,
 			versionCommand,
 		},
 		Before: func(ctx context.Context, command *cli.Command) (context.Context, error) {
-			logger, _, err := createLogger(command.Bool("debug"), command.String("log-level"), command.String("log-format"))
+			// Flag defaults from the config file must be in place before
+			// anything below reads a flag.
+			cfg, cfgPath, err := loadConfigFile(command.String("config"))
+			if err != nil {
+				return nil, err
+			}
+			if err := applyConfigFile(command, cfg); err != nil {
+				return nil, fmt.Errorf("invalid config file %s: %w", cfgPath, err)
+			}
+
+			debug := command.Bool("debug")
+			quiet := command.Bool("quiet") && !debug
+			logLevel := command.String("log-level")
+			if quiet {
+				logLevel = zapcore.ErrorLevel.String()
+			}
+
+			logger, _, err := createLogger(debug, logLevel, command.String("log-format"))
 			if err != nil {
 				return nil, err
 			}
 
-			logger.Debug("logger created", zap.String("log_level", command.String("log-level")))
+			if debug && command.Bool("quiet") {
+				logger.Warn("--quiet is ignored with --debug")
+			}
+			logger.Debug("logger created", zap.String("log_level", logLevel))
 
 			loggerDeferFunc = func() error {
 				return logger.Sync()
@@ -67,6 +107,7 @@ func main() {
 
 			ctx = withLogger(ctx, logger)
 			ctx = withInteractive(ctx, isInteractiveEnvironment())
+			ctx = withQuiet(ctx, quiet)
 
 			return ctx, nil
 		},
@@ -75,6 +116,16 @@ func main() {
 				return
 			}
 
+			if command.String("error-format") == "json" {
+				if loggerDeferFunc != nil {
+					_ = loggerDeferFunc()
+				}
+				if writeErr := writeJSONError(os.Stderr, err); writeErr != nil {
+					log.Fatal(err.Error())
+				}
+				os.Exit(1)
+			}
+
 			if logger := tryLogger(ctx); logger != nil {
 				logger.Fatal(err.Error())
 			} else {
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+const ociJobPrefix = "oci://"
+
+func isOCIJob(jobFilename string) bool {
+	return strings.HasPrefix(jobFilename, ociJobPrefix)
+}
+
+// readOCIJob pulls a job file stored as an OCI artifact, e.g. one pushed with
+// `oras push registry.example.com/jobs/inventory:v1 job.hcl`. Registry
+// credentials come from the docker configuration, including its credential
+// helpers, the same way `docker pull` finds them.
+func readOCIJob(ctx context.Context, jobFilename string) ([]byte, error) {
+	repo, err := remote.NewRepository(strings.TrimPrefix(jobFilename, ociJobPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCI reference '%s': %w", jobFilename, err)
+	}
+	if repo.Reference.Reference == "" {
+		return nil, fmt.Errorf("OCI reference '%s' must include a tag or digest", jobFilename)
+	}
+
+	store, err := credentials.NewStoreFromDocker(credentials.StoreOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load docker credentials: %w", err)
+	}
+	repo.Client = &auth.Client{
+		Client:     retry.DefaultClient,
+		Cache:      auth.NewCache(),
+		Credential: credentials.Credential(store),
+	}
+
+	return fetchOCIJob(ctx, repo, repo.Reference.Reference)
+}
+
+// fetchOCIJob fetches the manifest at reference and returns the content of
+// its job file layer, checked against the layer's digest.
+func fetchOCIJob(ctx context.Context, target oras.ReadOnlyTarget, reference string) ([]byte, error) {
+	desc, manifestBytes, err := oras.FetchBytes(ctx, target, reference, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	if desc.MediaType != ocispec.MediaTypeImageManifest {
+		return nil, fmt.Errorf("expected an OCI image manifest, got media type %q", desc.MediaType)
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	layer, err := jobLayer(manifest.Layers)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := content.FetchAll(ctx, target, layer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch layer %s: %w", layer.Digest, err)
+	}
+	return body, nil
+}
+
+// orasUnpackAnnotation marks a layer `oras push` made by archiving a
+// directory.
+const orasUnpackAnnotation = "io.deis.oras.content.unpack"
+
+// jobLayer picks the layer holding the job file: the only layer, or else the
+// only one whose title ends in ".hcl". The layer must hold the file itself,
+// not a compressed archive such as the one `oras push` makes of a directory.
+func jobLayer(layers []ocispec.Descriptor) (ocispec.Descriptor, error) {
+	layer, err := selectJobLayer(layers)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if layer.Annotations[orasUnpackAnnotation] == "true" ||
+		strings.HasSuffix(layer.MediaType, "+gzip") ||
+		strings.HasSuffix(layer.MediaType, "+zstd") {
+		return ocispec.Descriptor{}, fmt.Errorf("layer %s is an archive (media type %q); push the job file itself, not a directory", layer.Digest, layer.MediaType)
+	}
+	return layer, nil
+}
+
+func selectJobLayer(layers []ocispec.Descriptor) (ocispec.Descriptor, error) {
+	switch len(layers) {
+	case 0:
+		return ocispec.Descriptor{}, fmt.Errorf("artifact has no layers")
+	case 1:
+		return layers[0], nil
+	}
+
+	var candidates []ocispec.Descriptor
+	for _, layer := range layers {
+		if strings.HasSuffix(layer.Annotations[ocispec.AnnotationTitle], ".hcl") {
+			candidates = append(candidates, layer)
+		}
+	}
+	switch len(candidates) {
+	case 1:
+		return candidates[0], nil
+	case 0:
+		return ocispec.Descriptor{}, fmt.Errorf("artifact has %d layers and none is titled *.hcl", len(layers))
+	default:
+		return ocispec.Descriptor{}, fmt.Errorf("artifact has %d layers titled *.hcl, expected exactly one", len(candidates))
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/memory"
+)
+
+const ociJobArtifactType = "application/vnd.infracollect.job"
+
+// pushBlob stores data in store and returns its descriptor, titled title
+// when it is not empty.
+func pushBlob(t *testing.T, store *memory.Store, mediaType, title string, data []byte) ocispec.Descriptor {
+	t.Helper()
+	desc := content.NewDescriptorFromBytes(mediaType, data)
+	require.NoError(t, store.Push(t.Context(), desc, bytes.NewReader(data)))
+	if title != "" {
+		desc.Annotations = map[string]string{ocispec.AnnotationTitle: title}
+	}
+	return desc
+}
+
+// tagManifest packs layers into a manifest tagged tag.
+func tagManifest(t *testing.T, store *memory.Store, tag string, layers ...ocispec.Descriptor) {
+	t.Helper()
+	desc, err := oras.PackManifest(t.Context(), store, oras.PackManifestVersion1_1, ociJobArtifactType, oras.PackManifestOptions{Layers: layers})
+	require.NoError(t, err)
+	require.NoError(t, store.Tag(t.Context(), desc, tag))
+}
+
+func TestFetchOCIJob(t *testing.T) {
+	store := memory.New()
+	job := []byte(`step "static" "s" { value = "hi" }`)
+
+	single := pushBlob(t, store, "application/vnd.oci.image.layer.v1.tar", "job.hcl", job)
+	tagManifest(t, store, "single", single)
+
+	readme := pushBlob(t, store, "text/markdown", "README.md", []byte("# Inventory"))
+	tagManifest(t, store, "with-readme", readme, single)
+
+	index, err := json.Marshal(ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: []ocispec.Descriptor{},
+	})
+	require.NoError(t, err)
+	indexDesc := content.NewDescriptorFromBytes(ocispec.MediaTypeImageIndex, index)
+	require.NoError(t, store.Push(t.Context(), indexDesc, bytes.NewReader(index)))
+	require.NoError(t, store.Tag(t.Context(), indexDesc, "index"))
+
+	tests := []struct {
+		name      string
+		reference string
+		want      []byte
+		wantErr   string
+	}{
+		{name: "single layer", reference: "single", want: job},
+		{name: "the layer titled *.hcl", reference: "with-readme", want: job},
+		{name: "missing tag", reference: "missing", wantErr: "failed to fetch manifest"},
+		{name: "index instead of a manifest", reference: "index", wantErr: `expected an OCI image manifest, got media type "application/vnd.oci.image.index.v1+json"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetchOCIJob(t.Context(), store, tt.reference)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFetchOCIJob_LayerDigestMismatch(t *testing.T) {
+	store := memory.New()
+	layer := pushBlob(t, store, "application/vnd.oci.image.layer.v1.tar", "job.hcl", []byte("job"))
+	// A manifest claiming a larger layer than the one stored fails the
+	// content check instead of returning the blob.
+	layer.Size++
+	tagManifest(t, store, "v1", layer)
+
+	_, err := fetchOCIJob(t.Context(), store, "v1")
+	assert.Error(t, err)
+}
+
+func TestJobLayer(t *testing.T) {
+	layer := func(mediaType, title string) ocispec.Descriptor {
+		desc := content.NewDescriptorFromBytes(mediaType, []byte(title))
+		if title != "" {
+			desc.Annotations = map[string]string{ocispec.AnnotationTitle: title}
+		}
+		return desc
+	}
+	const plain = "application/vnd.oci.image.layer.v1.tar"
+
+	job := layer(plain, "job.hcl")
+	tests := []struct {
+		name    string
+		layers  []ocispec.Descriptor
+		want    ocispec.Descriptor
+		wantErr string
+	}{
+		{name: "only layer, whatever its title", layers: []ocispec.Descriptor{layer(plain, "inventory")}, want: layer(plain, "inventory")},
+		{name: "the one layer titled *.hcl", layers: []ocispec.Descriptor{layer("text/markdown", "README.md"), job}, want: job},
+		{name: "no layers", wantErr: "artifact has no layers"},
+		{name: "no layer titled *.hcl", layers: []ocispec.Descriptor{layer(plain, "a.txt"), layer(plain, "b.txt")}, wantErr: "artifact has 2 layers and none is titled *.hcl"},
+		{name: "several layers titled *.hcl", layers: []ocispec.Descriptor{job, layer(plain, "other.hcl")}, wantErr: "artifact has 2 layers titled *.hcl, expected exactly one"},
+		{name: "gzipped layer", layers: []ocispec.Descriptor{layer(ocispec.MediaTypeImageLayerGzip, "jobs")}, wantErr: `is an archive (media type "application/vnd.oci.image.layer.v1.tar+gzip")`},
+		{name: "zstd layer", layers: []ocispec.Descriptor{layer(ocispec.MediaTypeImageLayerZstd, "job.hcl")}, wantErr: "is an archive"},
+		{
+			name: "directory pushed by oras",
+			layers: []ocispec.Descriptor{func() ocispec.Descriptor {
+				d := layer(plain, "jobs")
+				d.Annotations[orasUnpackAnnotation] = "true"
+				return d
+			}()},
+			wantErr: "push the job file itself, not a directory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jobLayer(tt.layers)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReadOCIJob_InvalidReference(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		wantErr string
+	}{
+		{name: "no tag or digest", ref: "oci://registry.example.com/jobs/inventory", wantErr: "must include a tag or digest"},
+		{name: "malformed", ref: "oci://registry.example.com/Jobs:v1", wantErr: "invalid OCI reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readOCIJob(t.Context(), tt.ref)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
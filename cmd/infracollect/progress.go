@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/infracollect/infracollect/internal/runner"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/term"
+)
+
+// showProgress reports whether collect should print step progress: only for
+// interactive runs whose stderr is a terminal, not with --quiet, and not when
+// debug logging already narrates every step.
+func showProgress(ctx context.Context, logger *zap.Logger) bool {
+	return isInteractive(ctx) &&
+		!isQuiet(ctx) &&
+		term.IsTerminal(int(os.Stderr.Fd())) &&
+		!logger.Core().Enabled(zapcore.DebugLevel)
+}
+
+// progressReporter prints one `[3/10] resolving http_get(users)...` line per
+// step as the runner starts it.
+func progressReporter(w io.Writer) runner.ProgressFunc {
+	var mu sync.Mutex
+	return func(ev runner.ProgressEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = fmt.Fprintf(w, "[%d/%d] resolving %s(%s)...\n", ev.Index, ev.Total, ev.Step.Type, ev.Step.ID)
+	}
+}
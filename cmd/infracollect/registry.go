@@ -5,17 +5,22 @@ import (
 
 	"github.com/infracollect/infracollect/internal/engine"
 	"github.com/infracollect/infracollect/internal/engine/steps"
+	"github.com/infracollect/infracollect/internal/integrations/aws"
 	"github.com/infracollect/infracollect/internal/integrations/http"
+	"github.com/infracollect/infracollect/internal/integrations/ssh"
 	"github.com/infracollect/infracollect/internal/integrations/terraform"
 	"go.uber.org/zap"
 )
 
 // buildRegistry wires up the default set of collectors and steps. It is the
 // single place the CLI constructs an engine.Registry — both `collect` and
-// `validate` share it so their surface areas never drift.
-func buildRegistry(logger *zap.Logger, allowedEnv []string) (*engine.Registry, error) {
+// `validate` share it so their surface areas never drift. An empty
+// tfPluginCache keeps the terraform client's default plugin cache.
+func buildRegistry(logger *zap.Logger, allowedEnv, allowedPaths []string, tfPluginCache string) (*engine.Registry, error) {
 	registry := engine.NewRegistry(logger)
 	registry.RegisterDependency(engine.AllowedEnvVarsDepKey, allowedEnv)
+	registry.RegisterDependency(engine.AllowedPathsDepKey, allowedPaths)
+	registry.RegisterDependency(terraform.PluginCacheDirDepKey, tfPluginCache)
 
 	if err := terraform.Register(registry); err != nil {
 		return nil, fmt.Errorf("register terraform integration: %w", err)
@@ -23,6 +28,12 @@ func buildRegistry(logger *zap.Logger, allowedEnv []string) (*engine.Registry, e
 	if err := http.Register(registry); err != nil {
 		return nil, fmt.Errorf("register http integration: %w", err)
 	}
+	if err := aws.Register(registry); err != nil {
+		return nil, fmt.Errorf("register aws integration: %w", err)
+	}
+	if err := ssh.Register(registry); err != nil {
+		return nil, fmt.Errorf("register ssh integration: %w", err)
+	}
 	if err := steps.Register(registry); err != nil {
 		return nil, fmt.Errorf("register builtin steps: %w", err)
 	}
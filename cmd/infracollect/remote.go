@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+var remoteUserFlag = &cli.StringFlag{
+	Name:    "remote-user",
+	Usage:   "User name sent with HTTP basic auth when fetching job files from http(s) URLs",
+	Sources: cli.EnvVars("INFRACOLLECT_REMOTE_USER"),
+}
+
+var remotePasswordFlag = &cli.StringFlag{
+	Name:    "remote-password",
+	Usage:   "Password sent with HTTP basic auth when fetching job files from http(s) URLs; prefer the environment variable to keep it out of shell history",
+	Sources: cli.EnvVars("INFRACOLLECT_REMOTE_PASSWORD"),
+}
+
+var remoteHeaderFlag = &cli.StringSliceFlag{
+	Name:  "remote-header",
+	Usage: "Header sent when fetching job files from http(s) URLs (\"Name: value\", can be repeated)",
+}
+
+// remoteAuth holds the credentials applied to http(s) job file requests.
+// Its values are secrets: they are never logged, and errors about them
+// name the flag or header, not the value.
+type remoteAuth struct {
+	user     string
+	password string
+	headers  http.Header
+}
+
+func remoteAuthFromCommand(command *cli.Command) (remoteAuth, error) {
+	auth := remoteAuth{
+		user:     command.String("remote-user"),
+		password: command.String("remote-password"),
+		headers:  make(http.Header),
+	}
+	if auth.password != "" && auth.user == "" {
+		return remoteAuth{}, fmt.Errorf("--remote-password requires --remote-user")
+	}
+	for i, header := range command.StringSlice("remote-header") {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return remoteAuth{}, fmt.Errorf("--remote-header %d must have the form \"Name: value\"", i+1)
+		}
+		auth.headers.Add(name, strings.TrimSpace(value))
+	}
+	return auth, nil
+}
+
+// apply sets the headers and basic auth on req. Basic auth is applied last,
+// so it wins over an Authorization --remote-header.
+func (a remoteAuth) apply(req *http.Request) {
+	for name, values := range a.headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if a.user != "" {
+		req.SetBasicAuth(a.user, a.password)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/infracollect/infracollect/internal/runner"
+	"github.com/urfave/cli/v3"
+)
+
+var schemaCommand = &cli.Command{
+	Name:  "schema",
+	Usage: "Print the JSON Schema describing collect job files",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "Write the schema to this file instead of stdout",
+		},
+	},
+	Action: func(ctx context.Context, command *cli.Command) error {
+		logger := getLogger(ctx)
+
+		registry, err := buildRegistry(logger.Named("registry"), nil, nil, "")
+		if err != nil {
+			return fmt.Errorf("failed to build registry: %w", err)
+		}
+
+		data, err := json.MarshalIndent(runner.JobJSONSchema(registry), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode schema: %w", err)
+		}
+		data = append(data, '\n')
+
+		if path := command.String("output"); path != "" {
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				return fmt.Errorf("failed to write schema to '%s': %w", path, err)
+			}
+			return nil
+		}
+
+		_, err = os.Stdout.Write(data)
+		return err
+	},
+}
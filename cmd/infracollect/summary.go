@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/infracollect/infracollect/internal/runner"
+)
+
+// runSummary is the document --summary writes: one entry per job file, in
+// argument order.
+type runSummary struct {
+	Jobs []jobSummary `json:"jobs"`
+}
+
+// jobSummary carries the job's overall status, which also covers jobs that
+// never produced a run report (a parse error, or skipped after --fail-fast).
+type jobSummary struct {
+	File   string            `json:"file"`
+	Status string            `json:"status"`
+	Error  string            `json:"error,omitempty"`
+	Report *runner.RunReport `json:"report"`
+}
+
+// writeRunSummary writes the --summary file. It is a no-op when path is
+// empty.
+func writeRunSummary(path string, outcomes []jobOutcome, reports map[string]*runner.RunReport) error {
+	if path == "" {
+		return nil
+	}
+
+	summary := runSummary{Jobs: make([]jobSummary, 0, len(outcomes))}
+	for _, o := range outcomes {
+		job := jobSummary{
+			File:   o.filename,
+			Status: runner.ReportStatusSucceeded,
+			Report: reports[o.filename],
+		}
+		switch {
+		case o.skipped:
+			job.Status = runner.ReportStatusSkipped
+		case o.err != nil:
+			job.Status = runner.ReportStatusFailed
+			job.Error = o.err.Error()
+		}
+		summary.Jobs = append(summary.Jobs, job)
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run summary: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write run summary to '%s': %w", path, err)
+	}
+	return nil
+}
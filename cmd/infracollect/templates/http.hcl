@@ -0,0 +1,32 @@
+# A starter infracollect job that calls an HTTP API. Validate it with
+# `infracollect validate job.hcl` and run it with `infracollect collect job.hcl`.
+#
+# Reference: https://infracollect.github.io/infracollect/reference/collectors/http/
+
+job {
+  name = "starter"
+}
+
+# The collector holds the connection settings shared by its steps. Pass
+# secrets with `--pass-env TOKEN` and reference them as env.TOKEN, e.g.
+#   headers = { Authorization = "Bearer ${env.TOKEN}" }
+collector "http" "api" {
+  base_url = "https://api.github.com"
+  timeout  = "30s"
+}
+
+step "http_get" "repo" {
+  collector = collector.http.api
+  path      = "/repos/infracollect/infracollect"
+}
+
+output {
+  encoding "json" {
+    indent = "  "
+  }
+
+  # Results are written to ./output/<step type>/<step id>.json.
+  sink "filesystem" {
+    path = "./output"
+  }
+}
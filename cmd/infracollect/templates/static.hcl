@@ -0,0 +1,26 @@
+# A starter infracollect job. Validate it with `infracollect validate job.hcl`
+# and run it with `infracollect collect job.hcl`.
+#
+# Reference: https://infracollect.github.io/infracollect/reference/job-structure/
+
+job {
+  name = "starter"
+}
+
+# Static steps need no collector. `value` embeds content inline; use
+# `filepath = "./data.json"` to read a file next to the job instead.
+step "static" "greeting" {
+  value    = "{\"message\": \"hello from infracollect\"}"
+  parse_as = "json"
+}
+
+output {
+  encoding "json" {
+    indent = "  "
+  }
+
+  # Results are written to ./output/<step type>/<step id>.json.
+  sink "filesystem" {
+    path = "./output"
+  }
+}
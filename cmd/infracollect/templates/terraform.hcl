@@ -0,0 +1,38 @@
+# A starter infracollect job that reads Terraform provider data sources.
+# Validate it with `infracollect validate job.hcl` and run it with
+# `infracollect collect job.hcl`. The provider is downloaded on first run.
+#
+# Reference: https://infracollect.github.io/infracollect/reference/collectors/terraform/
+
+job {
+  name = "starter"
+}
+
+# Any attribute besides provider and version is passed to the provider as
+# its configuration. List the data sources a provider offers with
+# `infracollect terraform datasources hashicorp/kubernetes`.
+collector "terraform" "k8s" {
+  provider    = "hashicorp/kubernetes"
+  version     = "~> 2.0"
+  config_path = "~/.kube/config"
+}
+
+step "terraform_datasource" "deployments" {
+  collector = collector.terraform.k8s
+  datasource "kubernetes_resources" {
+    api_version = "apps/v1"
+    kind        = "Deployment"
+    namespace   = "kube-system"
+  }
+}
+
+output {
+  encoding "json" {
+    indent = "  "
+  }
+
+  # Results are written to ./output/<step type>/<step id>.json.
+  sink "filesystem" {
+    path = "./output"
+  }
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/zapr"
+	"github.com/infracollect/infracollect/internal/integrations/terraform"
+	tfclient "github.com/infracollect/tf-data-client"
+	"github.com/urfave/cli/v3"
+)
+
+var tfPluginCacheFlag = &cli.StringFlag{
+	Name:    "tf-plugin-cache",
+	Usage:   "Download terraform provider plugins to this directory and reuse them across runs; it must be writable",
+	Sources: cli.EnvVars("INFRACOLLECT_TF_PLUGIN_CACHE"),
+}
+
+var terraformCommand = &cli.Command{
+	Name:  "terraform",
+	Usage: "Inspect terraform providers",
+	Commands: []*cli.Command{
+		terraformDataSourcesCommand,
+	},
+}
+
+var terraformDataSourcesCommand = &cli.Command{
+	Name:    "datasources",
+	Aliases: []string{"list-datasources"},
+	Usage:   "List the data sources a terraform provider offers",
+	UsageText: "infracollect terraform datasources <provider> [version]\n\n" +
+		"e.g. infracollect terraform datasources hashicorp/kubernetes 2.35.1",
+	Flags: []cli.Flag{
+		tfPluginCacheFlag,
+	},
+	Arguments: []cli.Argument{
+		&cli.StringArg{
+			Name:      "provider",
+			UsageText: "<provider>",
+		},
+		&cli.StringArg{
+			Name:      "version",
+			UsageText: "[version]",
+		},
+	},
+	Action: func(ctx context.Context, command *cli.Command) error {
+		logger := getLogger(ctx)
+
+		source := command.StringArg("provider")
+		if source == "" {
+			return fmt.Errorf("no provider provided")
+		}
+
+		opts := []tfclient.Option{tfclient.WithLogger(zapr.NewLogger(logger.Named("terraform")))}
+		if dir := command.String("tf-plugin-cache"); dir != "" {
+			opts = append(opts, tfclient.WithCacheDir(dir))
+		}
+		client, err := tfclient.New(opts...)
+		if err != nil {
+			return fmt.Errorf("failed to create terraform client: %w", err)
+		}
+		defer func() { _ = client.Close() }()
+
+		names, err := terraform.ListDataSources(ctx, client, source, command.StringArg("version"))
+		if err != nil {
+			return fmt.Errorf("failed to list data sources of '%s': %w", source, err)
+		}
+
+		for _, name := range names {
+			_, _ = fmt.Fprintln(os.Stdout, name)
+		}
+		return nil
+	},
+}
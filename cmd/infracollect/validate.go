@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/hashicorp/hcl/v2"
 	"github.com/infracollect/infracollect/internal/runner"
+	validation "github.com/urfave/cli-validation"
 	"github.com/urfave/cli/v3"
 	"go.uber.org/zap"
 )
@@ -18,6 +20,27 @@ var validateCommand = &cli.Command{
 			Name:  "pass-env",
 			Usage: "Environment variables to pass through to job execution (can be repeated)",
 		},
+		&cli.StringSliceFlag{
+			Name:  "allow-path",
+			Usage: "Directory static steps with allow_absolute may read absolute paths from (can be repeated)",
+		},
+		strictEnvFlag,
+		overlayFlag,
+		jobVarFlag,
+		printVarsFlag,
+		remoteUserFlag,
+		remotePasswordFlag,
+		remoteHeaderFlag,
+		&cli.BoolFlag{
+			Name:  "check-connectivity",
+			Usage: "Also check collectors over the network, such as base URL reachability and provider lookup",
+		},
+		&cli.StringFlag{
+			Name:      "format",
+			Value:     "text",
+			Usage:     "Output format (text, json); json prints the diagnostics as a JSON array on stdout",
+			Validator: validation.Enum("text", "json"),
+		},
 	},
 	Arguments: []cli.Argument{
 		&cli.StringArg{
@@ -36,28 +59,80 @@ var validateCommand = &cli.Command{
 		logger = logger.With(zap.String("job_filename", jobFilename))
 		logger.Debug("validating job file")
 
-		jobFile, _, err := readJobFile(ctx, jobFilename)
+		jsonOutput := command.String("format") == "json"
+		report := func(diags hcl.Diagnostics) {
+			if jsonOutput {
+				_ = writeJSONDiags(os.Stdout, diags)
+				return
+			}
+			writeDiags(command, diags)
+		}
+
+		auth, err := remoteAuthFromCommand(command)
+		if err != nil {
+			return err
+		}
+		jobFile, isRemote, err := readJobFile(ctx, jobFilename, auth)
 		if err != nil {
-			return fmt.Errorf("failed to read job file '%s': %w", jobFilename, err)
+			err = fmt.Errorf("failed to read job file '%s': %w", jobFilename, err)
+			// Tools reading --format json expect the array on stdout even
+			// when there is no file to parse.
+			if jsonOutput {
+				report(hcl.Diagnostics{{
+					Severity: hcl.DiagError,
+					Summary:  "Failed to read job file",
+					Detail:   err.Error(),
+				}})
+			}
+			return err
 		}
 
-		tmpl, diags := runner.ParseJobTemplate(jobFile, jobFilename)
+		parseOpts, err := parseOptions(command, isRemote)
+		if err != nil {
+			return err
+		}
+		tmpl, diags := runner.ParseJobTemplate(jobFile, jobFilename, parseOpts...)
 		if diags.HasErrors() {
-			writeDiags(diags)
+			report(diags)
 			return fmt.Errorf("job file '%s' is invalid", jobFilename)
 		}
 
 		allowedEnv := command.StringSlice("pass-env")
-		registry, err := buildRegistry(logger.Named("registry"), allowedEnv)
+		jobVars, err := parseJobVars(command.StringSlice("job-var"))
+		if err != nil {
+			return err
+		}
+		if command.Bool("print-vars") {
+			if err := printVars(tmpl, jobFilename, allowedEnv, jobVars); err != nil {
+				return err
+			}
+		}
+
+		registry, err := buildRegistry(logger.Named("registry"), allowedEnv, command.StringSlice("allow-path"), "")
 		if err != nil {
 			return fmt.Errorf("failed to build registry: %w", err)
 		}
-		if _, diags := runner.New(logger.Named("runner"), tmpl, registry, allowedEnv); diags.HasErrors() {
-			writeDiags(diags)
+		r, runnerDiags := runner.New(logger.Named("runner"), tmpl, registry, allowedEnv,
+			runner.WithJobVars(jobVars),
+		)
+		diags = append(diags, runnerDiags...)
+		if command.Bool("strict-env") {
+			diags = append(diags, runner.ValidateStrictEnv([]*runner.JobTemplate{tmpl}, allowedEnv)...)
+		}
+		if !diags.HasErrors() && command.Bool("check-connectivity") {
+			diags = append(diags, r.ValidateCollectors(ctx)...)
+		}
+		if diags.HasErrors() {
+			report(diags)
 			return fmt.Errorf("job file '%s' is invalid", jobFilename)
 		}
 
-		_, _ = fmt.Fprintf(os.Stdout, "OK %s (job: %s)\n", jobFilename, tmpl.JobName())
+		if jsonOutput {
+			return writeJSONDiags(os.Stdout, diags)
+		}
+		if !isQuiet(ctx) {
+			_, _ = fmt.Fprintf(os.Stdout, "%s %s (job: %s)\n", newPalette(command, os.Stdout).success("OK"), jobFilename, tmpl.JobName())
+		}
 		return nil
 	},
 }
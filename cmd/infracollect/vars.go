@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/infracollect/infracollect/internal/runner"
+	"github.com/urfave/cli/v3"
+)
+
+var printVarsFlag = &cli.BoolFlag{
+	Name:  "print-vars",
+	Usage: "Print the variables and functions available to job expressions before running (secret-looking env values are redacted)",
+}
+
+var strictEnvFlag = &cli.BoolFlag{
+	Name:  "strict-env",
+	Usage: "Fail when a variable passed with --pass-env is never used by the job, which usually means a typo",
+}
+
+var jobVarFlag = &cli.StringSliceFlag{
+	Name:  "job-var",
+	Usage: "Set env.KEY to VALUE in job expressions, overriding the environment (KEY=VALUE, can be repeated)",
+}
+
+// parseJobVars splits each KEY=VALUE pair on its first "=", so values may
+// contain "=" themselves. Keys must be valid HCL identifiers so they can be
+// referenced as env.KEY.
+func parseJobVars(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --job-var %q: expected KEY=VALUE", pair)
+		}
+		if !hclsyntax.ValidIdentifier(key) {
+			return nil, fmt.Errorf("invalid --job-var %q: %q is not a valid variable name", pair, key)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// printVars writes the variables of the job's base evaluation context to
+// stderr, so it never mixes with results on a stdout sink. The block is
+// written at once so concurrent jobs do not interleave.
+func printVars(tmpl *runner.JobTemplate, jobFilename string, allowedEnv []string, jobVars map[string]string) error {
+	evalCtx, err := runner.BuildBaseEvalContext(tmpl, allowedEnv, jobVars)
+	if err != nil {
+		return fmt.Errorf("failed to build variables for job '%s': %w", jobFilename, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Variables for %s (job: %s):\n", jobFilename, tmpl.JobName())
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	for _, v := range runner.ListVariables(evalCtx) {
+		value := strconv.Quote(v.Value)
+		if v.Sensitive {
+			value = "(redacted)"
+		}
+		if v.Builtin {
+			value += "  (built-in)"
+		}
+		fmt.Fprintf(tw, "  %s\t= %s\n", v.Name, value)
+	}
+	_ = tw.Flush()
+	if len(allowedEnv) == 0 && len(jobVars) == 0 {
+		fmt.Fprintln(&buf, "  (no env.* variables: pass them with --pass-env)")
+	}
+	fmt.Fprintf(&buf, "Functions: %s\n", strings.Join(runner.FunctionNames(evalCtx), ", "))
+
+	_, err = os.Stderr.Write(buf.Bytes())
+	return err
+}
@@ -2,61 +2,43 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"runtime/debug"
+	"os"
 
+	"github.com/infracollect/infracollect/internal/buildinfo"
 	"github.com/urfave/cli/v3"
 )
 
-// Build information populated at init() from debug.ReadBuildInfo().
-var (
-	Version   = "unknown"
-	GoVersion = "unknown"
-	Commit    = "unknown"
-	BuildTime = "unknown"
-	Modified  bool
-)
-
-func init() {
-	parseBuildInfo()
-}
-
-func parseBuildInfo() {
-	info, ok := debug.ReadBuildInfo()
-	if !ok {
-		return
-	}
-
-	Version = info.Main.Version
-	GoVersion = info.GoVersion
-
-	for _, setting := range info.Settings {
-		switch setting.Key {
-		case "vcs.revision":
-			Commit = setting.Value
-		case "vcs.time":
-			BuildTime = setting.Value
-		case "vcs.modified":
-			Modified = setting.Value == "true"
-		}
-	}
-}
-
 var versionCommand = &cli.Command{
 	Name:  "version",
 	Usage: "Print version information",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print version information as JSON",
+		},
+	},
 	Action: func(ctx context.Context, command *cli.Command) error {
-		fmt.Printf("version: %s\n", Version)
-		fmt.Printf("go: %s\n", GoVersion)
-		if Commit != "unknown" {
-			if Modified {
-				fmt.Printf("commit: %s (dirty)\n", Commit)
+		info := buildinfo.Get()
+
+		if command.Bool("json") {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(info)
+		}
+
+		fmt.Printf("version: %s\n", info.Version)
+		fmt.Printf("go: %s\n", info.GoVersion)
+		if info.Commit != "" {
+			if info.Modified {
+				fmt.Printf("commit: %s (dirty)\n", info.Commit)
 			} else {
-				fmt.Printf("commit: %s\n", Commit)
+				fmt.Printf("commit: %s\n", info.Commit)
 			}
 		}
-		if BuildTime != "unknown" {
-			fmt.Printf("built: %s\n", BuildTime)
+		if info.BuildTime != "" {
+			fmt.Printf("built: %s\n", info.BuildTime)
 		}
 		return nil
 	},
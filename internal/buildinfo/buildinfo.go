@@ -0,0 +1,87 @@
+// Package buildinfo describes the running infracollect binary.
+//
+// Release builds stamp the version, commit and build time with -ldflags:
+//
+//	go build -ldflags "\
+//	  -X github.com/infracollect/infracollect/internal/buildinfo.Version=v1.2.0 \
+//	  -X github.com/infracollect/infracollect/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/infracollect/infracollect/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
+//	  ./cmd/infracollect
+//
+// Values that are not stamped fall back to the module and VCS information
+// the Go toolchain embeds (debug.ReadBuildInfo).
+package buildinfo
+
+import (
+	"runtime/debug"
+)
+
+const unknown = "unknown"
+
+var (
+	Version   = unknown
+	Commit    = unknown
+	BuildTime = unknown
+	GoVersion = unknown
+	Modified  bool
+)
+
+// Info is a snapshot of the build metadata, shaped for `version --json`.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+	Modified  bool   `json:"modified"`
+}
+
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+
+	GoVersion = info.GoVersion
+	if Version == unknown && info.Main.Version != "" {
+		Version = info.Main.Version
+	}
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if Commit == unknown {
+				Commit = setting.Value
+			}
+		case "vcs.time":
+			if BuildTime == unknown {
+				BuildTime = setting.Value
+			}
+		case "vcs.modified":
+			Modified = setting.Value == "true"
+		}
+	}
+}
+
+// Get returns the build metadata. Commit and BuildTime are empty when
+// neither stamped nor recorded by the toolchain.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: GoVersion,
+		Modified:  Modified,
+	}
+	if info.Commit == unknown {
+		info.Commit = ""
+	}
+	if info.BuildTime == unknown {
+		info.BuildTime = ""
+	}
+	return info
+}
+
+// UserAgent is the User-Agent infracollect sends on outgoing requests.
+func UserAgent() string {
+	return "infracollect/" + Version
+}
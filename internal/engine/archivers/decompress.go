@@ -0,0 +1,45 @@
+package archivers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// NewDecompressor detects the compression of r from its leading magic bytes
+// and returns a reader over the decompressed stream together with the
+// detected type. Input that is neither gzip nor zstd is passed through as
+// CompressionNone.
+func NewDecompressor(r io.Reader) (io.ReadCloser, CompressionType, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, "", fmt.Errorf("failed to read archive header: %w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		return gr, CompressionGzip, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create zstd reader: %w", err)
+		}
+		return zr.IOReadCloser(), CompressionZstd, nil
+	default:
+		return io.NopCloser(br), CompressionNone, nil
+	}
+}
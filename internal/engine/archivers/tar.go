@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/infracollect/infracollect/internal/engine"
 	"github.com/klauspost/compress/zstd"
@@ -21,23 +22,62 @@ const (
 	CompressionNone CompressionType = "none"
 )
 
+// Compression level bounds accepted by NewTarArchiver. zstd levels follow the
+// reference zstd command line and are mapped onto the closest encoder preset.
+const (
+	MinGzipLevel = gzip.BestSpeed
+	MaxGzipLevel = gzip.BestCompression
+	MinZstdLevel = 1
+	MaxZstdLevel = 22
+)
+
+// DefaultEntryMode is the permission bits of every archive entry unless
+// WithEntryMode sets others.
+const DefaultEntryMode = 0o644
+
 // TarArchiver creates tar archives with optional compression.
 type TarArchiver struct {
 	buf         *bytes.Buffer
 	compressor  io.WriteCloser
 	tarWriter   *tar.Writer
 	compression CompressionType
+	mode        int64
+	modTime     time.Time
 	closed      bool
 }
 
+// TarArchiverOption configures a TarArchiver.
+type TarArchiverOption func(*TarArchiver)
+
+// WithEntryMode sets the permission bits of every entry.
+func WithEntryMode(mode int64) TarArchiverOption {
+	return func(a *TarArchiver) {
+		a.mode = mode
+	}
+}
+
+// WithModTime sets the modification time of every entry. Without it entries
+// carry the Unix epoch; either way identical content yields byte-identical
+// archives.
+func WithModTime(t time.Time) TarArchiverOption {
+	return func(a *TarArchiver) {
+		a.modTime = t
+	}
+}
+
 // NewTarArchiver creates a new tar archiver with the specified compression.
 // Supported compression types: "gzip", "zstd", "none".
-// If compression is empty, defaults to "gzip".
-func NewTarArchiver(compression string) (engine.Archiver, error) {
+// If compression is empty, defaults to "gzip". A nil level keeps the
+// algorithm's default level; otherwise it must lie within the range of the
+// selected algorithm, and it cannot be combined with "none".
+func NewTarArchiver(compression string, level *int, opts ...TarArchiverOption) (engine.Archiver, error) {
 	ct := CompressionType(compression)
 	if ct == "" {
 		ct = CompressionGzip
 	}
+	if err := validateLevel(ct, level); err != nil {
+		return nil, err
+	}
 
 	buf := new(bytes.Buffer)
 	var compressor io.WriteCloser
@@ -45,9 +85,20 @@ func NewTarArchiver(compression string) (engine.Archiver, error) {
 
 	switch ct {
 	case CompressionGzip:
-		compressor = gzip.NewWriter(buf)
+		if level == nil {
+			compressor = gzip.NewWriter(buf)
+			break
+		}
+		compressor, err = gzip.NewWriterLevel(buf, *level)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+		}
 	case CompressionZstd:
-		compressor, err = zstd.NewWriter(buf)
+		var opts []zstd.EOption
+		if level != nil {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(*level)))
+		}
+		compressor, err = zstd.NewWriter(buf, opts...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create zstd writer: %w", err)
 		}
@@ -59,12 +110,38 @@ func NewTarArchiver(compression string) (engine.Archiver, error) {
 
 	tarWriter := tar.NewWriter(compressor)
 
-	return &TarArchiver{
+	archiver := &TarArchiver{
 		buf:         buf,
 		compressor:  compressor,
 		tarWriter:   tarWriter,
 		compression: ct,
-	}, nil
+		mode:        DefaultEntryMode,
+	}
+	for _, opt := range opts {
+		opt(archiver)
+	}
+	return archiver, nil
+}
+
+// validateLevel checks that level, when set, is valid for compression ct.
+// Unknown compression types are left for NewTarArchiver to reject.
+func validateLevel(ct CompressionType, level *int) error {
+	if level == nil {
+		return nil
+	}
+	switch ct {
+	case CompressionGzip:
+		if *level < MinGzipLevel || *level > MaxGzipLevel {
+			return fmt.Errorf("gzip compression level must be between %d and %d, got %d", MinGzipLevel, MaxGzipLevel, *level)
+		}
+	case CompressionZstd:
+		if *level < MinZstdLevel || *level > MaxZstdLevel {
+			return fmt.Errorf("zstd compression level must be between %d and %d, got %d", MinZstdLevel, MaxZstdLevel, *level)
+		}
+	case CompressionNone:
+		return fmt.Errorf("compression level cannot be set when compression is none")
+	}
+	return nil
 }
 
 // AddFile adds a file to the tar archive.
@@ -85,9 +162,10 @@ func (a *TarArchiver) AddFile(ctx context.Context, filename string, data io.Read
 	}
 
 	header := &tar.Header{
-		Name: filename,
-		Mode: 0644,
-		Size: int64(len(content)),
+		Name:    filename,
+		Mode:    a.mode,
+		Size:    int64(len(content)),
+		ModTime: a.modTime,
 	}
 
 	if err := a.tarWriter.WriteHeader(header); err != nil {
@@ -3,39 +3,25 @@ package archivers
 import (
 	"archive/tar"
 	"bytes"
-	"compress/gzip"
 	"fmt"
 	"io"
 	"testing"
+	"time"
 
-	"github.com/klauspost/compress/zstd"
-	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-// readTarEntries decompresses the reader (gzip, zstd, or none) and returns a map of filename -> content.
+// readTarEntries decompresses the reader, checking that the detected
+// compression matches, and returns a map of filename -> content.
 func readTarEntries(r io.Reader, compression string) (map[string]string, error) {
-	var decompressed io.Reader
-	switch compression {
-	case "gzip":
-		gr, err := gzip.NewReader(r)
-		if err != nil {
-			return nil, err
-		}
-		defer lo.Must0(gr.Close())
-		decompressed = gr
-	case "zstd":
-		zr, err := zstd.NewReader(r)
-		if err != nil {
-			return nil, err
-		}
-		defer zr.Close()
-		decompressed = zr
-	case "none":
-		decompressed = r
-	default:
-		return nil, fmt.Errorf("unknown compression: %s", compression)
+	decompressed, detected, err := NewDecompressor(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = decompressed.Close() }()
+	if string(detected) != compression {
+		return nil, fmt.Errorf("detected %s compression, want %s", detected, compression)
 	}
 	tr := tar.NewReader(decompressed)
 	found := make(map[string]string)
@@ -92,7 +78,7 @@ func TestNewTarArchiver(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			archiver, err := NewTarArchiver(tt.compression)
+			archiver, err := NewTarArchiver(tt.compression, nil)
 			if tt.wantErr {
 				require.Error(t, err, "NewTarArchiver() expected error")
 				return
@@ -103,8 +89,54 @@ func TestNewTarArchiver(t *testing.T) {
 	}
 }
 
+func TestNewTarArchiver_Level(t *testing.T) {
+	level := func(l int) *int { return &l }
+
+	tests := []struct {
+		name        string
+		compression string
+		level       *int
+		wantErr     string
+	}{
+		{name: "gzip fastest", compression: "gzip", level: level(1)},
+		{name: "gzip best", compression: "gzip", level: level(9)},
+		{name: "default compression with level", compression: "", level: level(5)},
+		{name: "zstd fastest", compression: "zstd", level: level(1)},
+		{name: "zstd default", compression: "zstd", level: level(3)},
+		{name: "zstd best", compression: "zstd", level: level(22)},
+		{name: "gzip level too low", compression: "gzip", level: level(0), wantErr: "gzip compression level must be between 1 and 9, got 0"},
+		{name: "gzip level too high", compression: "gzip", level: level(10), wantErr: "gzip compression level must be between 1 and 9, got 10"},
+		{name: "zstd level too high", compression: "zstd", level: level(23), wantErr: "zstd compression level must be between 1 and 22, got 23"},
+		{name: "level without compression", compression: "none", level: level(1), wantErr: "compression level cannot be set when compression is none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archiver, err := NewTarArchiver(tt.compression, tt.level)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			content := "compressed at a chosen level"
+			require.NoError(t, archiver.AddFile(t.Context(), "level.txt", bytes.NewReader([]byte(content))))
+			reader, err := archiver.Close()
+			require.NoError(t, err)
+
+			want := tt.compression
+			if want == "" {
+				want = "gzip"
+			}
+			found, err := readTarEntries(reader, want)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{"level.txt": content}, found)
+		})
+	}
+}
+
 func TestTarArchiver_AddFile(t *testing.T) {
-	archiver, err := NewTarArchiver("gzip")
+	archiver, err := NewTarArchiver("gzip", nil)
 	require.NoError(t, err)
 
 	content := "hello, world!"
@@ -121,7 +153,7 @@ func TestTarArchiver_AddFile(t *testing.T) {
 }
 
 func TestTarArchiver_MultipleFiles(t *testing.T) {
-	archiver, err := NewTarArchiver("gzip")
+	archiver, err := NewTarArchiver("gzip", nil)
 	require.NoError(t, err)
 
 	files := map[string]string{
@@ -146,7 +178,7 @@ func TestTarArchiver_MultipleFiles(t *testing.T) {
 }
 
 func TestTarArchiver_Zstd(t *testing.T) {
-	archiver, err := NewTarArchiver("zstd")
+	archiver, err := NewTarArchiver("zstd", nil)
 	require.NoError(t, err)
 
 	content := "zstd compressed content"
@@ -163,7 +195,7 @@ func TestTarArchiver_Zstd(t *testing.T) {
 }
 
 func TestTarArchiver_NoCompression(t *testing.T) {
-	archiver, err := NewTarArchiver("none")
+	archiver, err := NewTarArchiver("none", nil)
 	require.NoError(t, err)
 
 	content := "uncompressed content"
@@ -180,7 +212,7 @@ func TestTarArchiver_NoCompression(t *testing.T) {
 }
 
 func TestTarArchiver_CloseTwice(t *testing.T) {
-	archiver, err := NewTarArchiver("gzip")
+	archiver, err := NewTarArchiver("gzip", nil)
 	require.NoError(t, err)
 
 	_, err = archiver.Close()
@@ -192,7 +224,7 @@ func TestTarArchiver_CloseTwice(t *testing.T) {
 }
 
 func TestTarArchiver_AddFileAfterClose(t *testing.T) {
-	archiver, err := NewTarArchiver("gzip")
+	archiver, err := NewTarArchiver("gzip", nil)
 	require.NoError(t, err)
 
 	_, err = archiver.Close()
@@ -202,3 +234,52 @@ func TestTarArchiver_AddFileAfterClose(t *testing.T) {
 	err = archiver.AddFile(ctx, "test.txt", bytes.NewReader([]byte("content")))
 	require.Error(t, err, "AddFile() after Close() should error")
 }
+
+func TestTarArchiver_EntryOptions(t *testing.T) {
+	mtime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		opts     []TarArchiverOption
+		wantMode int64
+		wantTime time.Time
+	}{
+		{name: "defaults", wantMode: DefaultEntryMode, wantTime: time.Unix(0, 0)},
+		{
+			name:     "mode and modtime",
+			opts:     []TarArchiverOption{WithEntryMode(0o600), WithModTime(mtime)},
+			wantMode: 0o600,
+			wantTime: mtime,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archiver, err := NewTarArchiver("none", nil, tt.opts...)
+			require.NoError(t, err)
+			require.NoError(t, archiver.AddFile(t.Context(), "a.json", bytes.NewReader([]byte("{}"))))
+			reader, err := archiver.Close()
+			require.NoError(t, err)
+
+			header, err := tar.NewReader(reader).Next()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMode, header.Mode)
+			assert.True(t, tt.wantTime.Equal(header.ModTime), "modtime %s, want %s", header.ModTime, tt.wantTime)
+		})
+	}
+}
+
+func TestTarArchiver_Reproducible(t *testing.T) {
+	build := func() []byte {
+		archiver, err := NewTarArchiver("gzip", nil, WithModTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+		require.NoError(t, err)
+		require.NoError(t, archiver.AddFile(t.Context(), "a.json", bytes.NewReader([]byte(`{"a":1}`))))
+		reader, err := archiver.Close()
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return data
+	}
+
+	assert.Equal(t, build(), build(), "identical content and modtime yield identical archives")
+}
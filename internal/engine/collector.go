@@ -12,3 +12,12 @@ type Collector interface {
 	Closer
 	Start(context.Context) error
 }
+
+// CollectorValidator is implemented by collectors that can check their
+// configuration against the outside world without being started, for
+// example that a host is reachable or that a provider exists. The validate
+// command calls Validate on collectors that were created but not started,
+// so misconfiguration is caught before a real run.
+type CollectorValidator interface {
+	Validate(ctx context.Context) error
+}
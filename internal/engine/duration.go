@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration parses a user-supplied timeout or interval. It accepts Go
+// duration syntax ("90s", "1m30s") and, for backward compatibility with
+// attributes that used to be integers, a bare number of seconds ("30").
+// HCL converts numbers to strings on decode, so `timeout = 30`,
+// `timeout = "30s"` and `timeout = env.TIMEOUT` all land here.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("duration is empty")
+	}
+
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("duration %q must not be negative", s)
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: expected seconds or a Go duration such as \"30s\"", s)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration %q must not be negative", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in        string
+		want      time.Duration
+		expectErr string
+	}{
+		{in: "30", want: 30 * time.Second},
+		{in: "1.5", want: 1500 * time.Millisecond},
+		{in: " 45 ", want: 45 * time.Second},
+		{in: "90s", want: 90 * time.Second},
+		{in: "1m30s", want: 90 * time.Second},
+		{in: "250ms", want: 250 * time.Millisecond},
+		{in: "", expectErr: "duration is empty"},
+		{in: "-5", expectErr: "must not be negative"},
+		{in: "-5s", expectErr: "must not be negative"},
+		{in: "soon", expectErr: `invalid duration "soon"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDuration(tt.in)
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
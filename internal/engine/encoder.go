@@ -16,3 +16,16 @@ type Encoder interface {
 	// FileExtension returns extension without dot (e.g., "json").
 	FileExtension() string
 }
+
+// MetaInliner is implemented by encoders that can embed a result's Meta in
+// the encoded result itself. When InlinesMeta returns true the separate meta
+// file is not written.
+type MetaInliner interface {
+	InlinesMeta() bool
+}
+
+// ResultsEncoder is implemented by encoders that can encode every result of
+// a run into a single document keyed by result key, for combined output.
+type ResultsEncoder interface {
+	EncodeResults(ctx context.Context, results map[string]Result) (io.Reader, error)
+}
@@ -0,0 +1,29 @@
+package encoders
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// canonicalize normalizes v into the generic JSON value space (map[string]any,
+// []any, json.Number, string, bool, nil). encoding/json sorts map keys, but
+// only for values it walks itself: json.Marshaler implementations and
+// json.RawMessage payloads are emitted verbatim, so whatever key order they
+// carry leaks into the output. Round-tripping through the decoder brings
+// every nested object under the sorted-key guarantee, which makes the
+// encoded document byte-reproducible.
+func canonicalize(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out any
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+	return out, nil
+}
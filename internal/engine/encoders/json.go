@@ -12,13 +12,39 @@ import (
 
 // JSONEncoder encodes results as JSON.
 type JSONEncoder struct {
-	indent string
+	indent      string
+	includeMeta bool
+	sortKeys    bool
 }
 
-func NewJSONEncoder(indent string) engine.Encoder {
-	return &JSONEncoder{
+type JSONEncoderOption func(*JSONEncoder)
+
+// WithIncludeMeta wraps every encoded result as {"meta": {...}, "data": ...}
+// instead of emitting the bare data, keeping provenance next to the data.
+func WithIncludeMeta() JSONEncoderOption {
+	return func(e *JSONEncoder) {
+		e.includeMeta = true
+	}
+}
+
+// WithSortKeys canonicalizes every result before encoding so objects are
+// emitted with sorted keys at every depth, including inside json.Marshaler
+// and json.RawMessage values. Identical data then always encodes to
+// identical bytes.
+func WithSortKeys() JSONEncoderOption {
+	return func(e *JSONEncoder) {
+		e.sortKeys = true
+	}
+}
+
+func NewJSONEncoder(indent string, opts ...JSONEncoderOption) engine.Encoder {
+	e := &JSONEncoder{
 		indent: indent,
 	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
 func (e *JSONEncoder) EncodeResult(ctx context.Context, result engine.Result) (io.Reader, error) {
@@ -28,21 +54,48 @@ func (e *JSONEncoder) EncodeResult(ctx context.Context, result engine.Result) (i
 		encoder.SetIndent("", e.indent)
 	}
 
-	if err := encoder.Encode(result.Data); err != nil {
+	var value any = result.Data
+	if e.includeMeta {
+		meta := result.Meta
+		if meta == nil {
+			meta = map[string]string{}
+		}
+		value = struct {
+			Meta map[string]string `json:"meta"`
+			Data any               `json:"data"`
+		}{Meta: meta, Data: result.Data}
+	}
+	if e.sortKeys {
+		canonical, err := canonicalize(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to canonicalize result: %w", err)
+		}
+		value = canonical
+	}
+
+	if err := encoder.Encode(value); err != nil {
 		return nil, fmt.Errorf("failed to encode result as JSON: %w", err)
 	}
 
 	return &buff, nil
 }
 
+// EncodeResults encodes every result into a single document keyed by result
+// key. The results are canonicalized first so nested objects are emitted with
+// sorted keys at every depth and the combined document is byte-reproducible.
 func (e *JSONEncoder) EncodeResults(ctx context.Context, results map[string]engine.Result) (io.Reader, error) {
+	canonical, err := canonicalize(results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to canonicalize results: %w", err)
+	}
+
 	var buff bytes.Buffer
 	encoder := json.NewEncoder(&buff)
 	if e.indent != "" {
 		encoder.SetIndent("", e.indent)
 	}
 
-	if err := encoder.Encode(results); err != nil {
+	if err := encoder.Encode(canonical); err != nil {
 		return nil, fmt.Errorf("failed to encode results as JSON: %w", err)
 	}
 
@@ -63,6 +116,11 @@ func (e *JSONEncoder) EncodeMeta(ctx context.Context, meta map[string]string) (i
 	return &buff, nil
 }
 
+// InlinesMeta reports whether EncodeResult already embeds the meta.
+func (e *JSONEncoder) InlinesMeta() bool {
+	return e.includeMeta
+}
+
 func (e *JSONEncoder) FileExtension() string {
 	return "json"
 }
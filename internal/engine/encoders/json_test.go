@@ -0,0 +1,141 @@
+package encoders
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeResults(t *testing.T, enc engine.Encoder, results map[string]engine.Result) string {
+	t.Helper()
+	reader, err := enc.(*JSONEncoder).EncodeResults(t.Context(), results)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestJSONEncoder_EncodeResults_ByteIdenticalAcrossRuns(t *testing.T) {
+	build := func(reverse bool) map[string]engine.Result {
+		keys := []string{"zeta", "alpha", "mid", "beta"}
+		if reverse {
+			for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+				keys[i], keys[j] = keys[j], keys[i]
+			}
+		}
+		nested := make(map[string]any)
+		for _, k := range keys {
+			nested[k] = map[string]any{"name": k, k: []any{k, map[string]any{"y": 1, "x": 2}}}
+		}
+		return map[string]engine.Result{
+			"static/b": {Data: nested, Meta: map[string]string{"z": "1", "a": "2"}},
+			"static/a": {Data: []any{nested}},
+		}
+	}
+
+	enc := NewJSONEncoder("  ")
+	first := encodeResults(t, enc, build(false))
+	for i := range 20 {
+		got := encodeResults(t, enc, build(i%2 == 1))
+		require.Equal(t, first, got, "run %d produced different bytes", i)
+	}
+}
+
+func TestJSONEncoder_EncodeResults_SortsRawMessageKeys(t *testing.T) {
+	enc := NewJSONEncoder("")
+	got := encodeResults(t, enc, map[string]engine.Result{
+		"exec/raw": {Data: json.RawMessage(`{"b":{"d":1,"c":2},"a":[{"z":true,"y":false}]}`)},
+	})
+
+	assert.JSONEq(t, `{"exec/raw":{"data":{"a":[{"y":false,"z":true}],"b":{"c":2,"d":1}}}}`, got)
+	assert.Equal(t, `{"exec/raw":{"data":{"a":[{"y":false,"z":true}],"b":{"c":2,"d":1}}}}`+"\n", got)
+}
+
+func TestJSONEncoder_EncodeResults_PreservesNumberPrecision(t *testing.T) {
+	enc := NewJSONEncoder("")
+	got := encodeResults(t, enc, map[string]engine.Result{
+		"static/n": {Data: json.RawMessage(`{"big":9007199254740993}`)},
+	})
+
+	assert.Equal(t, `{"static/n":{"data":{"big":9007199254740993}}}`+"\n", got)
+}
+
+func TestJSONEncoder_EncodeResult_IncludeMeta(t *testing.T) {
+	cases := []struct {
+		name   string
+		opts   []JSONEncoderOption
+		result engine.Result
+		want   string
+	}{
+		{
+			name:   "bare data by default",
+			result: engine.Result{Data: map[string]any{"a": 1}, Meta: map[string]string{"k": "v"}},
+			want:   `{"a":1}`,
+		},
+		{
+			name:   "wrapped with meta",
+			opts:   []JSONEncoderOption{WithIncludeMeta()},
+			result: engine.Result{Data: map[string]any{"a": 1}, Meta: map[string]string{"k": "v"}},
+			want:   `{"meta":{"k":"v"},"data":{"a":1}}`,
+		},
+		{
+			name:   "empty meta stays an object",
+			opts:   []JSONEncoderOption{WithIncludeMeta()},
+			result: engine.Result{Data: []any{"x"}},
+			want:   `{"meta":{},"data":["x"]}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			enc := NewJSONEncoder("", tc.opts...)
+			reader, err := enc.EncodeResult(t.Context(), tc.result)
+			require.NoError(t, err)
+			data, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want+"\n", string(data))
+			assert.Equal(t, len(tc.opts) > 0, enc.(engine.MetaInliner).InlinesMeta())
+		})
+	}
+}
+
+func TestJSONEncoder_EncodeResult_SortKeys(t *testing.T) {
+	raw := json.RawMessage(`{"b":{"d":1,"c":2},"a":[{"z":true,"y":false}],"n":9007199254740993}`)
+	cases := []struct {
+		name   string
+		opts   []JSONEncoderOption
+		result engine.Result
+		want   string
+	}{
+		{
+			name:   "raw messages verbatim by default",
+			result: engine.Result{Data: raw},
+			want:   `{"b":{"d":1,"c":2},"a":[{"z":true,"y":false}],"n":9007199254740993}`,
+		},
+		{
+			name:   "sorted at every depth",
+			opts:   []JSONEncoderOption{WithSortKeys()},
+			result: engine.Result{Data: raw},
+			want:   `{"a":[{"y":false,"z":true}],"b":{"c":2,"d":1},"n":9007199254740993}`,
+		},
+		{
+			name:   "wrapper keys sorted with include_meta",
+			opts:   []JSONEncoderOption{WithSortKeys(), WithIncludeMeta()},
+			result: engine.Result{Data: raw, Meta: map[string]string{"k": "v"}},
+			want:   `{"data":{"a":[{"y":false,"z":true}],"b":{"c":2,"d":1},"n":9007199254740993},"meta":{"k":"v"}}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			enc := NewJSONEncoder("", tc.opts...)
+			reader, err := enc.EncodeResult(t.Context(), tc.result)
+			require.NoError(t, err)
+			data, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want+"\n", string(data))
+		})
+	}
+}
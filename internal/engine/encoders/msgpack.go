@@ -0,0 +1,103 @@
+package encoders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MsgpackEncoder encodes results as MessagePack. Results are first reduced to
+// their JSON shape; integral numbers are written as the smallest MessagePack
+// integer that holds them and all other numbers as float64. Map keys are
+// written in sorted order so the output is byte-reproducible.
+type MsgpackEncoder struct{}
+
+func NewMsgpackEncoder() engine.Encoder {
+	return &MsgpackEncoder{}
+}
+
+func (e *MsgpackEncoder) EncodeResult(ctx context.Context, result engine.Result) (io.Reader, error) {
+	reader, err := encodeMsgpack(result.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result as MessagePack: %w", err)
+	}
+	return reader, nil
+}
+
+func (e *MsgpackEncoder) EncodeMeta(ctx context.Context, meta map[string]string) (io.Reader, error) {
+	reader, err := encodeMsgpack(meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode meta as MessagePack: %w", err)
+	}
+	return reader, nil
+}
+
+func (e *MsgpackEncoder) FileExtension() string {
+	return "msgpack"
+}
+
+func encodeMsgpack(v any) (io.Reader, error) {
+	canonical, err := canonicalize(v)
+	if err != nil {
+		return nil, err
+	}
+	value, err := msgpackValue(canonical)
+	if err != nil {
+		return nil, err
+	}
+
+	var buff bytes.Buffer
+	encoder := msgpack.NewEncoder(&buff)
+	encoder.SetSortMapKeys(true)
+	encoder.UseCompactInts(true)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return &buff, nil
+}
+
+// msgpackValue replaces the json.Number leaves of a canonical value with
+// int64, uint64 or float64 so they are encoded as MessagePack numbers rather
+// than strings.
+func msgpackValue(v any) (any, error) {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			converted, err := msgpackValue(item)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = converted
+		}
+		return val, nil
+	case []any:
+		for i, item := range val {
+			converted, err := msgpackValue(item)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = converted
+		}
+		return val, nil
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n, nil
+		}
+		if n, err := strconv.ParseUint(val.String(), 10, 64); err == nil {
+			return n, nil
+		}
+		n, err := val.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("unsupported number %s: %w", val, err)
+		}
+		return n, nil
+	default:
+		return v, nil
+	}
+}
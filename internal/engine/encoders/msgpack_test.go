@@ -0,0 +1,91 @@
+package encoders
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func decodeMsgpack(t *testing.T, reader io.Reader) any {
+	t.Helper()
+	var out any
+	require.NoError(t, msgpack.NewDecoder(reader).Decode(&out))
+	return out
+}
+
+func TestMsgpackEncoder_EncodeResult(t *testing.T) {
+	tests := []struct {
+		name string
+		data any
+		want any
+	}{
+		{
+			name: "nested map",
+			data: map[string]any{
+				"name":    "web",
+				"enabled": true,
+				"owner":   nil,
+				"tags":    []any{"a", map[string]any{"k": "v"}},
+			},
+			want: map[string]any{
+				"name":    "web",
+				"enabled": true,
+				"owner":   nil,
+				"tags":    []any{"a", map[string]any{"k": "v"}},
+			},
+		},
+		{
+			name: "numbers keep integer precision",
+			data: map[string]any{"count": 2, "ratio": 0.5, "big": uint64(1<<63 + 1), "neg": -3},
+			want: map[string]any{"count": int8(2), "ratio": 0.5, "big": uint64(1<<63 + 1), "neg": int8(-3)},
+		},
+		{
+			name: "top-level array",
+			data: []any{"x", "y"},
+			want: []any{"x", "y"},
+		},
+		{
+			name: "null data",
+			data: nil,
+			want: nil,
+		},
+	}
+
+	encoder := NewMsgpackEncoder()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := encoder.EncodeResult(t.Context(), engine.Result{ID: "r", Data: tt.data})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, decodeMsgpack(t, reader))
+		})
+	}
+}
+
+func TestMsgpackEncoder_DeterministicOutput(t *testing.T) {
+	data := map[string]any{"b": 1, "a": map[string]any{"z": 1, "y": 2}, "c": []any{1}}
+	encoder := NewMsgpackEncoder()
+
+	first, err := encoder.EncodeResult(t.Context(), engine.Result{Data: data})
+	require.NoError(t, err)
+	firstBytes, err := io.ReadAll(first)
+	require.NoError(t, err)
+	for range 10 {
+		again, err := encoder.EncodeResult(t.Context(), engine.Result{Data: data})
+		require.NoError(t, err)
+		againBytes, err := io.ReadAll(again)
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(firstBytes, againBytes), "map keys must be written in sorted order")
+	}
+}
+
+func TestMsgpackEncoder_EncodeMeta(t *testing.T) {
+	reader, err := NewMsgpackEncoder().EncodeMeta(t.Context(), map[string]string{"kind": "static"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"kind": "static"}, decodeMsgpack(t, reader))
+	assert.Equal(t, "msgpack", NewMsgpackEncoder().FileExtension())
+}
@@ -0,0 +1,285 @@
+package encoders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+// DefaultParquetCompression is the codec used when none is configured.
+const DefaultParquetCompression = "snappy"
+
+var parquetCodecs = map[string]compress.Codec{
+	"none":   &parquet.Uncompressed,
+	"snappy": &parquet.Snappy,
+	"gzip":   &parquet.Gzip,
+	"zstd":   &parquet.Zstd,
+}
+
+// ParquetEncoder encodes tabular results, lists of objects, as Parquet
+// files. The columns are the keys of the first row; every column is
+// optional, so later rows may leave some out, but they may not add new ones.
+// A column's type is inferred from its values: strings, booleans, int64
+// when every number is integral and double otherwise. Nested objects and
+// arrays are stored as JSON strings. An empty list is written as a file with
+// no columns and no rows.
+type ParquetEncoder struct {
+	codec compress.Codec
+}
+
+// NewParquetEncoder returns a Parquet encoder compressing with compression:
+// "none", "snappy", "gzip" or "zstd". Empty selects
+// DefaultParquetCompression.
+func NewParquetEncoder(compression string) (engine.Encoder, error) {
+	if compression == "" {
+		compression = DefaultParquetCompression
+	}
+	codec, ok := parquetCodecs[compression]
+	if !ok {
+		return nil, fmt.Errorf("unsupported parquet compression %q (supported: none, snappy, gzip, zstd)", compression)
+	}
+	return &ParquetEncoder{codec: codec}, nil
+}
+
+func (e *ParquetEncoder) EncodeResult(ctx context.Context, result engine.Result) (io.Reader, error) {
+	canonical, err := canonicalize(result.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result as Parquet: %w", err)
+	}
+	rows, ok := canonical.([]any)
+	if !ok {
+		return nil, fmt.Errorf("parquet encoding needs a list of objects, got %s", jsonKind(canonical))
+	}
+	reader, err := e.encodeRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result as Parquet: %w", err)
+	}
+	return reader, nil
+}
+
+// EncodeMeta writes the meta as a single row with one string column per key.
+func (e *ParquetEncoder) EncodeMeta(ctx context.Context, meta map[string]string) (io.Reader, error) {
+	row := make(map[string]any, len(meta))
+	for k, v := range meta {
+		row[k] = v
+	}
+	reader, err := e.encodeRows([]any{row})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode meta as Parquet: %w", err)
+	}
+	return reader, nil
+}
+
+func (e *ParquetEncoder) FileExtension() string {
+	return "parquet"
+}
+
+// parquetColumnType is the inferred type of a column.
+type parquetColumnType int
+
+const (
+	parquetNull parquetColumnType = iota // only nulls seen so far
+	parquetString
+	parquetBool
+	parquetInt
+	parquetDouble
+	parquetJSON
+)
+
+func (e *ParquetEncoder) encodeRows(rows []any) (io.Reader, error) {
+	if len(rows) == 0 {
+		// There is no row to infer columns from, but an empty result is
+		// still a result: write a valid file with no columns and no rows
+		// rather than failing the whole output.
+		return e.write(parquet.NewSchema("result", parquet.Group{}), nil)
+	}
+
+	objects := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		obj, ok := row.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("parquet encoding needs a list of objects, element %d is %s", i, jsonKind(row))
+		}
+		objects[i] = obj
+	}
+
+	columns := slices.Sorted(maps.Keys(objects[0]))
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("cannot infer a parquet schema from an empty first row")
+	}
+	types := make(map[string]parquetColumnType, len(columns))
+	for i, obj := range objects {
+		for key, value := range obj {
+			if _, inFirst := objects[0][key]; !inFirst {
+				return nil, fmt.Errorf("row %d has column %q that the first row lacks", i, key)
+			}
+			merged, err := mergeColumnType(types[key], value)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: row %d: %w", key, i, err)
+			}
+			types[key] = merged
+		}
+	}
+
+	group := make(parquet.Group, len(columns))
+	for _, column := range columns {
+		group[column] = parquet.Optional(parquetNode(types[column]))
+	}
+	schema := parquet.NewSchema("result", group)
+
+	parquetRows := make([]parquet.Row, len(objects))
+	for i, obj := range objects {
+		row := make(parquet.Row, len(columns))
+		// parquet.Group orders its fields by name, which is the order of
+		// columns, so the index of a column is its leaf column index.
+		for col, column := range columns {
+			value, err := parquetValue(types[column], obj[column])
+			if err != nil {
+				return nil, fmt.Errorf("column %q: row %d: %w", column, i, err)
+			}
+			definition := 1
+			if value.IsNull() {
+				definition = 0
+			}
+			row[col] = value.Level(0, definition, col)
+		}
+		parquetRows[i] = row
+	}
+	return e.write(schema, parquetRows)
+}
+
+// write encodes rows, which follow schema, as a Parquet file.
+func (e *ParquetEncoder) write(schema *parquet.Schema, rows []parquet.Row) (io.Reader, error) {
+	var buff bytes.Buffer
+	writer := parquet.NewWriter(&buff, schema, parquet.Compression(e.codec))
+	if _, err := writer.WriteRows(rows); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return &buff, nil
+}
+
+// mergeColumnType widens current with the type of value. Integers widen to
+// doubles; any other mix of types is an error.
+func mergeColumnType(current parquetColumnType, value any) (parquetColumnType, error) {
+	var next parquetColumnType
+	switch v := value.(type) {
+	case nil:
+		return current, nil
+	case string:
+		next = parquetString
+	case bool:
+		next = parquetBool
+	case json.Number:
+		next = parquetDouble
+		if _, err := v.Int64(); err == nil {
+			next = parquetInt
+		}
+	case map[string]any, []any:
+		next = parquetJSON
+	default:
+		return current, fmt.Errorf("unsupported value of type %T", value)
+	}
+
+	switch {
+	case current == parquetNull || current == next:
+		return next, nil
+	case current == parquetInt && next == parquetDouble, current == parquetDouble && next == parquetInt:
+		return parquetDouble, nil
+	default:
+		return current, fmt.Errorf("%s value in %s column", parquetTypeName(next), parquetTypeName(current))
+	}
+}
+
+func parquetNode(typ parquetColumnType) parquet.Node {
+	switch typ {
+	case parquetBool:
+		return parquet.Leaf(parquet.BooleanType)
+	case parquetInt:
+		return parquet.Leaf(parquet.Int64Type)
+	case parquetDouble:
+		return parquet.Leaf(parquet.DoubleType)
+	case parquetJSON:
+		return parquet.JSON()
+	default:
+		// A column holding only nulls has no type to infer; a string
+		// column is the most forgiving choice for readers.
+		return parquet.String()
+	}
+}
+
+func parquetValue(typ parquetColumnType, value any) (parquet.Value, error) {
+	if value == nil {
+		return parquet.NullValue(), nil
+	}
+	switch typ {
+	case parquetString:
+		return parquet.ByteArrayValue([]byte(value.(string))), nil
+	case parquetBool:
+		return parquet.BooleanValue(value.(bool)), nil
+	case parquetInt:
+		n, err := value.(json.Number).Int64()
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int64Value(n), nil
+	case parquetDouble:
+		n, err := value.(json.Number).Float64()
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.DoubleValue(n), nil
+	case parquetJSON:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.ByteArrayValue(data), nil
+	}
+	return parquet.Value{}, fmt.Errorf("unexpected column type %d", typ)
+}
+
+func parquetTypeName(typ parquetColumnType) string {
+	switch typ {
+	case parquetString:
+		return "string"
+	case parquetBool:
+		return "boolean"
+	case parquetInt:
+		return "integer"
+	case parquetDouble:
+		return "number"
+	case parquetJSON:
+		return "object or array"
+	}
+	return "null"
+}
+
+// jsonKind names the JSON kind of a canonical value for error messages.
+func jsonKind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case json.Number:
+		return "a number"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,138 @@
+package encoders
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readParquet returns the column names and types of the encoded file and
+// its rows as maps of column name to value.
+func readParquet(t *testing.T, reader io.Reader) (map[string]string, []map[string]any) {
+	t.Helper()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	columns := make(map[string]string)
+	fields := file.Schema().Fields()
+	for _, field := range fields {
+		columns[field.Name()] = field.Type().String()
+	}
+
+	rows := make([]parquet.Row, file.NumRows())
+	r := parquet.NewReader(file)
+	n, err := r.ReadRows(rows)
+	if err != nil && err != io.EOF {
+		require.NoError(t, err)
+	}
+	require.Equal(t, len(rows), n)
+
+	var out []map[string]any
+	for _, row := range rows {
+		m := make(map[string]any, len(row))
+		for _, value := range row {
+			name := fields[value.Column()].Name()
+			switch {
+			case value.IsNull():
+				m[name] = nil
+			case value.Kind() == parquet.ByteArray:
+				m[name] = string(value.ByteArray())
+			case value.Kind() == parquet.Int64:
+				m[name] = value.Int64()
+			case value.Kind() == parquet.Double:
+				m[name] = value.Double()
+			case value.Kind() == parquet.Boolean:
+				m[name] = value.Boolean()
+			}
+		}
+		out = append(out, m)
+	}
+	return columns, out
+}
+
+func TestParquetEncoder_EncodeResult(t *testing.T) {
+	data := []any{
+		map[string]any{"id": 1, "name": "web", "enabled": true, "cpu": 2, "tags": []any{"a"}, "owner": nil},
+		map[string]any{"id": 2, "name": "db", "enabled": false, "cpu": 0.5, "tags": map[string]any{"k": "v"}},
+	}
+
+	for _, compression := range []string{"", "none", "snappy", "gzip", "zstd"} {
+		t.Run("compression "+compression, func(t *testing.T) {
+			encoder, err := NewParquetEncoder(compression)
+			require.NoError(t, err)
+
+			reader, err := encoder.EncodeResult(t.Context(), engine.Result{ID: "r", Data: data})
+			require.NoError(t, err)
+
+			columns, rows := readParquet(t, reader)
+			assert.Equal(t, map[string]string{
+				"cpu":     "DOUBLE",
+				"enabled": "BOOLEAN",
+				"id":      "INT(64,true)",
+				"name":    "STRING",
+				"owner":   "STRING",
+				"tags":    "JSON",
+			}, columns)
+			assert.Equal(t, []map[string]any{
+				{"id": int64(1), "name": "web", "enabled": true, "cpu": 2.0, "tags": `["a"]`, "owner": nil},
+				{"id": int64(2), "name": "db", "enabled": false, "cpu": 0.5, "tags": `{"k":"v"}`, "owner": nil},
+			}, rows)
+		})
+	}
+}
+
+func TestParquetEncoder_EncodeResultEmptyList(t *testing.T) {
+	encoder, err := NewParquetEncoder("")
+	require.NoError(t, err)
+
+	reader, err := encoder.EncodeResult(t.Context(), engine.Result{Data: []any{}})
+	require.NoError(t, err)
+	columns, rows := readParquet(t, reader)
+	assert.Empty(t, columns)
+	assert.Empty(t, rows)
+}
+
+func TestParquetEncoder_EncodeResultErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        any
+		errContains string
+	}{
+		{name: "object", data: map[string]any{"a": 1}, errContains: "parquet encoding needs a list of objects, got an object"},
+		{name: "list of scalars", data: []any{map[string]any{"a": 1}, "x"}, errContains: "element 1 is a string"},
+		{name: "new column", data: []any{map[string]any{"a": 1}, map[string]any{"a": 2, "b": 3}}, errContains: `row 1 has column "b" that the first row lacks`},
+		{name: "mixed types", data: []any{map[string]any{"a": 1}, map[string]any{"a": "x"}}, errContains: `column "a": row 1: string value in integer column`},
+	}
+
+	encoder, err := NewParquetEncoder("")
+	require.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := encoder.EncodeResult(t.Context(), engine.Result{Data: tt.data})
+			assert.ErrorContains(t, err, tt.errContains)
+		})
+	}
+}
+
+func TestParquetEncoder_EncodeMeta(t *testing.T) {
+	encoder, err := NewParquetEncoder("")
+	require.NoError(t, err)
+	assert.Equal(t, "parquet", encoder.FileExtension())
+
+	reader, err := encoder.EncodeMeta(t.Context(), map[string]string{"kind": "static", "rows": "2"})
+	require.NoError(t, err)
+	_, rows := readParquet(t, reader)
+	assert.Equal(t, []map[string]any{{"kind": "static", "rows": "2"}}, rows)
+}
+
+func TestNewParquetEncoder_UnsupportedCompression(t *testing.T) {
+	_, err := NewParquetEncoder("brotli")
+	assert.ErrorContains(t, err, `unsupported parquet compression "brotli"`)
+}
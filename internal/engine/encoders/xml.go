@@ -0,0 +1,145 @@
+package encoders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+const (
+	xmlResultElement = "result"
+	xmlMetaElement   = "meta"
+	xmlItemElement   = "item"
+)
+
+// XMLEncoder encodes results as XML documents. Results are first reduced to
+// their JSON shape, then mapped onto elements:
+//
+//   - a result is wrapped in a <result> root element, meta in <meta>;
+//   - object keys become child elements, emitted in sorted key order;
+//   - array elements become repeated <item> children of the array's element;
+//   - scalars become the element's text and null becomes an empty element.
+//
+// Keys that are not valid XML names are sanitized: every character outside
+// letters, digits, '_', '-' and '.' is replaced by '_', and a leading '_' is
+// added when the name would not start with a letter or '_' (including names
+// starting with "xml", which XML reserves).
+type XMLEncoder struct{}
+
+func NewXMLEncoder() engine.Encoder {
+	return &XMLEncoder{}
+}
+
+func (e *XMLEncoder) EncodeResult(ctx context.Context, result engine.Result) (io.Reader, error) {
+	reader, err := encodeXMLDocument(xmlResultElement, result.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result as XML: %w", err)
+	}
+	return reader, nil
+}
+
+func (e *XMLEncoder) EncodeMeta(ctx context.Context, meta map[string]string) (io.Reader, error) {
+	reader, err := encodeXMLDocument(xmlMetaElement, meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode meta as XML: %w", err)
+	}
+	return reader, nil
+}
+
+func (e *XMLEncoder) FileExtension() string {
+	return "xml"
+}
+
+func encodeXMLDocument(root string, v any) (io.Reader, error) {
+	canonical, err := canonicalize(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buff bytes.Buffer
+	buff.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(&buff)
+	encoder.Indent("", "  ")
+	if err := encodeXMLElement(encoder, root, canonical); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	buff.WriteString("\n")
+
+	return &buff, nil
+}
+
+func encodeXMLElement(encoder *xml.Encoder, name string, v any) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlElementName(name)}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch val := v.(type) {
+	case nil:
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if err := encodeXMLElement(encoder, k, val[k]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range val {
+			if err := encodeXMLElement(encoder, xmlItemElement, item); err != nil {
+				return err
+			}
+		}
+	case string:
+		if err := encoder.EncodeToken(xml.CharData(val)); err != nil {
+			return err
+		}
+	case json.Number:
+		if err := encoder.EncodeToken(xml.CharData(val.String())); err != nil {
+			return err
+		}
+	case bool:
+		if err := encoder.EncodeToken(xml.CharData(strconv.FormatBool(val))); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported value of type %T", v)
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// xmlElementName turns an arbitrary object key into a valid XML element name.
+func xmlElementName(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	name := b.String()
+
+	if name == "" || !(unicode.IsLetter([]rune(name)[0]) || name[0] == '_') ||
+		strings.HasPrefix(strings.ToLower(name), "xml") {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,104 @@
+package encoders
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, reader io.Reader) string {
+	t.Helper()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestXMLEncoder_EncodeResult(t *testing.T) {
+	tests := []struct {
+		name string
+		data any
+		want string
+	}{
+		{
+			name: "nested map with sorted keys",
+			data: map[string]any{"zone": "eu", "count": 2, "enabled": true, "owner": nil},
+			want: `<result>
+  <count>2</count>
+  <enabled>true</enabled>
+  <owner></owner>
+  <zone>eu</zone>
+</result>`,
+		},
+		{
+			name: "arrays become repeated items",
+			data: map[string]any{"tags": []any{"a", map[string]any{"k": "v"}}},
+			want: `<result>
+  <tags>
+    <item>a</item>
+    <item>
+      <k>v</k>
+    </item>
+  </tags>
+</result>`,
+		},
+		{
+			name: "top-level array",
+			data: []any{1, 2},
+			want: `<result>
+  <item>1</item>
+  <item>2</item>
+</result>`,
+		},
+		{
+			name: "invalid names are sanitized",
+			data: map[string]any{"1st": "a", "has space": "b", "xmlns": "c", "": "d"},
+			want: `<result>
+  <_>d</_>
+  <_1st>a</_1st>
+  <has_space>b</has_space>
+  <_xmlns>c</_xmlns>
+</result>`,
+		},
+		{
+			name: "text is escaped",
+			data: map[string]any{"expr": "a < b && c"},
+			want: `<result>
+  <expr>a &lt; b &amp;&amp; c</expr>
+</result>`,
+		},
+		{
+			name: "raw JSON keeps number precision",
+			data: json.RawMessage(`{"big":9007199254740993}`),
+			want: `<result>
+  <big>9007199254740993</big>
+</result>`,
+		},
+	}
+
+	enc := NewXMLEncoder()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := enc.EncodeResult(t.Context(), engine.Result{Data: tt.data})
+			require.NoError(t, err)
+			assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+tt.want+"\n", readAll(t, reader))
+		})
+	}
+}
+
+func TestXMLEncoder_EncodeMeta(t *testing.T) {
+	reader, err := NewXMLEncoder().EncodeMeta(t.Context(), map[string]string{"url": "https://x/?a=1&b=2"})
+	require.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<meta>
+  <url>https://x/?a=1&amp;b=2</url>
+</meta>
+`, readAll(t, reader))
+}
+
+func TestXMLEncoder_FileExtension(t *testing.T) {
+	assert.Equal(t, "xml", NewXMLEncoder().FileExtension())
+}
@@ -11,3 +11,22 @@ type Sink interface {
 	Closer
 	Write(ctx context.Context, path string, data io.Reader) error
 }
+
+// ExistenceChecker is implemented by sinks that can tell whether an object
+// has already been written, which lets a resumed run skip it. Sinks that
+// wrap others implement it unconditionally and report through
+// ChecksExistence whether the sinks they wrap can answer.
+type ExistenceChecker interface {
+	ChecksExistence() bool
+	Exists(ctx context.Context, path string) (bool, error)
+}
+
+// AsExistenceChecker returns sink as an ExistenceChecker when it can answer
+// existence checks.
+func AsExistenceChecker(sink Sink) (ExistenceChecker, bool) {
+	checker, ok := sink.(ExistenceChecker)
+	if !ok || !checker.ChecksExistence() {
+		return nil, false
+	}
+	return checker, true
+}
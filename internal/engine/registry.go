@@ -2,6 +2,7 @@ package engine
 
 import (
 	"fmt"
+	"reflect"
 	"slices"
 	"sync"
 
@@ -59,6 +60,17 @@ type StepDescriptor struct {
 	// NewStepFactory. Heterogeneous kinds need a hand-rolled StepFactory (or
 	// a future NewMultiKindStepDescriptor helper).
 	AllowedCollectorKinds []string
+
+	// ConfigType is the gohcl-tagged struct Factory decodes the step body
+	// into. It is informational (schema export) and may be nil for
+	// hand-rolled factories.
+	ConfigType reflect.Type
+
+	// Validate, when set, is called by BuildPipeline with the step body
+	// before anything is evaluated, so `infracollect validate` reports the
+	// problems it finds. It may only judge what is wrong whatever the runtime
+	// values turn out to be, such as a literal empty list.
+	Validate func(helper *RegistryHelper, body hcl.Body) hcl.Diagnostics
 }
 
 // TypedCollectorFactory builds a Collector from an already-decoded config
@@ -91,6 +103,7 @@ type TypedStepFactoryWithoutCollector[S any] func(
 
 const (
 	AllowedEnvVarsDepKey = "allowedEnvVars"
+	AllowedPathsDepKey   = "allowedPaths"
 )
 
 // NewCollectorFactory wraps a typed factory with a gohcl.DecodeBody pass,
@@ -201,6 +214,7 @@ func NewTypedStepDescriptor[C Collector, S any](
 		Factory:               NewStepFactory(kind, f),
 		RequiresCollector:     true,
 		AllowedCollectorKinds: []string{collectorKind},
+		ConfigType:            reflect.TypeFor[S](),
 	}
 }
 
@@ -213,8 +227,9 @@ func NewTypedStepDescriptorWithoutCollector[S any](
 	f TypedStepFactoryWithoutCollector[S],
 ) StepDescriptor {
 	return StepDescriptor{
-		Kind:    kind,
-		Factory: NewStepFactoryWithoutCollector(kind, f),
+		Kind:       kind,
+		Factory:    NewStepFactoryWithoutCollector(kind, f),
+		ConfigType: reflect.TypeFor[S](),
 	}
 }
 
@@ -260,16 +275,18 @@ func MustGetRegistryDependency[T any](h *RegistryHelper, key string) T {
 }
 
 type Registry struct {
-	mu         sync.RWMutex
-	collectors map[string]CollectorFactory
-	steps      map[string]StepDescriptor
-	helper     *RegistryHelper
+	mu               sync.RWMutex
+	collectors       map[string]CollectorFactory
+	collectorConfigs map[string]reflect.Type
+	steps            map[string]StepDescriptor
+	helper           *RegistryHelper
 }
 
 func NewRegistry(logger *zap.Logger) *Registry {
 	return &Registry{
-		collectors: make(map[string]CollectorFactory),
-		steps:      make(map[string]StepDescriptor),
+		collectors:       make(map[string]CollectorFactory),
+		collectorConfigs: make(map[string]reflect.Type),
+		steps:            make(map[string]StepDescriptor),
 		helper: &RegistryHelper{
 			logger: logger,
 			deps:   make(map[string]any),
@@ -303,6 +320,28 @@ func (r *Registry) RegisterCollector(kind string, factory CollectorFactory) erro
 	return nil
 }
 
+// RegisterTypedCollector installs a typed collector factory under its kind
+// and records its config struct so tooling (schema export) can describe the
+// collector body. It is the collector counterpart of NewTypedStepDescriptor.
+func RegisterTypedCollector[T any](r *Registry, kind string, f TypedCollectorFactory[T]) error {
+	if err := r.RegisterCollector(kind, NewCollectorFactory(kind, f)); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectorConfigs[kind] = reflect.TypeFor[T]()
+	return nil
+}
+
+// CollectorConfigType returns the config struct recorded for a collector
+// kind by RegisterTypedCollector, if any.
+func (r *Registry) CollectorConfigType(kind string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.collectorConfigs[kind]
+	return t, ok
+}
+
 // RegisterStep installs a step descriptor under its Kind. It rejects
 // malformed descriptors up-front so mistakes surface at boot time rather
 // than as late factory-dispatch failures:
@@ -1,30 +1,76 @@
 package sinks
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
 
 	"github.com/infracollect/infracollect/internal/engine"
 )
 
+// ArchivePartsManifestSuffix is appended to the archive name to name the
+// manifest written next to the parts of a split archive.
+const ArchivePartsManifestSuffix = ".parts.json"
+
 // ArchiveSink wraps a sink and collects all writes into an archive.
-// On Close, it finalizes the archive and writes a single file to the inner sink.
+// On Close, it finalizes the archive and writes a single file to the inner
+// sink, or numbered parts and a manifest when the archive is larger than
+// the split size.
 type ArchiveSink struct {
 	inner       engine.Sink
 	archiver    engine.Archiver
 	archiveName string
+	splitSize   int64 // 0 never splits
+}
+
+// ArchiveSinkOption configures an ArchiveSink.
+type ArchiveSinkOption func(*ArchiveSink)
+
+// WithSplitSize splits an archive larger than size bytes into parts of at
+// most size bytes, written as <name>.001, <name>.002, ... alongside a
+// <name>.parts.json manifest listing them with their SHA-256 checksums.
+// Concatenating the parts in order gives back the archive. An archive of
+// size bytes or less is written whole, as without the option. Values below 1
+// disable splitting.
+func WithSplitSize(size int64) ArchiveSinkOption {
+	return func(s *ArchiveSink) {
+		s.splitSize = max(size, 0)
+	}
+}
+
+// ArchiveParts is the manifest of a split archive.
+type ArchiveParts struct {
+	Archive string        `json:"archive"`
+	Size    int64         `json:"size"`
+	SHA256  string        `json:"sha256"`
+	Parts   []ArchivePart `json:"parts"`
+}
+
+// ArchivePart is one part of a split archive.
+type ArchivePart struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
 }
 
 // NewArchiveSink creates a new archive sink that wraps the given inner sink.
 // All writes are collected into the archiver, and on Close, the complete archive
 // is written to the inner sink with the specified archive name.
-func NewArchiveSink(inner engine.Sink, archiver engine.Archiver, archiveName string) *ArchiveSink {
-	return &ArchiveSink{
+func NewArchiveSink(inner engine.Sink, archiver engine.Archiver, archiveName string, opts ...ArchiveSinkOption) *ArchiveSink {
+	s := &ArchiveSink{
 		inner:       inner,
 		archiver:    archiver,
 		archiveName: archiveName,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Name returns the name of this sink.
@@ -52,13 +98,98 @@ func (s *ArchiveSink) Close(ctx context.Context) error {
 		return fmt.Errorf("failed to finalize archive: %w", err)
 	}
 
+	if s.splitSize > 0 {
+		var size int64
+		reader, size, err = sizedReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		if size > s.splitSize {
+			if err := s.writeParts(ctx, reader, size); err != nil {
+				return err
+			}
+			return s.closeInner(ctx)
+		}
+	}
+
 	if err := s.inner.Write(ctx, s.archiveName, reader); err != nil {
 		return fmt.Errorf("failed to write archive to sink: %w", err)
 	}
 
+	return s.closeInner(ctx)
+}
+
+func (s *ArchiveSink) closeInner(ctx context.Context) error {
 	if err := s.inner.Close(ctx); err != nil {
 		return fmt.Errorf("failed to close inner sink: %w", err)
 	}
+	return nil
+}
+
+// writeParts writes the size bytes of reader as parts of at most splitSize
+// bytes, then the manifest listing them.
+func (s *ArchiveSink) writeParts(ctx context.Context, reader io.Reader, size int64) error {
+	whole := sha256.New()
+	reader = io.TeeReader(reader, whole)
+
+	manifest := ArchiveParts{Archive: s.archiveName, Size: size}
+	for offset, n := int64(0), 1; offset < size; offset, n = offset+s.splitSize, n+1 {
+		part := ArchivePart{
+			Name: fmt.Sprintf("%s.%03d", s.archiveName, n),
+			Size: min(s.splitSize, size-offset),
+		}
+		sum := sha256.New()
+		data := io.TeeReader(io.LimitReader(reader, part.Size), sum)
+		if err := s.inner.Write(ctx, part.Name, data); err != nil {
+			return fmt.Errorf("failed to write archive part %s to sink: %w", part.Name, err)
+		}
+		// The inner sink may stop reading early; drain the rest of the part
+		// so the checksums cover all of it.
+		if _, err := io.Copy(io.Discard, data); err != nil {
+			return fmt.Errorf("failed to read archive part %s: %w", part.Name, err)
+		}
+		part.SHA256 = hexSum(sum)
+		manifest.Parts = append(manifest.Parts, part)
+	}
+	manifest.SHA256 = hexSum(whole)
 
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode archive parts manifest: %w", err)
+	}
+	name := s.archiveName + ArchivePartsManifestSuffix
+	if err := s.inner.Write(ctx, name, bytes.NewReader(append(data, '\n'))); err != nil {
+		return fmt.Errorf("failed to write archive parts manifest to sink: %w", err)
+	}
 	return nil
 }
+
+// sizedReader returns the remaining length of r along with a reader over the
+// same bytes. Seekable readers are measured in place; others are read into
+// memory.
+func sizedReader(r io.Reader) (io.Reader, int64, error) {
+	if seeker, ok := r.(io.Seeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, err
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+		return r, end - start, nil
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
+func hexSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -5,7 +5,12 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io"
+	"strings"
 	"testing"
 
 	"github.com/infracollect/infracollect/internal/engine/archivers"
@@ -69,7 +74,7 @@ func readGzipTarToMap(data []byte) (map[string]string, error) {
 
 func newArchiveSinkWithGzip(t *testing.T, archiveName string) (*ArchiveSink, *mockSink) {
 	t.Helper()
-	archiver, err := archivers.NewTarArchiver("gzip")
+	archiver, err := archivers.NewTarArchiver("gzip", nil)
 	require.NoError(t, err)
 	mock := newMockSink()
 	return NewArchiveSink(mock, archiver, archiveName), mock
@@ -125,3 +130,109 @@ func TestArchiveSink_NameAndKind(t *testing.T) {
 	assert.Equal(t, "archive(output.tar.gz)->mock", sink.Name())
 	assert.Equal(t, "archive", sink.Kind())
 }
+
+func TestArchiveSink_SplitSize(t *testing.T) {
+	archiver, err := archivers.NewTarArchiver("gzip", nil)
+	require.NoError(t, err)
+	inner := newMockSink()
+	sink := NewArchiveSink(inner, archiver, "bundle.tar.gz", WithSplitSize(64))
+	ctx := t.Context()
+
+	files := map[string]string{
+		"step1.json": `{"step":1}`,
+		"step2.json": `{"step":2}`,
+	}
+	for name, content := range files {
+		require.NoError(t, sink.Write(ctx, name, strings.NewReader(content)))
+	}
+	require.NoError(t, sink.Close(ctx))
+	assert.True(t, inner.closed)
+	assert.NotContains(t, inner.writes, "bundle.tar.gz")
+
+	require.Contains(t, inner.writes, "bundle.tar.gz"+ArchivePartsManifestSuffix)
+	var manifest ArchiveParts
+	require.NoError(t, json.Unmarshal(inner.writes["bundle.tar.gz"+ArchivePartsManifestSuffix], &manifest))
+	assert.Equal(t, "bundle.tar.gz", manifest.Archive)
+	require.Greater(t, len(manifest.Parts), 1)
+	assert.Len(t, inner.writes, len(manifest.Parts)+1)
+
+	var whole []byte
+	for i, part := range manifest.Parts {
+		assert.Equal(t, fmt.Sprintf("bundle.tar.gz.%03d", i+1), part.Name)
+		data := inner.writes[part.Name]
+		if i < len(manifest.Parts)-1 {
+			assert.Len(t, data, 64)
+		}
+		assert.Equal(t, int64(len(data)), part.Size)
+		sum := sha256.Sum256(data)
+		assert.Equal(t, hex.EncodeToString(sum[:]), part.SHA256)
+		whole = append(whole, data...)
+	}
+	assert.Equal(t, int64(len(whole)), manifest.Size)
+	sum := sha256.Sum256(whole)
+	assert.Equal(t, hex.EncodeToString(sum[:]), manifest.SHA256)
+
+	found, err := readGzipTarToMap(whole)
+	require.NoError(t, err)
+	assert.Equal(t, files, found, "the parts reassemble into the archive")
+}
+
+// fixedArchiver returns its data, wrapped by wrap, as the finalized archive.
+type fixedArchiver struct {
+	data string
+	wrap func(io.Reader) io.Reader
+}
+
+func (a *fixedArchiver) AddFile(context.Context, string, io.Reader) error { return nil }
+func (a *fixedArchiver) Extension() string                                { return ".bin" }
+func (a *fixedArchiver) Close() (io.Reader, error) {
+	return a.wrap(strings.NewReader(a.data)), nil
+}
+
+func TestArchiveSink_SplitSizeBoundaries(t *testing.T) {
+	seekable := func(r io.Reader) io.Reader { return r }
+	streamed := func(r io.Reader) io.Reader { return io.MultiReader(r) }
+
+	tests := []struct {
+		name      string
+		data      string
+		splitSize int64
+		wrap      func(io.Reader) io.Reader
+		wantParts map[string]string
+	}{
+		{name: "below the threshold", data: "abcdef", splitSize: 10, wrap: seekable},
+		{name: "at the threshold", data: "abcdef", splitSize: 6, wrap: seekable},
+		{name: "disabled", data: "abcdef", splitSize: 0, wrap: seekable},
+		{
+			name:      "uneven last part",
+			data:      "abcdefg",
+			splitSize: 3,
+			wrap:      seekable,
+			wantParts: map[string]string{"out.bin.001": "abc", "out.bin.002": "def", "out.bin.003": "g"},
+		},
+		{
+			name:      "unseekable archive",
+			data:      "abcdef",
+			splitSize: 4,
+			wrap:      streamed,
+			wantParts: map[string]string{"out.bin.001": "abcd", "out.bin.002": "ef"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newMockSink()
+			sink := NewArchiveSink(inner, &fixedArchiver{data: tt.data, wrap: tt.wrap}, "out.bin", WithSplitSize(tt.splitSize))
+			require.NoError(t, sink.Close(t.Context()))
+
+			if tt.wantParts == nil {
+				assert.Equal(t, map[string][]byte{"out.bin": []byte(tt.data)}, inner.writes)
+				return
+			}
+			for name, want := range tt.wantParts {
+				assert.Equal(t, want, string(inner.writes[name]), name)
+			}
+			assert.Len(t, inner.writes, len(tt.wantParts)+1)
+			assert.Contains(t, inner.writes, "out.bin"+ArchivePartsManifestSuffix)
+		})
+	}
+}
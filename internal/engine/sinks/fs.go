@@ -14,6 +14,9 @@ import (
 
 type FilesystemSink struct {
 	fs afero.Fs
+	// path is the output directory when the sink was created from one; it
+	// names the sink in logs and run reports.
+	path string
 }
 
 func NewFilesystemSink(fs afero.Fs) engine.Sink {
@@ -28,10 +31,13 @@ func NewFilesystemSinkFromPath(path string) (engine.Sink, error) {
 		return nil, fmt.Errorf("failed to create output directory %s: %w", cleanPath, err)
 	}
 
-	return NewFilesystemSink(afero.NewBasePathFs(afero.NewOsFs(), cleanPath)), nil
+	return &FilesystemSink{fs: afero.NewBasePathFs(afero.NewOsFs(), cleanPath), path: cleanPath}, nil
 }
 
 func (s *FilesystemSink) Name() string {
+	if s.path != "" {
+		return fmt.Sprintf("filesystem(%s)", s.path)
+	}
 	return fmt.Sprintf("filesystem(%s)", s.fs.Name())
 }
 
@@ -63,6 +69,20 @@ func (s *FilesystemSink) Write(ctx context.Context, path string, data io.Reader)
 	return nil
 }
 
+// ChecksExistence reports true: files can always be looked up.
+func (s *FilesystemSink) ChecksExistence() bool {
+	return true
+}
+
+// Exists reports whether a file is already written at path.
+func (s *FilesystemSink) Exists(ctx context.Context, path string) (bool, error) {
+	exists, err := afero.Exists(s.fs, path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return exists, nil
+}
+
 func (s *FilesystemSink) Close(ctx context.Context) error {
 	return nil
 }
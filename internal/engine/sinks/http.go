@@ -0,0 +1,183 @@
+package sinks
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+const (
+	HTTPCompressionNone    = "none"
+	HTTPCompressionGzip    = "gzip"
+	HTTPCompressionDeflate = "deflate"
+
+	// HTTPPathHeader carries the output path of the file in each request, so
+	// a single endpoint can tell the written files apart.
+	HTTPPathHeader = "X-Infracollect-Path"
+
+	// maxErrorBodyBytes bounds how much of a failed response is quoted in the
+	// returned error.
+	maxErrorBodyBytes = 512
+
+	// DefaultHTTPSinkTimeout bounds each request, upload included, when
+	// HTTPConfig.Timeout is zero.
+	DefaultHTTPSinkTimeout = 5 * time.Minute
+)
+
+// HTTPConfig contains configuration for the HTTP sink.
+type HTTPConfig struct {
+	URL     string
+	Headers map[string]string
+	// Compress is the request body encoding: "none" (or empty), "gzip" or
+	// "deflate".
+	Compress string
+	// Username and Password, when Username is set, authenticate every
+	// request with HTTP basic auth.
+	Username string
+	Password string
+	// Timeout bounds each request, from connecting to reading the response.
+	// Zero selects DefaultHTTPSinkTimeout. It is ignored when Client is set.
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+// HTTPSink POSTs every written file to a single URL.
+type HTTPSink struct {
+	url      string
+	headers  map[string]string
+	compress string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewHTTPSink creates a new HTTP sink with the given configuration.
+func NewHTTPSink(cfg HTTPConfig) (engine.Sink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	compress := cfg.Compress
+	if compress == "" {
+		compress = HTTPCompressionNone
+	}
+	switch compress {
+	case HTTPCompressionNone, HTTPCompressionGzip, HTTPCompressionDeflate:
+	default:
+		return nil, fmt.Errorf("unsupported compress %q (supported: none, gzip, deflate)", cfg.Compress)
+	}
+
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must not be negative")
+	}
+	client := cfg.Client
+	if client == nil {
+		client = cleanhttp.DefaultPooledClient()
+		client.Timeout = cfg.Timeout
+		if client.Timeout == 0 {
+			client.Timeout = DefaultHTTPSinkTimeout
+		}
+	}
+
+	return &HTTPSink{
+		url:      cfg.URL,
+		headers:  cfg.Headers,
+		compress: compress,
+		username: cfg.Username,
+		password: cfg.Password,
+		client:   client,
+	}, nil
+}
+
+func (s *HTTPSink) Name() string {
+	return fmt.Sprintf("http(%s)", s.url)
+}
+
+func (s *HTTPSink) Kind() string {
+	return "http"
+}
+
+// Write POSTs data to the sink URL. With compression enabled the body is
+// compressed through a pipe while it is sent, so the payload is never held in
+// memory as a whole.
+func (s *HTTPSink) Write(ctx context.Context, path string, data io.Reader) error {
+	body := data
+	if s.compress != HTTPCompressionNone {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(s.compressTo(pw, data))
+		}()
+		defer pr.Close()
+		body = pr
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request for %s: %w", s.url, err)
+	}
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	req.Header.Set(HTTPPathHeader, path)
+	if contentType := contentTypeFromPath(path); contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if s.compress != HTTPCompressionNone {
+		req.Header.Set("Content-Encoding", s.compress)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post %s to %s: %w", path, s.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	if resp.StatusCode == http.StatusUnsupportedMediaType && s.compress != HTTPCompressionNone {
+		return Permanent(fmt.Errorf("%s rejected Content-Encoding %s for %s (%s); set compress = \"none\" if the server does not accept compressed bodies",
+			s.url, s.compress, path, resp.Status))
+	}
+	err = fmt.Errorf("failed to post %s to %s: %s: %s", path, s.url, resp.Status, strings.TrimSpace(string(snippet)))
+	if isPermanentHTTPStatus(resp.StatusCode) {
+		return Permanent(err)
+	}
+	return err
+}
+
+// compressTo copies data into w through the configured compressor.
+func (s *HTTPSink) compressTo(w io.Writer, data io.Reader) error {
+	var zw io.WriteCloser
+	switch s.compress {
+	case HTTPCompressionGzip:
+		zw = gzip.NewWriter(w)
+	case HTTPCompressionDeflate:
+		zw = zlib.NewWriter(w)
+	default:
+		return fmt.Errorf("unsupported compress %q", s.compress)
+	}
+	if _, err := io.Copy(zw, data); err != nil {
+		return errors.Join(fmt.Errorf("failed to compress body: %w", err), zw.Close())
+	}
+	return zw.Close()
+}
+
+func (s *HTTPSink) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,173 @@
+package sinks
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedRequest is what the test server saw for a single POST.
+type receivedRequest struct {
+	header http.Header
+	body   string
+}
+
+func newRecordingServer(t *testing.T, status int) (*httptest.Server, *receivedRequest) {
+	t.Helper()
+	got := &receivedRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		switch r.Header.Get("Content-Encoding") {
+		case "gzip":
+			zr, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = zr
+		case "deflate":
+			zr, err := zlib.NewReader(r.Body)
+			require.NoError(t, err)
+			body = zr
+		}
+		data, err := io.ReadAll(body)
+		require.NoError(t, err)
+
+		got.header = r.Header.Clone()
+		got.body = string(data)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("nope"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+func TestHTTPSink_Write(t *testing.T) {
+	tests := []struct {
+		name         string
+		compress     string
+		wantEncoding string
+	}{
+		{name: "uncompressed by default", compress: "", wantEncoding: ""},
+		{name: "none", compress: "none", wantEncoding: ""},
+		{name: "gzip", compress: "gzip", wantEncoding: "gzip"},
+		{name: "deflate", compress: "deflate", wantEncoding: "deflate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, got := newRecordingServer(t, http.StatusOK)
+			sink, err := NewHTTPSink(HTTPConfig{
+				URL:      srv.URL,
+				Headers:  map[string]string{"Authorization": "Bearer token"},
+				Compress: tt.compress,
+			})
+			require.NoError(t, err)
+
+			payload := strings.Repeat(`{"a":1}`, 1000)
+			require.NoError(t, sink.Write(t.Context(), "static/a.json", strings.NewReader(payload)))
+
+			assert.Equal(t, payload, got.body)
+			assert.Equal(t, tt.wantEncoding, got.header.Get("Content-Encoding"))
+			assert.Equal(t, "static/a.json", got.header.Get(HTTPPathHeader))
+			assert.Equal(t, "application/json", got.header.Get("Content-Type"))
+			assert.Equal(t, "Bearer token", got.header.Get("Authorization"))
+		})
+	}
+}
+
+func TestHTTPSink_BasicAuth(t *testing.T) {
+	srv, got := newRecordingServer(t, http.StatusOK)
+	sink, err := NewHTTPSink(HTTPConfig{URL: srv.URL, Username: "collector", Password: "s3cr3t"})
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Write(t.Context(), "static/a.json", strings.NewReader("{}")))
+	assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("collector:s3cr3t")), got.header.Get("Authorization"))
+	assert.NotContains(t, sink.Name(), "s3cr3t")
+}
+
+func TestHTTPSink_WriteErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		compress      string
+		wantErr       string
+		wantPermanent bool
+	}{
+		{
+			name:          "rejected encoding",
+			status:        http.StatusUnsupportedMediaType,
+			compress:      "gzip",
+			wantErr:       "rejected Content-Encoding gzip",
+			wantPermanent: true,
+		},
+		{
+			name:    "server error quotes the response",
+			status:  http.StatusInternalServerError,
+			wantErr: "500 Internal Server Error: nope",
+		},
+		{
+			name:          "auth failure is permanent",
+			status:        http.StatusUnauthorized,
+			wantErr:       "401 Unauthorized",
+			wantPermanent: true,
+		},
+		{
+			name:    "throttling is retryable",
+			status:  http.StatusTooManyRequests,
+			wantErr: "429 Too Many Requests",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := newRecordingServer(t, tt.status)
+			sink, err := NewHTTPSink(HTTPConfig{URL: srv.URL, Compress: tt.compress})
+			require.NoError(t, err)
+
+			err = sink.Write(t.Context(), "static/a.json", strings.NewReader("{}"))
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantPermanent, IsPermanent(err))
+		})
+	}
+}
+
+func TestHTTPSink_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	sink, err := NewHTTPSink(HTTPConfig{URL: srv.URL, Timeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+
+	err = sink.Write(t.Context(), "static/a.json", strings.NewReader("{}"))
+	require.Error(t, err, "an unresponsive endpoint does not hang the write")
+	assert.False(t, IsPermanent(err), "a timeout is worth retrying")
+}
+
+func TestNewHTTPSink_DefaultTimeout(t *testing.T) {
+	sink, err := NewHTTPSink(HTTPConfig{URL: "http://example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultHTTPSinkTimeout, sink.(*HTTPSink).client.Timeout)
+}
+
+func TestNewHTTPSink_Validation(t *testing.T) {
+	_, err := NewHTTPSink(HTTPConfig{})
+	assert.ErrorContains(t, err, "url is required")
+
+	_, err = NewHTTPSink(HTTPConfig{URL: "http://example.com", Compress: "brotli"})
+	assert.ErrorContains(t, err, `unsupported compress "brotli"`)
+
+	_, err = NewHTTPSink(HTTPConfig{URL: "http://example.com", Timeout: -time.Second})
+	assert.ErrorContains(t, err, "timeout must not be negative")
+}
@@ -0,0 +1,95 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+// MultiSink fans every write out to several sinks.
+type MultiSink struct {
+	sinks []engine.Sink
+}
+
+// NewMultiSink creates a sink that writes to, and closes, each of sinks.
+func NewMultiSink(sinks ...engine.Sink) *MultiSink {
+	return &MultiSink{sinks: sinks}
+}
+
+// Name returns the name of this sink.
+func (s *MultiSink) Name() string {
+	names := make([]string, len(s.sinks))
+	for i, sink := range s.sinks {
+		names[i] = sink.Name()
+	}
+	return fmt.Sprintf("multi(%s)", strings.Join(names, ", "))
+}
+
+// Kind returns the kind of this sink.
+func (s *MultiSink) Kind() string {
+	return "multi"
+}
+
+// Write buffers data once, since a reader can only be consumed once, and
+// hands each sink its own reader over the buffer. Every sink is attempted
+// even when an earlier one fails; the failures are joined.
+func (s *MultiSink) Write(ctx context.Context, path string, data io.Reader) error {
+	buff, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("failed to read data: %w", err)
+	}
+
+	var errs []error
+	for _, sink := range s.sinks {
+		if err := sink.Write(ctx, path, bytes.NewReader(buff)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ChecksExistence reports whether every sink can answer existence checks.
+func (s *MultiSink) ChecksExistence() bool {
+	for _, sink := range s.sinks {
+		if _, ok := engine.AsExistenceChecker(sink); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Exists reports whether path is written to every sink; an object missing
+// from any of them still needs writing.
+func (s *MultiSink) Exists(ctx context.Context, path string) (bool, error) {
+	for _, sink := range s.sinks {
+		checker, ok := engine.AsExistenceChecker(sink)
+		if !ok {
+			return false, fmt.Errorf("%s: existence checks are not supported", sink.Name())
+		}
+		exists, err := checker.Exists(ctx, path)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", sink.Name(), err)
+		}
+		if !exists {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Close closes every sink, continuing past errors so each gets a chance to
+// flush.
+func (s *MultiSink) Close(ctx context.Context) error {
+	var errs []error
+	for _, sink := range s.sinks {
+		if err := sink.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
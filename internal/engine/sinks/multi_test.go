@@ -0,0 +1,83 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingSink fails every Write and Close with err.
+type failingSink struct {
+	err    error
+	closed bool
+}
+
+func (f *failingSink) Name() string { return "failing" }
+func (f *failingSink) Kind() string { return "failing" }
+
+func (f *failingSink) Write(context.Context, string, io.Reader) error { return f.err }
+
+func (f *failingSink) Close(context.Context) error {
+	f.closed = true
+	return f.err
+}
+
+func TestMultiSink_WritesToEverySink(t *testing.T) {
+	first, second := newMockSink(), newMockSink()
+	sink := NewMultiSink(first, second)
+
+	require.NoError(t, sink.Write(t.Context(), "static/a.json", strings.NewReader(`{"a":1}`)))
+	require.NoError(t, sink.Close(t.Context()))
+
+	assert.Equal(t, map[string][]byte{"static/a.json": []byte(`{"a":1}`)}, first.writes)
+	assert.Equal(t, first.writes, second.writes)
+	assert.True(t, first.closed)
+	assert.True(t, second.closed)
+	assert.Equal(t, "multi(mock, mock)", sink.Name())
+}
+
+func TestMultiSink_JoinsErrorsAndContinues(t *testing.T) {
+	boom := errors.New("boom")
+	failing := &failingSink{err: boom}
+	healthy := newMockSink()
+	sink := NewMultiSink(failing, healthy)
+
+	err := sink.Write(t.Context(), "static/a.json", strings.NewReader("data"))
+	assert.ErrorIs(t, err, boom)
+	assert.ErrorContains(t, err, "failing: boom")
+	assert.Equal(t, []byte("data"), healthy.writes["static/a.json"], "later sinks still receive the write")
+
+	err = sink.Close(t.Context())
+	assert.ErrorIs(t, err, boom)
+	assert.True(t, failing.closed)
+	assert.True(t, healthy.closed, "later sinks are still closed")
+}
+
+func TestMultiSink_Exists(t *testing.T) {
+	first, second := afero.NewMemMapFs(), afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(first, "static/a.json", []byte("{}"), 0o644))
+	require.NoError(t, afero.WriteFile(first, "static/b.json", []byte("{}"), 0o644))
+	require.NoError(t, afero.WriteFile(second, "static/a.json", []byte("{}"), 0o644))
+	sink := NewMultiSink(NewFilesystemSink(first), NewFilesystemSink(second))
+
+	checker, ok := engine.AsExistenceChecker(sink)
+	require.True(t, ok)
+
+	exists, err := checker.Exists(t.Context(), "static/a.json")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = checker.Exists(t.Context(), "static/b.json")
+	require.NoError(t, err)
+	assert.False(t, exists, "an object missing from one sink still needs writing")
+
+	_, ok = engine.AsExistenceChecker(NewMultiSink(NewFilesystemSink(first), &failingSink{}))
+	assert.False(t, ok, "one sink without existence checks disables them")
+}
@@ -0,0 +1,167 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+const (
+	DefaultRetryMaxAttempts = 3
+	DefaultRetryBaseDelay   = time.Second
+	DefaultRetryMaxDelay    = 30 * time.Second
+)
+
+// permanentError marks a write failure that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, e.g. a rejected credential or a
+// missing bucket. RetrySink gives up on such errors at once. A nil err stays
+// nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err, or any error it wraps, was marked with
+// Permanent.
+func IsPermanent(err error) bool {
+	var perm *permanentError
+	return errors.As(err, &perm)
+}
+
+// isPermanentHTTPStatus reports whether a response status means the request
+// itself is wrong (bad auth, missing target, invalid payload), so sending it
+// again would fail the same way. Timeouts and throttling are retryable.
+func isPermanentHTTPStatus(code int) bool {
+	if code == http.StatusRequestTimeout || code == http.StatusTooManyRequests {
+		return false
+	}
+	return code >= 400 && code < 500
+}
+
+// RetryConfig controls how RetrySink retries failed writes.
+type RetryConfig struct {
+	// MaxAttempts is the total number of tries per write, including the
+	// first. Zero selects DefaultRetryMaxAttempts.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry, doubled after each
+	// attempt up to MaxDelay. Zero values select the defaults.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// RetrySink retries failed writes to the sink it wraps with exponential
+// backoff. Errors marked with Permanent are returned without retrying.
+type RetrySink struct {
+	inner       engine.Sink
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// NewRetrySink wraps inner so that each Write is retried according to cfg.
+func NewRetrySink(inner engine.Sink, cfg RetryConfig) (*RetrySink, error) {
+	if cfg.MaxAttempts < 0 {
+		return nil, fmt.Errorf("max_attempts must not be negative")
+	}
+	if cfg.BaseDelay < 0 || cfg.MaxDelay < 0 {
+		return nil, fmt.Errorf("retry delays must not be negative")
+	}
+
+	s := &RetrySink{
+		inner:       inner,
+		maxAttempts: cfg.MaxAttempts,
+		baseDelay:   cfg.BaseDelay,
+		maxDelay:    cfg.MaxDelay,
+	}
+	if s.maxAttempts == 0 {
+		s.maxAttempts = DefaultRetryMaxAttempts
+	}
+	if s.baseDelay == 0 {
+		s.baseDelay = DefaultRetryBaseDelay
+	}
+	if s.maxDelay == 0 {
+		s.maxDelay = DefaultRetryMaxDelay
+	}
+	if s.baseDelay > s.maxDelay {
+		return nil, fmt.Errorf("base_delay %s exceeds max_delay %s", s.baseDelay, s.maxDelay)
+	}
+	return s, nil
+}
+
+// Name returns the wrapped sink's name; retrying does not change where the
+// data goes.
+func (s *RetrySink) Name() string {
+	return s.inner.Name()
+}
+
+// Kind returns the wrapped sink's kind.
+func (s *RetrySink) Kind() string {
+	return s.inner.Kind()
+}
+
+// Write buffers data once so every attempt can replay it, then writes to the
+// wrapped sink until it succeeds, fails with a permanent error, the attempts
+// run out, or ctx is done.
+func (s *RetrySink) Write(ctx context.Context, path string, data io.Reader) error {
+	buff, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("failed to read data: %w", err)
+	}
+
+	delay := s.baseDelay
+	for attempt := 1; ; attempt++ {
+		err := s.inner.Write(ctx, path, bytes.NewReader(buff))
+		if err == nil {
+			return nil
+		}
+		if IsPermanent(err) {
+			return err
+		}
+		if attempt >= s.maxAttempts {
+			return fmt.Errorf("giving up after %d attempt(s): %w", attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, s.maxDelay)
+	}
+}
+
+// ChecksExistence reports whether the wrapped sink can answer existence
+// checks.
+func (s *RetrySink) ChecksExistence() bool {
+	_, ok := engine.AsExistenceChecker(s.inner)
+	return ok
+}
+
+// Exists asks the wrapped sink, without retrying.
+func (s *RetrySink) Exists(ctx context.Context, path string) (bool, error) {
+	checker, ok := engine.AsExistenceChecker(s.inner)
+	if !ok {
+		return false, fmt.Errorf("%s: existence checks are not supported", s.inner.Name())
+	}
+	return checker.Exists(ctx, path)
+}
+
+// Close closes the wrapped sink without retrying.
+func (s *RetrySink) Close(ctx context.Context) error {
+	return s.inner.Close(ctx)
+}
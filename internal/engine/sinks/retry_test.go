@@ -0,0 +1,127 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakySink fails its first len(errs) writes with errs, in order, then
+// succeeds and records the payload.
+type flakySink struct {
+	errs     []error
+	attempts int
+	written  string
+}
+
+func (f *flakySink) Name() string { return "flaky" }
+func (f *flakySink) Kind() string { return "flaky" }
+
+func (f *flakySink) Write(_ context.Context, _ string, data io.Reader) error {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	f.attempts++
+	if f.attempts <= len(f.errs) {
+		return f.errs[f.attempts-1]
+	}
+	f.written = string(content)
+	return nil
+}
+
+func (f *flakySink) Close(context.Context) error { return nil }
+
+func TestRetrySink_Write(t *testing.T) {
+	transient := errors.New("connection reset")
+	denied := Permanent(errors.New("access denied"))
+
+	tests := []struct {
+		name         string
+		errs         []error
+		wantAttempts int
+		wantErr      string
+	}{
+		{
+			name:         "succeeds first time",
+			wantAttempts: 1,
+		},
+		{
+			name:         "replays the payload after transient errors",
+			errs:         []error{transient, transient},
+			wantAttempts: 3,
+		},
+		{
+			name:         "gives up after max attempts",
+			errs:         []error{transient, transient, transient},
+			wantAttempts: 3,
+			wantErr:      "giving up after 3 attempt(s): connection reset",
+		},
+		{
+			name:         "permanent errors are not retried",
+			errs:         []error{denied},
+			wantAttempts: 1,
+			wantErr:      "access denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &flakySink{errs: tt.errs}
+			sink, err := NewRetrySink(inner, RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond})
+			require.NoError(t, err)
+
+			err = sink.Write(t.Context(), "static/a.json", strings.NewReader("payload"))
+			assert.Equal(t, tt.wantAttempts, inner.attempts)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "payload", inner.written)
+		})
+	}
+}
+
+func TestRetrySink_StopsOnCancel(t *testing.T) {
+	inner := &flakySink{errs: []error{errors.New("boom"), errors.New("boom")}}
+	sink, err := NewRetrySink(inner, RetryConfig{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	err = sink.Write(ctx, "a.json", strings.NewReader("{}"))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, inner.attempts)
+}
+
+func TestRetrySink_NameIsInner(t *testing.T) {
+	sink, err := NewRetrySink(newMockSink(), RetryConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "mock", sink.Name())
+	assert.Equal(t, "mock", sink.Kind())
+}
+
+func TestNewRetrySink_Validation(t *testing.T) {
+	_, err := NewRetrySink(newMockSink(), RetryConfig{MaxAttempts: -1})
+	assert.ErrorContains(t, err, "max_attempts must not be negative")
+
+	_, err = NewRetrySink(newMockSink(), RetryConfig{BaseDelay: time.Minute, MaxDelay: time.Second})
+	assert.ErrorContains(t, err, "base_delay 1m0s exceeds max_delay 1s")
+}
+
+func TestPermanent(t *testing.T) {
+	assert.NoError(t, Permanent(nil))
+
+	base := errors.New("denied")
+	err := errors.Join(errors.New("context"), Permanent(base))
+	assert.True(t, IsPermanent(err))
+	assert.ErrorIs(t, err, base)
+	assert.False(t, IsPermanent(base))
+}
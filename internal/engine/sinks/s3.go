@@ -1,25 +1,79 @@
 package sinks
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"path"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/infracollect/infracollect/internal/engine"
 )
 
+const (
+	DefaultS3MaxAttempts    = 3
+	DefaultS3RetryBaseDelay = 500 * time.Millisecond
+
+	maxS3RetryDelay = 30 * time.Second
+
+	// maxS3ObjectTags is the number of tags S3 accepts on a single object.
+	maxS3ObjectTags = 10
+
+	// MaxS3PresignExpiry is the longest validity SigV4 allows for a
+	// presigned URL.
+	MaxS3PresignExpiry = 7 * 24 * time.Hour
+	// S3PresignedURLsFile is the sidecar object, next to the outputs, that
+	// maps each output path to its presigned GET URL.
+	S3PresignedURLsFile = "presigned-urls.json"
+)
+
+// retryableS3ErrorCodes are the S3 error codes that signal throttling or a
+// transient server-side failure.
+var retryableS3ErrorCodes = []string{
+	"SlowDown",
+	"ServiceUnavailable",
+	"InternalError",
+	"RequestTimeout",
+	"Throttling",
+	"ThrottlingException",
+	"RequestLimitExceeded",
+}
+
 // S3Uploader is an interface for uploading objects to S3.
 // This allows for easy mocking in tests.
 type S3Uploader interface {
 	Upload(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error)
 }
 
+// S3Presigner is an interface for presigning GetObject requests.
+// This allows for easy mocking in tests.
+type S3Presigner interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+// S3ObjectLookup is an interface for looking up object metadata.
+// This allows for easy mocking in tests.
+type S3ObjectLookup interface {
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
 // S3Config contains configuration for the S3 sink.
 type S3Config struct {
 	Bucket          string
@@ -29,6 +83,27 @@ type S3Config struct {
 	AccessKeyID     string
 	SecretAccessKey string
 	ForcePathStyle  bool
+
+	// RoleARN and WebIdentityTokenFile assume a role with an OIDC token,
+	// e.g. the one GitHub Actions issues. RoleSessionName is optional.
+	RoleARN              string
+	WebIdentityTokenFile string
+	RoleSessionName      string
+
+	// MaxAttempts bounds how many times an upload is tried when S3 answers
+	// with a retryable error; RetryBaseDelay is the wait before the first
+	// retry, doubled after each attempt. Zero values select the defaults.
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+
+	// Metadata is stored as user-defined object metadata (x-amz-meta-*) and
+	// Tags as object tags on every uploaded object.
+	Metadata map[string]string
+	Tags     map[string]string
+
+	// PresignGet, when positive, presigns a GET URL valid this long for
+	// every uploaded object; see WithS3PresignGet.
+	PresignGet time.Duration
 }
 
 // S3Sink writes output to S3-compatible object storage.
@@ -36,10 +111,87 @@ type S3Sink struct {
 	bucket   string
 	prefix   string
 	uploader S3Uploader
+
+	metadata map[string]string
+	// tagging is the URL-encoded tag set sent with every upload; empty when
+	// no tags are configured.
+	tagging string
+
+	maxAttempts    int
+	retryBaseDelay time.Duration
+
+	// lookup answers Exists; nil when existence checks are unsupported.
+	lookup S3ObjectLookup
+
+	presigner     S3Presigner
+	presignExpiry time.Duration
+	// presigned maps output paths to their presigned URLs until Close
+	// uploads them.
+	mu        sync.Mutex
+	presigned map[string]string
+}
+
+type S3SinkOption func(*S3Sink)
+
+// WithS3Retry overrides the retry policy. Values below one attempt or a
+// non-positive delay keep the respective default.
+func WithS3Retry(maxAttempts int, baseDelay time.Duration) S3SinkOption {
+	return func(s *S3Sink) {
+		if maxAttempts > 0 {
+			s.maxAttempts = maxAttempts
+		}
+		if baseDelay > 0 {
+			s.retryBaseDelay = baseDelay
+		}
+	}
+}
+
+// WithS3ObjectAttributes sets the user-defined metadata and tags stored on
+// every uploaded object.
+func WithS3ObjectAttributes(metadata, tags map[string]string) S3SinkOption {
+	return func(s *S3Sink) {
+		s.metadata = metadata
+		s.tagging = encodeS3Tagging(tags)
+	}
+}
+
+// WithS3PresignGet presigns a GET URL, valid for expiry, for every object
+// written. The URLs are uploaded on Close as S3PresignedURLsFile, keyed by
+// output path, for a downstream consumer to fetch the objects without AWS
+// credentials.
+func WithS3PresignGet(presigner S3Presigner, expiry time.Duration) S3SinkOption {
+	return func(s *S3Sink) {
+		s.presigner = presigner
+		s.presignExpiry = expiry
+		s.presigned = make(map[string]string)
+	}
+}
+
+// WithS3ObjectLookup enables Exists, which looks objects up with lookup.
+func WithS3ObjectLookup(lookup S3ObjectLookup) S3SinkOption {
+	return func(s *S3Sink) {
+		s.lookup = lookup
+	}
+}
+
+// encodeS3Tagging encodes tags as the URL query string PutObject expects.
+func encodeS3Tagging(tags map[string]string) string {
+	values := url.Values{}
+	for key, value := range tags {
+		values.Set(key, value)
+	}
+	return values.Encode()
 }
 
 // NewS3Sink creates a new S3 sink with the given configuration.
 func NewS3Sink(ctx context.Context, cfg S3Config) (engine.Sink, error) {
+	if len(cfg.Tags) > maxS3ObjectTags {
+		return nil, fmt.Errorf("s3 objects accept at most %d tags, got %d", maxS3ObjectTags, len(cfg.Tags))
+	}
+	if cfg.PresignGet < 0 || cfg.PresignGet > MaxS3PresignExpiry {
+		return nil, fmt.Errorf("presign_get must be between 0 and %s, got %s", MaxS3PresignExpiry, cfg.PresignGet)
+	}
+
 	var opts []func(*config.LoadOptions) error
 
 	// Set region if provided
@@ -47,18 +199,19 @@ func NewS3Sink(ctx context.Context, cfg S3Config) (engine.Sink, error) {
 		opts = append(opts, config.WithRegion(cfg.Region))
 	}
 
-	// Set explicit credentials if provided
-	if cfg.AccessKeyID != "" && cfg.SecretAccessKey != "" {
-		opts = append(opts, config.WithCredentialsProvider(
-			credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
-		))
-	}
-
 	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
+	provider, err := s3CredentialsProvider(awsCfg, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if provider != nil {
+		awsCfg.Credentials = provider
+	}
+
 	// Build S3 client options
 	var s3Opts []func(*s3.Options)
 
@@ -79,21 +232,59 @@ func NewS3Sink(ctx context.Context, cfg S3Config) (engine.Sink, error) {
 	client := s3.NewFromConfig(awsCfg, s3Opts...)
 	uploader := manager.NewUploader(client)
 
-	return &S3Sink{
-		bucket:   cfg.Bucket,
-		prefix:   cfg.Prefix,
-		uploader: uploader,
-	}, nil
+	sinkOpts := []S3SinkOption{
+		WithS3Retry(cfg.MaxAttempts, cfg.RetryBaseDelay),
+		WithS3ObjectAttributes(cfg.Metadata, cfg.Tags),
+		WithS3ObjectLookup(client),
+	}
+	if cfg.PresignGet > 0 {
+		sinkOpts = append(sinkOpts, WithS3PresignGet(s3.NewPresignClient(client), cfg.PresignGet))
+	}
+	return NewS3SinkWithUploader(cfg.Bucket, cfg.Prefix, uploader, sinkOpts...), nil
+}
+
+// s3CredentialsProvider picks the credentials source for cfg: static keys
+// first, then web identity, and nil to keep the default chain loaded into
+// awsCfg.
+func s3CredentialsProvider(awsCfg aws.Config, cfg S3Config) (aws.CredentialsProvider, error) {
+	if cfg.AccessKeyID != "" && cfg.SecretAccessKey != "" {
+		return credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, ""), nil
+	}
+
+	if cfg.RoleARN == "" && cfg.WebIdentityTokenFile == "" {
+		return nil, nil
+	}
+	if cfg.RoleARN == "" || cfg.WebIdentityTokenFile == "" {
+		return nil, fmt.Errorf("web identity credentials require both role_arn and web_identity_token_file")
+	}
+
+	provider := stscreds.NewWebIdentityRoleProvider(
+		sts.NewFromConfig(awsCfg),
+		cfg.RoleARN,
+		stscreds.IdentityTokenFile(cfg.WebIdentityTokenFile),
+		func(o *stscreds.WebIdentityRoleOptions) {
+			if cfg.RoleSessionName != "" {
+				o.RoleSessionName = cfg.RoleSessionName
+			}
+		},
+	)
+	return aws.NewCredentialsCache(provider), nil
 }
 
 // NewS3SinkWithUploader creates a new S3 sink with a custom uploader.
 // This is useful for testing.
-func NewS3SinkWithUploader(bucket, prefix string, uploader S3Uploader) engine.Sink {
-	return &S3Sink{
-		bucket:   bucket,
-		prefix:   prefix,
-		uploader: uploader,
+func NewS3SinkWithUploader(bucket, prefix string, uploader S3Uploader, opts ...S3SinkOption) engine.Sink {
+	s := &S3Sink{
+		bucket:         bucket,
+		prefix:         prefix,
+		uploader:       uploader,
+		maxAttempts:    DefaultS3MaxAttempts,
+		retryBaseDelay: DefaultS3RetryBaseDelay,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *S3Sink) Name() string {
@@ -107,29 +298,144 @@ func (s *S3Sink) Kind() string {
 	return "s3"
 }
 
+// Write uploads data, retrying with exponential backoff while S3 reports
+// throttling or a transient failure. The body is buffered up front so every
+// attempt resends the full payload.
 func (s *S3Sink) Write(ctx context.Context, objectPath string, data io.Reader) error {
-	key := objectPath
-	if s.prefix != "" {
-		key = path.Join(s.prefix, objectPath)
+	key := s.key(objectPath)
+
+	body, err := io.ReadAll(data)
+	if err != nil {
+		return fmt.Errorf("failed to read data for s3://%s/%s: %w", s.bucket, key, err)
+	}
+
+	if err := s.upload(ctx, objectPath, body); err != nil {
+		return err
+	}
+	if s.presigner == nil {
+		return nil
 	}
 
-	input := &s3.PutObjectInput{
+	req, err := s.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
-		Body:   data,
+	}, s3.WithPresignExpires(s.presignExpiry))
+	if err != nil {
+		return fmt.Errorf("failed to presign s3://%s/%s: %w", s.bucket, key, err)
 	}
+	s.mu.Lock()
+	s.presigned[objectPath] = req.URL
+	s.mu.Unlock()
+	return nil
+}
+
+// ChecksExistence reports whether the sink was given an S3ObjectLookup.
+// With presign_get it reports false: S3PresignedURLsFile lists only the
+// objects written by this run, so resuming would drop the URLs of the
+// objects it skips.
+func (s *S3Sink) ChecksExistence() bool {
+	return s.lookup != nil && s.presigner == nil
+}
 
-	// Set Content-Type based on file extension
-	if contentType := contentTypeFromPath(objectPath); contentType != "" {
-		input.ContentType = aws.String(contentType)
+// Exists reports whether an object is already stored at objectPath. A
+// missing object is not an error; any other failure, such as AccessDenied,
+// is.
+func (s *S3Sink) Exists(ctx context.Context, objectPath string) (bool, error) {
+	key := s.key(objectPath)
+	if s.lookup == nil {
+		return false, fmt.Errorf("existence checks are not configured for s3://%s", s.bucket)
 	}
 
-	_, err := s.uploader.Upload(ctx, input)
-	if err != nil {
-		return fmt.Errorf("failed to upload to s3://%s/%s: %w", s.bucket, key, err)
+	_, err := s.lookup.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err == nil {
+		return true, nil
 	}
+	var respErr *smithyhttp.ResponseError
+	if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to look up s3://%s/%s: %w", s.bucket, key, err)
+}
 
-	return nil
+func (s *S3Sink) key(objectPath string) string {
+	if s.prefix != "" {
+		return path.Join(s.prefix, objectPath)
+	}
+	return objectPath
+}
+
+func (s *S3Sink) upload(ctx context.Context, objectPath string, body []byte) error {
+	key := s.key(objectPath)
+	delay := s.retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		input := &s3.PutObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(body),
+		}
+
+		// Set Content-Type based on file extension
+		if contentType := contentTypeFromPath(objectPath); contentType != "" {
+			input.ContentType = aws.String(contentType)
+		}
+		if len(s.metadata) > 0 {
+			input.Metadata = s.metadata
+		}
+		if s.tagging != "" {
+			input.Tagging = aws.String(s.tagging)
+		}
+
+		_, err := s.uploader.Upload(ctx, input)
+		if err == nil {
+			return nil
+		}
+		if attempt >= s.maxAttempts || !isRetryableS3Error(err) {
+			err = fmt.Errorf("failed to upload to s3://%s/%s after %d attempt(s): %w", s.bucket, key, attempt, err)
+			if isPermanentS3Error(err) {
+				return Permanent(err)
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to upload to s3://%s/%s: %w", s.bucket, key, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxS3RetryDelay)
+	}
+}
+
+// isRetryableS3Error reports whether err is S3 throttling or a transient
+// server-side failure, judged by the API error code or the HTTP status.
+func isRetryableS3Error(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && slices.Contains(retryableS3ErrorCodes, apiErr.ErrorCode()) {
+		return true
+	}
+
+	var respErr *smithyhttp.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.HTTPStatusCode() {
+		case http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// isPermanentS3Error reports whether S3 rejected the request itself, e.g.
+// AccessDenied or NoSuchBucket, rather than failing to serve it.
+func isPermanentS3Error(err error) bool {
+	var respErr *smithyhttp.ResponseError
+	return errors.As(err, &respErr) && isPermanentHTTPStatus(respErr.HTTPStatusCode())
 }
 
 // contentTypeFromPath returns the Content-Type based on the file extension.
@@ -142,6 +448,10 @@ func contentTypeFromPath(p string) string {
 		return "application/x-yaml"
 	case ".xml":
 		return "application/xml"
+	case ".msgpack":
+		return "application/x-msgpack"
+	case ".parquet":
+		return "application/vnd.apache.parquet"
 	case ".txt":
 		return "text/plain"
 	case ".tar":
@@ -155,6 +465,19 @@ func contentTypeFromPath(p string) string {
 	}
 }
 
+// Close uploads the presigned URLs of the objects written, if any were
+// presigned.
 func (s *S3Sink) Close(ctx context.Context) error {
-	return nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.presigned) == 0 {
+		return nil
+	}
+
+	body, err := json.MarshalIndent(s.presigned, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode presigned URLs: %w", err)
+	}
+	clear(s.presigned)
+	return s.upload(ctx, S3PresignedURLsFile, body)
 }
@@ -3,17 +3,31 @@ package sinks
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/infracollect/infracollect/internal/engine"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// mockUploader records every upload. Calls fail with errs in order until
+// the slice is exhausted, after which they succeed.
 type mockUploader struct {
 	uploads []mockUpload
+	errs    []error
 }
 
 type mockUpload struct {
@@ -21,19 +35,30 @@ type mockUpload struct {
 	key         string
 	body        []byte
 	contentType string
+	metadata    map[string]string
+	tagging     string
 }
 
 func (m *mockUploader) Upload(ctx context.Context, input *s3.PutObjectInput, opts ...func(*manager.Uploader)) (*manager.UploadOutput, error) {
 	body, _ := io.ReadAll(input.Body)
 	upload := mockUpload{
-		bucket: *input.Bucket,
-		key:    *input.Key,
-		body:   body,
+		bucket:   *input.Bucket,
+		key:      *input.Key,
+		body:     body,
+		metadata: input.Metadata,
+	}
+	if input.Tagging != nil {
+		upload.tagging = *input.Tagging
 	}
 	if input.ContentType != nil {
 		upload.contentType = *input.ContentType
 	}
 	m.uploads = append(m.uploads, upload)
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		return nil, err
+	}
 	return &manager.UploadOutput{}, nil
 }
 
@@ -152,6 +177,16 @@ func TestS3Sink_Write_ContentType(t *testing.T) {
 			path:                "data.xml",
 			expectedContentType: "application/xml",
 		},
+		{
+			name:                "msgpack file",
+			path:                "data.msgpack",
+			expectedContentType: "application/x-msgpack",
+		},
+		{
+			name:                "parquet file",
+			path:                "data.parquet",
+			expectedContentType: "application/vnd.apache.parquet",
+		},
 		{
 			name:                "txt file",
 			path:                "readme.txt",
@@ -207,3 +242,303 @@ func TestS3Sink_Write_ContentType(t *testing.T) {
 		})
 	}
 }
+
+func TestS3Sink_Write_ObjectAttributes(t *testing.T) {
+	tests := []struct {
+		name         string
+		metadata     map[string]string
+		tags         map[string]string
+		wantMetadata map[string]string
+		wantTagging  string
+	}{
+		{
+			name: "none by default",
+		},
+		{
+			name:         "metadata",
+			metadata:     map[string]string{"job": "inventory"},
+			wantMetadata: map[string]string{"job": "inventory"},
+		},
+		{
+			name:        "tags are url-encoded and sorted",
+			tags:        map[string]string{"team": "platform & ops", "env": "prod"},
+			wantTagging: "env=prod&team=platform+%26+ops",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploader := &mockUploader{}
+			sink := NewS3SinkWithUploader("bucket", "", uploader, WithS3ObjectAttributes(tt.metadata, tt.tags))
+
+			require.NoError(t, sink.Write(t.Context(), "data.json", bytes.NewBufferString("{}")))
+
+			require.Len(t, uploader.uploads, 1)
+			assert.Equal(t, tt.wantMetadata, uploader.uploads[0].metadata)
+			assert.Equal(t, tt.wantTagging, uploader.uploads[0].tagging)
+		})
+	}
+}
+
+func TestNewS3Sink_TooManyTags(t *testing.T) {
+	tags := make(map[string]string)
+	for i := range maxS3ObjectTags + 1 {
+		tags[fmt.Sprintf("tag%d", i)] = "v"
+	}
+	_, err := NewS3Sink(t.Context(), S3Config{Bucket: "bucket", Region: "us-east-1", Tags: tags})
+	assert.ErrorContains(t, err, "at most 10 tags, got 11")
+}
+
+// mockPresigner returns a fake URL built from the bucket, key and expiry.
+type mockPresigner struct {
+	err error
+}
+
+func (m *mockPresigner) PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var opts s3.PresignOptions
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+	return &v4.PresignedHTTPRequest{
+		Method: http.MethodGet,
+		URL:    fmt.Sprintf("https://%s.s3.example.com/%s?X-Amz-Expires=%d", *params.Bucket, *params.Key, int(opts.Expires.Seconds())),
+	}, nil
+}
+
+func TestS3Sink_PresignGet(t *testing.T) {
+	uploader := &mockUploader{}
+	sink := NewS3SinkWithUploader("bucket", "runs/1", uploader, WithS3PresignGet(&mockPresigner{}, time.Hour))
+
+	require.NoError(t, sink.Write(t.Context(), "static/a.json", bytes.NewBufferString("{}")))
+	require.NoError(t, sink.Write(t.Context(), "static/a.meta.json", bytes.NewBufferString("{}")))
+	require.NoError(t, sink.Close(t.Context()))
+
+	require.Len(t, uploader.uploads, 3)
+	sidecar := uploader.uploads[2]
+	assert.Equal(t, "runs/1/"+S3PresignedURLsFile, sidecar.key)
+	assert.Equal(t, "application/json", sidecar.contentType)
+	assert.JSONEq(t, `{
+		"static/a.json": "https://bucket.s3.example.com/runs/1/static/a.json?X-Amz-Expires=3600",
+		"static/a.meta.json": "https://bucket.s3.example.com/runs/1/static/a.meta.json?X-Amz-Expires=3600"
+	}`, string(sidecar.body))
+
+	require.NoError(t, sink.Close(t.Context()))
+	assert.Len(t, uploader.uploads, 3, "a second close must not upload the sidecar again")
+}
+
+func TestS3Sink_PresignGetNotConfigured(t *testing.T) {
+	uploader := &mockUploader{}
+	sink := NewS3SinkWithUploader("bucket", "", uploader)
+
+	require.NoError(t, sink.Write(t.Context(), "data.json", bytes.NewBufferString("{}")))
+	require.NoError(t, sink.Close(t.Context()))
+	assert.Len(t, uploader.uploads, 1)
+}
+
+func TestS3Sink_PresignGetError(t *testing.T) {
+	sink := NewS3SinkWithUploader("bucket", "", &mockUploader{}, WithS3PresignGet(&mockPresigner{err: errors.New("anonymous credentials")}, time.Hour))
+
+	err := sink.Write(t.Context(), "data.json", bytes.NewBufferString("{}"))
+	assert.ErrorContains(t, err, "failed to presign s3://bucket/data.json: anonymous credentials")
+}
+
+func TestNewS3Sink_PresignGetOutOfRange(t *testing.T) {
+	_, err := NewS3Sink(t.Context(), S3Config{Bucket: "bucket", Region: "us-east-1", PresignGet: 8 * 24 * time.Hour})
+	assert.ErrorContains(t, err, "presign_get must be between 0 and 168h0m0s, got 192h0m0s")
+}
+
+func slowDown() error {
+	return &smithy.GenericAPIError{Code: "SlowDown", Message: "Please reduce your request rate."}
+}
+
+func TestS3Sink_WriteRetries(t *testing.T) {
+	unavailable := &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusServiceUnavailable}},
+		Err:      errors.New("service unavailable"),
+	}
+
+	tests := []struct {
+		name        string
+		errs        []error
+		maxAttempts int
+		wantUploads int
+		wantErr     string
+	}{
+		{
+			name:        "succeeds after throttling",
+			errs:        []error{slowDown(), slowDown()},
+			maxAttempts: 3,
+			wantUploads: 3,
+		},
+		{
+			name:        "retries on 503",
+			errs:        []error{unavailable},
+			maxAttempts: 3,
+			wantUploads: 2,
+		},
+		{
+			name:        "gives up after max attempts",
+			errs:        []error{slowDown(), slowDown(), slowDown()},
+			maxAttempts: 2,
+			wantUploads: 2,
+			wantErr:     "after 2 attempt(s)",
+		},
+		{
+			name:        "does not retry permanent errors",
+			errs:        []error{&smithy.GenericAPIError{Code: "AccessDenied"}},
+			maxAttempts: 3,
+			wantUploads: 1,
+			wantErr:     "AccessDenied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploader := &mockUploader{errs: tt.errs}
+			sink := NewS3SinkWithUploader("bucket", "", uploader, WithS3Retry(tt.maxAttempts, time.Millisecond))
+
+			err := sink.Write(t.Context(), "data.json", bytes.NewBufferString("payload"))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Len(t, uploader.uploads, tt.wantUploads)
+			for _, upload := range uploader.uploads {
+				assert.Equal(t, "payload", string(upload.body), "every attempt resends the full body")
+			}
+		})
+	}
+}
+
+func TestS3Sink_WriteRetryStopsOnCancel(t *testing.T) {
+	uploader := &mockUploader{errs: []error{slowDown(), slowDown()}}
+	sink := NewS3SinkWithUploader("bucket", "", uploader, WithS3Retry(5, time.Hour))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	err := sink.Write(ctx, "data.json", bytes.NewBufferString("payload"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, uploader.uploads, 1)
+}
+
+func TestS3CredentialsProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         S3Config
+		want        aws.CredentialsProvider // nil keeps the default chain
+		errContains string
+	}{
+		{
+			name: "default chain",
+			cfg:  S3Config{},
+		},
+		{
+			name: "static keys",
+			cfg:  S3Config{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+			want: credentials.StaticCredentialsProvider{},
+		},
+		{
+			name: "web identity",
+			cfg:  S3Config{RoleARN: "arn:aws:iam::123456789012:role/ci", WebIdentityTokenFile: "/tmp/token"},
+			want: &stscreds.WebIdentityRoleProvider{},
+		},
+		{
+			name: "static keys take precedence over web identity",
+			cfg: S3Config{
+				AccessKeyID:          "AKID",
+				SecretAccessKey:      "secret",
+				RoleARN:              "arn:aws:iam::123456789012:role/ci",
+				WebIdentityTokenFile: "/tmp/token",
+			},
+			want: credentials.StaticCredentialsProvider{},
+		},
+		{
+			name:        "role without token file",
+			cfg:         S3Config{RoleARN: "arn:aws:iam::123456789012:role/ci"},
+			errContains: "require both role_arn and web_identity_token_file",
+		},
+		{
+			name:        "token file without role",
+			cfg:         S3Config{WebIdentityTokenFile: "/tmp/token"},
+			errContains: "require both role_arn and web_identity_token_file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := s3CredentialsProvider(aws.Config{Region: "us-east-1"}, tt.cfg)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want == nil {
+				assert.Nil(t, provider)
+				return
+			}
+			require.NotNil(t, provider)
+			assert.True(t, aws.IsCredentialsProvider(provider, tt.want), "got %T", provider)
+		})
+	}
+}
+
+// mockObjectLookup answers HeadObject from the set of keys it holds.
+type mockObjectLookup struct {
+	keys map[string]bool
+	err  error
+}
+
+func (m *mockObjectLookup) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if !m.keys[*params.Key] {
+		return nil, &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+			Err:      errors.New("not found"),
+		}
+	}
+	return &s3.HeadObjectOutput{}, nil
+}
+
+func TestS3Sink_Exists(t *testing.T) {
+	lookup := &mockObjectLookup{keys: map[string]bool{"runs/1/static/a.json": true}}
+	sink := NewS3SinkWithUploader("bucket", "runs/1", &mockUploader{}, WithS3ObjectLookup(lookup)).(*S3Sink)
+
+	exists, err := sink.Exists(t.Context(), "static/a.json")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = sink.Exists(t.Context(), "static/b.json")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	lookup.err = errors.New("access denied")
+	_, err = sink.Exists(t.Context(), "static/a.json")
+	assert.ErrorContains(t, err, "failed to look up s3://bucket/runs/1/static/a.json: access denied")
+}
+
+func TestS3Sink_ExistsWithPresignGet(t *testing.T) {
+	lookup := &mockObjectLookup{keys: map[string]bool{"static/a.json": true}}
+	sink := NewS3SinkWithUploader("bucket", "", &mockUploader{},
+		WithS3ObjectLookup(lookup),
+		WithS3PresignGet(&mockPresigner{}, time.Hour),
+	)
+
+	_, ok := engine.AsExistenceChecker(sink)
+	assert.False(t, ok, "a resumed run would leave the skipped objects out of the presigned URLs")
+}
+
+func TestS3Sink_ExistsNotConfigured(t *testing.T) {
+	sink := NewS3SinkWithUploader("bucket", "", &mockUploader{})
+
+	_, ok := engine.AsExistenceChecker(sink)
+	assert.False(t, ok)
+}
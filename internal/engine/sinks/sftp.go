@@ -0,0 +1,192 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/infracollect/infracollect/internal/sshclient"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTPConfig contains configuration for the SFTP sink.
+type SFTPConfig struct {
+	// SSH holds the connection settings. Host keys are checked against the
+	// known hosts file unless InsecureIgnoreHostKey is set.
+	SSH sshclient.Config
+	// RemoteDir is the directory files are written under. Relative paths
+	// start at the login directory; empty writes there directly.
+	RemoteDir string
+}
+
+// SFTPSink uploads every written file over SFTP. The connection is opened on
+// the first write and kept until Close, or until it breaks: a connection
+// error drops it, and the next write dials again.
+type SFTPSink struct {
+	ssh       sshclient.Config
+	remoteDir string
+
+	mu        sync.Mutex
+	sshClient *ssh.Client
+	client    *sftp.Client
+}
+
+// NewSFTPSink creates a new SFTP sink with the given configuration. No
+// connection is made until the first write.
+func NewSFTPSink(cfg SFTPConfig) (engine.Sink, error) {
+	sshCfg, err := cfg.SSH.WithDefaults()
+	if err != nil {
+		return nil, err
+	}
+	return &SFTPSink{ssh: sshCfg, remoteDir: cfg.RemoteDir}, nil
+}
+
+func (s *SFTPSink) Name() string {
+	if s.remoteDir != "" {
+		return fmt.Sprintf("sftp(%s@%s:%s)", s.ssh.User, s.ssh.Address(), s.remoteDir)
+	}
+	return fmt.Sprintf("sftp(%s@%s)", s.ssh.User, s.ssh.Address())
+}
+
+func (s *SFTPSink) Kind() string {
+	return "sftp"
+}
+
+// connect opens the SSH connection and SFTP session once. Callers hold mu.
+func (s *SFTPSink) connect(ctx context.Context) (*sftp.Client, error) {
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	sshClient, err := sshclient.Dial(ctx, s.ssh)
+	if err != nil {
+		if isPermanentSSHError(err) {
+			return nil, Permanent(err)
+		}
+		return nil, err
+	}
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		_ = sshClient.Close()
+		return nil, fmt.Errorf("failed to start sftp session on %s: %w", s.ssh.Address(), err)
+	}
+
+	s.sshClient = sshClient
+	s.client = client
+	return client, nil
+}
+
+// Write streams data to <remote_dir>/<path>, creating missing parent
+// directories and replacing an existing file. A connection found broken
+// before any data is sent, such as one the server closed while idle, is
+// dialed again once.
+func (s *SFTPSink) Write(ctx context.Context, filePath string, data io.Reader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	remote := path.Join(s.remoteDir, filePath)
+	f, err := s.openRemote(ctx, remote)
+	if err != nil && isSFTPConnectionError(err) {
+		s.disconnect()
+		f, err = s.openRemote(ctx, remote)
+	}
+	if err != nil {
+		if isSFTPConnectionError(err) {
+			s.disconnect()
+		}
+		return err
+	}
+
+	if _, err := f.ReadFrom(data); err != nil {
+		if isSFTPConnectionError(err) {
+			s.disconnect()
+		}
+		return errors.Join(fmt.Errorf("failed to write remote file %s: %w", remote, err), f.Close())
+	}
+	if err := f.Close(); err != nil {
+		if isSFTPConnectionError(err) {
+			s.disconnect()
+		}
+		return fmt.Errorf("failed to close remote file %s: %w", remote, err)
+	}
+	return nil
+}
+
+// openRemote connects if needed and opens remote for writing, creating its
+// parent directories. Callers hold mu.
+func (s *SFTPSink) openRemote(ctx context.Context, remote string) (*sftp.File, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir := path.Dir(remote); dir != "." && dir != "/" {
+		if err := client.MkdirAll(dir); err != nil {
+			return nil, fmt.Errorf("failed to create remote directory %s: %w", dir, err)
+		}
+	}
+
+	f, err := client.OpenFile(remote, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open remote file %s: %w", remote, err)
+	}
+	return f, nil
+}
+
+// disconnect drops the connection so the next write dials again. Callers
+// hold mu.
+func (s *SFTPSink) disconnect() error {
+	if s.client == nil {
+		return nil
+	}
+	err := errors.Join(s.client.Close(), s.sshClient.Close())
+	s.client, s.sshClient = nil, nil
+	return err
+}
+
+// isSFTPConnectionError reports whether err means the connection itself is
+// gone, as opposed to the server refusing an operation.
+func isSFTPConnectionError(err error) bool {
+	var statusErr *sftp.StatusError
+	if errors.As(err, &statusErr) {
+		code := statusErr.FxCode()
+		return code == sftp.ErrSSHFxConnectionLost || code == sftp.ErrSSHFxNoConnection
+	}
+	return errors.Is(err, sftp.ErrSSHFxConnectionLost) ||
+		errors.Is(err, sftp.ErrSSHFxNoConnection) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// isPermanentSSHError reports whether the server's host key or our
+// credentials were rejected, which no amount of reconnecting fixes.
+func isPermanentSSHError(err error) bool {
+	var keyErr *knownhosts.KeyError
+	var revokedErr *knownhosts.RevokedError
+	return errors.As(err, &keyErr) || errors.As(err, &revokedErr) ||
+		strings.Contains(err.Error(), "ssh: unable to authenticate")
+}
+
+func (s *SFTPSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.disconnect()
+}
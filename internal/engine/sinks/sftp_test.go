@@ -0,0 +1,276 @@
+package sinks
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/infracollect/infracollect/internal/sshclient"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpTestServer is an in-process SSH server accepting the password "secret"
+// and serving the sftp subsystem from root.
+type sftpTestServer struct {
+	addr    *net.TCPAddr
+	hostKey ssh.PublicKey
+	root    string
+
+	mu       sync.Mutex
+	conns    []net.Conn
+	accepted int
+}
+
+func newSFTPTestServer(t *testing.T) *sftpTestServer {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	s := &sftpTestServer{
+		addr:    listener.Addr().(*net.TCPAddr),
+		hostKey: hostSigner.PublicKey(),
+		root:    t.TempDir(),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.accepted++
+			s.mu.Unlock()
+			go s.serve(conn, config)
+		}
+	}()
+	return s
+}
+
+// dropConnections closes every open connection, as a server restart would.
+func (s *sftpTestServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *sftpTestServer) acceptedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted
+}
+
+func (s *sftpTestServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer channel.Close()
+			for req := range requests {
+				var payload struct{ Name string }
+				_ = ssh.Unmarshal(req.Payload, &payload)
+				if req.Type != "subsystem" || payload.Name != "sftp" {
+					_ = req.Reply(false, nil)
+					continue
+				}
+				_ = req.Reply(true, nil)
+				server, err := sftp.NewServer(channel, sftp.WithServerWorkingDirectory(s.root))
+				if err != nil {
+					return
+				}
+				_ = server.Serve()
+				return
+			}
+		}()
+	}
+}
+
+func (s *sftpTestServer) knownHosts(t *testing.T, trusted bool) string {
+	t.Helper()
+	content := ""
+	if trusted {
+		content = knownhosts.Line([]string{knownhosts.Normalize(s.addr.String())}, s.hostKey) + "\n"
+	}
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func (s *sftpTestServer) sshConfig() sshclient.Config {
+	return sshclient.Config{
+		Host:     s.addr.IP.String(),
+		Port:     s.addr.Port,
+		User:     "collector",
+		Password: "secret",
+	}
+}
+
+func TestSFTPSink_Write(t *testing.T) {
+	srv := newSFTPTestServer(t)
+	sshCfg := srv.sshConfig()
+	sshCfg.KnownHostsPath = srv.knownHosts(t, true)
+
+	sink, err := NewSFTPSink(SFTPConfig{SSH: sshCfg, RemoteDir: "exports/daily"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sink.Close(t.Context()) })
+
+	require.NoError(t, sink.Write(t.Context(), "static/a.json", strings.NewReader(`{"a":1}`)))
+	require.NoError(t, sink.Write(t.Context(), "static/a.json", strings.NewReader(`{}`)))
+	require.NoError(t, sink.Write(t.Context(), "http_get/b.json", strings.NewReader(`[]`)))
+	require.NoError(t, sink.Close(t.Context()))
+
+	data, err := os.ReadFile(filepath.Join(srv.root, "exports", "daily", "static", "a.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{}`, string(data), "an existing file is replaced")
+	data, err = os.ReadFile(filepath.Join(srv.root, "exports", "daily", "http_get", "b.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `[]`, string(data))
+}
+
+func TestSFTPSink_RedialsAfterConnectionLoss(t *testing.T) {
+	srv := newSFTPTestServer(t)
+	sshCfg := srv.sshConfig()
+	sshCfg.KnownHostsPath = srv.knownHosts(t, true)
+
+	sink, err := NewSFTPSink(SFTPConfig{SSH: sshCfg})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sink.Close(t.Context()) })
+
+	require.NoError(t, sink.Write(t.Context(), "a.json", strings.NewReader(`{"a":1}`)))
+	require.NoError(t, sink.Write(t.Context(), "b.json", strings.NewReader(`{"b":2}`)))
+	assert.Equal(t, 1, srv.acceptedCount(), "the connection is reused between writes")
+
+	srv.dropConnections()
+	require.Eventually(t, func() bool {
+		_, err := sink.(*SFTPSink).client.Getwd()
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, sink.Write(t.Context(), "c.json", strings.NewReader(`{"c":3}`)))
+	assert.Equal(t, 2, srv.acceptedCount(), "a dropped connection is dialed again")
+
+	data, err := os.ReadFile(filepath.Join(srv.root, "c.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"c":3}`, string(data))
+}
+
+func TestIsSFTPConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "connection lost", err: fmt.Errorf("open: %w", sftp.ErrSSHFxConnectionLost), want: true},
+		{name: "eof", err: fmt.Errorf("write: %w", io.EOF), want: true},
+		{name: "closed network connection", err: net.ErrClosed, want: true},
+		{name: "permission denied", err: fmt.Errorf("open: %w", sftp.ErrSSHFxPermissionDenied), want: false},
+		{name: "other", err: assert.AnError, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isSFTPConnectionError(tt.err))
+		})
+	}
+}
+
+func TestSFTPSink_HostKeyVerification(t *testing.T) {
+	srv := newSFTPTestServer(t)
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *sshclient.Config)
+		wantErr string
+	}{
+		{
+			name: "unknown host key is rejected",
+			modify: func(cfg *sshclient.Config) {
+				cfg.KnownHostsPath = srv.knownHosts(t, false)
+			},
+			wantErr: "knownhosts: key is unknown",
+		},
+		{
+			name: "check can be disabled explicitly",
+			modify: func(cfg *sshclient.Config) {
+				cfg.InsecureIgnoreHostKey = true
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := srv.sshConfig()
+			tt.modify(&cfg)
+			sink, err := NewSFTPSink(SFTPConfig{SSH: cfg})
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = sink.Close(t.Context()) })
+
+			err = sink.Write(t.Context(), "a.json", strings.NewReader("{}"))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.FileExists(t, filepath.Join(srv.root, "a.json"))
+		})
+	}
+}
+
+func TestSFTPSink_ConnectsLazily(t *testing.T) {
+	sink, err := NewSFTPSink(SFTPConfig{SSH: sshclient.Config{
+		Host:     "127.0.0.1",
+		Port:     1,
+		User:     "collector",
+		Password: "secret",
+	}})
+	require.NoError(t, err, "no connection is made until the first write")
+	assert.Equal(t, "sftp(collector@127.0.0.1:1)", sink.Name())
+	assert.NoError(t, sink.Close(t.Context()))
+}
+
+func TestNewSFTPSink_Validation(t *testing.T) {
+	_, err := NewSFTPSink(SFTPConfig{SSH: sshclient.Config{User: "u", Password: "p"}})
+	assert.ErrorContains(t, err, "host is required")
+
+	_, err = NewSFTPSink(SFTPConfig{SSH: sshclient.Config{Host: "h", User: "u"}})
+	assert.ErrorContains(t, err, "either private_key_path or password is required")
+}
@@ -0,0 +1,116 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"aead.dev/minisign"
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+// MinisignSignatureSuffix is appended to an object's path to name its
+// signature, the file name `minisign -V` looks for by default.
+const MinisignSignatureSuffix = ".minisig"
+
+// SigningSink wraps a sink and writes a detached minisign signature next to
+// every object written through it. The signature covers exactly the bytes
+// the wrapped sink read, so it must sit below any archive or compression
+// that changes them.
+type SigningSink struct {
+	inner engine.Sink
+	key   minisign.PrivateKey
+}
+
+// NewSigningSink creates a sink that signs every write to inner with key.
+func NewSigningSink(inner engine.Sink, key minisign.PrivateKey) *SigningSink {
+	return &SigningSink{inner: inner, key: key}
+}
+
+// LoadMinisignKey reads a minisign private key file, decrypting it with
+// password when it is encrypted, as keys created by `minisign -G` are unless
+// -W is given.
+func LoadMinisignKey(keyPath, password string) (minisign.PrivateKey, error) {
+	raw, err := os.ReadFile(keyPath)
+	if err != nil {
+		return minisign.PrivateKey{}, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	var key minisign.PrivateKey
+	if minisign.IsEncrypted(raw) {
+		if password == "" {
+			return minisign.PrivateKey{}, fmt.Errorf("private key %s is encrypted: set password", keyPath)
+		}
+		key, err = minisign.DecryptKey(password, raw)
+	} else {
+		err = key.UnmarshalText(raw)
+	}
+	if err != nil {
+		return minisign.PrivateKey{}, fmt.Errorf("failed to load private key %s: %w", keyPath, err)
+	}
+	return key, nil
+}
+
+// Name returns the wrapped sink's name; signing does not change where the
+// data goes.
+func (s *SigningSink) Name() string {
+	return s.inner.Name()
+}
+
+// Kind returns the wrapped sink's kind.
+func (s *SigningSink) Kind() string {
+	return s.inner.Kind()
+}
+
+// Write hashes data as the wrapped sink reads it, then writes the signature
+// to path with MinisignSignatureSuffix appended. A failed write is not
+// signed.
+func (s *SigningSink) Write(ctx context.Context, objectPath string, data io.Reader) error {
+	reader := minisign.NewReader(data)
+	if err := s.inner.Write(ctx, objectPath, reader); err != nil {
+		return err
+	}
+
+	trustedComment := "timestamp:" + strconv.FormatInt(time.Now().Unix(), 10) + "\tfile:" + path.Base(objectPath)
+	untrustedComment := "signature from infracollect secret key " + strings.ToUpper(strconv.FormatUint(s.key.ID(), 16))
+	signature := reader.SignWithComments(s.key, trustedComment, untrustedComment)
+
+	if err := s.inner.Write(ctx, objectPath+MinisignSignatureSuffix, bytes.NewReader(signature)); err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+	return nil
+}
+
+// ChecksExistence reports whether the wrapped sink can answer existence
+// checks.
+func (s *SigningSink) ChecksExistence() bool {
+	_, ok := engine.AsExistenceChecker(s.inner)
+	return ok
+}
+
+// Exists reports whether both the object and its signature are written, so
+// an object left unsigned by an interrupted run is written again.
+func (s *SigningSink) Exists(ctx context.Context, objectPath string) (bool, error) {
+	checker, ok := engine.AsExistenceChecker(s.inner)
+	if !ok {
+		return false, fmt.Errorf("%s: existence checks are not supported", s.inner.Name())
+	}
+	for _, p := range []string{objectPath, objectPath + MinisignSignatureSuffix} {
+		exists, err := checker.Exists(ctx, p)
+		if err != nil || !exists {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Close closes the wrapped sink.
+func (s *SigningSink) Close(ctx context.Context) error {
+	return s.inner.Close(ctx)
+}
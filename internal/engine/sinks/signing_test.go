@@ -0,0 +1,98 @@
+package sinks
+
+import (
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aead.dev/minisign"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigningSink_WritesVerifiableSignature(t *testing.T) {
+	public, private, err := minisign.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	inner := newMockSink()
+	sink := NewSigningSink(inner, private)
+
+	require.NoError(t, sink.Write(t.Context(), "jobs/inventory.tar.gz", strings.NewReader("archive bytes")))
+	require.NoError(t, sink.Close(t.Context()))
+
+	assert.True(t, inner.closed)
+	assert.Equal(t, []byte("archive bytes"), inner.writes["jobs/inventory.tar.gz"])
+	signature := inner.writes["jobs/inventory.tar.gz"+MinisignSignatureSuffix]
+	require.NotEmpty(t, signature)
+	assert.True(t, minisign.Verify(public, []byte("archive bytes"), signature))
+	assert.False(t, minisign.Verify(public, []byte("tampered bytes"), signature))
+
+	var parsed minisign.Signature
+	require.NoError(t, parsed.UnmarshalText(signature))
+	assert.Contains(t, parsed.TrustedComment, "\tfile:inventory.tar.gz")
+}
+
+func TestSigningSink_FailedWriteIsNotSigned(t *testing.T) {
+	_, private, err := minisign.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	boom := errors.New("boom")
+	sink := NewSigningSink(&failingSink{err: boom}, private)
+
+	assert.ErrorIs(t, sink.Write(t.Context(), "static/a.json", strings.NewReader("data")), boom)
+}
+
+func TestLoadMinisignKey(t *testing.T) {
+	public, private, err := minisign.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	text, err := private.MarshalText()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "infracollect.key")
+	require.NoError(t, os.WriteFile(keyPath, text, 0o600))
+	garbagePath := filepath.Join(dir, "garbage.key")
+	require.NoError(t, os.WriteFile(garbagePath, []byte("not a key"), 0o600))
+
+	t.Run("unencrypted key", func(t *testing.T) {
+		key, err := LoadMinisignKey(keyPath, "")
+		require.NoError(t, err)
+		assert.True(t, key.Equal(private))
+		assert.Equal(t, public.ID(), key.ID())
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadMinisignKey(filepath.Join(dir, "missing.key"), "")
+		assert.ErrorContains(t, err, "failed to read private key")
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		_, err := LoadMinisignKey(garbagePath, "")
+		assert.ErrorContains(t, err, "failed to load private key")
+	})
+}
+
+func TestSigningSink_Exists(t *testing.T) {
+	_, private, err := minisign.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "signed.json", []byte("{}"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "signed.json"+MinisignSignatureSuffix, []byte("sig"), 0o644))
+	require.NoError(t, afero.WriteFile(fs, "unsigned.json", []byte("{}"), 0o644))
+	sink := NewSigningSink(NewFilesystemSink(fs), private)
+
+	exists, err := sink.Exists(t.Context(), "signed.json")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = sink.Exists(t.Context(), "unsigned.json")
+	require.NoError(t, err)
+	assert.False(t, exists, "an object without its signature must be written again")
+
+	assert.False(t, NewSigningSink(newMockSink(), private).ChecksExistence())
+}
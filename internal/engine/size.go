@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits maps the accepted size suffixes, lowercased, to their number of
+// bytes. KB, MB, ... are decimal; KiB, MiB, ... are binary.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// ParseSize parses a user-supplied byte size such as "500MB", "1.5 GiB" or a
+// bare number of bytes ("1048576"). Units are case-insensitive; KB, MB, GB
+// and TB are powers of 1000 and KiB, MiB, GiB and TiB powers of 1024.
+func ParseSize(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return 0, fmt.Errorf("size is empty")
+	}
+
+	i := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+'
+	})
+	number, unit := trimmed, ""
+	if i >= 0 {
+		number, unit = trimmed[:i], strings.TrimSpace(trimmed[i:])
+	}
+
+	multiplier, ok := sizeUnits[strings.ToLower(unit)]
+	value, err := strconv.ParseFloat(number, 64)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("invalid size %q: expected a number of bytes or a size such as \"500MB\"", s)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("size %q must not be negative", s)
+	}
+
+	bytes := value * multiplier
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return int64(bytes), nil
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in        string
+		want      int64
+		expectErr string
+	}{
+		{in: "1048576", want: 1048576},
+		{in: "512B", want: 512},
+		{in: "500MB", want: 500_000_000},
+		{in: "500mb", want: 500_000_000},
+		{in: "1.5 GB", want: 1_500_000_000},
+		{in: " 4KiB ", want: 4096},
+		{in: "2MiB", want: 2 << 20},
+		{in: "1TiB", want: 1 << 40},
+		{in: "", expectErr: "size is empty"},
+		{in: "-5MB", expectErr: "must not be negative"},
+		{in: "5XB", expectErr: `invalid size "5XB"`},
+		{in: "MB", expectErr: `invalid size "MB"`},
+		{in: "1e30TB", expectErr: `invalid size "1e30TB"`},
+		{in: "99999999TB", expectErr: "is too large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSize(tt.in)
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
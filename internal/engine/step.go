@@ -9,6 +9,23 @@ type Step interface {
 	Resolve(ctx context.Context) (Result, error)
 }
 
+// Revalidator is implemented by steps that can check whether a result they
+// returned before is still current, for example with an HTTP conditional
+// request, which is cheaper than resolving again. Revalidate returns
+// previous and true when it is still current, or a freshly resolved result
+// and false otherwise.
+type Revalidator interface {
+	Revalidate(ctx context.Context, previous Result) (Result, bool, error)
+}
+
+// SelfRetrying is implemented by steps that retry their own failures, such
+// as a step with a retry policy of its own. The runner's step retries skip
+// steps for which RetriesFailures reports true, so failures are not retried
+// twice.
+type SelfRetrying interface {
+	RetriesFailures() bool
+}
+
 type StepFunc func(ctx context.Context) (Result, error)
 
 type stepFunction struct {
@@ -0,0 +1,133 @@
+package steps
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/infracollect/infracollect/internal/engine/archivers"
+	"github.com/spf13/afero"
+)
+
+const (
+	ArchiveReadStepKind = "archive_read"
+)
+
+type ArchiveReadStepConfig struct {
+	// Path is the tar archive to read, relative to the working directory.
+	Path string
+	// Entries are path.Match patterns; when set, only matching entries are
+	// read.
+	Entries []string
+}
+
+// NewArchiveReadStep reads a tar archive (plain, gzip or zstd) written by a
+// previous run. Like the static step, the path is sandboxed to the working
+// directory.
+func NewArchiveReadStep(name string, cfg ArchiveReadStepConfig) (engine.Step, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	for _, pattern := range cfg.Entries {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid entries pattern %q: %w", pattern, err)
+		}
+	}
+
+	rootDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	fs := afero.NewBasePathFs(afero.NewOsFs(), rootDir)
+	return newArchiveReadFileStep(name, fs, cfg), nil
+}
+
+func newArchiveReadFileStep(name string, fs afero.Fs, cfg ArchiveReadStepConfig) engine.Step {
+	return engine.StepFunction(name, ArchiveReadStepKind, func(ctx context.Context) (engine.Result, error) {
+		f, err := fs.Open(cfg.Path)
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to open archive %s: %w", cfg.Path, err)
+		}
+		defer f.Close()
+
+		r, compression, err := archivers.NewDecompressor(f)
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to read archive %s: %w", cfg.Path, err)
+		}
+		defer r.Close()
+
+		entries, err := readArchiveEntries(ctx, tar.NewReader(r), cfg.Entries)
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to read archive %s: %w", cfg.Path, err)
+		}
+
+		return engine.Result{
+			Data: entries,
+			Meta: map[string]string{
+				"path":        cfg.Path,
+				"compression": string(compression),
+				"entries":     strconv.Itoa(len(entries)),
+			},
+		}, nil
+	})
+}
+
+// readArchiveEntries returns the regular files in tr keyed by entry name.
+// Entries ending in .json are parsed; everything else is kept as a string.
+func readArchiveEntries(ctx context.Context, tr *tar.Reader, patterns []string) (map[string]any, error) {
+	entries := make(map[string]any)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar header: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || !matchesAny(header.Name, patterns) {
+			continue
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read entry %s: %w", header.Name, err)
+		}
+
+		if !strings.HasSuffix(header.Name, ".json") {
+			entries[header.Name] = string(content)
+			continue
+		}
+		var parsed any
+		if err := json.Unmarshal(content, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse entry %s as json: %w", header.Name, err)
+		}
+		entries[header.Name] = parsed
+	}
+}
+
+// matchesAny reports whether name matches one of patterns; no patterns
+// matches everything.
+func matchesAny(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package steps
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/infracollect/infracollect/internal/engine/archivers"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeArchive builds a tar archive with the archiver the output block uses
+// and stores it in fs at name.
+func writeArchive(t *testing.T, fs afero.Fs, name, compression string, files map[string]string) {
+	t.Helper()
+	archiver, err := archivers.NewTarArchiver(compression, nil)
+	require.NoError(t, err)
+	for filename, content := range files {
+		require.NoError(t, archiver.AddFile(t.Context(), filename, strings.NewReader(content)))
+	}
+	r, err := archiver.Close()
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, afero.WriteFile(fs, name, data, 0644))
+}
+
+func TestArchiveReadStep(t *testing.T) {
+	files := map[string]string{
+		"static/config.json": `{"region": "eu-west-1"}`,
+		"exec/uname.json":    `["Linux"]`,
+		"report.txt":         "done",
+	}
+
+	tests := []struct {
+		name            string
+		compression     string
+		entries         []string
+		wantData        map[string]any
+		wantCompression string
+	}{
+		{
+			name:        "gzip",
+			compression: "gzip",
+			wantData: map[string]any{
+				"static/config.json": map[string]any{"region": "eu-west-1"},
+				"exec/uname.json":    []any{"Linux"},
+				"report.txt":         "done",
+			},
+			wantCompression: "gzip",
+		},
+		{
+			name:        "zstd",
+			compression: "zstd",
+			entries:     []string{"static/*"},
+			wantData: map[string]any{
+				"static/config.json": map[string]any{"region": "eu-west-1"},
+			},
+			wantCompression: "zstd",
+		},
+		{
+			name:        "uncompressed with several patterns",
+			compression: "none",
+			entries:     []string{"exec/*.json", "*.txt"},
+			wantData: map[string]any{
+				"exec/uname.json": []any{"Linux"},
+				"report.txt":      "done",
+			},
+			wantCompression: "none",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			writeArchive(t, fs, "previous/run.tar", tt.compression, files)
+
+			step := newArchiveReadFileStep("test", fs, ArchiveReadStepConfig{Path: "previous/run.tar", Entries: tt.entries})
+			result, err := step.Resolve(t.Context())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantData, result.Data)
+			assert.Equal(t, tt.wantCompression, result.Meta["compression"])
+			assert.Equal(t, "previous/run.tar", result.Meta["path"])
+		})
+	}
+}
+
+func TestArchiveReadStep_Errors(t *testing.T) {
+	baseFs := afero.NewMemMapFs()
+	writeArchive(t, baseFs, "outside.tar.gz", "gzip", map[string]string{"a.json": "{}"})
+	writeArchive(t, baseFs, "allowed/bad.tar.gz", "gzip", map[string]string{"a.json": "{not json"})
+	require.NoError(t, afero.WriteFile(baseFs, "allowed/plain.txt", []byte("not a tar archive at all, definitely not"), 0644))
+	sandboxed := afero.NewBasePathFs(baseFs, "allowed")
+
+	tests := []struct {
+		name        string
+		path        string
+		errContains string
+	}{
+		{name: "missing archive", path: "missing.tar.gz", errContains: "failed to open archive missing.tar.gz"},
+		{name: "path traversal", path: "../outside.tar.gz", errContains: "failed to open archive"},
+		{name: "invalid json entry", path: "bad.tar.gz", errContains: "failed to parse entry a.json as json"},
+		{name: "not a tar archive", path: "plain.txt", errContains: "failed to read tar header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := newArchiveReadFileStep("test", sandboxed, ArchiveReadStepConfig{Path: tt.path})
+			_, err := step.Resolve(t.Context())
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tt.errContains)
+		})
+	}
+}
+
+func TestNewArchiveReadStep_Validation(t *testing.T) {
+	_, err := NewArchiveReadStep("test", ArchiveReadStepConfig{})
+	assert.ErrorContains(t, err, "path is required")
+
+	_, err = NewArchiveReadStep("test", ArchiveReadStepConfig{Path: "run.tar.gz", Entries: []string{"[bad"}})
+	assert.ErrorContains(t, err, `invalid entries pattern "[bad"`)
+}
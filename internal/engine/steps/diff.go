@@ -0,0 +1,82 @@
+package steps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/wI2L/jsondiff"
+)
+
+const (
+	DiffStepKind = "diff"
+)
+
+type DiffStepConfig struct {
+	// From and To are the evaluated values to compare, usually an earlier
+	// and a current step result.
+	From any
+	To   any
+}
+
+// NewDiffStep compares From against To and reports the difference both as
+// an RFC 6902 JSON Patch and as lists of added, removed and changed JSON
+// Pointer paths.
+func NewDiffStep(name string, cfg DiffStepConfig) (engine.Step, error) {
+	return engine.StepFunction(name, DiffStepKind, func(ctx context.Context) (engine.Result, error) {
+		patch, err := jsondiff.Compare(cfg.From, cfg.To)
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to compare from and to: %w", err)
+		}
+
+		data, err := diffResult(patch)
+		if err != nil {
+			return engine.Result{}, err
+		}
+
+		return engine.Result{
+			Data: data,
+			Meta: map[string]string{
+				"equal":      strconv.FormatBool(len(patch) == 0),
+				"operations": strconv.Itoa(len(patch)),
+			},
+		}, nil
+	}), nil
+}
+
+// diffResult shapes patch into the step result. The patch is round-tripped
+// through encoding/json so the result holds only plain JSON values.
+func diffResult(patch jsondiff.Patch) (map[string]any, error) {
+	added, removed, changed := []any{}, []any{}, []any{}
+	for _, op := range patch {
+		switch op.Type {
+		case jsondiff.OperationAdd:
+			added = append(added, op.Path)
+		case jsondiff.OperationRemove:
+			removed = append(removed, op.Path)
+		default:
+			changed = append(changed, op.Path)
+		}
+	}
+
+	ops := []any{}
+	if len(patch) > 0 {
+		raw, err := json.Marshal(patch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode patch: %w", err)
+		}
+		if err := json.Unmarshal(raw, &ops); err != nil {
+			return nil, fmt.Errorf("failed to decode patch: %w", err)
+		}
+	}
+
+	return map[string]any{
+		"equal":   len(patch) == 0,
+		"added":   added,
+		"removed": removed,
+		"changed": changed,
+		"patch":   ops,
+	}, nil
+}
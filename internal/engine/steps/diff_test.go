@@ -0,0 +1,76 @@
+package steps
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffStep_Resolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      any
+		to        any
+		wantData  map[string]any
+		wantEqual string
+	}{
+		{
+			name: "no differences",
+			from: map[string]any{"a": float64(1), "b": []any{"x"}},
+			to:   map[string]any{"a": float64(1), "b": []any{"x"}},
+			wantData: map[string]any{
+				"equal":   true,
+				"added":   []any{},
+				"removed": []any{},
+				"changed": []any{},
+				"patch":   []any{},
+			},
+			wantEqual: "true",
+		},
+		{
+			name: "added, removed and changed keys",
+			from: map[string]any{"keep": "same", "gone": "old", "region": "eu-west-1"},
+			to:   map[string]any{"keep": "same", "new": true, "region": "us-east-1"},
+			wantData: map[string]any{
+				"equal":   false,
+				"added":   []any{"/new"},
+				"removed": []any{"/gone"},
+				"changed": []any{"/region"},
+				"patch": []any{
+					map[string]any{"op": "remove", "path": "/gone"},
+					map[string]any{"op": "add", "path": "/new", "value": true},
+					map[string]any{"op": "replace", "path": "/region", "value": "us-east-1"},
+				},
+			},
+			wantEqual: "false",
+		},
+		{
+			name: "nested paths",
+			from: map[string]any{"server": map[string]any{"ports": []any{float64(80)}}},
+			to:   map[string]any{"server": map[string]any{"ports": []any{float64(80), float64(443)}}},
+			wantData: map[string]any{
+				"equal":   false,
+				"added":   []any{"/server/ports/-"},
+				"removed": []any{},
+				"changed": []any{},
+				"patch": []any{
+					map[string]any{"op": "add", "path": "/server/ports/-", "value": float64(443)},
+				},
+			},
+			wantEqual: "false",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := NewDiffStep("test", DiffStepConfig{From: tt.from, To: tt.to})
+			require.NoError(t, err)
+
+			result, err := step.Resolve(t.Context())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantData, result.Data)
+			assert.Equal(t, tt.wantEqual, result.Meta["equal"])
+		})
+	}
+}
@@ -24,6 +24,7 @@ const (
 
 	defaultTimeout = 30 * time.Second
 	defaultFormat  = "json"
+	execWaitDelay  = time.Second
 )
 
 var (
@@ -38,6 +39,12 @@ type ExecStepConfig struct {
 	Format     *string
 	Env        map[string]string
 	AllowedEnv []string
+	// CleanEnv starts the child from an empty environment: safeEnvVars are
+	// not inherited, so the child sees only AllowedEnv and Env. Without a
+	// PATH among them, Program[0] must be a path.
+	CleanEnv bool
+	// MaxOutputBytes caps how much stdout is captured. Nil means unlimited.
+	MaxOutputBytes *int
 }
 
 func NewExecStep(name string, logger *zap.Logger, cfg ExecStepConfig) (engine.Step, error) {
@@ -47,13 +54,17 @@ func NewExecStep(name string, logger *zap.Logger, cfg ExecStepConfig) (engine.St
 
 	timeout := defaultTimeout
 	if cfg.Timeout != nil {
-		parsed, err := time.ParseDuration(*cfg.Timeout)
+		parsed, err := engine.ParseDuration(*cfg.Timeout)
 		if err != nil {
-			return nil, fmt.Errorf("invalid timeout %q: %w", *cfg.Timeout, err)
+			return nil, fmt.Errorf("invalid timeout: %w", err)
 		}
 		timeout = parsed
 	}
 
+	if cfg.MaxOutputBytes != nil && *cfg.MaxOutputBytes <= 0 {
+		return nil, fmt.Errorf("max_output_bytes must be positive, got %d", *cfg.MaxOutputBytes)
+	}
+
 	format := defaultFormat
 	if cfg.Format != nil {
 		format = *cfg.Format
@@ -72,28 +83,50 @@ func NewExecStep(name string, logger *zap.Logger, cfg ExecStepConfig) (engine.St
 		}
 	}
 
-	return engine.StepFunction(name, ExecStepKind, func(ctx context.Context) (engine.Result, error) {
-		ctx, cancel := context.WithTimeout(ctx, timeout)
+	return engine.StepFunction(name, ExecStepKind, func(runCtx context.Context) (engine.Result, error) {
+		ctx, cancel := context.WithTimeout(runCtx, timeout)
 		defer cancel()
 
-		cmd := exec.CommandContext(ctx, cfg.Program[0], cfg.Program[1:]...)
-
-		if workingDir != "" {
-			cmd.Dir = workingDir
+		// Build the environment for the child process. A clean environment
+		// drops safeEnvVars but keeps the allowlist. A non-nil empty slice
+		// matters: a nil env would inherit os.Environ().
+		allowedVariables := slices.Clone(cfg.AllowedEnv)
+		if !cfg.CleanEnv {
+			allowedVariables = append(allowedVariables, safeEnvVars...)
 		}
-
-		// Build the environment for the child process.
-		allowedVariables := append(cfg.AllowedEnv, safeEnvVars...)
-		cmd.Env = lo.Filter(os.Environ(), func(kv string, _ int) bool {
+		env := lo.Filter(os.Environ(), func(kv string, _ int) bool {
 			name, _, ok := strings.Cut(kv, "=")
 			if !ok {
 				return false
 			}
 			return slices.Contains(allowedVariables, name)
 		})
-
+		if env == nil {
+			env = []string{}
+		}
 		for k, v := range cfg.Env {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+			env = append(env, fmt.Sprintf("%s=%s", k, v))
+		}
+
+		program := cfg.Program[0]
+		if cfg.CleanEnv {
+			// exec.Command resolves bare names against our own PATH; with a
+			// clean environment only the PATH the child gets counts.
+			resolved, err := lookPathIn(program, envValue(env, "PATH"))
+			if err != nil {
+				return engine.Result{}, err
+			}
+			program = resolved
+		}
+
+		cmd := exec.CommandContext(ctx, program, cfg.Program[1:]...)
+		// Once the context is done, stop waiting for grandchildren that
+		// still hold stdout/stderr open.
+		cmd.WaitDelay = execWaitDelay
+		cmd.Env = env
+
+		if workingDir != "" {
+			cmd.Dir = workingDir
 		}
 
 		if cfg.Input != nil {
@@ -107,6 +140,11 @@ func NewExecStep(name string, logger *zap.Logger, cfg ExecStepConfig) (engine.St
 		var stdout, stderr bytes.Buffer
 		cmd.Stdout = &stdout
 		cmd.Stderr = &stderr
+		var limited *limitedWriter
+		if cfg.MaxOutputBytes != nil {
+			limited = &limitedWriter{w: &stdout, limit: *cfg.MaxOutputBytes}
+			cmd.Stdout = limited
+		}
 
 		logger.Debug("invoking exec step",
 			zap.String("step", name),
@@ -127,8 +165,14 @@ func NewExecStep(name string, logger *zap.Logger, cfg ExecStepConfig) (engine.St
 			zap.Duration("duration", duration),
 		)
 
+		if limited != nil && limited.exceeded {
+			return engine.Result{}, fmt.Errorf("command output exceeds max_output_bytes of %d bytes", limited.limit)
+		}
 		if err != nil {
 			stderrStr := strings.TrimSpace(stderr.String())
+			if runCtx.Err() != nil {
+				return engine.Result{}, fmt.Errorf("command interrupted: %w", context.Cause(runCtx))
+			}
 			if ctx.Err() == context.DeadlineExceeded {
 				return engine.Result{}, fmt.Errorf("command timed out after %s: %s", timeout, stderrStr)
 			}
@@ -163,3 +207,57 @@ func NewExecStep(name string, logger *zap.Logger, cfg ExecStepConfig) (engine.St
 		return engine.Result{Data: map[string]any{"output": encodedBuf.String()}, Meta: meta}, nil
 	}), nil
 }
+
+// limitedWriter passes writes through until more than limit bytes arrive,
+// then fails every write. The failed write makes exec stop copying the
+// child's stdout, and the step reports the limit instead of truncated data.
+type limitedWriter struct {
+	w        io.Writer
+	limit    int
+	n        int
+	exceeded bool
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.n+len(p) > l.limit {
+		l.exceeded = true
+		return 0, fmt.Errorf("output exceeds %d bytes", l.limit)
+	}
+	l.n += len(p)
+	return l.w.Write(p)
+}
+
+// lookPathIn resolves file like exec.LookPath, but searches pathEnv instead
+// of the current process's PATH. Names containing a path separator are
+// returned unchanged.
+func lookPathIn(file, pathEnv string) (string, error) {
+	if strings.ContainsRune(file, filepath.Separator) || strings.ContainsRune(file, '/') {
+		return file, nil
+	}
+	if pathEnv == "" {
+		return "", fmt.Errorf("program %q is not a path and clean_env is set without a PATH in env or allowed_env", file)
+	}
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir == "" {
+			continue
+		}
+		candidate := filepath.Join(dir, file)
+		info, err := os.Stat(candidate)
+		if err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("program %q not found in PATH %q", file, pathEnv)
+}
+
+// envValue returns the value of name in env, a list of KEY=VALUE pairs. The
+// last pair wins, as it does for exec.Cmd.
+func envValue(env []string, name string) string {
+	var value string
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == name {
+			value = v
+		}
+	}
+	return value
+}
@@ -1,12 +1,19 @@
 package steps
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/infracollect/infracollect/internal/engine"
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -38,6 +45,12 @@ func TestNewExecStep_Validation(t *testing.T) {
 			wantErr:     true,
 			errContains: "invalid timeout",
 		},
+		{
+			name:        "error when max_output_bytes is not positive",
+			cfg:         ExecStepConfig{Program: []string{"echo"}, MaxOutputBytes: lo.ToPtr(0)},
+			wantErr:     true,
+			errContains: "max_output_bytes must be positive",
+		},
 		{
 			name:    "accepts valid program",
 			cfg:     ExecStepConfig{Program: []string{"echo", "hello"}},
@@ -127,6 +140,36 @@ func TestExecStep_Input(t *testing.T) {
 	assert.Equal(t, expected, result.Data)
 }
 
+func TestExecStep_MaxOutputBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		limit   int
+		wantErr string
+	}{
+		{name: "within limit", script: `echo '{"ok": true}'`, limit: 64},
+		{name: "over limit", script: `echo '{"items": [1, 2, 3]}'`, limit: 8, wantErr: "command output exceeds max_output_bytes of 8 bytes"},
+		{name: "endless output", script: "yes", limit: 1024, wantErr: "command output exceeds max_output_bytes of 1024 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := NewExecStep("test", zap.NewNop(), ExecStepConfig{
+				Program:        []string{"sh", "-c", tt.script},
+				MaxOutputBytes: lo.ToPtr(tt.limit),
+			})
+			require.NoError(t, err)
+
+			result, err := step.Resolve(t.Context())
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, map[string]any{"ok": true}, result.Data)
+		})
+	}
+}
+
 func TestExecStep_NonZeroExit(t *testing.T) {
 	step, err := NewExecStep("test", zap.NewNop(), ExecStepConfig{
 		Program: []string{"sh", "-c", "echo 'error message' >&2; exit 1"},
@@ -151,6 +194,24 @@ func TestExecStep_Timeout(t *testing.T) {
 	assert.ErrorContains(t, err, "timed out")
 }
 
+func TestExecStep_InterruptedByRunContext(t *testing.T) {
+	step, err := NewExecStep("test", zap.NewNop(), ExecStepConfig{
+		// The backgrounded sleep keeps stdout open after sh is killed.
+		Program: []string{"sh", "-c", "sleep 10 & sleep 10"},
+	})
+	require.NoError(t, err)
+
+	runErr := errors.New("run timed out")
+	ctx, cancel := context.WithTimeoutCause(t.Context(), 100*time.Millisecond, runErr)
+	defer cancel()
+
+	start := time.Now()
+	_, err = step.Resolve(ctx)
+	assert.ErrorIs(t, err, runErr)
+	assert.ErrorContains(t, err, "command interrupted")
+	assert.Less(t, time.Since(start), 5*time.Second, "should not wait for orphaned children")
+}
+
 func TestExecStep_Environment(t *testing.T) {
 	step, err := NewExecStep("test", zap.NewNop(), ExecStepConfig{
 		Program: []string{"sh", "-c", `echo "{\"test_var\": \"$TEST_VAR\", \"home_set\": \"$(test -n \"$HOME\" && echo true || echo false)\"}"`},
@@ -216,6 +277,109 @@ func TestExecStep_AllowedEnvEmptyOnlyPassesSafeVars(t *testing.T) {
 	assert.Equal(t, "", data["secret"])
 }
 
+func TestExecStep_CleanEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on windows")
+	}
+	t.Setenv("ALLOWED_VAR", "allowed")
+
+	step, err := NewExecStep("test", zap.NewNop(), ExecStepConfig{
+		Program:    []string{"sh", "-c", `echo "{\"home\": \"$HOME\", \"allowed\": \"$ALLOWED_VAR\", \"path\": \"$PATH\", \"custom\": \"$CUSTOM\"}"`},
+		Format:     lo.ToPtr("json"),
+		Env:        map[string]string{"PATH": os.Getenv("PATH"), "CUSTOM": "value"},
+		AllowedEnv: []string{"ALLOWED_VAR"},
+		CleanEnv:   true,
+	})
+	require.NoError(t, err)
+
+	result, err := step.Resolve(t.Context())
+	require.NoError(t, err)
+
+	data, ok := result.Data.(map[string]any)
+	require.True(t, ok)
+	// Safe vars are not inherited; allowlisted vars and Env are.
+	assert.Equal(t, "", data["home"])
+	assert.Equal(t, "allowed", data["allowed"])
+	assert.Equal(t, os.Getenv("PATH"), data["path"])
+	assert.Equal(t, "value", data["custom"])
+}
+
+func TestExecStep_CleanEnvRequiresPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on windows")
+	}
+
+	tests := []struct {
+		name        string
+		program     []string
+		env         map[string]string
+		errContains string
+	}{
+		{
+			name:        "bare name without PATH",
+			program:     []string{"sh", "-c", "echo {}"},
+			errContains: `program "sh" is not a path and clean_env is set without a PATH in env or allowed_env`,
+		},
+		{
+			name:        "bare name not in provided PATH",
+			program:     []string{"sh", "-c", "echo {}"},
+			env:         map[string]string{"PATH": t.TempDir()},
+			errContains: `program "sh" not found in PATH`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := NewExecStep("test", zap.NewNop(), ExecStepConfig{
+				Program:  tt.program,
+				Env:      tt.env,
+				CleanEnv: true,
+			})
+			require.NoError(t, err)
+
+			_, err = step.Resolve(t.Context())
+			assert.ErrorContains(t, err, tt.errContains)
+		})
+	}
+}
+
+func TestExecStep_CleanEnvAllowedPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on windows")
+	}
+
+	step, err := NewExecStep("test", zap.NewNop(), ExecStepConfig{
+		Program:    []string{"sh", "-c", `echo "{\"path\": \"$PATH\"}"`},
+		Format:     lo.ToPtr("json"),
+		AllowedEnv: []string{"PATH"},
+		CleanEnv:   true,
+	})
+	require.NoError(t, err)
+
+	result, err := step.Resolve(t.Context())
+	require.NoError(t, err, "an allowlisted PATH resolves bare program names")
+	assert.Equal(t, map[string]any{"path": os.Getenv("PATH")}, result.Data)
+}
+
+func TestExecStep_CleanEnvAbsoluteProgram(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on windows")
+	}
+	sh, err := exec.LookPath("sh")
+	require.NoError(t, err)
+
+	step, err := NewExecStep("test", zap.NewNop(), ExecStepConfig{
+		Program:  []string{sh, "-c", `echo "{\"home\": \"$HOME\"}"`},
+		Format:   lo.ToPtr("json"),
+		CleanEnv: true,
+	})
+	require.NoError(t, err)
+
+	result, err := step.Resolve(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"home": ""}, result.Data)
+}
+
 func TestExecStep_WorkingDirectory(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("skipping on Windows")
@@ -298,3 +462,139 @@ func TestExecStep_CommandNotFound(t *testing.T) {
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "command failed")
 }
+
+// newExecRegistry returns a registry with the builtin steps, passing the
+// given variables to jobs.
+func newExecRegistry(t *testing.T, passed ...string) *engine.Registry {
+	t.Helper()
+	registry := engine.NewRegistry(zap.NewNop())
+	registry.RegisterDependency(engine.AllowedEnvVarsDepKey, passed)
+	require.NoError(t, Register(registry))
+	return registry
+}
+
+func parseExecBody(t *testing.T, src string) hcl.Body {
+	t.Helper()
+	file, diags := hclsyntax.ParseConfig([]byte(src), "exec.hcl", hcl.InitialPos)
+	require.False(t, diags.HasErrors(), diags.Error())
+	return file.Body
+}
+
+func TestValidateExecBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         string
+		wantSummary string
+		wantWarning bool
+	}{
+		{name: "literal program", src: `program = ["echo", "hi"]`},
+		{name: "program from an expression", src: `program = step.static.cmd.data`},
+		{name: "passed allowed_env", src: "program = [\"env\"]\nallowed_env = [\"TOKEN\"]"},
+		{name: "missing program", src: `timeout = "5s"`, wantSummary: "Missing required argument"},
+		{name: "empty program", src: `program = []`, wantSummary: "Empty exec program"},
+		{
+			name:        "allowed_env not passed",
+			src:         "program = [\"env\"]\nallowed_env = [\"SECRET\"]",
+			wantSummary: "Environment variable not passed",
+			wantWarning: true,
+		},
+	}
+
+	registry := newExecRegistry(t, "TOKEN")
+	desc, ok := registry.StepDescriptor(ExecStepKind)
+	require.True(t, ok)
+	require.NotNil(t, desc.Validate)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := desc.Validate(registry.Helper(), parseExecBody(t, tt.src))
+			if tt.wantSummary == "" {
+				assert.Empty(t, diags)
+				return
+			}
+			require.Len(t, diags, 1)
+			assert.Equal(t, tt.wantSummary, diags[0].Summary)
+			assert.Equal(t, tt.wantWarning, diags[0].Severity == hcl.DiagWarning)
+		})
+	}
+}
+
+func TestExecStep_StepAllowedEnv(t *testing.T) {
+	t.Setenv("FIRST_VAR", "first")
+	t.Setenv("SECOND_VAR", "second")
+
+	registry := newExecRegistry(t, "FIRST_VAR", "SECOND_VAR")
+	step, diags := registry.CreateStep(ExecStepKind, "test", nil, parseExecBody(t, `
+program     = ["sh", "-c", "printf '%s-%s' \"$FIRST_VAR\" \"$SECOND_VAR\""]
+format      = "raw"
+allowed_env = ["FIRST_VAR"]
+`), nil)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	result, err := step.Resolve(t.Context())
+	require.NoError(t, err)
+	data, ok := result.Data.(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("first-")), data["output"])
+}
+
+func TestExecStep_StepAllowedEnvErrors(t *testing.T) {
+	registry := newExecRegistry(t, "FIRST_VAR")
+
+	_, diags := registry.CreateStep(ExecStepKind, "test", nil, parseExecBody(t, `
+program     = ["env"]
+allowed_env = ["OTHER_VAR"]
+`), nil)
+	require.True(t, diags.HasErrors())
+	assert.Contains(t, diags.Error(), `allowed_env lists "OTHER_VAR", which is not passed to the job`)
+}
+
+func TestExecStep_StepCleanEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on windows")
+	}
+	t.Setenv("FIRST_VAR", "first")
+	t.Setenv("SECOND_VAR", "second")
+	sh, err := exec.LookPath("sh")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "allowed_env is inherited",
+			src: `
+program     = ["` + sh + `", "-c", "printf '%s-%s-%s' \"$FIRST_VAR\" \"$SECOND_VAR\" \"$HOME\""]
+format      = "raw"
+allowed_env = ["FIRST_VAR"]
+clean_env   = true
+`,
+			want: "first--",
+		},
+		{
+			name: "without allowed_env nothing is inherited",
+			src: `
+program   = ["` + sh + `", "-c", "printf '%s-%s-%s' \"$FIRST_VAR\" \"$SECOND_VAR\" \"$HOME\""]
+format    = "raw"
+clean_env = true
+`,
+			want: "--",
+		},
+	}
+
+	registry := newExecRegistry(t, "FIRST_VAR", "SECOND_VAR")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, diags := registry.CreateStep(ExecStepKind, "test", nil, parseExecBody(t, tt.src), nil)
+			require.False(t, diags.HasErrors(), diags.Error())
+
+			result, err := step.Resolve(t.Context())
+			require.NoError(t, err)
+			data, ok := result.Data.(map[string]any)
+			require.True(t, ok)
+			assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(tt.want)), data["output"])
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+const (
+	FlattenStepKind = "flatten"
+
+	DefaultFlattenSeparator = "."
+)
+
+type FlattenStepConfig struct {
+	// From is the evaluated value to flatten: an object, or a list of
+	// objects flattened one by one.
+	From any
+	// Separator joins the keys of nested objects. Empty selects
+	// DefaultFlattenSeparator.
+	Separator string
+	// MaxDepth is the number of nesting levels collapsed into a key; deeper
+	// values are kept as they are. Zero means no limit.
+	MaxDepth int
+	// IndexArrays flattens arrays too, using each element's index as its
+	// key. By default arrays are kept as values.
+	IndexArrays bool
+}
+
+// NewFlattenStep collapses nested objects into a single-level object whose
+// keys are the joined paths to each leaf, e.g. {"a":{"b":1}} becomes
+// {"a.b":1}. When From is a list, each element is flattened on its own,
+// producing one flat object per row.
+func NewFlattenStep(name string, cfg FlattenStepConfig) (engine.Step, error) {
+	if cfg.MaxDepth < 0 {
+		return nil, fmt.Errorf("max_depth must not be negative, got %d", cfg.MaxDepth)
+	}
+	if cfg.Separator == "" {
+		cfg.Separator = DefaultFlattenSeparator
+	}
+
+	return engine.StepFunction(name, FlattenStepKind, func(ctx context.Context) (engine.Result, error) {
+		switch from := cfg.From.(type) {
+		case map[string]any:
+			flat, err := flattenObject(from, cfg)
+			if err != nil {
+				return engine.Result{}, err
+			}
+			return engine.Result{
+				Data: flat,
+				Meta: map[string]string{"keys": strconv.Itoa(len(flat))},
+			}, nil
+		case []any:
+			rows := make([]any, len(from))
+			for i, item := range from {
+				obj, ok := item.(map[string]any)
+				if !ok {
+					return engine.Result{}, fmt.Errorf("from[%d] must be an object, got %T", i, item)
+				}
+				flat, err := flattenObject(obj, cfg)
+				if err != nil {
+					return engine.Result{}, fmt.Errorf("from[%d]: %w", i, err)
+				}
+				rows[i] = flat
+			}
+			return engine.Result{
+				Data: rows,
+				Meta: map[string]string{"rows": strconv.Itoa(len(rows))},
+			}, nil
+		default:
+			return engine.Result{}, fmt.Errorf("from must be an object or a list of objects, got %T", cfg.From)
+		}
+	}), nil
+}
+
+func flattenObject(obj map[string]any, cfg FlattenStepConfig) (map[string]any, error) {
+	flat := make(map[string]any)
+	for k, v := range obj {
+		if err := flattenInto(flat, k, v, 1, cfg); err != nil {
+			return nil, err
+		}
+	}
+	return flat, nil
+}
+
+// flattenInto stores v under key, descending into non-empty objects (and
+// arrays, with IndexArrays) until MaxDepth levels have been joined. Two paths
+// that join to the same key, such as "a.b" and {"a":{"b":...}}, are an error
+// rather than a silent overwrite.
+func flattenInto(flat map[string]any, key string, v any, depth int, cfg FlattenStepConfig) error {
+	canDescend := cfg.MaxDepth == 0 || depth <= cfg.MaxDepth
+	switch val := v.(type) {
+	case map[string]any:
+		if canDescend && len(val) > 0 {
+			for k, item := range val {
+				if err := flattenInto(flat, key+cfg.Separator+k, item, depth+1, cfg); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case []any:
+		if canDescend && cfg.IndexArrays && len(val) > 0 {
+			for i, item := range val {
+				if err := flattenInto(flat, key+cfg.Separator+strconv.Itoa(i), item, depth+1, cfg); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	if _, exists := flat[key]; exists {
+		return fmt.Errorf("flattening produces key %q more than once", key)
+	}
+	flat[key] = v
+	return nil
+}
@@ -0,0 +1,127 @@
+package steps
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlattenStep_Resolve(t *testing.T) {
+	nested := map[string]any{
+		"name": "web",
+		"spec": map[string]any{
+			"cpu":    2,
+			"labels": map[string]any{"team": "core"},
+		},
+		"tags": []any{"a", map[string]any{"k": "v"}},
+	}
+
+	tests := []struct {
+		name        string
+		cfg         FlattenStepConfig
+		wantData    any
+		wantMeta    map[string]string
+		errContains string
+	}{
+		{
+			name: "nested objects become dotted keys",
+			cfg:  FlattenStepConfig{From: nested},
+			wantData: map[string]any{
+				"name":             "web",
+				"spec.cpu":         2,
+				"spec.labels.team": "core",
+				"tags":             []any{"a", map[string]any{"k": "v"}},
+			},
+			wantMeta: map[string]string{"keys": "4"},
+		},
+		{
+			name: "custom separator",
+			cfg:  FlattenStepConfig{From: map[string]any{"a": map[string]any{"b": 1}}, Separator: "_"},
+			wantData: map[string]any{
+				"a_b": 1,
+			},
+			wantMeta: map[string]string{"keys": "1"},
+		},
+		{
+			name: "arrays indexed",
+			cfg:  FlattenStepConfig{From: nested, IndexArrays: true},
+			wantData: map[string]any{
+				"name":             "web",
+				"spec.cpu":         2,
+				"spec.labels.team": "core",
+				"tags.0":           "a",
+				"tags.1.k":         "v",
+			},
+			wantMeta: map[string]string{"keys": "5"},
+		},
+		{
+			name: "max depth keeps deeper values",
+			cfg:  FlattenStepConfig{From: nested, MaxDepth: 1},
+			wantData: map[string]any{
+				"name":        "web",
+				"spec.cpu":    2,
+				"spec.labels": map[string]any{"team": "core"},
+				"tags":        []any{"a", map[string]any{"k": "v"}},
+			},
+			wantMeta: map[string]string{"keys": "4"},
+		},
+		{
+			name: "empty objects and arrays are kept",
+			cfg:  FlattenStepConfig{From: map[string]any{"a": map[string]any{}, "b": []any{}}, IndexArrays: true},
+			wantData: map[string]any{
+				"a": map[string]any{},
+				"b": []any{},
+			},
+			wantMeta: map[string]string{"keys": "2"},
+		},
+		{
+			name: "list of objects flattened per row",
+			cfg: FlattenStepConfig{From: []any{
+				map[string]any{"id": 1, "owner": map[string]any{"name": "ana"}},
+				map[string]any{"id": 2, "owner": map[string]any{"name": "bo"}},
+			}},
+			wantData: []any{
+				map[string]any{"id": 1, "owner.name": "ana"},
+				map[string]any{"id": 2, "owner.name": "bo"},
+			},
+			wantMeta: map[string]string{"rows": "2"},
+		},
+		{
+			name:        "colliding keys",
+			cfg:         FlattenStepConfig{From: map[string]any{"a.b": 1, "a": map[string]any{"b": 2}}},
+			errContains: `flattening produces key "a.b" more than once`,
+		},
+		{
+			name:        "list element that is not an object",
+			cfg:         FlattenStepConfig{From: []any{map[string]any{}, "x"}},
+			errContains: "from[1] must be an object, got string",
+		},
+		{
+			name:        "scalar from",
+			cfg:         FlattenStepConfig{From: "x"},
+			errContains: "from must be an object or a list of objects, got string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := NewFlattenStep("test", tt.cfg)
+			require.NoError(t, err)
+
+			result, err := step.Resolve(t.Context())
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantData, result.Data)
+			assert.Equal(t, tt.wantMeta, result.Meta)
+		})
+	}
+}
+
+func TestNewFlattenStep_Validation(t *testing.T) {
+	_, err := NewFlattenStep("test", FlattenStepConfig{From: map[string]any{}, MaxDepth: -1})
+	assert.ErrorContains(t, err, "max_depth must not be negative")
+}
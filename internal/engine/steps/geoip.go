@@ -0,0 +1,190 @@
+package steps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/ohler55/ojg/jp"
+	"github.com/oschwald/geoip2-golang"
+	"go.uber.org/zap"
+)
+
+const (
+	GeoIPStepKind = "enrich_geoip"
+
+	// geoIPTargetSuffix names the enrichment key when Target is empty:
+	// "<ip field>_geoip".
+	geoIPTargetSuffix = "_geoip"
+)
+
+type GeoIPStepConfig struct {
+	// From is the evaluated value holding the IP addresses, usually a step
+	// result.
+	From any
+	// Path is a JSONPath to the IP address fields. It must end with a member
+	// name, the field holding the address, e.g. "$.hosts[*].ip".
+	Path string
+	// Target is the key the enrichment is stored under, next to each IP
+	// field. Empty selects "<field>_geoip".
+	Target string
+	// Databases are paths to MaxMind databases (.mmdb). Country and City
+	// databases provide the country, ASN databases the autonomous system.
+	Databases []string
+}
+
+// geoIPDatabase is the part of a MaxMind reader the step uses. Each method
+// fails with geoip2.InvalidMethodError when the database does not hold that
+// kind of record.
+type geoIPDatabase interface {
+	Country(ip net.IP) (*geoip2.Country, error)
+	ASN(ip net.IP) (*geoip2.ASN, error)
+	Close() error
+}
+
+// openGeoIPDatabase is swapped out by tests, which have no MaxMind database
+// at hand.
+var openGeoIPDatabase = func(path string) (geoIPDatabase, error) {
+	return geoip2.Open(path)
+}
+
+// NewGeoIPStep enriches the IP address fields matched by Path with their
+// country and autonomous system. The enrichment is stored next to each IP
+// field. It is null when no database knows the address, when the field does
+// not hold a valid address, or when no database could be opened: a missing
+// database degrades the enrichment and logs a warning rather than failing
+// the step.
+func NewGeoIPStep(name string, logger *zap.Logger, cfg GeoIPStepConfig) (engine.Step, error) {
+	expr, err := jp.ParseString(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", cfg.Path, err)
+	}
+	var field jp.Child
+	if len(expr) >= 2 {
+		field, _ = expr[len(expr)-1].(jp.Child)
+	}
+	if field == "" {
+		return nil, fmt.Errorf("path %q must end with the name of the IP field, such as $.hosts[*].ip", cfg.Path)
+	}
+	parents := expr[:len(expr)-1]
+
+	target := cfg.Target
+	if target == "" {
+		target = string(field) + geoIPTargetSuffix
+	}
+	if target == string(field) {
+		return nil, fmt.Errorf("target must differ from the IP field %q", field)
+	}
+
+	return engine.StepFunction(name, GeoIPStepKind, func(ctx context.Context) (engine.Result, error) {
+		databases := openGeoIPDatabases(logger, cfg.Databases)
+		defer func() {
+			for _, db := range databases {
+				_ = db.Close()
+			}
+		}()
+
+		// From is a private copy converted from cty, so it is enriched in
+		// place.
+		enriched, unknown := 0, 0
+		for _, parent := range parents.Get(cfg.From) {
+			obj, ok := parent.(map[string]any)
+			if !ok {
+				continue
+			}
+			value, ok := obj[string(field)].(string)
+			if !ok {
+				continue
+			}
+
+			enrichment, err := lookupGeoIP(databases, value)
+			if err != nil {
+				return engine.Result{}, err
+			}
+			if enrichment == nil {
+				obj[target] = nil
+				unknown++
+				continue
+			}
+			obj[target] = enrichment
+			enriched++
+		}
+
+		return engine.Result{
+			Data: cfg.From,
+			Meta: map[string]string{
+				"enriched":  strconv.Itoa(enriched),
+				"unknown":   strconv.Itoa(unknown),
+				"databases": strconv.Itoa(len(databases)),
+			},
+		}, nil
+	}), nil
+}
+
+// openGeoIPDatabases opens every database it can, warning about the others.
+func openGeoIPDatabases(logger *zap.Logger, paths []string) []geoIPDatabase {
+	databases := make([]geoIPDatabase, 0, len(paths))
+	for _, path := range paths {
+		db, err := openGeoIPDatabase(path)
+		if err != nil {
+			logger.Warn("geoip database unavailable, its enrichment will be null",
+				zap.String("database", path),
+				zap.Error(err),
+			)
+			continue
+		}
+		databases = append(databases, db)
+	}
+	return databases
+}
+
+// lookupGeoIP returns the enrichment of address across databases, or nil
+// when address is not an IP or no database knows it. A database that does
+// not hold a kind of record is skipped; any other lookup error is returned.
+func lookupGeoIP(databases []geoIPDatabase, address string) (map[string]any, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, nil
+	}
+
+	enrichment := map[string]any{
+		"country_code": nil,
+		"country":      nil,
+		"asn":          nil,
+		"as_org":       nil,
+	}
+	found := false
+	var invalidMethod geoip2.InvalidMethodError
+
+	for _, db := range databases {
+		country, err := db.Country(ip)
+		switch {
+		case errors.As(err, &invalidMethod):
+		case err != nil:
+			return nil, fmt.Errorf("failed to look up country of %s: %w", address, err)
+		case country.Country.IsoCode != "":
+			enrichment["country_code"] = country.Country.IsoCode
+			enrichment["country"] = country.Country.Names["en"]
+			found = true
+		}
+
+		asn, err := db.ASN(ip)
+		switch {
+		case errors.As(err, &invalidMethod):
+		case err != nil:
+			return nil, fmt.Errorf("failed to look up ASN of %s: %w", address, err)
+		case asn.AutonomousSystemNumber != 0:
+			enrichment["asn"] = int64(asn.AutonomousSystemNumber)
+			enrichment["as_org"] = asn.AutonomousSystemOrganization
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, nil
+	}
+	return enrichment, nil
+}
@@ -0,0 +1,163 @@
+package steps
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeGeoIPDatabase answers from in-memory records. A nil map means the
+// database does not hold that kind of record.
+type fakeGeoIPDatabase struct {
+	countries map[string]string // ip -> ISO code
+	asns      map[string]uint   // ip -> AS number
+	err       error
+}
+
+func (f *fakeGeoIPDatabase) Country(ip net.IP) (*geoip2.Country, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.countries == nil {
+		return nil, geoip2.InvalidMethodError{Method: "Country", DatabaseType: "GeoLite2-ASN"}
+	}
+	var record geoip2.Country
+	record.Country.IsoCode = f.countries[ip.String()]
+	record.Country.Names = map[string]string{"en": "Name of " + record.Country.IsoCode}
+	return &record, nil
+}
+
+func (f *fakeGeoIPDatabase) ASN(ip net.IP) (*geoip2.ASN, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.asns == nil {
+		return nil, geoip2.InvalidMethodError{Method: "ASN", DatabaseType: "GeoLite2-Country"}
+	}
+	number := f.asns[ip.String()]
+	return &geoip2.ASN{AutonomousSystemNumber: number, AutonomousSystemOrganization: "AS" + ip.String()}, nil
+}
+
+func (f *fakeGeoIPDatabase) Close() error { return nil }
+
+// withGeoIPDatabases makes openGeoIPDatabase serve databases by path; other
+// paths fail to open.
+func withGeoIPDatabases(t *testing.T, databases map[string]geoIPDatabase) {
+	t.Helper()
+	previous := openGeoIPDatabase
+	openGeoIPDatabase = func(path string) (geoIPDatabase, error) {
+		if db, ok := databases[path]; ok {
+			return db, nil
+		}
+		return nil, errors.New("no such file")
+	}
+	t.Cleanup(func() { openGeoIPDatabase = previous })
+}
+
+func TestGeoIPStep_Resolve(t *testing.T) {
+	withGeoIPDatabases(t, map[string]geoIPDatabase{
+		"country.mmdb": &fakeGeoIPDatabase{countries: map[string]string{"192.0.2.1": "FR"}},
+		"asn.mmdb":     &fakeGeoIPDatabase{asns: map[string]uint{"192.0.2.1": 64500, "2001:db8::1": 64501}},
+	})
+
+	hosts := func() any {
+		return map[string]any{"items": []any{
+			map[string]any{"name": "a", "ip": "192.0.2.1"},
+			map[string]any{"name": "b", "ip": "2001:db8::1"},
+			map[string]any{"name": "c", "ip": "198.51.100.7"},
+			map[string]any{"name": "d", "ip": "not-an-ip"},
+			map[string]any{"name": "e"},
+		}}
+	}
+
+	tests := []struct {
+		name     string
+		cfg      GeoIPStepConfig
+		expected []any
+		wantMeta map[string]string
+	}{
+		{
+			name: "country and asn databases",
+			cfg:  GeoIPStepConfig{Path: "$.items[*].ip", Databases: []string{"country.mmdb", "asn.mmdb"}},
+			expected: []any{
+				map[string]any{"name": "a", "ip": "192.0.2.1", "ip_geoip": map[string]any{
+					"country_code": "FR", "country": "Name of FR", "asn": int64(64500), "as_org": "AS192.0.2.1",
+				}},
+				map[string]any{"name": "b", "ip": "2001:db8::1", "ip_geoip": map[string]any{
+					"country_code": nil, "country": nil, "asn": int64(64501), "as_org": "AS2001:db8::1",
+				}},
+				map[string]any{"name": "c", "ip": "198.51.100.7", "ip_geoip": nil},
+				map[string]any{"name": "d", "ip": "not-an-ip", "ip_geoip": nil},
+				map[string]any{"name": "e"},
+			},
+			wantMeta: map[string]string{"enriched": "2", "unknown": "2", "databases": "2"},
+		},
+		{
+			name: "missing database degrades to null",
+			cfg:  GeoIPStepConfig{Path: "$.items[*].ip", Target: "geo", Databases: []string{"missing.mmdb"}},
+			expected: []any{
+				map[string]any{"name": "a", "ip": "192.0.2.1", "geo": nil},
+				map[string]any{"name": "b", "ip": "2001:db8::1", "geo": nil},
+				map[string]any{"name": "c", "ip": "198.51.100.7", "geo": nil},
+				map[string]any{"name": "d", "ip": "not-an-ip", "geo": nil},
+				map[string]any{"name": "e"},
+			},
+			wantMeta: map[string]string{"enriched": "0", "unknown": "4", "databases": "0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.From = hosts()
+			step, err := NewGeoIPStep("test", zap.NewNop(), cfg)
+			require.NoError(t, err)
+
+			result, err := step.Resolve(t.Context())
+			require.NoError(t, err)
+			assert.Equal(t, map[string]any{"items": tt.expected}, result.Data)
+			assert.Equal(t, tt.wantMeta, result.Meta)
+		})
+	}
+}
+
+func TestGeoIPStep_LookupError(t *testing.T) {
+	withGeoIPDatabases(t, map[string]geoIPDatabase{
+		"broken.mmdb": &fakeGeoIPDatabase{err: errors.New("corrupt search tree")},
+	})
+
+	step, err := NewGeoIPStep("test", zap.NewNop(), GeoIPStepConfig{
+		From:      map[string]any{"ip": "192.0.2.1"},
+		Path:      "$.ip",
+		Databases: []string{"broken.mmdb"},
+	})
+	require.NoError(t, err)
+
+	_, err = step.Resolve(t.Context())
+	assert.ErrorContains(t, err, "failed to look up country of 192.0.2.1: corrupt search tree")
+}
+
+func TestNewGeoIPStep_Validation(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         GeoIPStepConfig
+		errContains string
+	}{
+		{name: "invalid path", cfg: GeoIPStepConfig{Path: "$.["}, errContains: "invalid path"},
+		{name: "root path", cfg: GeoIPStepConfig{Path: "$"}, errContains: "must end with the name of the IP field"},
+		{name: "wildcard last", cfg: GeoIPStepConfig{Path: "$.ips[*]"}, errContains: "must end with the name of the IP field"},
+		{name: "target is the field", cfg: GeoIPStepConfig{Path: "$.ip", Target: "ip"}, errContains: "target must differ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGeoIPStep("test", zap.NewNop(), tt.cfg)
+			assert.ErrorContains(t, err, tt.errContains)
+		})
+	}
+}
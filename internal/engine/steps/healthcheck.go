@@ -0,0 +1,211 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+const (
+	HealthcheckStepKind = "healthcheck"
+
+	DefaultHealthcheckTimeout = 5 * time.Second
+
+	// healthcheckMaxBody bounds how much of an HTTP response is drained so
+	// the connection can be reused.
+	healthcheckMaxBody = 64 << 10
+)
+
+// HealthcheckTarget is one endpoint probed by the healthcheck step. Exactly
+// one of URL and Address is set.
+type HealthcheckTarget struct {
+	// Name keys the target's entry in the result.
+	Name string
+	// URL is probed with an HTTP GET.
+	URL string
+	// Address is a host:port probed by opening a TCP connection.
+	Address string
+	// ExpectedStatus is the HTTP status the URL must answer with. Zero
+	// accepts any 2xx status.
+	ExpectedStatus int
+	// Timeout bounds the probe. Zero uses the step's timeout.
+	Timeout time.Duration
+}
+
+type HealthcheckStepConfig struct {
+	Targets []HealthcheckTarget
+	// Timeout is the default probe timeout. Zero selects
+	// DefaultHealthcheckTimeout.
+	Timeout time.Duration
+}
+
+// NewHealthcheckStep probes every target concurrently and returns an object
+// keyed by target name with whether it is healthy, how long the probe took
+// and why it failed. An unhealthy target is part of the collected data, not
+// a step failure; only invalid configuration or cancellation fail the step.
+func NewHealthcheckStep(name string, cfg HealthcheckStepConfig) (engine.Step, error) {
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("at least one target is required")
+	}
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must not be negative, got %s", cfg.Timeout)
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultHealthcheckTimeout
+	}
+
+	seen := make(map[string]bool, len(cfg.Targets))
+	for _, target := range cfg.Targets {
+		if seen[target.Name] {
+			return nil, fmt.Errorf("duplicate target %q", target.Name)
+		}
+		seen[target.Name] = true
+		if err := validateHealthcheckTarget(target); err != nil {
+			return nil, fmt.Errorf("target %q: %w", target.Name, err)
+		}
+	}
+
+	client := cleanhttp.DefaultPooledClient()
+
+	return engine.StepFunction(name, HealthcheckStepKind, func(ctx context.Context) (engine.Result, error) {
+		results := make([]map[string]any, len(cfg.Targets))
+		var wg sync.WaitGroup
+		for i, target := range cfg.Targets {
+			wg.Go(func() {
+				results[i] = probeTarget(ctx, client, target, cfg.Timeout)
+			})
+		}
+		wg.Wait()
+
+		// A probe cut short by cancellation says nothing about the target.
+		if err := ctx.Err(); err != nil {
+			return engine.Result{}, err
+		}
+
+		data := make(map[string]any, len(results))
+		healthy := 0
+		for i, target := range cfg.Targets {
+			data[target.Name] = results[i]
+			if results[i]["ok"] == true {
+				healthy++
+			}
+		}
+		return engine.Result{
+			Data: data,
+			Meta: map[string]string{
+				"targets": strconv.Itoa(len(cfg.Targets)),
+				"healthy": strconv.Itoa(healthy),
+			},
+		}, nil
+	}), nil
+}
+
+func validateHealthcheckTarget(target HealthcheckTarget) error {
+	switch {
+	case target.URL != "" && target.Address != "":
+		return fmt.Errorf("url and address are mutually exclusive")
+	case target.URL == "" && target.Address == "":
+		return fmt.Errorf("one of url or address is required")
+	case target.Timeout < 0:
+		return fmt.Errorf("timeout must not be negative, got %s", target.Timeout)
+	}
+
+	if target.Address != "" {
+		if target.ExpectedStatus != 0 {
+			return fmt.Errorf("expected_status only applies to url targets")
+		}
+		if _, _, err := net.SplitHostPort(target.Address); err != nil {
+			return fmt.Errorf("address must be host:port: %w", err)
+		}
+		return nil
+	}
+
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url must use http or https scheme, got: %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("url %q has no host", target.URL)
+	}
+	if target.ExpectedStatus != 0 && (target.ExpectedStatus < 100 || target.ExpectedStatus > 599) {
+		return fmt.Errorf("expected_status must be between 100 and 599, got %d", target.ExpectedStatus)
+	}
+	return nil
+}
+
+// probeTarget returns the target's entry in the step result: ok, latency_ms
+// and error, plus status for URL targets that answered.
+func probeTarget(ctx context.Context, client *http.Client, target HealthcheckTarget, defaultTimeout time.Duration) map[string]any {
+	timeout := target.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := map[string]any{"ok": false, "error": nil}
+	start := time.Now()
+	var err error
+	if target.Address != "" {
+		err = probeTCP(ctx, target.Address)
+	} else {
+		var status int
+		status, err = probeHTTP(ctx, client, target.URL)
+		if status != 0 {
+			result["status"] = status
+		}
+		if err == nil && !statusMatches(status, target.ExpectedStatus) {
+			err = fmt.Errorf("unexpected status %d", status)
+		}
+	}
+	result["latency_ms"] = time.Since(start).Milliseconds()
+
+	if err != nil {
+		result["error"] = err.Error()
+		return result
+	}
+	result["ok"] = true
+	return result
+}
+
+func probeTCP(ctx context.Context, address string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func probeHTTP(ctx context.Context, client *http.Client, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.CopyN(io.Discard, resp.Body, healthcheckMaxBody)
+	return resp.StatusCode, nil
+}
+
+func statusMatches(status, expected int) bool {
+	if expected == 0 {
+		return status >= 200 && status < 300
+	}
+	return status == expected
+}
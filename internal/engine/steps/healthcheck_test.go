@@ -0,0 +1,172 @@
+package steps
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthcheckStep_Resolve(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	require.NoError(t, closed.Close())
+
+	step, err := NewHealthcheckStep("test", HealthcheckStepConfig{
+		Targets: []HealthcheckTarget{
+			{Name: "api", URL: healthy.URL},
+			{Name: "maintenance", URL: unavailable.URL, ExpectedStatus: http.StatusServiceUnavailable},
+			{Name: "down", URL: unavailable.URL},
+			{Name: "slow", URL: slow.URL, Timeout: 50 * time.Millisecond},
+			{Name: "db", Address: listener.Addr().String()},
+			{Name: "cache", Address: closedAddr},
+		},
+	})
+	require.NoError(t, err)
+
+	result, err := step.Resolve(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"targets": "6", "healthy": "3"}, result.Meta)
+
+	data, ok := result.Data.(map[string]any)
+	require.True(t, ok)
+
+	tests := []struct {
+		target      string
+		wantOK      bool
+		wantStatus  any
+		errContains string
+	}{
+		{target: "api", wantOK: true, wantStatus: http.StatusOK},
+		{target: "maintenance", wantOK: true, wantStatus: http.StatusServiceUnavailable},
+		{target: "down", wantStatus: http.StatusServiceUnavailable, errContains: "unexpected status 503"},
+		{target: "slow", errContains: "deadline exceeded"},
+		{target: "db", wantOK: true},
+		{target: "cache", errContains: "connection refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, ok := data[tt.target].(map[string]any)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantOK, got["ok"])
+			assert.Equal(t, tt.wantStatus, got["status"])
+			assert.Contains(t, got, "latency_ms")
+			if tt.errContains == "" {
+				assert.Nil(t, got["error"])
+				return
+			}
+			assert.Contains(t, got["error"], tt.errContains)
+		})
+	}
+}
+
+func TestHealthcheckStep_Cancelled(t *testing.T) {
+	step, err := NewHealthcheckStep("test", HealthcheckStepConfig{
+		Targets: []HealthcheckTarget{{Name: "api", URL: "http://127.0.0.1:1"}},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = step.Resolve(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNewHealthcheckStep_Validation(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         HealthcheckStepConfig
+		errContains string
+	}{
+		{
+			name:        "no targets",
+			cfg:         HealthcheckStepConfig{},
+			errContains: "at least one target is required",
+		},
+		{
+			name: "duplicate target",
+			cfg: HealthcheckStepConfig{Targets: []HealthcheckTarget{
+				{Name: "a", Address: "db:5432"},
+				{Name: "a", Address: "db:5433"},
+			}},
+			errContains: `duplicate target "a"`,
+		},
+		{
+			name:        "url and address",
+			cfg:         HealthcheckStepConfig{Targets: []HealthcheckTarget{{Name: "a", URL: "http://x", Address: "x:1"}}},
+			errContains: "url and address are mutually exclusive",
+		},
+		{
+			name:        "neither url nor address",
+			cfg:         HealthcheckStepConfig{Targets: []HealthcheckTarget{{Name: "a"}}},
+			errContains: "one of url or address is required",
+		},
+		{
+			name:        "address without port",
+			cfg:         HealthcheckStepConfig{Targets: []HealthcheckTarget{{Name: "a", Address: "db"}}},
+			errContains: "address must be host:port",
+		},
+		{
+			name:        "expected_status on address",
+			cfg:         HealthcheckStepConfig{Targets: []HealthcheckTarget{{Name: "a", Address: "db:1", ExpectedStatus: 200}}},
+			errContains: "expected_status only applies to url targets",
+		},
+		{
+			name:        "unsupported scheme",
+			cfg:         HealthcheckStepConfig{Targets: []HealthcheckTarget{{Name: "a", URL: "ftp://x"}}},
+			errContains: `url must use http or https scheme, got: "ftp"`,
+		},
+		{
+			name:        "expected_status out of range",
+			cfg:         HealthcheckStepConfig{Targets: []HealthcheckTarget{{Name: "a", URL: "http://x", ExpectedStatus: 42}}},
+			errContains: "expected_status must be between 100 and 599, got 42",
+		},
+		{
+			name:        "negative timeout",
+			cfg:         HealthcheckStepConfig{Targets: []HealthcheckTarget{{Name: "a", URL: "http://x"}}, Timeout: -time.Second},
+			errContains: "timeout must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHealthcheckStep("test", tt.cfg)
+			assert.ErrorContains(t, err, tt.errContains)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+const (
+	LimitStepKind = "limit"
+)
+
+type LimitStepConfig struct {
+	// From is the evaluated value to slice, usually a step result.
+	From any
+	// Count is the maximum number of items kept.
+	Count int
+	// Offset is the number of leading items skipped.
+	Offset int
+	// Strict fails the step when From is not a list instead of passing it
+	// through unchanged.
+	Strict bool
+}
+
+// NewLimitStep keeps at most Count items of a list, starting at Offset.
+// Windows past the end of the list are truncated rather than rejected.
+func NewLimitStep(name string, cfg LimitStepConfig) (engine.Step, error) {
+	if cfg.Count < 0 {
+		return nil, fmt.Errorf("count must not be negative, got %d", cfg.Count)
+	}
+	if cfg.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative, got %d", cfg.Offset)
+	}
+
+	return engine.StepFunction(name, LimitStepKind, func(ctx context.Context) (engine.Result, error) {
+		items, ok := cfg.From.([]any)
+		if !ok {
+			if cfg.Strict {
+				return engine.Result{}, fmt.Errorf("from must be a list, got %T", cfg.From)
+			}
+			return engine.Result{Data: cfg.From}, nil
+		}
+
+		start := min(cfg.Offset, len(items))
+		end := min(start+cfg.Count, len(items))
+		window := make([]any, end-start)
+		copy(window, items[start:end])
+
+		return engine.Result{
+			Data: window,
+			Meta: map[string]string{
+				"total":    strconv.Itoa(len(items)),
+				"returned": strconv.Itoa(len(window)),
+			},
+		}, nil
+	}), nil
+}
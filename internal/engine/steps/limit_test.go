@@ -0,0 +1,90 @@
+package steps
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitStep_Resolve(t *testing.T) {
+	items := []any{"a", "b", "c", "d", "e"}
+
+	tests := []struct {
+		name        string
+		cfg         LimitStepConfig
+		wantData    any
+		wantMeta    map[string]string
+		errContains string
+	}{
+		{
+			name:     "first items",
+			cfg:      LimitStepConfig{From: items, Count: 2},
+			wantData: []any{"a", "b"},
+			wantMeta: map[string]string{"total": "5", "returned": "2"},
+		},
+		{
+			name:     "window with offset",
+			cfg:      LimitStepConfig{From: items, Count: 2, Offset: 3},
+			wantData: []any{"d", "e"},
+			wantMeta: map[string]string{"total": "5", "returned": "2"},
+		},
+		{
+			name:     "window truncated at the end",
+			cfg:      LimitStepConfig{From: items, Count: 10, Offset: 4},
+			wantData: []any{"e"},
+			wantMeta: map[string]string{"total": "5", "returned": "1"},
+		},
+		{
+			name:     "offset past the end",
+			cfg:      LimitStepConfig{From: items, Count: 2, Offset: 9},
+			wantData: []any{},
+			wantMeta: map[string]string{"total": "5", "returned": "0"},
+		},
+		{
+			name:     "non-list passes through",
+			cfg:      LimitStepConfig{From: map[string]any{"a": "b"}, Count: 1},
+			wantData: map[string]any{"a": "b"},
+		},
+		{
+			name:        "non-list fails when strict",
+			cfg:         LimitStepConfig{From: map[string]any{"a": "b"}, Count: 1, Strict: true},
+			errContains: "from must be a list, got map[string]interface {}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := NewLimitStep("test", tt.cfg)
+			require.NoError(t, err)
+
+			result, err := step.Resolve(t.Context())
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantData, result.Data)
+			assert.Equal(t, tt.wantMeta, result.Meta)
+		})
+	}
+}
+
+func TestNewLimitStep_Validation(t *testing.T) {
+	_, err := NewLimitStep("test", LimitStepConfig{Count: -1})
+	assert.ErrorContains(t, err, "count must not be negative")
+
+	_, err = NewLimitStep("test", LimitStepConfig{Count: 1, Offset: -1})
+	assert.ErrorContains(t, err, "offset must not be negative")
+}
+
+func TestLimitStep_DoesNotAliasInput(t *testing.T) {
+	items := []any{"a", "b", "c"}
+	step, err := NewLimitStep("test", LimitStepConfig{From: items, Count: 2})
+	require.NoError(t, err)
+
+	result, err := step.Resolve(t.Context())
+	require.NoError(t, err)
+	result.Data.([]any)[0] = "changed"
+	assert.Equal(t, "a", items[0])
+}
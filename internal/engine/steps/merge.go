@@ -0,0 +1,120 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+const (
+	MergeStepKind = "merge"
+
+	MergeStrategyShallow = "shallow"
+	MergeStrategyDeep    = "deep"
+
+	MergeConflictOverwrite = "overwrite"
+	MergeConflictError     = "error"
+)
+
+type MergeStepConfig struct {
+	// Sources are the evaluated `from` values, merged left to right. Every
+	// source must be an object.
+	Sources        []any
+	Strategy       *string
+	ConflictPolicy *string
+}
+
+func NewMergeStep(name string, cfg MergeStepConfig) (engine.Step, error) {
+	if len(cfg.Sources) == 0 {
+		return nil, fmt.Errorf("from must reference at least one value")
+	}
+
+	strategy := MergeStrategyDeep
+	if cfg.Strategy != nil {
+		strategy = *cfg.Strategy
+	}
+	if strategy != MergeStrategyShallow && strategy != MergeStrategyDeep {
+		return nil, fmt.Errorf("unknown strategy %q (known: %s, %s)", strategy, MergeStrategyShallow, MergeStrategyDeep)
+	}
+
+	policy := MergeConflictOverwrite
+	if cfg.ConflictPolicy != nil {
+		policy = *cfg.ConflictPolicy
+	}
+	if policy != MergeConflictOverwrite && policy != MergeConflictError {
+		return nil, fmt.Errorf("unknown conflict_policy %q (known: %s, %s)", policy, MergeConflictOverwrite, MergeConflictError)
+	}
+
+	sources := make([]map[string]any, 0, len(cfg.Sources))
+	for i, src := range cfg.Sources {
+		m, ok := src.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("from[%d] must be an object, got %T", i, src)
+		}
+		sources = append(sources, m)
+	}
+
+	return engine.StepFunction(name, MergeStepKind, func(ctx context.Context) (engine.Result, error) {
+		merged := make(map[string]any)
+		for _, src := range sources {
+			if err := mergeInto(merged, src, strategy == MergeStrategyDeep, policy, nil); err != nil {
+				return engine.Result{}, err
+			}
+		}
+
+		meta := map[string]string{
+			"merge_strategy": strategy,
+			"merge_sources":  strconv.Itoa(len(sources)),
+		}
+		return engine.Result{Data: merged, Meta: meta}, nil
+	}), nil
+}
+
+// mergeInto copies src into dst. With deep set, keys holding objects on both
+// sides are merged recursively; any other collision is a conflict resolved by
+// policy (later value wins, or an error naming the dotted key path). Nested
+// maps are cloned before they are stored so later merges never mutate a
+// source result.
+func mergeInto(dst, src map[string]any, deep bool, policy string, path []string) error {
+	for key, val := range src {
+		existing, exists := dst[key]
+		if !exists {
+			dst[key] = cloneValue(val)
+			continue
+		}
+
+		keyPath := append(path[:len(path):len(path)], key)
+		if deep {
+			existingMap, ok1 := existing.(map[string]any)
+			valMap, ok2 := val.(map[string]any)
+			if ok1 && ok2 {
+				if err := mergeInto(existingMap, valMap, deep, policy, keyPath); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if policy == MergeConflictError {
+			return fmt.Errorf("conflicting values for key %q", strings.Join(keyPath, "."))
+		}
+		dst[key] = cloneValue(val)
+	}
+	return nil
+}
+
+func cloneValue(v any) any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	out := maps.Clone(m)
+	for k, nested := range out {
+		out[k] = cloneValue(nested)
+	}
+	return out
+}
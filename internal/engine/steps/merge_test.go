@@ -0,0 +1,122 @@
+package steps
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeStep_Resolve(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         MergeStepConfig
+		wantData    map[string]any
+		wantMeta    map[string]string
+		errContains string
+	}{
+		{
+			name: "deep merge combines nested objects",
+			cfg: MergeStepConfig{Sources: []any{
+				map[string]any{"a": 1, "nested": map[string]any{"x": 1}},
+				map[string]any{"b": 2, "nested": map[string]any{"y": 2}},
+			}},
+			wantData: map[string]any{"a": 1, "b": 2, "nested": map[string]any{"x": 1, "y": 2}},
+			wantMeta: map[string]string{"merge_strategy": "deep", "merge_sources": "2"},
+		},
+		{
+			name: "shallow merge replaces nested objects",
+			cfg: MergeStepConfig{
+				Sources: []any{
+					map[string]any{"nested": map[string]any{"x": 1}},
+					map[string]any{"nested": map[string]any{"y": 2}},
+				},
+				Strategy: lo.ToPtr(MergeStrategyShallow),
+			},
+			wantData: map[string]any{"nested": map[string]any{"y": 2}},
+			wantMeta: map[string]string{"merge_strategy": "shallow", "merge_sources": "2"},
+		},
+		{
+			name: "later source wins on overwrite",
+			cfg: MergeStepConfig{Sources: []any{
+				map[string]any{"k": "first"},
+				map[string]any{"k": "second"},
+				map[string]any{"k": "third"},
+			}},
+			wantData: map[string]any{"k": "third"},
+			wantMeta: map[string]string{"merge_strategy": "deep", "merge_sources": "3"},
+		},
+		{
+			name: "error policy reports dotted path",
+			cfg: MergeStepConfig{
+				Sources: []any{
+					map[string]any{"a": map[string]any{"b": 1}},
+					map[string]any{"a": map[string]any{"b": 2}},
+				},
+				ConflictPolicy: lo.ToPtr(MergeConflictError),
+			},
+			errContains: `conflicting values for key "a.b"`,
+		},
+		{
+			name: "error policy allows disjoint deep keys",
+			cfg: MergeStepConfig{
+				Sources: []any{
+					map[string]any{"a": map[string]any{"b": 1}},
+					map[string]any{"a": map[string]any{"c": 2}},
+				},
+				ConflictPolicy: lo.ToPtr(MergeConflictError),
+			},
+			wantData: map[string]any{"a": map[string]any{"b": 1, "c": 2}},
+			wantMeta: map[string]string{"merge_strategy": "deep", "merge_sources": "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := NewMergeStep("test", tt.cfg)
+			require.NoError(t, err)
+
+			result, err := step.Resolve(t.Context())
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantData, result.Data)
+			assert.Equal(t, tt.wantMeta, result.Meta)
+		})
+	}
+}
+
+func TestNewMergeStep_Validation(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         MergeStepConfig
+		errContains string
+	}{
+		{name: "no sources", cfg: MergeStepConfig{}, errContains: "at least one value"},
+		{name: "non-object source", cfg: MergeStepConfig{Sources: []any{[]any{1}}}, errContains: "from[0] must be an object"},
+		{name: "unknown strategy", cfg: MergeStepConfig{Sources: []any{map[string]any{}}, Strategy: lo.ToPtr("union")}, errContains: `unknown strategy "union"`},
+		{name: "unknown policy", cfg: MergeStepConfig{Sources: []any{map[string]any{}}, ConflictPolicy: lo.ToPtr("skip")}, errContains: `unknown conflict_policy "skip"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMergeStep("test", tt.cfg)
+			assert.ErrorContains(t, err, tt.errContains)
+		})
+	}
+}
+
+func TestMergeStep_DoesNotMutateSources(t *testing.T) {
+	first := map[string]any{"nested": map[string]any{"x": 1}}
+	second := map[string]any{"nested": map[string]any{"y": 2}}
+
+	step, err := NewMergeStep("test", MergeStepConfig{Sources: []any{first, second}})
+	require.NoError(t, err)
+	_, err = step.Resolve(t.Context())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{"nested": map[string]any{"x": 1}}, first)
+}
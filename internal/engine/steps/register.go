@@ -1,19 +1,57 @@
 package steps
 
 import (
+	"fmt"
+	"slices"
+
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/samber/lo"
 )
 
 // StaticHCLConfig is the HCL-level shape of a `step "static" "<id>" { ... }` block.
-// Filepath and Value are mutually exclusive; exactly one must be set. The
+// Filepath, Value and Base64 are mutually exclusive; exactly one must be set. The
 // choice is validated at construction time rather than at the HCL schema
 // level because HCL labeled-block discrimination would cost more ergonomics
 // than it buys for a two-arm union.
 type StaticHCLConfig struct {
 	Filepath *string `hcl:"filepath,optional"`
 	Value    *string `hcl:"value,optional"`
-	ParseAs  *string `hcl:"parse_as,optional"`
+	// Inline content encoded as standard base64, decoded when the step runs.
+	// The decoded content is treated like value, including parse_as.
+	Base64  *string `hcl:"base64,optional"`
+	ParseAs *string `hcl:"parse_as,optional"`
+	// Read an absolute filepath (or a file:// URL) from the host filesystem
+	// instead of the working directory. The path must lie under a directory
+	// passed with --allow-path.
+	AllowAbsolute *bool `hcl:"allow_absolute,optional"`
+}
+
+// ArchiveReadHCLConfig is the HCL-level shape of a
+// `step "archive_read" "<id>" { ... }` block.
+type ArchiveReadHCLConfig struct {
+	// Tar archive from a previous run (.tar, .tar.gz or .tar.zst), relative to
+	// the working directory. The compression is detected from the content.
+	Path string `hcl:"path"`
+	// Glob patterns (e.g. "static/*.json") selecting which entries to read.
+	// Defaults to every entry.
+	Entries []string `hcl:"entries,optional"`
+}
+
+// SQLiteHCLConfig is the HCL-level shape of a `step "sqlite" "<id>" { ... }` block.
+//
+//	step "sqlite" "hosts" {
+//	  path  = "inventory.db"
+//	  query = "SELECT name, address FROM hosts WHERE active = 1"
+//	}
+type SQLiteHCLConfig struct {
+	// SQLite database file, relative to the working directory. It is opened
+	// read-only.
+	Path string `hcl:"path"`
+	// SQL query whose rows are returned, one object per row keyed by
+	// column name.
+	Query string `hcl:"query"`
 }
 
 // ExecHCLConfig is the HCL-level shape of a `step "exec" "<id>" { ... }` block.
@@ -24,6 +62,160 @@ type ExecHCLConfig struct {
 	Timeout    *string           `hcl:"timeout,optional"`
 	Format     *string           `hcl:"format,optional"`
 	Env        map[string]string `hcl:"env,optional"`
+	// AllowedEnv narrows the variables inherited from infracollect's own
+	// environment to these names, on top of the safe defaults. Each must be
+	// passed to the job with --pass-env. Unset inherits every passed variable,
+	// or none with clean_env.
+	AllowedEnv []string `hcl:"allowed_env,optional"`
+	CleanEnv   bool     `hcl:"clean_env,optional"`
+	// MaxOutputBytes fails the step when stdout is larger; unset is unlimited.
+	MaxOutputBytes *int `hcl:"max_output_bytes,optional"`
+}
+
+// MergeHCLConfig is the HCL-level shape of a `step "merge" "<id>" { ... }` block.
+//
+//	step "merge" "inventory" {
+//	  from            = [step.http_get.users.data, step.static.overrides.data]
+//	  strategy        = "deep"
+//	  conflict_policy = "error"
+//	}
+//
+// Referencing other steps in `from` orders the merge after them in the DAG.
+type MergeHCLConfig struct {
+	// From lists the objects to merge, left to right.
+	From hcl.Expression `hcl:"from"`
+	// Strategy is "deep" (default) or "shallow".
+	Strategy *string `hcl:"strategy,optional"`
+	// ConflictPolicy is "overwrite" (default, later source wins) or "error".
+	ConflictPolicy *string `hcl:"conflict_policy,optional"`
+}
+
+// DiffHCLConfig is the HCL-level shape of a `step "diff" "<id>" { ... }` block.
+//
+//	step "diff" "inventory" {
+//	  from = step.archive_read.previous.data["static/inventory.json"]
+//	  to   = step.static.inventory.data
+//	}
+type DiffHCLConfig struct {
+	// The earlier value, usually a step result or an entry of a previous
+	// run's archive.
+	From hcl.Expression `hcl:"from"`
+	// The later value compared against from.
+	To hcl.Expression `hcl:"to"`
+}
+
+// LimitHCLConfig is the HCL-level shape of a `step "limit" "<id>" { ... }` block.
+//
+//	step "limit" "sample" {
+//	  from  = step.http_get.users.data
+//	  count = 10
+//	}
+type LimitHCLConfig struct {
+	// The list to slice, usually a step result.
+	From hcl.Expression `hcl:"from"`
+	// Maximum number of items to keep.
+	Count int `hcl:"count"`
+	// Number of leading items to skip. Defaults to 0.
+	Offset *int `hcl:"offset,optional"`
+	// Fail when from is not a list. By default non-list values pass through
+	// unchanged.
+	Strict *bool `hcl:"strict,optional"`
+}
+
+// FlattenHCLConfig is the HCL-level shape of a `step "flatten" "<id>" { ... }` block.
+//
+//	step "flatten" "users" {
+//	  from      = step.http_get.users.data
+//	  separator = "_"
+//	}
+type FlattenHCLConfig struct {
+	// The object to flatten, or a list of objects flattened one by one,
+	// usually a step result.
+	From hcl.Expression `hcl:"from"`
+	// String joining nested keys. Defaults to ".".
+	Separator *string `hcl:"separator,optional"`
+	// Number of nesting levels collapsed into a key; deeper values are kept
+	// as they are. Defaults to 0, no limit.
+	MaxDepth *int `hcl:"max_depth,optional"`
+	// Flatten arrays too, keyed by element index (e.g. "tags.0"). By default
+	// arrays are kept as values.
+	IndexArrays *bool `hcl:"index_arrays,optional"`
+}
+
+// GeoIPHCLConfig is the HCL-level shape of a `step "enrich_geoip" "<id>" { ... }` block.
+//
+//	step "enrich_geoip" "hosts" {
+//	  from      = step.http_get.hosts.data
+//	  path      = "$.items[*].public_ip"
+//	  databases = ["GeoLite2-Country.mmdb", "GeoLite2-ASN.mmdb"]
+//	}
+type GeoIPHCLConfig struct {
+	// The value holding the IP addresses, usually a step result.
+	From hcl.Expression `hcl:"from"`
+	// JSONPath to the IP address fields, ending with the field name.
+	Path string `hcl:"path"`
+	// Paths to MaxMind databases (.mmdb). Country and City databases give
+	// the country, ASN databases the autonomous system.
+	Databases []string `hcl:"databases"`
+	// Key the enrichment is stored under, next to each IP field. Defaults
+	// to "<field>_geoip".
+	Target *string `hcl:"target,optional"`
+}
+
+// WASMHCLConfig is the HCL-level shape of a `step "wasm" "<id>" { ... }` block.
+//
+//	step "wasm" "normalize" {
+//	  module = "transforms/normalize.wasm"
+//	  from   = step.http_get.users.data
+//	}
+type WASMHCLConfig struct {
+	// Path to the WASI (preview 1) module, relative to the working directory.
+	Module string `hcl:"module"`
+	// The value passed to the module as JSON on stdin, usually a step
+	// result.
+	From hcl.Expression `hcl:"from"`
+	// Exported function to call. Defaults to "_start", the entry point of a
+	// WASI command module; other functions are called after "_initialize".
+	Function *string `hcl:"function,optional"`
+	// Maximum run time, e.g. "10s". Defaults to 30s.
+	Timeout *string `hcl:"timeout,optional"`
+	// Maximum linear memory of the module in MiB, up to 4096.
+	MaxMemoryMB *int `hcl:"max_memory_mb,optional"`
+	// Fails the step when the module writes more than this to stdout or
+	// to stderr. Defaults to 64 MiB.
+	MaxOutputBytes *int `hcl:"max_output_bytes,optional"`
+}
+
+// HealthcheckHCLConfig is the HCL-level shape of a
+// `step "healthcheck" "<id>" { ... }` block.
+//
+//	step "healthcheck" "uptime" {
+//	  target "api" {
+//	    url = "https://api.example.com/healthz"
+//	  }
+//	  target "db" {
+//	    address = "db.internal:5432"
+//	  }
+//	}
+type HealthcheckHCLConfig struct {
+	// Default timeout of each probe, e.g. "2s". Defaults to "5s".
+	Timeout *string `hcl:"timeout,optional"`
+	// Endpoints to probe, keyed in the result by their label.
+	Targets []HealthcheckTargetBlock `hcl:"target,block"`
+}
+
+// HealthcheckTargetBlock is one `target "<name>" { ... }` block of a
+// healthcheck step. Exactly one of url and address must be set.
+type HealthcheckTargetBlock struct {
+	Name string `hcl:"name,label"`
+	// http:// or https:// URL probed with a GET request.
+	URL *string `hcl:"url,optional"`
+	// host:port probed by opening a TCP connection.
+	Address *string `hcl:"address,optional"`
+	// HTTP status the URL must answer with. Defaults to any 2xx status.
+	ExpectedStatus *int `hcl:"expected_status,optional"`
+	// Timeout of this probe, overriding the step's timeout.
+	Timeout *string `hcl:"timeout,optional"`
 }
 
 // execInputBlock lets users supply a free-form attribute set as stdin for
@@ -37,17 +229,266 @@ type execInputBlock struct {
 func Register(registry *engine.Registry) error {
 	return registry.RegisterSteps(
 		engine.NewTypedStepDescriptorWithoutCollector(StaticStepKind, newStaticStep),
-		engine.NewTypedStepDescriptorWithoutCollector(ExecStepKind, newExecStep),
+		execStepDescriptor(),
+		engine.NewTypedStepDescriptorWithoutCollector(MergeStepKind, newMergeStep),
+		engine.NewTypedStepDescriptorWithoutCollector(ArchiveReadStepKind, newArchiveReadStep),
+		engine.NewTypedStepDescriptorWithoutCollector(DiffStepKind, newDiffStep),
+		engine.NewTypedStepDescriptorWithoutCollector(LimitStepKind, newLimitStep),
+		engine.NewTypedStepDescriptorWithoutCollector(FlattenStepKind, newFlattenStep),
+		engine.NewTypedStepDescriptorWithoutCollector(HealthcheckStepKind, newHealthcheckStep),
+		engine.NewTypedStepDescriptorWithoutCollector(GeoIPStepKind, newGeoIPStep),
+		engine.NewTypedStepDescriptorWithoutCollector(WASMStepKind, newWASMStep),
+		engine.NewTypedStepDescriptorWithoutCollector(SQLiteStepKind, newSQLiteStep),
 	)
 }
 
 func newStaticStep(
-	_ *engine.RegistryHelper,
+	helper *engine.RegistryHelper,
 	id string,
 	_ *hcl.EvalContext,
 	cfg StaticHCLConfig,
 ) (engine.Step, error) {
-	return NewStaticStep(id, StaticStepConfig(cfg))
+	allowedPaths, _ := engine.GetRegistryDependency[[]string](helper, engine.AllowedPathsDepKey)
+
+	return NewStaticStep(id, StaticStepConfig{
+		Filepath:      cfg.Filepath,
+		Value:         cfg.Value,
+		Base64:        cfg.Base64,
+		ParseAs:       cfg.ParseAs,
+		AllowAbsolute: lo.FromPtr(cfg.AllowAbsolute),
+		AllowedPaths:  allowedPaths,
+	})
+}
+
+func newDiffStep(
+	_ *engine.RegistryHelper,
+	id string,
+	ctx *hcl.EvalContext,
+	cfg DiffHCLConfig,
+) (engine.Step, error) {
+	from, err := evalDiffValue(cfg.From, ctx, "from")
+	if err != nil {
+		return nil, err
+	}
+	to, err := evalDiffValue(cfg.To, ctx, "to")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDiffStep(id, DiffStepConfig{From: from, To: to})
+}
+
+func evalDiffValue(expr hcl.Expression, ctx *hcl.EvalContext, attr string) (any, error) {
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to evaluate diff step %s: %w", attr, diags)
+	}
+	v, err := engine.CtyToAny(val)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert diff step %s: %w", attr, err)
+	}
+	return v, nil
+}
+
+func newLimitStep(
+	_ *engine.RegistryHelper,
+	id string,
+	ctx *hcl.EvalContext,
+	cfg LimitHCLConfig,
+) (engine.Step, error) {
+	val, diags := cfg.From.Value(ctx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to evaluate limit step from: %w", diags)
+	}
+	from, err := engine.CtyToAny(val)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert limit step from: %w", err)
+	}
+
+	return NewLimitStep(id, LimitStepConfig{
+		From:   from,
+		Count:  cfg.Count,
+		Offset: lo.FromPtr(cfg.Offset),
+		Strict: lo.FromPtr(cfg.Strict),
+	})
+}
+
+func newFlattenStep(
+	_ *engine.RegistryHelper,
+	id string,
+	ctx *hcl.EvalContext,
+	cfg FlattenHCLConfig,
+) (engine.Step, error) {
+	val, diags := cfg.From.Value(ctx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to evaluate flatten step from: %w", diags)
+	}
+	from, err := engine.CtyToAny(val)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert flatten step from: %w", err)
+	}
+	if cfg.Separator != nil && *cfg.Separator == "" {
+		return nil, fmt.Errorf("separator must not be empty")
+	}
+
+	return NewFlattenStep(id, FlattenStepConfig{
+		From:        from,
+		Separator:   lo.FromPtr(cfg.Separator),
+		MaxDepth:    lo.FromPtr(cfg.MaxDepth),
+		IndexArrays: lo.FromPtr(cfg.IndexArrays),
+	})
+}
+
+func newGeoIPStep(
+	helper *engine.RegistryHelper,
+	id string,
+	ctx *hcl.EvalContext,
+	cfg GeoIPHCLConfig,
+) (engine.Step, error) {
+	val, diags := cfg.From.Value(ctx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to evaluate enrich_geoip step from: %w", diags)
+	}
+	from, err := engine.CtyToAny(val)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert enrich_geoip step from: %w", err)
+	}
+
+	return NewGeoIPStep(id, helper.Logger(), GeoIPStepConfig{
+		From:      from,
+		Path:      cfg.Path,
+		Target:    lo.FromPtr(cfg.Target),
+		Databases: cfg.Databases,
+	})
+}
+
+func newWASMStep(
+	helper *engine.RegistryHelper,
+	id string,
+	ctx *hcl.EvalContext,
+	cfg WASMHCLConfig,
+) (engine.Step, error) {
+	val, diags := cfg.From.Value(ctx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to evaluate wasm step from: %w", diags)
+	}
+	from, err := engine.CtyToAny(val)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert wasm step from: %w", err)
+	}
+
+	return NewWASMStep(id, helper.Logger(), WASMStepConfig{
+		Module:         cfg.Module,
+		Function:       lo.FromPtr(cfg.Function),
+		From:           from,
+		Timeout:        cfg.Timeout,
+		MaxMemoryMB:    cfg.MaxMemoryMB,
+		MaxOutputBytes: cfg.MaxOutputBytes,
+	})
+}
+
+func newHealthcheckStep(
+	_ *engine.RegistryHelper,
+	id string,
+	_ *hcl.EvalContext,
+	cfg HealthcheckHCLConfig,
+) (engine.Step, error) {
+	stepCfg := HealthcheckStepConfig{}
+	if cfg.Timeout != nil {
+		timeout, err := engine.ParseDuration(*cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		stepCfg.Timeout = timeout
+	}
+
+	for _, block := range cfg.Targets {
+		target := HealthcheckTarget{
+			Name:           block.Name,
+			URL:            lo.FromPtr(block.URL),
+			Address:        lo.FromPtr(block.Address),
+			ExpectedStatus: lo.FromPtr(block.ExpectedStatus),
+		}
+		if block.Timeout != nil {
+			timeout, err := engine.ParseDuration(*block.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("target %q: invalid timeout: %w", block.Name, err)
+			}
+			target.Timeout = timeout
+		}
+		stepCfg.Targets = append(stepCfg.Targets, target)
+	}
+
+	return NewHealthcheckStep(id, stepCfg)
+}
+
+func newArchiveReadStep(
+	_ *engine.RegistryHelper,
+	id string,
+	_ *hcl.EvalContext,
+	cfg ArchiveReadHCLConfig,
+) (engine.Step, error) {
+	return NewArchiveReadStep(id, ArchiveReadStepConfig(cfg))
+}
+
+func newSQLiteStep(
+	_ *engine.RegistryHelper,
+	id string,
+	_ *hcl.EvalContext,
+	cfg SQLiteHCLConfig,
+) (engine.Step, error) {
+	return NewSQLiteStep(id, SQLiteStepConfig(cfg))
+}
+
+func execStepDescriptor() engine.StepDescriptor {
+	desc := engine.NewTypedStepDescriptorWithoutCollector(ExecStepKind, newExecStep)
+	desc.Validate = validateExecBody
+	return desc
+}
+
+// validateExecBody reports, before the job runs, an exec step whose program
+// is a literal empty list. A literal allowed_env naming a variable that is
+// not passed only warns: validate cannot see --pass-all-env, and the factory
+// checks again at run time. Expressions that need the eval context are left
+// to the factory.
+func validateExecBody(helper *engine.RegistryHelper, body hcl.Body) hcl.Diagnostics {
+	content, _, diags := body.PartialContent(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "program", Required: true},
+			{Name: "allowed_env"},
+		},
+	})
+
+	if attr, ok := content.Attributes["program"]; ok {
+		val, valDiags := attr.Expr.Value(nil)
+		if !valDiags.HasErrors() && val.IsWhollyKnown() && !val.IsNull() && val.CanIterateElements() && val.LengthInt() == 0 {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Empty exec program",
+				Detail:   "The program attribute must list the command to run, followed by its arguments.",
+				Subject:  attr.Expr.Range().Ptr(),
+			})
+		}
+	}
+
+	if attr, ok := content.Attributes["allowed_env"]; ok {
+		var names []string
+		if gohcl.DecodeExpression(attr.Expr, nil, &names).HasErrors() {
+			return diags
+		}
+		passed, _ := engine.GetRegistryDependency[[]string](helper, engine.AllowedEnvVarsDepKey)
+		for _, name := range names {
+			if !slices.Contains(passed, name) {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagWarning,
+					Summary:  "Environment variable not passed",
+					Detail:   fmt.Sprintf("allowed_env lists %q, which is not passed to the job. Add --pass-env %s.", name, name),
+					Subject:  attr.Expr.Range().Ptr(),
+				})
+			}
+		}
+	}
+	return diags
 }
 
 func newExecStep(
@@ -57,6 +498,18 @@ func newExecStep(
 	cfg ExecHCLConfig,
 ) (engine.Step, error) {
 	allowedEnv := engine.MustGetRegistryDependency[[]string](helper, engine.AllowedEnvVarsDepKey)
+	switch {
+	case cfg.AllowedEnv != nil:
+		for _, name := range cfg.AllowedEnv {
+			if !slices.Contains(allowedEnv, name) {
+				return nil, fmt.Errorf("allowed_env lists %q, which is not passed to the job; add --pass-env %s", name, name)
+			}
+		}
+		allowedEnv = cfg.AllowedEnv
+	case cfg.CleanEnv:
+		// A clean environment inherits only what the step lists itself.
+		allowedEnv = nil
+	}
 
 	var input map[string]any
 	if cfg.Input != nil {
@@ -75,5 +528,34 @@ func newExecStep(
 		Format:     cfg.Format,
 		Env:        cfg.Env,
 		AllowedEnv: allowedEnv,
+		CleanEnv:   cfg.CleanEnv,
+
+		MaxOutputBytes: cfg.MaxOutputBytes,
+	})
+}
+
+func newMergeStep(
+	_ *engine.RegistryHelper,
+	id string,
+	ctx *hcl.EvalContext,
+	cfg MergeHCLConfig,
+) (engine.Step, error) {
+	val, diags := cfg.From.Value(ctx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to evaluate merge step from: %w", diags)
+	}
+	from, err := engine.CtyToAny(val)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert merge step from: %w", err)
+	}
+	sources, ok := from.([]any)
+	if !ok {
+		return nil, fmt.Errorf("from must be a list of step results, got %T", from)
+	}
+
+	return NewMergeStep(id, MergeStepConfig{
+		Sources:        sources,
+		Strategy:       cfg.Strategy,
+		ConflictPolicy: cfg.ConflictPolicy,
 	})
 }
@@ -0,0 +1,164 @@
+package steps
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/spf13/afero"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	SQLiteStepKind = "sqlite"
+)
+
+type SQLiteStepConfig struct {
+	// Path is the SQLite database file, relative to the working directory.
+	Path string
+	// Query is the SQL statement whose rows the step returns.
+	Query string
+}
+
+// NewSQLiteStep runs a query against a local SQLite database and returns
+// its rows. Like the static step, the path is sandboxed to the working
+// directory, and ATTACH is disabled so the query cannot reach other files.
+// The database is opened read-only, so the query cannot change it.
+func NewSQLiteStep(name string, cfg SQLiteStepConfig) (engine.Step, error) {
+	rootDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return newSQLiteFileStep(name, rootDir, cfg)
+}
+
+func newSQLiteFileStep(name, rootDir string, cfg SQLiteStepConfig) (engine.Step, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if strings.TrimSpace(cfg.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	fs := afero.NewBasePathFs(afero.NewOsFs(), rootDir).(*afero.BasePathFs)
+	dbPath, err := fs.RealPath(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("path %s must stay inside the working directory", cfg.Path)
+	}
+
+	step := engine.StepFunction(name, SQLiteStepKind, func(ctx context.Context) (engine.Result, error) {
+		// database/sql opening a missing file read-only fails with a vague
+		// "unable to open database file"; check first for a clearer error.
+		if _, err := os.Stat(dbPath); err != nil {
+			return engine.Result{}, fmt.Errorf("failed to open database %s: %w", cfg.Path, err)
+		}
+
+		db, err := sql.Open("sqlite", sqliteReadOnlyDSN(dbPath))
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to open database %s: %w", cfg.Path, err)
+		}
+		defer db.Close()
+
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to open database %s: %w", cfg.Path, err)
+		}
+		defer conn.Close()
+
+		// ATTACH would let the query open, or create, any file the process
+		// can reach, outside the sandbox and despite the read-only mode.
+		if _, err := sqlite.Limit(conn, sqlite3.SQLITE_LIMIT_ATTACHED, 0); err != nil {
+			return engine.Result{}, fmt.Errorf("failed to restrict database %s: %w", cfg.Path, err)
+		}
+
+		rows, err := conn.QueryContext(ctx, cfg.Query)
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to query database %s: %w", cfg.Path, err)
+		}
+		defer rows.Close()
+
+		data, err := scanRows(rows)
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to read rows from database %s: %w", cfg.Path, err)
+		}
+
+		return engine.Result{
+			Data: data,
+			Meta: map[string]string{
+				"path": cfg.Path,
+				"rows": strconv.Itoa(len(data)),
+			},
+		}, nil
+	})
+	return step, nil
+}
+
+// sqliteReadOnlyDSN returns a URI filename opening path read-only, with
+// query_only set so that not even temporary tables can be written.
+func sqliteReadOnlyDSN(path string) string {
+	query := url.Values{"mode": {"ro"}, "_pragma": {"query_only(1)"}}
+	u := url.URL{Scheme: "file", Path: path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// scanRows reads every row into a map keyed by column name.
+func scanRows(rows *sql.Rows) ([]map[string]any, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	seen := make(map[string]struct{}, len(columns))
+	for _, column := range columns {
+		if _, ok := seen[column]; ok {
+			return nil, fmt.Errorf("column %q appears more than once; give it an alias", column)
+		}
+		seen[column] = struct{}{}
+	}
+
+	result := []map[string]any{}
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			row[column] = columnValue(values[i])
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// columnValue converts a scanned value for output. BLOB values holding
+// UTF-8 text become strings, other BLOBs stay bytes, which the JSON encoder
+// writes as base64. Times are formatted as RFC 3339 so every encoder
+// renders them the same way.
+func columnValue(value any) any {
+	switch v := value.(type) {
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,147 @@
+package steps
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSQLiteDB creates the database name in dir and runs stmts against it.
+func writeSQLiteDB(t *testing.T, dir, name string, stmts ...string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(dir, name))
+	require.NoError(t, err)
+	defer db.Close()
+	for _, stmt := range stmts {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err)
+	}
+}
+
+func TestSQLiteStep(t *testing.T) {
+	dir := t.TempDir()
+	writeSQLiteDB(t, dir, "inventory.db",
+		`CREATE TABLE hosts (name TEXT, cores INTEGER, load REAL, notes TEXT, key BLOB, active INTEGER)`,
+		`INSERT INTO hosts VALUES ('web-1', 4, 0.5, NULL, x'00ff', 1)`,
+		`INSERT INTO hosts VALUES ('web-2', 8, 1.25, 'spare', 'abc', 1)`,
+		`INSERT INTO hosts VALUES ('db-1', 16, 2, NULL, NULL, 0)`,
+		`CREATE TABLE events (host TEXT, seen_at DATETIME)`,
+		`INSERT INTO events VALUES ('web-1', '2024-05-01 10:00:00')`,
+	)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantData  []map[string]any
+		expectErr string
+	}{
+		{
+			name:  "rows keyed by column",
+			query: `SELECT name, cores, load, notes, key FROM hosts WHERE active = 1 ORDER BY name`,
+			wantData: []map[string]any{
+				{"name": "web-1", "cores": int64(4), "load": 0.5, "notes": nil, "key": []byte{0x00, 0xff}},
+				{"name": "web-2", "cores": int64(8), "load": 1.25, "notes": "spare", "key": "abc"},
+			},
+		},
+		{
+			name:     "times as rfc 3339",
+			query:    `SELECT host, seen_at FROM events`,
+			wantData: []map[string]any{{"host": "web-1", "seen_at": "2024-05-01T10:00:00Z"}},
+		},
+		{
+			name:     "no rows",
+			query:    `SELECT name FROM hosts WHERE cores > 100`,
+			wantData: []map[string]any{},
+		},
+		{
+			name:      "duplicate column",
+			query:     `SELECT name, name FROM hosts`,
+			expectErr: `column "name" appears more than once`,
+		},
+		{
+			name:      "read-only",
+			query:     `DELETE FROM hosts RETURNING name`,
+			expectErr: "readonly",
+		},
+		{
+			name:      "attach existing database",
+			query:     `ATTACH DATABASE 'inventory.db' AS other; SELECT name FROM other.hosts`,
+			expectErr: "too many attached databases",
+		},
+		{
+			name:      "attach new database",
+			query:     `ATTACH DATABASE 'created.db' AS other; CREATE TABLE other.t (x INTEGER)`,
+			expectErr: "too many attached databases",
+		},
+		{
+			name:      "temporary table",
+			query:     `CREATE TEMP TABLE scratch (x INTEGER)`,
+			expectErr: "readonly",
+		},
+		{
+			name:     "query only",
+			query:    `PRAGMA query_only`,
+			wantData: []map[string]any{{"query_only": int64(1)}},
+		},
+		{
+			name:      "invalid query",
+			query:     `SELECT nope FROM hosts`,
+			expectErr: "failed to query database inventory.db",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := newSQLiteFileStep("test", dir, SQLiteStepConfig{Path: "inventory.db", Query: tt.query})
+			require.NoError(t, err)
+
+			result, err := step.Resolve(t.Context())
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantData, result.Data)
+			assert.Equal(t, "inventory.db", result.Meta["path"])
+		})
+	}
+
+	step, err := newSQLiteFileStep("test", dir, SQLiteStepConfig{Path: "inventory.db", Query: `SELECT count(*) AS n FROM hosts`})
+	require.NoError(t, err)
+	result, err := step.Resolve(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"n": int64(3)}}, result.Data, "rejected writes leave the database unchanged")
+	assert.NoFileExists(t, "created.db")
+	assert.NoFileExists(t, filepath.Join(dir, "created.db"))
+}
+
+func TestSQLiteStep_Validation(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := newSQLiteFileStep("test", dir, SQLiteStepConfig{Query: "SELECT 1"})
+	assert.ErrorContains(t, err, "path is required")
+
+	_, err = newSQLiteFileStep("test", dir, SQLiteStepConfig{Path: "inventory.db", Query: "  "})
+	assert.ErrorContains(t, err, "query is required")
+
+	step, err := newSQLiteFileStep("test", dir, SQLiteStepConfig{Path: "missing.db", Query: "SELECT 1"})
+	require.NoError(t, err)
+	_, err = step.Resolve(t.Context())
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.NoFileExists(t, filepath.Join(dir, "missing.db"), "a missing database must not be created")
+}
+
+func TestSQLiteStep_PathSandbox(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	writeSQLiteDB(t, outside, "secret.db", `CREATE TABLE t (x INTEGER)`)
+
+	rel, err := filepath.Rel(root, filepath.Join(outside, "secret.db"))
+	require.NoError(t, err)
+
+	_, err = newSQLiteFileStep("test", root, SQLiteStepConfig{Path: rel, Query: "SELECT * FROM t"})
+	assert.ErrorContains(t, err, "must stay inside the working directory")
+}
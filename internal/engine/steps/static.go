@@ -2,11 +2,13 @@ package steps
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/infracollect/infracollect/internal/engine"
 	"github.com/spf13/afero"
@@ -16,10 +18,24 @@ const (
 	StaticStepKind = "static"
 )
 
+// fileURLPrefix marks a filepath as an explicit absolute path, e.g.
+// file:///etc/os-release.
+const fileURLPrefix = "file://"
+
 type StaticStepConfig struct {
 	Filepath *string
 	Value    *string
-	ParseAs  *string
+	// Base64 is inline content encoded as standard base64; once decoded it is
+	// handled like Value.
+	Base64  *string
+	ParseAs *string
+	// AllowAbsolute lets an absolute filepath be read from the host
+	// filesystem instead of the working directory sandbox, as long as it lies
+	// under one of AllowedPaths.
+	AllowAbsolute bool
+	// AllowedPaths are the directory prefixes absolute filepaths may be read
+	// from (the --allow-path flag).
+	AllowedPaths []string
 }
 
 func NewStaticStep(name string, cfg StaticStepConfig) (engine.Step, error) {
@@ -27,8 +43,27 @@ func NewStaticStep(name string, cfg StaticStepConfig) (engine.Step, error) {
 		return nil, fmt.Errorf("both filepath and value are set")
 	}
 
-	if cfg.Filepath == nil && cfg.Value == nil {
-		return nil, fmt.Errorf("neither filepath nor value are set")
+	if cfg.Base64 != nil && (cfg.Filepath != nil || cfg.Value != nil) {
+		return nil, fmt.Errorf("base64 cannot be combined with filepath or value")
+	}
+
+	if cfg.Filepath == nil && cfg.Value == nil && cfg.Base64 == nil {
+		return nil, fmt.Errorf("none of filepath, value or base64 are set")
+	}
+
+	if cfg.Base64 != nil {
+		return newStaticBase64Step(name, *cfg.Base64, cfg.ParseAs), nil
+	}
+
+	if cfg.Filepath != nil && cfg.AllowAbsolute {
+		path := strings.TrimPrefix(*cfg.Filepath, fileURLPrefix)
+		if filepath.IsAbs(path) {
+			return newStaticAbsoluteFileStep(name, path, cfg)
+		}
+	}
+
+	if cfg.Filepath != nil && strings.HasPrefix(*cfg.Filepath, fileURLPrefix) {
+		return nil, fmt.Errorf("filepath %s requires allow_absolute = true", *cfg.Filepath)
 	}
 
 	if cfg.Filepath != nil {
@@ -52,22 +87,130 @@ func newStaticFileStep(name string, fs afero.Fs, cfg StaticStepConfig) engine.St
 		if err != nil {
 			return engine.Result{}, fmt.Errorf("failed to read filepath %s: %w", *cfg.Filepath, err)
 		}
+		return staticFileResult(*cfg.Filepath, data, cfg.ParseAs)
+	})
+}
+
+func staticFileResult(path string, data []byte, parseAs *string) (engine.Result, error) {
+	meta := map[string]string{
+		"filepath": path,
+	}
+
+	hasJSONExtension := strings.HasSuffix(path, ".json")
+	shouldParseAsJSON := hasJSONExtension && (parseAs == nil || *parseAs == "json")
+	if shouldParseAsJSON {
+		var parsed any
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return engine.Result{}, fmt.Errorf("failed to parse as json %s: %w", path, err)
+		}
+		return engine.Result{Data: parsed, Meta: meta}, nil
+	}
 
-		meta := map[string]string{
-			"filepath": *cfg.Filepath,
+	return engine.Result{Data: map[string]any{filepath.Base(path): string(data)}, Meta: meta}, nil
+}
+
+// newStaticAbsoluteFileStep reads an absolute path from the host filesystem.
+// Each time the step runs, the path is checked against allowedPaths, and the
+// path with symlinks resolved against allowedPaths with symlinks resolved, so
+// an allowed directory may itself be a symlink. The file is then read through
+// an os.Root opened on the allowed directory, so a symlink swapped in after
+// the check cannot lead outside it.
+func newStaticAbsoluteFileStep(name, path string, cfg StaticStepConfig) (engine.Step, error) {
+	if len(cfg.AllowedPaths) == 0 {
+		return nil, fmt.Errorf("allow_absolute requires at least one allowed path (--allow-path)")
+	}
+
+	allowed := make([]string, 0, len(cfg.AllowedPaths))
+	for _, dir := range cfg.AllowedPaths {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed path %s: %w", dir, err)
 		}
+		allowed = append(allowed, abs)
+	}
 
-		hasJSONExtension := strings.HasSuffix(*cfg.Filepath, ".json")
-		shouldParseAsJSON := hasJSONExtension && (cfg.ParseAs == nil || *cfg.ParseAs == "json")
-		if shouldParseAsJSON {
-			var parsed any
-			if err := json.Unmarshal(data, &parsed); err != nil {
-				return engine.Result{}, fmt.Errorf("failed to parse as json %s: %w", *cfg.Filepath, err)
-			}
-			return engine.Result{Data: parsed, Meta: meta}, nil
+	path = filepath.Clean(path)
+
+	return engine.StepFunction(name, "static", func(ctx context.Context) (engine.Result, error) {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to read filepath %s: %w", path, err)
+		}
+		dir, ok := allowedDirOf(resolved, resolveSymlinks(allowed))
+		if !isUnderAny(path, allowed) || !ok {
+			return engine.Result{}, fmt.Errorf("filepath %s is not under an allowed path (%s)", path, strings.Join(allowed, ", "))
+		}
+		data, err := readFileIn(dir, resolved)
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to read filepath %s: %w", path, err)
+		}
+		return staticFileResult(path, data, cfg.ParseAs)
+	}), nil
+}
+
+// readFileIn reads path, which lies under dir, without following any
+// symlink that leads out of dir.
+func readFileIn(dir, path string) ([]byte, error) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = root.Close() }()
+	return root.ReadFile(rel)
+}
+
+// resolveSymlinks returns dirs with symlinks resolved. A directory that
+// cannot be resolved, for example because it does not exist, is kept as is.
+func resolveSymlinks(dirs []string) []string {
+	resolved := make([]string, len(dirs))
+	for i, dir := range dirs {
+		if r, err := filepath.EvalSymlinks(dir); err == nil {
+			resolved[i] = r
+		} else {
+			resolved[i] = dir
+		}
+	}
+	return resolved
+}
+
+// isUnderAny reports whether path is one of dirs or lies beneath one of them.
+func isUnderAny(path string, dirs []string) bool {
+	_, ok := allowedDirOf(path, dirs)
+	return ok
+}
+
+// allowedDirOf returns the first of dirs that path is or lies beneath.
+func allowedDirOf(path string, dirs []string) (string, bool) {
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			continue
+		}
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return dir, true
 		}
+	}
+	return "", false
+}
 
-		return engine.Result{Data: map[string]any{filepath.Base(*cfg.Filepath): string(data)}, Meta: meta}, nil
+// newStaticBase64Step decodes encoded when the step runs. Whitespace is
+// ignored so long blobs can be wrapped in a heredoc. Without parse_as,
+// content that is not UTF-8 stays bytes, which the JSON encoder writes as
+// base64, like BLOB columns in the sqlite step.
+func newStaticBase64Step(name string, encoded string, parseAs *string) engine.Step {
+	return engine.StepFunction(name, "static", func(ctx context.Context) (engine.Result, error) {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to decode base64 value: %w", err)
+		}
+		if (parseAs == nil || *parseAs != "json") && !utf8.Valid(decoded) {
+			return engine.Result{Data: map[string]any{"value": decoded}}, nil
+		}
+		return newStaticValueStep(name, string(decoded), parseAs).Resolve(ctx)
 	})
 }
 
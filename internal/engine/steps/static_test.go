@@ -1,9 +1,11 @@
 package steps
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/infracollect/infracollect/internal/engine"
 	"github.com/samber/lo"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
@@ -155,6 +157,118 @@ func TestNewStaticStepWithFs_PathTraversal(t *testing.T) {
 	}
 }
 
+func TestNewStaticStep_AllowAbsolute(t *testing.T) {
+	allowedDir := t.TempDir()
+	otherDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(allowedDir, "os-release"), []byte("ID=test"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(otherDir, "secret.txt"), []byte("secret"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(otherDir, "secret.txt"), filepath.Join(allowedDir, "link.txt")))
+
+	// linkedDir is an allowed directory that is itself a symlink.
+	linkedDir := filepath.Join(t.TempDir(), "linked")
+	require.NoError(t, os.Symlink(allowedDir, linkedDir))
+
+	allowedFile := filepath.Join(allowedDir, "os-release")
+
+	tests := []struct {
+		name          string
+		filepath      string
+		allowAbsolute bool
+		allowedPaths  []string
+		wantData      any
+		wantErr       string
+	}{
+		{
+			name:          "reads absolute path under an allowed directory",
+			filepath:      allowedFile,
+			allowAbsolute: true,
+			allowedPaths:  []string{allowedDir},
+			wantData:      map[string]any{"os-release": "ID=test"},
+		},
+		{
+			name:          "accepts a file:// URL",
+			filepath:      "file://" + allowedFile,
+			allowAbsolute: true,
+			allowedPaths:  []string{allowedDir},
+			wantData:      map[string]any{"os-release": "ID=test"},
+		},
+		{
+			name:          "rejects a path outside the allowlist",
+			filepath:      filepath.Join(otherDir, "secret.txt"),
+			allowAbsolute: true,
+			allowedPaths:  []string{allowedDir},
+			wantErr:       "is not under an allowed path",
+		},
+		{
+			name:          "rejects traversal out of an allowed directory",
+			filepath:      allowedDir + "/../" + filepath.Base(otherDir) + "/secret.txt",
+			allowAbsolute: true,
+			allowedPaths:  []string{allowedDir},
+			wantErr:       "is not under an allowed path",
+		},
+		{
+			name:          "rejects a symlink pointing outside the allowlist",
+			filepath:      filepath.Join(allowedDir, "link.txt"),
+			allowAbsolute: true,
+			allowedPaths:  []string{allowedDir},
+			wantErr:       "is not under an allowed path",
+		},
+		{
+			name:          "reads through an allowed directory that is a symlink",
+			filepath:      filepath.Join(linkedDir, "os-release"),
+			allowAbsolute: true,
+			allowedPaths:  []string{linkedDir},
+			wantData:      map[string]any{"os-release": "ID=test"},
+		},
+		{
+			name:          "rejects a symlink out of an allowed directory that is a symlink",
+			filepath:      filepath.Join(linkedDir, "link.txt"),
+			allowAbsolute: true,
+			allowedPaths:  []string{linkedDir},
+			wantErr:       "is not under an allowed path",
+		},
+		{
+			name:          "does not treat a sibling with a shared prefix as allowed",
+			filepath:      allowedFile,
+			allowAbsolute: true,
+			allowedPaths:  []string{allowedDir[:len(allowedDir)-1]},
+			wantErr:       "is not under an allowed path",
+		},
+		{
+			name:          "requires an allowed path",
+			filepath:      allowedFile,
+			allowAbsolute: true,
+			wantErr:       "allow_absolute requires at least one allowed path",
+		},
+		{
+			name:     "file:// URL requires allow_absolute",
+			filepath: "file://" + allowedFile,
+			wantErr:  "requires allow_absolute = true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := NewStaticStep("test", StaticStepConfig{
+				Filepath:      &tt.filepath,
+				AllowAbsolute: tt.allowAbsolute,
+				AllowedPaths:  tt.allowedPaths,
+			})
+			if err == nil {
+				var result engine.Result
+				result, err = step.Resolve(t.Context())
+				if tt.wantErr == "" {
+					require.NoError(t, err)
+					assert.Equal(t, tt.wantData, result.Data)
+					return
+				}
+			}
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
 func TestNewStaticStep_Validation(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -172,10 +286,24 @@ func TestNewStaticStep_Validation(t *testing.T) {
 			errContains: "both filepath and value are set",
 		},
 		{
-			name:        "error when neither filepath nor value set",
+			name: "error when base64 is combined with value",
+			cfg: StaticStepConfig{
+				Value:  lo.ToPtr("test value"),
+				Base64: lo.ToPtr("aGVsbG8="),
+			},
+			wantErr:     true,
+			errContains: "base64 cannot be combined with filepath or value",
+		},
+		{
+			name:        "error when no source is set",
 			cfg:         StaticStepConfig{},
 			wantErr:     true,
-			errContains: "neither filepath nor value are set",
+			errContains: "none of filepath, value or base64 are set",
+		},
+		{
+			name:    "accepts base64 only",
+			cfg:     StaticStepConfig{Base64: lo.ToPtr("aGVsbG8=")},
+			wantErr: false,
 		},
 		{
 			name:    "accepts value only",
@@ -251,3 +379,71 @@ func TestNewStaticStep_ValueResolution(t *testing.T) {
 		})
 	}
 }
+
+func TestNewStaticStep_Base64Resolution(t *testing.T) {
+	tests := []struct {
+		name        string
+		encoded     string
+		parseAs     *string
+		wantData    any
+		errContains string
+	}{
+		{
+			name:     "decodes into value",
+			encoded:  "aGVsbG8gd29ybGQ=",
+			wantData: map[string]any{"value": "hello world"},
+		},
+		{
+			name:     "ignores whitespace",
+			encoded:  "aGVsbG8g\n  d29ybGQ=\n",
+			wantData: map[string]any{"value": "hello world"},
+		},
+		{
+			name:     "parses decoded content as JSON",
+			encoded:  "eyJrZXkiOiAidmFsdWUifQ==",
+			parseAs:  lo.ToPtr("json"),
+			wantData: map[string]any{"key": "value"},
+		},
+		{
+			name:     "keeps binary content as bytes",
+			encoded:  "/wD+",
+			wantData: map[string]any{"value": []byte{0xff, 0x00, 0xfe}},
+		},
+		{
+			name:        "invalid base64",
+			encoded:     "not base64!",
+			errContains: "failed to decode base64 value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := NewStaticStep("test", StaticStepConfig{Base64: &tt.encoded, ParseAs: tt.parseAs})
+			require.NoError(t, err, "invalid content is only reported when the step runs")
+
+			result, err := step.Resolve(t.Context())
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantData, result.Data)
+		})
+	}
+}
+
+func TestReadFileIn(t *testing.T) {
+	allowedDir := t.TempDir()
+	otherDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(allowedDir, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(otherDir, "secret.txt"), []byte("secret"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(otherDir, "secret.txt"), filepath.Join(allowedDir, "link.txt")))
+
+	data, err := readFileIn(allowedDir, filepath.Join(allowedDir, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+
+	// A symlink that appears after the allowlist check is still refused.
+	_, err = readFileIn(allowedDir, filepath.Join(allowedDir, "link.txt"))
+	assert.Error(t, err)
+}
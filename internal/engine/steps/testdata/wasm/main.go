@@ -0,0 +1,61 @@
+//go:build wasip1
+
+// Command wasm is the module the wasm step tests run. It is built for
+// GOOS=wasip1 GOARCH=wasm by the tests, both as a command (_start) and as a
+// reactor exporting "shout".
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type request struct {
+	Op    string   `json:"op"`
+	Items []string `json:"items"`
+}
+
+func main() {
+	var req request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch req.Op {
+	case "upper":
+		out := make([]string, len(req.Items))
+		for i, item := range req.Items {
+			out[i] = strings.ToUpper(item)
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(out)
+	case "probe":
+		_, readErr := os.ReadDir("/")
+		_ = json.NewEncoder(os.Stdout).Encode(map[string]any{
+			"env":           len(os.Environ()),
+			"root_readable": readErr == nil,
+		})
+	case "fail":
+		fmt.Fprintln(os.Stderr, "bad input")
+		os.Exit(3)
+	case "garbage":
+		fmt.Print("not json")
+	case "spin":
+		for {
+		}
+	case "flood":
+		chunk := []byte(strings.Repeat("x", 1024))
+		for {
+			if _, err := os.Stdout.Write(chunk); err != nil {
+				os.Exit(1)
+			}
+		}
+	}
+}
+
+//go:wasmexport shout
+func shout() {
+	_ = json.NewEncoder(os.Stdout).Encode(map[string]bool{"shout": true})
+}
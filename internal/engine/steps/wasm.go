@@ -0,0 +1,217 @@
+package steps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+	"go.uber.org/zap"
+)
+
+const (
+	WASMStepKind = "wasm"
+
+	// wasmStartFunction is the entry point of a WASI command module, the
+	// equivalent of main.
+	wasmStartFunction = "_start"
+	// wasmInitializeFunction sets up a WASI reactor module before its
+	// exports are called.
+	wasmInitializeFunction = "_initialize"
+	// wasmPagesPerMB converts max_memory_mb into 64 KiB memory pages.
+	wasmPagesPerMB = 16
+	// wasmMaxMemoryMB is the most a 32-bit WebAssembly memory can address.
+	wasmMaxMemoryMB = 4096
+	// wasmDefaultMaxOutputBytes caps what a module may write to stdout, and
+	// separately to stderr, when max_output_bytes is not set.
+	wasmDefaultMaxOutputBytes = 64 << 20
+)
+
+// wasmCompilationCache keeps compiled modules for the life of the process,
+// so a module run by several steps or for_each iterations is compiled once.
+var wasmCompilationCache = wazero.NewCompilationCache()
+
+type WASMStepConfig struct {
+	// Module is the path to the WebAssembly module (.wasm).
+	Module string
+	// Function is the exported function to call. Empty selects _start, the
+	// entry point of a WASI command module.
+	Function string
+	// From is the evaluated value passed to the module as JSON on stdin,
+	// usually a step result.
+	From any
+	// Timeout bounds the run. Nil selects the exec step's default of 30s.
+	Timeout *string
+	// MaxMemoryMB caps the module's linear memory. Nil keeps the
+	// WebAssembly limit of 4 GiB.
+	MaxMemoryMB *int
+	// MaxOutputBytes caps how much the module may write to stdout, and to
+	// stderr. Nil selects wasmDefaultMaxOutputBytes.
+	MaxOutputBytes *int
+}
+
+// NewWASMStep runs a WASI (preview 1) module as a sandboxed transform: the
+// module reads From as JSON on stdin and writes its result as JSON to
+// stdout. It gets no arguments, no environment variables, no host
+// filesystem and no network; its clock and random source are the runtime's
+// deterministic stand-ins. A non-zero exit status fails the step with the
+// module's stderr.
+func NewWASMStep(name string, logger *zap.Logger, cfg WASMStepConfig) (engine.Step, error) {
+	if cfg.Module == "" {
+		return nil, fmt.Errorf("module is required")
+	}
+
+	function := cfg.Function
+	if function == "" {
+		function = wasmStartFunction
+	}
+
+	timeout := defaultTimeout
+	if cfg.Timeout != nil {
+		parsed, err := engine.ParseDuration(*cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		timeout = parsed
+	}
+
+	runtimeConfig := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithCompilationCache(wasmCompilationCache)
+	if cfg.MaxMemoryMB != nil {
+		if *cfg.MaxMemoryMB <= 0 || *cfg.MaxMemoryMB > wasmMaxMemoryMB {
+			return nil, fmt.Errorf("max_memory_mb must be between 1 and %d, got %d", wasmMaxMemoryMB, *cfg.MaxMemoryMB)
+		}
+		runtimeConfig = runtimeConfig.WithMemoryLimitPages(uint32(*cfg.MaxMemoryMB * wasmPagesPerMB))
+	}
+
+	maxOutputBytes := wasmDefaultMaxOutputBytes
+	if cfg.MaxOutputBytes != nil {
+		if *cfg.MaxOutputBytes <= 0 {
+			return nil, fmt.Errorf("max_output_bytes must be positive, got %d", *cfg.MaxOutputBytes)
+		}
+		maxOutputBytes = *cfg.MaxOutputBytes
+	}
+
+	input, err := json.Marshal(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode from as JSON: %w", err)
+	}
+
+	return engine.StepFunction(name, WASMStepKind, func(runCtx context.Context) (engine.Result, error) {
+		code, err := os.ReadFile(cfg.Module)
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to read module: %w", err)
+		}
+
+		runtime := wazero.NewRuntimeWithConfig(runCtx, runtimeConfig)
+		defer func() { _ = runtime.Close(context.WithoutCancel(runCtx)) }()
+
+		if _, err := wasi_snapshot_preview1.Instantiate(runCtx, runtime); err != nil {
+			return engine.Result{}, fmt.Errorf("failed to instantiate WASI: %w", err)
+		}
+
+		compiled, err := runtime.CompileModule(runCtx, code)
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to compile module %s: %w", cfg.Module, err)
+		}
+		exported, ok := compiled.ExportedFunctions()[function]
+		if !ok {
+			return engine.Result{}, fmt.Errorf("module %s does not export function %q", cfg.Module, function)
+		}
+		if len(exported.ParamTypes()) > 0 || len(exported.ResultTypes()) > 0 {
+			return engine.Result{}, fmt.Errorf("function %q of module %s must take no parameters and return nothing", function, cfg.Module)
+		}
+
+		// The timeout bounds the module's run, not its compilation.
+		ctx, cancel := context.WithTimeout(runCtx, timeout)
+		defer cancel()
+
+		// Both streams are buffered in memory, so a module writing without
+		// end fails the step instead of exhausting it.
+		var stdout, stderr bytes.Buffer
+		limitedStdout := &limitedWriter{w: &stdout, limit: maxOutputBytes}
+		moduleConfig := wazero.NewModuleConfig().
+			WithName("").
+			WithArgs(name).
+			WithStdin(bytes.NewReader(input)).
+			WithStdout(limitedStdout).
+			WithStderr(&limitedWriter{w: &stderr, limit: maxOutputBytes})
+		if function != wasmStartFunction {
+			moduleConfig = moduleConfig.WithStartFunctions(wasmInitializeFunction)
+		}
+
+		logger.Debug("invoking wasm step",
+			zap.String("step", name),
+			zap.String("module", cfg.Module),
+			zap.String("function", function),
+			zap.Duration("timeout", timeout),
+		)
+		start := time.Now()
+		err = runWASMModule(ctx, runtime, compiled, moduleConfig, function)
+		logger.Debug("wasm step finished",
+			zap.String("step", name),
+			zap.Duration("duration", time.Since(start)),
+		)
+
+		if limitedStdout.exceeded {
+			return engine.Result{}, fmt.Errorf("module output exceeds max_output_bytes of %d bytes", maxOutputBytes)
+		}
+		if err != nil {
+			stderrStr := strings.TrimSpace(stderr.String())
+			if runCtx.Err() != nil {
+				return engine.Result{}, fmt.Errorf("module interrupted: %w", context.Cause(runCtx))
+			}
+			if ctx.Err() == context.DeadlineExceeded {
+				return engine.Result{}, fmt.Errorf("module timed out after %s: %s", timeout, stderrStr)
+			}
+			if stderrStr != "" {
+				return engine.Result{}, fmt.Errorf("module failed: %w: %s", err, stderrStr)
+			}
+			return engine.Result{}, fmt.Errorf("module failed: %w", err)
+		}
+
+		var parsed any
+		if err := json.NewDecoder(&stdout).Decode(&parsed); err != nil {
+			return engine.Result{}, fmt.Errorf("failed to parse module output as JSON: %w", err)
+		}
+
+		return engine.Result{
+			Data: parsed,
+			Meta: map[string]string{
+				"wasm_module":   cfg.Module,
+				"wasm_function": function,
+			},
+		}, nil
+	}), nil
+}
+
+// runWASMModule instantiates compiled, which runs its start functions, then
+// calls function unless it is the _start already run. An exit with status 0
+// is a success.
+func runWASMModule(
+	ctx context.Context,
+	runtime wazero.Runtime,
+	compiled wazero.CompiledModule,
+	config wazero.ModuleConfig,
+	function string,
+) error {
+	mod, err := runtime.InstantiateModule(ctx, compiled, config)
+	if err == nil && function != wasmStartFunction {
+		_, err = mod.ExportedFunction(function).Call(ctx)
+	}
+
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+		return nil
+	}
+	return err
+}
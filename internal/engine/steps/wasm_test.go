@@ -0,0 +1,136 @@
+package steps
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// buildTestWASMModule compiles testdata/wasm for wasip1 with the given
+// -buildmode and returns the module's path.
+func buildTestWASMModule(t *testing.T, buildMode string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building a wasm module is slow")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not found")
+	}
+
+	out := filepath.Join(t.TempDir(), buildMode+".wasm")
+	cmd := exec.Command(gobin, "build", "-buildmode="+buildMode, "-o", out, "./testdata/wasm")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return out
+}
+
+func TestWASMStep(t *testing.T) {
+	command := buildTestWASMModule(t, "exe")
+	reactor := buildTestWASMModule(t, "c-shared")
+
+	t.Setenv("WASM_STEP_SECRET", "do not leak")
+
+	tests := []struct {
+		name        string
+		cfg         WASMStepConfig
+		expected    any
+		errContains string
+	}{
+		{
+			name: "transforms stdin",
+			cfg: WASMStepConfig{
+				Module: command,
+				From:   map[string]any{"op": "upper", "items": []any{"a", "b"}},
+			},
+			expected: []any{"A", "B"},
+		},
+		{
+			name:     "sandboxed from host environment and filesystem",
+			cfg:      WASMStepConfig{Module: command, From: map[string]any{"op": "probe"}},
+			expected: map[string]any{"env": float64(0), "root_readable": false},
+		},
+		{
+			name:     "exported function of a reactor",
+			cfg:      WASMStepConfig{Module: reactor, Function: "shout", From: map[string]any{}},
+			expected: map[string]any{"shout": true},
+		},
+		{
+			name:        "non-zero exit",
+			cfg:         WASMStepConfig{Module: command, From: map[string]any{"op": "fail"}},
+			errContains: "module failed: module closed with exit_code(3): bad input",
+		},
+		{
+			name:        "output is not JSON",
+			cfg:         WASMStepConfig{Module: command, From: map[string]any{"op": "garbage"}},
+			errContains: "failed to parse module output as JSON",
+		},
+		{
+			name:        "timeout",
+			cfg:         WASMStepConfig{Module: command, From: map[string]any{"op": "spin"}, Timeout: lo.ToPtr("100ms")},
+			errContains: "module timed out after 100ms",
+		},
+		{
+			name:        "output over max_output_bytes",
+			cfg:         WASMStepConfig{Module: command, From: map[string]any{"op": "flood"}, MaxOutputBytes: lo.ToPtr(64 * 1024)},
+			errContains: "module output exceeds max_output_bytes of 65536 bytes",
+		},
+		{
+			name:     "output under max_output_bytes",
+			cfg:      WASMStepConfig{Module: command, From: map[string]any{"op": "upper", "items": []any{"a"}}, MaxOutputBytes: lo.ToPtr(64)},
+			expected: []any{"A"},
+		},
+		{
+			name:        "missing function",
+			cfg:         WASMStepConfig{Module: command, Function: "nope", From: map[string]any{}},
+			errContains: `does not export function "nope"`,
+		},
+		{
+			name:        "missing module",
+			cfg:         WASMStepConfig{Module: filepath.Join(t.TempDir(), "missing.wasm")},
+			errContains: "failed to read module",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := NewWASMStep("test", zap.NewNop(), tt.cfg)
+			require.NoError(t, err)
+
+			result, err := step.Resolve(t.Context())
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Data)
+		})
+	}
+}
+
+func TestNewWASMStep_Validation(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         WASMStepConfig
+		errContains string
+	}{
+		{name: "no module", cfg: WASMStepConfig{}, errContains: "module is required"},
+		{name: "bad timeout", cfg: WASMStepConfig{Module: "m.wasm", Timeout: lo.ToPtr("soon")}, errContains: "invalid timeout"},
+		{name: "memory too large", cfg: WASMStepConfig{Module: "m.wasm", MaxMemoryMB: lo.ToPtr(8192)}, errContains: "max_memory_mb must be between 1 and 4096"},
+		{name: "non-positive output cap", cfg: WASMStepConfig{Module: "m.wasm", MaxOutputBytes: lo.ToPtr(0)}, errContains: "max_output_bytes must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWASMStep("test", zap.NewNop(), tt.cfg)
+			assert.ErrorContains(t, err, tt.errContains)
+		})
+	}
+}
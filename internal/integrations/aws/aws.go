@@ -0,0 +1,118 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+// CollectorConfig is the HCL-level shape of a `collector "aws" "<id>" { ... }` block.
+//
+//	collector "aws" "prod" {
+//	  region  = "us-east-1"
+//	  profile = "audit"
+//	}
+//
+// Credentials are resolved through the AWS SDK default chain (environment,
+// shared config, instance roles); `profile` selects a shared-config profile.
+type CollectorConfig struct {
+	Region   string `hcl:"region,optional"`
+	Profile  string `hcl:"profile,optional"`
+	Endpoint string `hcl:"endpoint,optional"`
+}
+
+// SecretsMetadataStepConfig is the HCL-level shape of a
+// `step "aws_secretsmanager_secrets" "<id>" { ... }` block. Only secret
+// metadata is collected; secret values are never read.
+//
+//	step "aws_secretsmanager_secrets" "prod" {
+//	  collector = collector.aws.prod
+//	  filters = {
+//	    name = ["prod/"]
+//	  }
+//	}
+type SecretsMetadataStepConfig struct {
+	Filters                map[string][]string `hcl:"filters,optional"`
+	IncludePlannedDeletion bool                `hcl:"include_planned_deletion,optional"`
+}
+
+// DescribeStepConfig is the HCL-level shape of a
+// `step "aws_describe" "<id>" { ... }` block.
+//
+//	step "aws_describe" "instances" {
+//	  collector = collector.aws.prod
+//	  operation = "ec2:DescribeInstances"
+//	  params = {
+//	    Filters = [{ Name = "instance-state-name", Values = ["running"] }]
+//	  }
+//	}
+type DescribeStepConfig struct {
+	// Read-only operation to call as "service:Operation", e.g.
+	// "ec2:DescribeInstances" or "s3:ListBuckets". Every page is collected.
+	Operation string `hcl:"operation"`
+	// Operation input using the AWS API field names, e.g. Filters or
+	// MaxResults. Pagination tokens are handled by the step.
+	Params hcl.Expression `hcl:"params,optional"`
+}
+
+func Register(registry *engine.Registry) error {
+	if err := engine.RegisterTypedCollector(registry, CollectorKind, newCollector); err != nil {
+		return err
+	}
+
+	return registry.RegisterSteps(
+		engine.NewTypedStepDescriptor(SecretsMetadataStepKind, CollectorKind, newSecretsMetadataStep),
+		engine.NewTypedStepDescriptor(DescribeStepKind, CollectorKind, newDescribeStep),
+	)
+}
+
+func newCollector(
+	_ *engine.RegistryHelper,
+	_ *hcl.EvalContext,
+	cfg CollectorConfig,
+) (engine.Collector, error) {
+	return NewCollector(Config(cfg))
+}
+
+func newSecretsMetadataStep(
+	_ *engine.RegistryHelper,
+	_ string,
+	collector *Collector,
+	_ *hcl.EvalContext,
+	cfg SecretsMetadataStepConfig,
+) (engine.Step, error) {
+	return NewSecretsMetadataStep(collector, SecretsMetadataConfig(cfg))
+}
+
+func newDescribeStep(
+	_ *engine.RegistryHelper,
+	_ string,
+	collector *Collector,
+	ctx *hcl.EvalContext,
+	cfg DescribeStepConfig,
+) (engine.Step, error) {
+	var params map[string]any
+	if cfg.Params != nil {
+		val, diags := cfg.Params.Value(ctx)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to evaluate aws_describe params: %w", diags)
+		}
+		if !val.IsNull() {
+			v, err := engine.CtyToAny(val)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert aws_describe params: %w", err)
+			}
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("params must be an object, got %T", v)
+			}
+			params = m
+		}
+	}
+
+	return NewDescribeStep(collector, DescribeConfig{
+		Operation: cfg.Operation,
+		Params:    params,
+	})
+}
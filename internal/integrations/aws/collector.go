@@ -0,0 +1,122 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+const (
+	CollectorKind = "aws"
+)
+
+type Config struct {
+	Region   string
+	Profile  string
+	Endpoint string
+}
+
+// Collector holds a resolved AWS SDK configuration shared by every AWS step
+// bound to it. Service clients are built from that configuration in Start,
+// so steps running concurrently only read them.
+type Collector struct {
+	cfg       Config
+	awsConfig *aws.Config
+
+	secretsManager SecretsManagerAPI
+}
+
+type CollectorOption func(*Collector)
+
+// WithSecretsManagerClient injects the Secrets Manager client instead of
+// building one from the resolved AWS configuration.
+func WithSecretsManagerClient(client SecretsManagerAPI) CollectorOption {
+	return func(c *Collector) {
+		c.secretsManager = client
+	}
+}
+
+func NewCollector(cfg Config, opts ...CollectorOption) (engine.Collector, error) {
+	collector := &Collector{cfg: cfg}
+	for _, opt := range opts {
+		opt(collector)
+	}
+	return collector, nil
+}
+
+func (c *Collector) Name() string {
+	region := c.Region()
+	if region == "" {
+		region = "default"
+	}
+	return fmt.Sprintf("%s(%s)", CollectorKind, region)
+}
+
+func (c *Collector) Kind() string {
+	return CollectorKind
+}
+
+// Start resolves the AWS configuration through the SDK default chain. It is
+// idempotent.
+func (c *Collector) Start(ctx context.Context) error {
+	if c.awsConfig != nil {
+		return nil
+	}
+
+	var opts []func(*config.LoadOptions) error
+	if c.cfg.Region != "" {
+		opts = append(opts, config.WithRegion(c.cfg.Region))
+	}
+	if c.cfg.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(c.cfg.Profile))
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if c.cfg.Endpoint != "" {
+		awsCfg.BaseEndpoint = aws.String(c.cfg.Endpoint)
+	}
+
+	c.awsConfig = &awsCfg
+	if c.secretsManager == nil {
+		c.secretsManager = secretsmanager.NewFromConfig(awsCfg)
+	}
+	return nil
+}
+
+func (c *Collector) Close(ctx context.Context) error {
+	return nil
+}
+
+// Region returns the effective region: the resolved one once started, the
+// configured one otherwise.
+func (c *Collector) Region() string {
+	if c.awsConfig != nil {
+		return c.awsConfig.Region
+	}
+	return c.cfg.Region
+}
+
+// AWSConfig returns the resolved AWS configuration service clients are
+// built from.
+func (c *Collector) AWSConfig() (aws.Config, error) {
+	if c.awsConfig == nil {
+		return aws.Config{}, fmt.Errorf("%w: %s", engine.ErrCollectorNotStarted, c.Name())
+	}
+	return *c.awsConfig, nil
+}
+
+// SecretsManager returns the Secrets Manager client. The returned interface
+// only exposes metadata listing; see SecretsManagerAPI.
+func (c *Collector) SecretsManager() (SecretsManagerAPI, error) {
+	if c.secretsManager == nil {
+		return nil, fmt.Errorf("%w: %s", engine.ErrCollectorNotStarted, c.Name())
+	}
+	return c.secretsManager, nil
+}
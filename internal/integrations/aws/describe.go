@@ -0,0 +1,189 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+const (
+	DescribeStepKind = "aws_describe"
+)
+
+// pager is the shape shared by the SDK's generated paginators.
+type pager[Out, Opt any] interface {
+	HasMorePages() bool
+	NextPage(ctx context.Context, optFns ...func(*Opt)) (*Out, error)
+}
+
+// describeFunc runs one read-only operation with params (the operation's
+// input in its API field names) and returns every item across all pages.
+type describeFunc func(ctx context.Context, cfg aws.Config, params map[string]any) ([]any, error)
+
+// describeOperations lists the supported "service:Operation" names. Each one
+// is list- or describe-only, and the items are taken from the output's list
+// field.
+var describeOperations = map[string]describeFunc{
+	"ec2:DescribeInstances": paginated(
+		func(cfg aws.Config, in *ec2.DescribeInstancesInput) pager[ec2.DescribeInstancesOutput, ec2.Options] {
+			return ec2.NewDescribeInstancesPaginator(ec2.NewFromConfig(cfg), in)
+		},
+		func(out *ec2.DescribeInstancesOutput) any { return out.Reservations },
+	),
+	"ec2:DescribeSecurityGroups": paginated(
+		func(cfg aws.Config, in *ec2.DescribeSecurityGroupsInput) pager[ec2.DescribeSecurityGroupsOutput, ec2.Options] {
+			return ec2.NewDescribeSecurityGroupsPaginator(ec2.NewFromConfig(cfg), in)
+		},
+		func(out *ec2.DescribeSecurityGroupsOutput) any { return out.SecurityGroups },
+	),
+	"ec2:DescribeSubnets": paginated(
+		func(cfg aws.Config, in *ec2.DescribeSubnetsInput) pager[ec2.DescribeSubnetsOutput, ec2.Options] {
+			return ec2.NewDescribeSubnetsPaginator(ec2.NewFromConfig(cfg), in)
+		},
+		func(out *ec2.DescribeSubnetsOutput) any { return out.Subnets },
+	),
+	"ec2:DescribeVolumes": paginated(
+		func(cfg aws.Config, in *ec2.DescribeVolumesInput) pager[ec2.DescribeVolumesOutput, ec2.Options] {
+			return ec2.NewDescribeVolumesPaginator(ec2.NewFromConfig(cfg), in)
+		},
+		func(out *ec2.DescribeVolumesOutput) any { return out.Volumes },
+	),
+	"ec2:DescribeVpcs": paginated(
+		func(cfg aws.Config, in *ec2.DescribeVpcsInput) pager[ec2.DescribeVpcsOutput, ec2.Options] {
+			return ec2.NewDescribeVpcsPaginator(ec2.NewFromConfig(cfg), in)
+		},
+		func(out *ec2.DescribeVpcsOutput) any { return out.Vpcs },
+	),
+	"s3:ListBuckets": paginated(
+		func(cfg aws.Config, in *s3.ListBucketsInput) pager[s3.ListBucketsOutput, s3.Options] {
+			return s3.NewListBucketsPaginator(s3.NewFromConfig(cfg), in)
+		},
+		func(out *s3.ListBucketsOutput) any { return out.Buckets },
+	),
+}
+
+// DescribeOperations returns the supported operation names, sorted.
+func DescribeOperations() []string {
+	return slices.Sorted(maps.Keys(describeOperations))
+}
+
+// paginated builds a describeFunc from an SDK paginator constructor and the
+// accessor for the output's list field.
+func paginated[In, Out, Opt any](
+	newPager func(aws.Config, *In) pager[Out, Opt],
+	items func(*Out) any,
+) describeFunc {
+	return func(ctx context.Context, cfg aws.Config, params map[string]any) ([]any, error) {
+		input := new(In)
+		if err := decodeDescribeParams(params, input); err != nil {
+			return nil, err
+		}
+
+		all := []any{}
+		p := newPager(cfg, input)
+		for p.HasMorePages() {
+			page, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			pageItems, err := toJSONList(items(page))
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, pageItems...)
+		}
+		return all, nil
+	}
+}
+
+// decodeDescribeParams fills input from params through encoding/json, so
+// params use the API field names (e.g. Filters, MaxResults). Unknown fields
+// are rejected to surface typos.
+func decodeDescribeParams(params map[string]any, input any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode params: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(input); err != nil {
+		return fmt.Errorf("invalid params: %w", err)
+	}
+	return nil
+}
+
+// toJSONList round-trips an SDK slice through encoding/json so the result
+// holds only plain JSON values.
+func toJSONList(v any) ([]any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+	var items []any
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return items, nil
+}
+
+type DescribeConfig struct {
+	// Operation is "service:Operation", e.g. "ec2:DescribeInstances".
+	Operation string
+	// Params is the operation input, keyed by API field name.
+	Params map[string]any
+}
+
+type describeStep struct {
+	collector *Collector
+	config    DescribeConfig
+	describe  describeFunc
+}
+
+func NewDescribeStep(collector *Collector, cfg DescribeConfig) (engine.Step, error) {
+	describe, ok := describeOperations[cfg.Operation]
+	if !ok {
+		return nil, fmt.Errorf("unsupported operation %q (supported: %s)", cfg.Operation, strings.Join(DescribeOperations(), ", "))
+	}
+	return &describeStep{collector: collector, config: cfg, describe: describe}, nil
+}
+
+func (s *describeStep) Name() string {
+	return fmt.Sprintf("%s(%s)", DescribeStepKind, s.config.Operation)
+}
+
+func (s *describeStep) Kind() string {
+	return DescribeStepKind
+}
+
+func (s *describeStep) Resolve(ctx context.Context) (engine.Result, error) {
+	cfg, err := s.collector.AWSConfig()
+	if err != nil {
+		return engine.Result{}, err
+	}
+
+	items, err := s.describe(ctx, cfg, s.config.Params)
+	if err != nil {
+		return engine.Result{}, fmt.Errorf("failed to call %s: %w", s.config.Operation, err)
+	}
+
+	meta := map[string]string{
+		"aws_region": s.collector.Region(),
+		"operation":  s.config.Operation,
+		"item_count": strconv.Itoa(len(items)),
+	}
+
+	return engine.Result{Data: items, Meta: meta}, nil
+}
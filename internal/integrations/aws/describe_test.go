@@ -0,0 +1,144 @@
+package aws
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEC2 answers DescribeVpcs over the EC2 query protocol with one VPC per
+// page, keyed by the incoming NextToken, and records every request form.
+type fakeEC2 struct {
+	mu    sync.Mutex
+	forms []url.Values
+}
+
+func (f *fakeEC2) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.forms = append(f.forms, r.PostForm)
+	f.mu.Unlock()
+
+	vpc, next := "vpc-1", "<nextToken>page-2</nextToken>"
+	if r.PostForm.Get("NextToken") == "page-2" {
+		vpc, next = "vpc-2", ""
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = fmt.Fprintf(w, `<DescribeVpcsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>req</requestId>
+  <vpcSet><item><vpcId>%s</vpcId><cidrBlock>10.0.0.0/16</cidrBlock><state>available</state></item></vpcSet>
+  %s
+</DescribeVpcsResponse>`, vpc, next)
+}
+
+func newStartedCollector(t *testing.T, endpoint string) *Collector {
+	t.Helper()
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent")
+
+	c, err := NewCollector(Config{Region: "eu-west-1", Endpoint: endpoint})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(t.Context()))
+	return c.(*Collector)
+}
+
+func TestDescribeStep_Resolve(t *testing.T) {
+	fake := &fakeEC2{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	step, err := NewDescribeStep(newStartedCollector(t, srv.URL), DescribeConfig{
+		Operation: "ec2:DescribeVpcs",
+		Params: map[string]any{
+			"Filters": []any{
+				map[string]any{"Name": "tag:env", "Values": []any{"prod"}},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "aws_describe(ec2:DescribeVpcs)", step.Name())
+
+	result, err := step.Resolve(t.Context())
+	require.NoError(t, err)
+
+	items, ok := result.Data.([]any)
+	require.True(t, ok)
+	require.Len(t, items, 2, "both pages are collected")
+	assert.Equal(t, "vpc-1", items[0].(map[string]any)["VpcId"])
+	assert.Equal(t, "available", items[0].(map[string]any)["State"])
+	assert.Equal(t, "vpc-2", items[1].(map[string]any)["VpcId"])
+
+	assert.Equal(t, map[string]string{
+		"aws_region": "eu-west-1",
+		"operation":  "ec2:DescribeVpcs",
+		"item_count": "2",
+	}, result.Meta)
+
+	require.Len(t, fake.forms, 2)
+	assert.Equal(t, "DescribeVpcs", fake.forms[0].Get("Action"))
+	assert.Equal(t, "tag:env", fake.forms[0].Get("Filter.1.Name"))
+	assert.Equal(t, "prod", fake.forms[0].Get("Filter.1.Value.1"))
+	assert.Equal(t, "page-2", fake.forms[1].Get("NextToken"))
+}
+
+func TestDescribeStep_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        DescribeConfig
+		wantNewErr string
+		wantErr    string
+	}{
+		{
+			name:       "unsupported operation",
+			cfg:        DescribeConfig{Operation: "ec2:TerminateInstances"},
+			wantNewErr: `unsupported operation "ec2:TerminateInstances" (supported: ec2:DescribeInstances`,
+		},
+		{
+			name: "unknown param",
+			cfg: DescribeConfig{
+				Operation: "ec2:DescribeVpcs",
+				Params:    map[string]any{"Filter": "typo"},
+			},
+			wantErr: `failed to call ec2:DescribeVpcs: invalid params: json: unknown field "Filter"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(&fakeEC2{})
+			t.Cleanup(srv.Close)
+
+			step, err := NewDescribeStep(newStartedCollector(t, srv.URL), tt.cfg)
+			if tt.wantNewErr != "" {
+				assert.ErrorContains(t, err, tt.wantNewErr)
+				return
+			}
+			require.NoError(t, err)
+
+			_, err = step.Resolve(t.Context())
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestDescribeStep_RequiresStartedCollector(t *testing.T) {
+	c, err := NewCollector(Config{Region: "eu-west-1"})
+	require.NoError(t, err)
+
+	step, err := NewDescribeStep(c.(*Collector), DescribeConfig{Operation: "s3:ListBuckets"})
+	require.NoError(t, err)
+
+	_, err = step.Resolve(t.Context())
+	assert.ErrorContains(t, err, "aws(eu-west-1)")
+}
@@ -0,0 +1,169 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+const (
+	SecretsMetadataStepKind = "aws_secretsmanager_secrets"
+)
+
+// SecretsManagerAPI is the subset of the Secrets Manager client the
+// integration depends on. It deliberately exposes ListSecrets only, which
+// never returns secret material: GetSecretValue and BatchGetSecretValue are
+// unreachable from any step by construction.
+type SecretsManagerAPI interface {
+	ListSecrets(
+		ctx context.Context,
+		params *secretsmanager.ListSecretsInput,
+		optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.ListSecretsOutput, error)
+}
+
+type SecretsMetadataConfig struct {
+	Filters                map[string][]string
+	IncludePlannedDeletion bool
+}
+
+type secretsMetadataStep struct {
+	collector *Collector
+	config    SecretsMetadataConfig
+}
+
+func NewSecretsMetadataStep(collector *Collector, cfg SecretsMetadataConfig) (engine.Step, error) {
+	known := types.FilterNameStringTypeAll.Values()
+	for key := range cfg.Filters {
+		if !slices.Contains(known, types.FilterNameStringType(key)) {
+			return nil, fmt.Errorf("unknown secrets manager filter %q (known: %v)", key, known)
+		}
+	}
+	return &secretsMetadataStep{collector: collector, config: cfg}, nil
+}
+
+func (s *secretsMetadataStep) Name() string {
+	return fmt.Sprintf("%s(%s)", SecretsMetadataStepKind, s.collector.Region())
+}
+
+func (s *secretsMetadataStep) Kind() string {
+	return SecretsMetadataStepKind
+}
+
+func (s *secretsMetadataStep) Resolve(ctx context.Context) (engine.Result, error) {
+	client, err := s.collector.SecretsManager()
+	if err != nil {
+		return engine.Result{}, err
+	}
+
+	input := &secretsmanager.ListSecretsInput{
+		IncludePlannedDeletion: aws.Bool(s.config.IncludePlannedDeletion),
+		SortOrder:              types.SortOrderTypeAsc,
+	}
+	filterKeys := make([]string, 0, len(s.config.Filters))
+	for key := range s.config.Filters {
+		filterKeys = append(filterKeys, key)
+	}
+	slices.Sort(filterKeys)
+	for _, key := range filterKeys {
+		input.Filters = append(input.Filters, types.Filter{
+			Key:    types.FilterNameStringType(key),
+			Values: s.config.Filters[key],
+		})
+	}
+
+	var secrets []any
+	paginator := secretsmanager.NewListSecretsPaginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return engine.Result{}, fmt.Errorf("failed to list secrets: %w", err)
+		}
+		for _, entry := range page.SecretList {
+			secrets = append(secrets, secretMetadata(entry))
+		}
+	}
+	if secrets == nil {
+		secrets = []any{}
+	}
+
+	meta := map[string]string{
+		"aws_region":   s.collector.Region(),
+		"secret_count": strconv.Itoa(len(secrets)),
+	}
+
+	return engine.Result{Data: secrets, Meta: meta}, nil
+}
+
+// secretMetadata projects a list entry onto an explicit allowlist of
+// metadata fields so that any value-bearing field added to the SDK type in
+// the future is not picked up implicitly.
+func secretMetadata(entry types.SecretListEntry) map[string]any {
+	out := map[string]any{
+		"name":             aws.ToString(entry.Name),
+		"arn":              aws.ToString(entry.ARN),
+		"rotation_enabled": aws.ToBool(entry.RotationEnabled),
+	}
+
+	setString := func(key string, v *string) {
+		if v != nil {
+			out[key] = *v
+		}
+	}
+	setTime := func(key string, v *time.Time) {
+		if v != nil {
+			out[key] = v.UTC().Format(time.RFC3339)
+		}
+	}
+
+	setString("description", entry.Description)
+	setString("kms_key_id", entry.KmsKeyId)
+	setString("owning_service", entry.OwningService)
+	setString("primary_region", entry.PrimaryRegion)
+	setString("rotation_lambda_arn", entry.RotationLambdaARN)
+	setTime("created_date", entry.CreatedDate)
+	setTime("deleted_date", entry.DeletedDate)
+	setTime("last_accessed_date", entry.LastAccessedDate)
+	setTime("last_changed_date", entry.LastChangedDate)
+	setTime("last_rotated_date", entry.LastRotatedDate)
+	setTime("next_rotation_date", entry.NextRotationDate)
+
+	if rules := entry.RotationRules; rules != nil {
+		r := map[string]any{}
+		if rules.AutomaticallyAfterDays != nil {
+			r["automatically_after_days"] = *rules.AutomaticallyAfterDays
+		}
+		if rules.Duration != nil {
+			r["duration"] = *rules.Duration
+		}
+		if rules.ScheduleExpression != nil {
+			r["schedule_expression"] = *rules.ScheduleExpression
+		}
+		out["rotation_rules"] = r
+	}
+
+	if len(entry.Tags) > 0 {
+		tags := make(map[string]any, len(entry.Tags))
+		for _, tag := range entry.Tags {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		out["tags"] = tags
+	}
+
+	if len(entry.SecretVersionsToStages) > 0 {
+		versions := make(map[string]any, len(entry.SecretVersionsToStages))
+		for id, stages := range entry.SecretVersionsToStages {
+			versions[id] = stages
+		}
+		out["version_stages"] = versions
+	}
+
+	return out
+}
@@ -0,0 +1,182 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockSecretsManager serves ListSecrets from pre-built pages keyed by the
+// incoming NextToken and records every input it receives.
+type mockSecretsManager struct {
+	pages  map[string]*secretsmanager.ListSecretsOutput
+	err    error
+	inputs []*secretsmanager.ListSecretsInput
+}
+
+func (m *mockSecretsManager) ListSecrets(
+	_ context.Context,
+	params *secretsmanager.ListSecretsInput,
+	_ ...func(*secretsmanager.Options),
+) (*secretsmanager.ListSecretsOutput, error) {
+	m.inputs = append(m.inputs, params)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.pages[aws.ToString(params.NextToken)], nil
+}
+
+func newSecretsStep(t *testing.T, client SecretsManagerAPI, cfg SecretsMetadataConfig) (*Collector, *secretsMetadataStep) {
+	t.Helper()
+	c, err := NewCollector(Config{Region: "eu-west-1"}, WithSecretsManagerClient(client))
+	require.NoError(t, err)
+	step, err := NewSecretsMetadataStep(c.(*Collector), cfg)
+	require.NoError(t, err)
+	return c.(*Collector), step.(*secretsMetadataStep)
+}
+
+func TestSecretsMetadataStep_Resolve(t *testing.T) {
+	changed := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	client := &mockSecretsManager{
+		pages: map[string]*secretsmanager.ListSecretsOutput{
+			"": {
+				SecretList: []types.SecretListEntry{{
+					Name:              aws.String("prod/db"),
+					ARN:               aws.String("arn:aws:secretsmanager:eu-west-1:123:secret:prod/db"),
+					Description:       aws.String("database credentials"),
+					RotationEnabled:   aws.Bool(true),
+					RotationLambdaARN: aws.String("arn:aws:lambda:eu-west-1:123:function:rotate"),
+					RotationRules:     &types.RotationRulesType{AutomaticallyAfterDays: aws.Int64(30)},
+					LastChangedDate:   &changed,
+					Tags:              []types.Tag{{Key: aws.String("team"), Value: aws.String("platform")}},
+					SecretVersionsToStages: map[string][]string{
+						"v1": {"AWSCURRENT"},
+					},
+				}},
+				NextToken: aws.String("page-2"),
+			},
+			"page-2": {
+				SecretList: []types.SecretListEntry{{
+					Name: aws.String("prod/api"),
+					ARN:  aws.String("arn:aws:secretsmanager:eu-west-1:123:secret:prod/api"),
+				}},
+			},
+		},
+	}
+
+	_, step := newSecretsStep(t, client, SecretsMetadataConfig{
+		Filters: map[string][]string{"name": {"prod/"}},
+	})
+
+	result, err := step.Resolve(t.Context())
+	require.NoError(t, err)
+
+	assert.Equal(t, []any{
+		map[string]any{
+			"name":                "prod/db",
+			"arn":                 "arn:aws:secretsmanager:eu-west-1:123:secret:prod/db",
+			"description":         "database credentials",
+			"rotation_enabled":    true,
+			"rotation_lambda_arn": "arn:aws:lambda:eu-west-1:123:function:rotate",
+			"rotation_rules":      map[string]any{"automatically_after_days": int64(30)},
+			"last_changed_date":   "2026-03-01T12:00:00Z",
+			"tags":                map[string]any{"team": "platform"},
+			"version_stages":      map[string]any{"v1": []string{"AWSCURRENT"}},
+		},
+		map[string]any{
+			"name":             "prod/api",
+			"arn":              "arn:aws:secretsmanager:eu-west-1:123:secret:prod/api",
+			"rotation_enabled": false,
+		},
+	}, result.Data)
+	assert.Equal(t, map[string]string{"aws_region": "eu-west-1", "secret_count": "2"}, result.Meta)
+
+	require.Len(t, client.inputs, 2)
+	assert.Equal(t, []types.Filter{{Key: types.FilterNameStringTypeName, Values: []string{"prod/"}}}, client.inputs[0].Filters)
+	assert.Equal(t, "page-2", aws.ToString(client.inputs[1].NextToken))
+}
+
+func TestSecretsMetadataStep_Resolve_Empty(t *testing.T) {
+	client := &mockSecretsManager{
+		pages: map[string]*secretsmanager.ListSecretsOutput{"": {}},
+	}
+	_, step := newSecretsStep(t, client, SecretsMetadataConfig{})
+
+	result, err := step.Resolve(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []any{}, result.Data)
+}
+
+func TestSecretsMetadataStep_Resolve_Error(t *testing.T) {
+	client := &mockSecretsManager{err: errors.New("access denied")}
+	_, step := newSecretsStep(t, client, SecretsMetadataConfig{})
+
+	_, err := step.Resolve(t.Context())
+	assert.ErrorContains(t, err, "failed to list secrets: access denied")
+}
+
+func TestNewSecretsMetadataStep_UnknownFilter(t *testing.T) {
+	c, err := NewCollector(Config{})
+	require.NoError(t, err)
+
+	_, err = NewSecretsMetadataStep(c.(*Collector), SecretsMetadataConfig{
+		Filters: map[string][]string{"value": {"x"}},
+	})
+	assert.ErrorContains(t, err, `unknown secrets manager filter "value"`)
+}
+
+func TestSecretsMetadataStep_RequiresStartedCollector(t *testing.T) {
+	c, err := NewCollector(Config{})
+	require.NoError(t, err)
+	step, err := NewSecretsMetadataStep(c.(*Collector), SecretsMetadataConfig{})
+	require.NoError(t, err)
+
+	_, err = step.Resolve(t.Context())
+	assert.ErrorContains(t, err, "collector not started")
+}
+
+func TestCollector_SecretsManager_Concurrent(t *testing.T) {
+	c := newStartedCollector(t, "http://127.0.0.1:1")
+	first, err := c.SecretsManager()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			client, err := c.SecretsManager()
+			assert.NoError(t, err)
+			assert.Same(t, first, client, "every step shares the client built in Start")
+		})
+	}
+	wg.Wait()
+}
+
+// TestSecretsManagerAPI_MetadataOnly pins the client surface: adding a
+// value-reading method to the interface must be a deliberate, reviewed change.
+func TestSecretsManagerAPI_MetadataOnly(t *testing.T) {
+	api := reflect.TypeFor[SecretsManagerAPI]()
+	methods := make([]string, 0, api.NumMethod())
+	for i := range api.NumMethod() {
+		methods = append(methods, api.Method(i).Name)
+	}
+	assert.Equal(t, []string{"ListSecrets"}, methods)
+}
+
+func TestSecretMetadata_NeverIncludesValues(t *testing.T) {
+	out := secretMetadata(types.SecretListEntry{
+		Name: aws.String("prod/db"),
+		ARN:  aws.String("arn"),
+	})
+	for _, key := range []string{"secret_string", "secret_binary", "value", "SecretString", "SecretBinary"} {
+		assert.NotContains(t, out, key)
+	}
+}
@@ -10,29 +10,78 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-cleanhttp"
+	"github.com/infracollect/infracollect/internal/buildinfo"
 	"github.com/infracollect/infracollect/internal/engine"
 	"github.com/samber/lo"
+	"golang.org/x/time/rate"
 )
 
 const (
-	CollectorKind  = "http"
-	DefaultTimeout = 30 * time.Second
+	CollectorKind       = "http"
+	DefaultTimeout      = 30 * time.Second
+	DefaultMaxRedirects = 10
 )
 
 var (
 	defaultHeaders = map[string]string{
-		"User-Agent":      "infracollect/0.1.0",
+		"User-Agent":      buildinfo.UserAgent(),
 		"Accept":          "application/json",
 		"Accept-Encoding": "gzip",
 	}
 )
 
 type Config struct {
-	BaseURL  string
-	Headers  map[string]string
-	Auth     *AuthConfig
-	Timeout  time.Duration
-	Insecure bool
+	BaseURL   string
+	Headers   map[string]string
+	Auth      *AuthConfig
+	Timeout   time.Duration
+	Insecure  bool
+	RateLimit *RateLimitConfig
+
+	// RecordDir saves every response to disk, keyed by method and URL.
+	// ReplayDir serves responses from such a directory instead of the
+	// network, failing on any request that was not recorded. At most one
+	// of the two may be set.
+	RecordDir string
+	ReplayDir string
+
+	// FollowRedirects defaults to true; false fails any request that is
+	// redirected. MaxRedirects caps how many redirects one request follows
+	// (DefaultMaxRedirects when nil). Credentials are never sent to a host
+	// other than the one originally requested.
+	FollowRedirects *bool
+	MaxRedirects    *int
+
+	// Proxy routes every request through the given http://, https:// or
+	// socks5:// URL, with credentials taken from its user info. When nil
+	// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables apply.
+	Proxy *string
+
+	// Transport tunes the connection pool. Nil keeps the defaults.
+	Transport *TransportConfig
+}
+
+// TransportConfig tunes the pooled transport shared by every step bound to
+// the collector. Nil fields keep the defaults of go-cleanhttp's pooled
+// transport: 100 idle connections in total, no per-host connection limit and
+// a 90s idle timeout.
+type TransportConfig struct {
+	// MaxIdleConns caps idle keep-alive connections across all hosts; 0
+	// means no limit.
+	MaxIdleConns *int
+	// MaxConnsPerHost caps connections to a single host, including those in
+	// use; 0 means no limit. Requests beyond it wait for a free connection.
+	MaxConnsPerHost *int
+	// IdleConnTimeout closes keep-alive connections idle for longer; 0
+	// keeps them open indefinitely.
+	IdleConnTimeout *time.Duration
+}
+
+// RateLimitConfig caps the request rate of every step bound to the
+// collector. Burst defaults to 1 when unset.
+type RateLimitConfig struct {
+	RequestsPerSecond float64
+	Burst             int
 }
 
 type AuthConfig struct {
@@ -45,10 +94,20 @@ type BasicAuthConfig struct {
 	Encoded  string
 }
 
+// Collector is shared by every step bound to it, and steps may use it
+// concurrently. Anything those steps must share, such as the rate limiter
+// or credentials an auth scheme fetches at run time, belongs here rather
+// than on the steps, and must be safe for concurrent use.
 type Collector struct {
 	baseURL    *url.URL
 	httpClient *http.Client
 	headers    map[string]string
+	limiter    *rate.Limiter
+
+	// probeClient is the client Validate reaches the base URL with: the
+	// collector's client without recording, or nil when replaying, since a
+	// replayed collector never touches the network.
+	probeClient *http.Client
 }
 
 type CollectOption func(*Collector)
@@ -87,6 +146,41 @@ func NewCollector(cfg Config, opts ...CollectOption) (engine.Collector, error) {
 		headers: headers,
 	}
 
+	if cfg.RateLimit != nil {
+		if cfg.RateLimit.RequestsPerSecond <= 0 {
+			return nil, fmt.Errorf("rate_limit requests_per_second must be positive, got: %v", cfg.RateLimit.RequestsPerSecond)
+		}
+		if cfg.RateLimit.Burst < 0 {
+			return nil, fmt.Errorf("rate_limit burst must not be negative, got: %d", cfg.RateLimit.Burst)
+		}
+		burst := cfg.RateLimit.Burst
+		if burst == 0 {
+			burst = 1
+		}
+		collector.limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit.RequestsPerSecond), burst)
+	}
+
+	if cfg.RecordDir != "" && cfg.ReplayDir != "" {
+		return nil, fmt.Errorf("record_dir and replay_dir are mutually exclusive")
+	}
+
+	maxRedirects := lo.FromPtrOr(cfg.MaxRedirects, DefaultMaxRedirects)
+	if maxRedirects < 0 {
+		return nil, fmt.Errorf("max_redirects must not be negative, got: %d", maxRedirects)
+	}
+
+	if err := validateTransport(cfg.Transport); err != nil {
+		return nil, err
+	}
+
+	var proxyURL *url.URL
+	if cfg.Proxy != nil {
+		proxyURL, err = parseProxyURL(*cfg.Proxy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	for _, opt := range opts {
 		opt(collector)
 	}
@@ -105,6 +199,10 @@ func NewCollector(cfg Config, opts ...CollectOption) (engine.Collector, error) {
 
 			transport.TLSClientConfig.InsecureSkipVerify = true
 		}
+		if proxyURL != nil {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+		applyTransport(transport, cfg.Transport)
 
 		collector.httpClient = &http.Client{
 			Transport: transport,
@@ -112,9 +210,125 @@ func NewCollector(cfg Config, opts ...CollectOption) (engine.Collector, error) {
 		}
 	}
 
+	if collector.httpClient.CheckRedirect == nil {
+		clone := *collector.httpClient
+		clone.CheckRedirect = redirectPolicy(lo.FromPtrOr(cfg.FollowRedirects, true), maxRedirects)
+		collector.httpClient = &clone
+	}
+
+	if cfg.ReplayDir == "" {
+		collector.probeClient = collector.httpClient
+	}
+
+	switch {
+	case cfg.RecordDir != "":
+		collector.httpClient = withTransport(collector.httpClient, &recordingTransport{
+			next: transportOrDefault(collector.httpClient),
+			dir:  cfg.RecordDir,
+		})
+	case cfg.ReplayDir != "":
+		collector.httpClient = withTransport(collector.httpClient, &replayingTransport{dir: cfg.ReplayDir})
+	}
+
 	return collector, nil
 }
 
+func validateTransport(cfg *TransportConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.MaxIdleConns != nil && *cfg.MaxIdleConns < 0 {
+		return fmt.Errorf("transport max_idle_conns must not be negative, got: %d", *cfg.MaxIdleConns)
+	}
+	if cfg.MaxConnsPerHost != nil && *cfg.MaxConnsPerHost < 0 {
+		return fmt.Errorf("transport max_conns_per_host must not be negative, got: %d", *cfg.MaxConnsPerHost)
+	}
+	if cfg.IdleConnTimeout != nil && *cfg.IdleConnTimeout < 0 {
+		return fmt.Errorf("transport idle_conn_timeout must not be negative, got: %s", *cfg.IdleConnTimeout)
+	}
+	return nil
+}
+
+// applyTransport overrides the pool settings of transport that cfg sets.
+func applyTransport(transport *http.Transport, cfg *TransportConfig) {
+	if cfg == nil {
+		return
+	}
+	if cfg.MaxIdleConns != nil {
+		transport.MaxIdleConns = *cfg.MaxIdleConns
+	}
+	if cfg.MaxConnsPerHost != nil {
+		transport.MaxConnsPerHost = *cfg.MaxConnsPerHost
+		// cleanhttp keeps GOMAXPROCS+1 idle connections per host; with a
+		// lower connection cap the extra idle slots could never be used.
+		if *cfg.MaxConnsPerHost > 0 {
+			transport.MaxIdleConnsPerHost = min(transport.MaxIdleConnsPerHost, *cfg.MaxConnsPerHost)
+		}
+	}
+	if cfg.IdleConnTimeout != nil {
+		transport.IdleConnTimeout = *cfg.IdleConnTimeout
+	}
+}
+
+// parseProxyURL validates a proxy URL. net/http speaks to http, https and
+// socks5 proxies itself, so no other scheme is accepted.
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		// The parse error quotes the raw URL, credentials included.
+		return nil, fmt.Errorf("proxy is not a valid URL")
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy must use http, https or socks5 scheme, got: %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy %q has no host", u.Redacted())
+	}
+	return u, nil
+}
+
+// credentialHeaders are dropped from a redirected request whose host differs
+// from the original one.
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// redirectPolicy builds the client's CheckRedirect. net/http already drops
+// credentials on redirects to unrelated domains, but still forwards them to
+// subdomains and to other ports of the same host; this policy forwards them
+// only to the exact host (and port) of the original request.
+func redirectPolicy(follow bool, maxRedirects int) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if !follow {
+			return fmt.Errorf("refusing redirect to %s: follow_redirects is false", req.URL.Redacted())
+		}
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects (max_redirects)", maxRedirects)
+		}
+		if req.URL.Host != via[0].URL.Host {
+			for _, header := range credentialHeaders {
+				req.Header.Del(header)
+			}
+		}
+		return nil
+	}
+}
+
+// withTransport returns a copy of client using transport, leaving a client
+// passed in through WithHttpClient untouched.
+func withTransport(client *http.Client, transport http.RoundTripper) *http.Client {
+	clone := *client
+	clone.Transport = transport
+	return &clone
+}
+
+func transportOrDefault(client *http.Client) http.RoundTripper {
+	if client.Transport != nil {
+		return client.Transport
+	}
+	return http.DefaultTransport
+}
+
 func (c *Collector) Name() string {
 	return fmt.Sprintf("%s(%s)", CollectorKind, c.baseURL.Host)
 }
@@ -131,7 +345,42 @@ func (c *Collector) Close(ctx context.Context) error {
 	return nil
 }
 
+// Validate sends a HEAD request to the base URL, with the collector's
+// headers, to check that its host resolves and accepts connections. Any
+// response counts as reachable, whatever its status: the base URL itself
+// need not be a resource.
+func (c *Collector) Validate(ctx context.Context) error {
+	if c.probeClient == nil {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.baseURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.probeClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("base_url '%s' is not reachable: %w", c.baseURL, err)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+// Do sends req through the collector's client. When a rate limit is
+// configured it blocks until a token is available or the request context is
+// done. The limiter is shared by every caller, so concurrent steps draw from
+// the same budget.
 func (c *Collector) Do(req *http.Request) (*http.Response, error) {
+	if c.limiter != nil {
+		if err := c.limiter.Wait(req.Context()); err != nil {
+			return nil, fmt.Errorf("rate limiter: %w", err)
+		}
+	}
+
 	for k, v := range c.headers {
 		if req.Header.Get(k) == "" {
 			req.Header.Set(k, v)
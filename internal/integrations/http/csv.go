@@ -0,0 +1,87 @@
+package http
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// validateCSVBlock checks the csv block of a step. It only applies to
+// response_type "csv".
+func validateCSVBlock(responseType string, block *CSVBlock) error {
+	if block == nil {
+		return nil
+	}
+	if responseType != "csv" {
+		return fmt.Errorf("csv block requires response_type \"csv\"")
+	}
+	if block.Delimiter == "" {
+		return nil
+	}
+	delimiter, size := utf8.DecodeRuneInString(block.Delimiter)
+	if size != len(block.Delimiter) {
+		return fmt.Errorf("csv delimiter must be a single character, got %q", block.Delimiter)
+	}
+	if delimiter == '"' || delimiter == '\r' || delimiter == '\n' || delimiter == utf8.RuneError {
+		return fmt.Errorf("invalid csv delimiter %q", block.Delimiter)
+	}
+	return nil
+}
+
+// parseCSVResponse decodes a CSV document. With a header row (the default)
+// every other row becomes an object keyed by the header's column names;
+// without one every row becomes a list of its fields. Fields are always
+// strings, and every row must have as many fields as the first.
+func parseCSVResponse(r io.Reader, block *CSVBlock) (any, error) {
+	reader := csv.NewReader(r)
+	header := true
+	if block != nil {
+		if block.Delimiter != "" {
+			reader.Comma, _ = utf8.DecodeRuneInString(block.Delimiter)
+		}
+		if block.Header != nil {
+			header = *block.Header
+		}
+	}
+
+	var columns []string
+	rows := []any{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !header {
+			fields := make([]any, len(record))
+			for i, field := range record {
+				fields[i] = field
+			}
+			rows = append(rows, fields)
+			continue
+		}
+
+		if columns == nil {
+			seen := make(map[string]bool, len(record))
+			for _, name := range record {
+				if seen[name] {
+					return nil, fmt.Errorf("duplicate column %q in header row", name)
+				}
+				seen[name] = true
+			}
+			columns = record
+			continue
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, name := range columns {
+			row[name] = record[i]
+		}
+		rows = append(rows, row)
+	}
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/infracollect/infracollect/internal/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestGetStep_HeadersFromStepResult runs a job whose second request
+// authenticates with a token returned by the first. Step attributes are
+// evaluated once the steps they reference have resolved, so headers and
+// params can use their results directly.
+func TestGetStep_HeadersFromStepResult(t *testing.T) {
+	var gotAuth, gotTenant string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login":
+			_, _ = w.Write([]byte(`{"token":"s3cr3t","tenant":"acme"}`))
+		case "/users":
+			gotAuth = r.Header.Get("Authorization")
+			gotTenant = r.URL.Query().Get("tenant")
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	src := fmt.Sprintf(`
+collector "http" "api" {
+  base_url = %q
+}
+
+step "http_get" "login" {
+  collector = collector.http.api
+  path      = "/login"
+}
+
+step "http_get" "users" {
+  collector = collector.http.api
+  path      = "/users"
+  headers = {
+    Authorization = "Bearer ${step.http_get.login.data.token}"
+  }
+  params = {
+    tenant = step.http_get.login.data.tenant
+  }
+}
+
+output {
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, server.URL, t.TempDir())
+
+	registry := engine.NewRegistry(zap.NewNop())
+	require.NoError(t, Register(registry))
+
+	tmpl, diags := runner.ParseJobTemplate([]byte(src), "headers.hcl")
+	require.False(t, diags.HasErrors(), diags.Error())
+	r, diags := runner.New(zap.NewNop(), tmpl, registry, nil)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	_, err := r.Run(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer s3cr3t", gotAuth)
+	assert.Equal(t, "acme", gotTenant)
+}
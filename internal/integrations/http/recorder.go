@@ -0,0 +1,181 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// recordedResponse is the on-disk shape of one recorded exchange. Body holds
+// the bytes exactly as received (still gzip-compressed when the server sent
+// Content-Encoding: gzip), so replay goes through the same decoding path.
+type recordedResponse struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// recordingTransport forwards requests to next and saves every response to
+// dir, keyed by request.
+type recordingTransport struct {
+	next http.RoundTripper
+	dir  string
+}
+
+// replayingTransport serves responses previously saved by recordingTransport
+// and never touches the network. A request with no recording is an error.
+type replayingTransport struct {
+	dir string
+}
+
+// recordingKey identifies a request by method, full URL (including the
+// query string) and body, so requests to the same URL with different
+// payloads are recorded apart. Headers are not part of the key: they carry
+// credentials and volatile values that would make recordings impossible to
+// share.
+func recordingKey(req *http.Request, body []byte) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, req.Method+" "+req.URL.String())
+	// Bodiless requests keep the key they had before bodies were hashed,
+	// so existing recordings still replay.
+	if len(body) > 0 {
+		_, _ = io.WriteString(h, "\n")
+		_, _ = h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func recordingPath(dir, key string) string {
+	return filepath.Join(dir, key+".json")
+}
+
+// readRequestBody returns the body of req, and the request to send on
+// with its body still unread. req itself is not modified.
+func readRequestBody(req *http.Request) ([]byte, *http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, req, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		defer func() { _ = rc.Close() }()
+		body, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		return body, req, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	return body, clone, nil
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, req, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response for recording: %w", err)
+	}
+
+	data, err := json.MarshalIndent(recordedResponse{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recording: %w", err)
+	}
+	if err := t.write(recordingKey(req, reqBody), data); err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// write stores a recording under key. It is written to a temporary file
+// and renamed, so a replay never reads a half-written recording.
+func (t *recordingTransport) write(key string, data []byte) error {
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create record_dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(t.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create recording: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	// CreateTemp uses 0600; recordings are meant to be shared.
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), recordingPath(t.dir, key)); err != nil {
+		return fmt.Errorf("failed to store recording: %w", err)
+	}
+	return nil
+}
+
+func (t *replayingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, req, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(recordingPath(t.dir, recordingKey(req, reqBody)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no recorded response for %s %s in replay_dir %s", req.Method, req.URL, t.dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	var rec recordedResponse
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to decode recording for %s %s: %w", req.Method, req.URL, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.StatusCode, http.StatusText(rec.StatusCode)),
+		StatusCode:    rec.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.Header,
+		Body:          io.NopCloser(bytes.NewReader(rec.Body)),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
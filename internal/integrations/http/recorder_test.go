@@ -0,0 +1,120 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector_RecordThenReplay(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"page":"` + r.URL.Query().Get("page") + `"}`))
+	}))
+	dir := t.TempDir()
+
+	resolve := func(c *Collector, page string) (any, error) {
+		step, err := NewGetStep(c, GetConfig{Path: "/users", Params: map[string]string{"page": page}})
+		require.NoError(t, err)
+		result, err := step.Resolve(t.Context())
+		return result.Data, err
+	}
+
+	recorder := newTestCollector(t, server, Config{RecordDir: dir})
+	data, err := resolve(recorder, "1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"page": "1"}, data)
+	assert.Equal(t, int32(1), hits.Load())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "one recording per request")
+
+	baseURL := server.URL
+	server.Close()
+
+	replayer, err := NewCollector(Config{BaseURL: baseURL, ReplayDir: dir})
+	require.NoError(t, err)
+
+	data, err = resolve(replayer.(*Collector), "1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"page": "1"}, data)
+	assert.Equal(t, int32(1), hits.Load(), "replay must not reach the network")
+
+	_, err = resolve(replayer.(*Collector), "2")
+	assert.ErrorContains(t, err, "no recorded response for GET "+baseURL+"/users?page=2")
+}
+
+func TestCollector_RecordsErrorResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("missing"))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	recorder := newTestCollector(t, server, Config{RecordDir: dir})
+	step, err := NewGetStep(recorder, GetConfig{Path: "/gone"})
+	require.NoError(t, err)
+	_, err = step.Resolve(t.Context())
+	require.ErrorContains(t, err, "404")
+
+	replayer, err := NewCollector(Config{BaseURL: server.URL, ReplayDir: dir})
+	require.NoError(t, err)
+	step, err = NewGetStep(replayer.(*Collector), GetConfig{Path: "/gone"})
+	require.NoError(t, err)
+	_, err = step.Resolve(t.Context())
+	assert.ErrorContains(t, err, "request failed with status 404: missing")
+}
+
+func TestRecordingTransport_KeysOnBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte("echo " + string(body)))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	post := func(client *http.Client, body string) string {
+		t.Helper()
+		// A reader without GetBody, as a RoundTripper may be given.
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, server.URL+"/search", io.NopCloser(strings.NewReader(body)))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		got, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(got)
+	}
+
+	recorder := &http.Client{Transport: &recordingTransport{next: http.DefaultTransport, dir: dir}}
+	assert.Equal(t, "echo a", post(recorder, "a"), "the body still reaches the server")
+	assert.Equal(t, "echo b", post(recorder, "b"))
+
+	recordings, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, recordings, 2, "one recording per body")
+	leftovers, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+
+	replayer := &http.Client{Transport: &replayingTransport{dir: dir}}
+	assert.Equal(t, "echo a", post(replayer, "a"))
+	assert.Equal(t, "echo b", post(replayer, "b"))
+}
+
+func TestNewCollector_RecordAndReplayExclusive(t *testing.T) {
+	_, err := NewCollector(Config{BaseURL: "https://example.com", RecordDir: "a", ReplayDir: "b"})
+	assert.ErrorContains(t, err, "record_dir and replay_dir are mutually exclusive")
+}
@@ -10,10 +10,16 @@ import (
 	"net/url"
 
 	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/ohler55/ojg/jp"
 )
 
 const (
 	GetStepKind = "http_get"
+
+	// Meta keys holding the response validators, sent back by conditional
+	// requests.
+	metaETag         = "etag"
+	metaLastModified = "last_modified"
 )
 
 type GetConfig struct {
@@ -21,18 +27,51 @@ type GetConfig struct {
 	Headers      map[string]string
 	Params       map[string]string
 	ResponseType string
+	// MaxResponseBytes caps the (decompressed) response body. Nil means
+	// unlimited.
+	MaxResponseBytes *int
+	// Select is a JSONPath expression applied to the parsed response. Nil
+	// or empty keeps the full response.
+	Select *string
+	// CSV configures parsing when ResponseType is "csv". Nil uses a comma
+	// delimiter and a header row.
+	CSV *CSVBlock
+	// Conditional makes Revalidate send a conditional request built from
+	// the previous result's etag and last_modified meta. Nil or false
+	// resolves again instead.
+	Conditional *bool
 }
 
 type getStep struct {
 	collector *Collector
 	config    GetConfig
+	selector  jp.Expr
 }
 
 func NewGetStep(collector *Collector, cfg GetConfig) (engine.Step, error) {
-	return &getStep{
+	if cfg.MaxResponseBytes != nil && *cfg.MaxResponseBytes <= 0 {
+		return nil, fmt.Errorf("max_response_bytes must be positive, got %d", *cfg.MaxResponseBytes)
+	}
+
+	if err := validateCSVBlock(cfg.ResponseType, cfg.CSV); err != nil {
+		return nil, err
+	}
+
+	step := &getStep{
 		collector: collector,
 		config:    cfg,
-	}, nil
+	}
+	if cfg.Select != nil && *cfg.Select != "" {
+		if cfg.ResponseType == "raw" {
+			return nil, fmt.Errorf("select cannot be used with response_type \"raw\"")
+		}
+		selector, err := jp.ParseString(*cfg.Select)
+		if err != nil {
+			return nil, fmt.Errorf("invalid select expression %q: %w", *cfg.Select, err)
+		}
+		step.selector = selector
+	}
+	return step, nil
 }
 
 func (s *getStep) Name() string {
@@ -44,41 +83,87 @@ func (s *getStep) Kind() string {
 }
 
 func (s *getStep) Resolve(ctx context.Context) (engine.Result, error) {
+	result, _, err := s.fetch(ctx, nil)
+	return result, err
+}
+
+// Revalidate implements engine.Revalidator. With conditional requests
+// enabled it sends the validators stored in previous.Meta and returns
+// previous when the server answers 304 Not Modified.
+func (s *getStep) Revalidate(ctx context.Context, previous engine.Result) (engine.Result, bool, error) {
+	if s.config.Conditional == nil || !*s.config.Conditional {
+		result, err := s.Resolve(ctx)
+		return result, false, err
+	}
+	return s.fetch(ctx, &previous)
+}
+
+// fetch performs the request. When previous is set, its etag and
+// last_modified meta are sent as validators and a 304 answer returns
+// previous with true.
+func (s *getStep) fetch(ctx context.Context, previous *engine.Result) (engine.Result, bool, error) {
 	reqURL, err := s.buildURL()
 	if err != nil {
-		return engine.Result{}, fmt.Errorf("failed to build request URL: %w", err)
+		return engine.Result{}, false, fmt.Errorf("failed to build request URL: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
 	if err != nil {
-		return engine.Result{}, fmt.Errorf("failed to create request: %w", err)
+		return engine.Result{}, false, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	for k, v := range s.config.Headers {
 		req.Header.Set(k, v)
 	}
+	conditional := false
+	if previous != nil {
+		if etag := previous.Meta[metaETag]; etag != "" {
+			req.Header.Set("If-None-Match", etag)
+			conditional = true
+		}
+		if lastModified := previous.Meta[metaLastModified]; lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+			conditional = true
+		}
+	}
 
 	resp, err := s.collector.Do(req)
 	if err != nil {
-		return engine.Result{}, fmt.Errorf("failed to execute request: %w", err)
+		return engine.Result{}, false, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if conditional && resp.StatusCode == http.StatusNotModified {
+		return *previous, true, nil
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return engine.Result{}, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+		return engine.Result{}, false, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
 	data, err := s.processResponse(resp.Header.Get("Content-Encoding"), resp.Body)
 	if err != nil {
-		return engine.Result{}, fmt.Errorf("failed to process response: %w", err)
+		return engine.Result{}, false, fmt.Errorf("failed to process response: %w", err)
 	}
 
 	meta := map[string]string{
 		"url": reqURL.String(),
 	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		meta[metaETag] = etag
+	}
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+		meta[metaLastModified] = lastModified
+	}
+	if s.selector != nil {
+		data, err = selectData(s.selector, data)
+		if err != nil {
+			return engine.Result{}, false, err
+		}
+		meta["select"] = s.selector.String()
+	}
 
-	return engine.Result{Data: data, Meta: meta}, nil
+	return engine.Result{Data: data, Meta: meta}, false, nil
 }
 
 func (s *getStep) buildURL() (*url.URL, error) {
@@ -117,6 +202,51 @@ func (s *getStep) processResponse(contentEncoding string, body io.ReadCloser) (a
 		body = gzipReader
 	}
 
+	var limited *limitedReader
+	if s.config.MaxResponseBytes != nil {
+		limited = &limitedReader{r: body, limit: int64(*s.config.MaxResponseBytes)}
+		body = io.NopCloser(limited)
+	}
+
+	data, err := decodeResponse(responseType, s.config.CSV, body)
+	if err != nil && limited != nil && limited.exceeded() {
+		// Decoders wrap or replace the read error; report the limit itself.
+		return nil, limited.err()
+	}
+	return data, err
+}
+
+// selectData applies a JSONPath selector. A definite path (only child names
+// and indexes) yields the one value it points at, and is an error when it
+// matches nothing, since the response lacks what the job expects. Any other
+// path (wildcards, slices, filters, unions, recursive descent) yields the
+// list of matches, empty when nothing matches.
+func selectData(selector jp.Expr, data any) (any, error) {
+	matches := selector.Get(data)
+	if !isDefinitePath(selector) {
+		if matches == nil {
+			return []any{}, nil
+		}
+		return matches, nil
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("select %s matched nothing in the response", selector)
+	}
+	return matches[0], nil
+}
+
+func isDefinitePath(selector jp.Expr) bool {
+	for _, frag := range selector {
+		switch frag.(type) {
+		case jp.Root, jp.At, jp.Bracket, jp.Child, jp.Nth:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func decodeResponse(responseType string, csvBlock *CSVBlock, body io.Reader) (any, error) {
 	switch responseType {
 	case "json":
 		var data any
@@ -124,6 +254,18 @@ func (s *getStep) processResponse(contentEncoding string, body io.ReadCloser) (a
 			return nil, fmt.Errorf("failed to parse JSON response: %w", err)
 		}
 		return data, nil
+	case "xml":
+		data, err := parseXMLResponse(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XML response: %w", err)
+		}
+		return data, nil
+	case "csv":
+		data, err := parseCSVResponse(body, csvBlock)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV response: %w", err)
+		}
+		return data, nil
 	case "raw":
 		raw, err := io.ReadAll(body)
 		if err != nil {
@@ -134,3 +276,33 @@ func (s *getStep) processResponse(contentEncoding string, body io.ReadCloser) (a
 		return nil, fmt.Errorf("unknown response_type: %s", responseType)
 	}
 }
+
+// limitedReader fails once more than limit bytes have been read, so an
+// oversized body is an error rather than a silently truncated result.
+type limitedReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.exceeded() {
+		return 0, l.err()
+	}
+	// Allow one byte past the limit so a body of exactly limit bytes passes.
+	if rest := l.limit + 1 - l.n; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.exceeded() {
+		return n - 1, l.err()
+	}
+	return n, err
+}
+
+func (l *limitedReader) exceeded() bool { return l.n > l.limit }
+
+func (l *limitedReader) err() error {
+	return fmt.Errorf("response body exceeds max_response_bytes of %d bytes", l.limit)
+}
@@ -1,10 +1,17 @@
 package http
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/infracollect/infracollect/internal/buildinfo"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -124,6 +131,7 @@ func TestGetStep_Resolve(t *testing.T) {
 				validateReq: func(t *testing.T, req *http.Request) {
 					assert.Equal(t, "custom-value", req.Header.Get("X-Custom-Header"))
 					assert.Equal(t, "Bearer token123", req.Header.Get("Authorization"))
+					assert.Equal(t, buildinfo.UserAgent(), req.Header.Get("User-Agent"))
 				},
 				validateMeta: func(t *testing.T, serverURL string, meta map[string]string) {
 					assert.Equal(t, serverURL+"/test", meta["url"])
@@ -202,6 +210,179 @@ func TestGetStep_Resolve(t *testing.T) {
 				response:  "not valid json",
 				expectErr: "failed to parse JSON",
 			},
+			{
+				name:   "xml",
+				config: GetConfig{Path: "/test", ResponseType: "xml"},
+				response: `<?xml version="1.0"?>
+<users xmlns="urn:example" count="2">
+  <user id="1"><name>alice</name></user>
+  <user id="2"><name>bob</name><note>admin</note></user>
+  <empty/>
+</users>`,
+				contentType: "application/xml",
+				expected: map[string]any{
+					"users": map[string]any{
+						"@count": "2",
+						"user": []any{
+							map[string]any{"@id": "1", "name": "alice"},
+							map[string]any{"@id": "2", "name": "bob", "note": "admin"},
+						},
+						"empty": "",
+					},
+				},
+			},
+			{
+				name:        "xml mixed text and attributes",
+				config:      GetConfig{Path: "/test", ResponseType: "xml"},
+				response:    `<price currency="EUR"> 12.50 </price>`,
+				contentType: "application/xml",
+				expected:    map[string]any{"price": map[string]any{"@currency": "EUR", "#text": "12.50"}},
+			},
+			{
+				name:        "invalid xml",
+				config:      GetConfig{Path: "/test", ResponseType: "xml"},
+				response:    "<open>",
+				contentType: "application/xml",
+				expectErr:   "failed to parse XML",
+			},
+			{
+				name:        "xml without root element",
+				config:      GetConfig{Path: "/test", ResponseType: "xml"},
+				response:    "",
+				contentType: "application/xml",
+				expectErr:   "document has no root element",
+			},
+		})
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		runGetStepTests(t, []getStepTest{
+			{
+				name:        "header row keys each row",
+				config:      GetConfig{Path: "/test", ResponseType: "csv"},
+				response:    "name,zone\nweb,a\n\"db, primary\",b\n",
+				contentType: "text/csv",
+				expected: []any{
+					map[string]any{"name": "web", "zone": "a"},
+					map[string]any{"name": "db, primary", "zone": "b"},
+				},
+			},
+			{
+				name:        "without header rows are lists",
+				config:      GetConfig{Path: "/test", ResponseType: "csv", CSV: &CSVBlock{Header: lo.ToPtr(false)}},
+				response:    "web,a\ndb,b\n",
+				contentType: "text/csv",
+				expected:    []any{[]any{"web", "a"}, []any{"db", "b"}},
+			},
+			{
+				name:        "custom delimiter",
+				config:      GetConfig{Path: "/test", ResponseType: "csv", CSV: &CSVBlock{Delimiter: ";"}},
+				response:    "name;zone\nweb;a\n",
+				contentType: "text/csv",
+				expected:    []any{map[string]any{"name": "web", "zone": "a"}},
+			},
+			{
+				name:        "empty body",
+				config:      GetConfig{Path: "/test", ResponseType: "csv"},
+				response:    "",
+				contentType: "text/csv",
+				expected:    []any{},
+			},
+			{
+				name:        "select applies to csv rows",
+				config:      GetConfig{Path: "/test", ResponseType: "csv", Select: lo.ToPtr("$[*].name")},
+				response:    "name,zone\nweb,a\ndb,b\n",
+				contentType: "text/csv",
+				expected:    []any{"web", "db"},
+			},
+			{
+				name:        "wrong number of fields",
+				config:      GetConfig{Path: "/test", ResponseType: "csv"},
+				response:    "name,zone\nweb,a\ndb\n",
+				contentType: "text/csv",
+				expectErr:   "failed to parse CSV response: record on line 3: wrong number of fields",
+			},
+			{
+				name:        "duplicate header column",
+				config:      GetConfig{Path: "/test", ResponseType: "csv"},
+				response:    "name,name\nweb,a\n",
+				contentType: "text/csv",
+				expectErr:   `duplicate column "name" in header row`,
+			},
+		})
+	})
+
+	t.Run("selection", func(t *testing.T) {
+		const body = `{"total": 2, "items": [{"name": "a", "tags": ["x"]}, {"name": "b", "tags": []}]}`
+		runGetStepTests(t, []getStepTest{
+			{
+				name:     "definite path returns the subtree",
+				config:   GetConfig{Path: "/test", Select: lo.ToPtr("$.items[0]")},
+				response: body,
+				expected: map[string]any{"name": "a", "tags": []any{"x"}},
+				validateMeta: func(t *testing.T, _ string, meta map[string]string) {
+					assert.Equal(t, "$.items[0]", meta["select"])
+				},
+			},
+			{
+				name:     "wildcard returns every match",
+				config:   GetConfig{Path: "/test", Select: lo.ToPtr("$.items[*].name")},
+				response: body,
+				expected: []any{"a", "b"},
+			},
+			{
+				name:     "wildcard matching nothing returns an empty list",
+				config:   GetConfig{Path: "/test", Select: lo.ToPtr("$.missing[*]")},
+				response: body,
+				expected: []any{},
+			},
+			{
+				name:     "definite path to a null value returns null",
+				config:   GetConfig{Path: "/test", Select: lo.ToPtr("$.owner")},
+				response: `{"owner": null}`,
+				expected: nil,
+			},
+			{
+				name:     "empty selector keeps the full response",
+				config:   GetConfig{Path: "/test", Select: lo.ToPtr("")},
+				response: `{"ok": true}`,
+				expected: map[string]any{"ok": true},
+			},
+			{
+				name:     "selects into xml responses",
+				config:   GetConfig{Path: "/test", ResponseType: "xml", Select: lo.ToPtr("$.users.user")},
+				response: `<users><user>alice</user><user>bob</user></users>`,
+				expected: []any{"alice", "bob"},
+			},
+		})
+	})
+
+	t.Run("size limits", func(t *testing.T) {
+		runGetStepTests(t, []getStepTest{
+			{
+				name:     "body within limit",
+				config:   GetConfig{Path: "/test", MaxResponseBytes: lo.ToPtr(16)},
+				response: `{"ok": true}`,
+				expected: map[string]any{"ok": true},
+			},
+			{
+				name:     "body exactly at limit",
+				config:   GetConfig{Path: "/test", ResponseType: "raw", MaxResponseBytes: lo.ToPtr(5)},
+				response: "hello",
+				expected: "hello",
+			},
+			{
+				name:      "json body over limit",
+				config:    GetConfig{Path: "/test", MaxResponseBytes: lo.ToPtr(8)},
+				response:  `{"items": [1, 2, 3]}`,
+				expectErr: "response body exceeds max_response_bytes of 8 bytes",
+			},
+			{
+				name:      "raw body over limit",
+				config:    GetConfig{Path: "/test", ResponseType: "raw", MaxResponseBytes: lo.ToPtr(4)},
+				response:  "hello",
+				expectErr: "response body exceeds max_response_bytes of 4 bytes",
+			},
 		})
 	})
 
@@ -231,3 +412,146 @@ func TestGetStep_Resolve(t *testing.T) {
 		})
 	})
 }
+
+func TestNewGetStep_RejectsNonPositiveLimit(t *testing.T) {
+	_, err := NewGetStep(nil, GetConfig{Path: "/test", MaxResponseBytes: lo.ToPtr(0)})
+	assert.ErrorContains(t, err, "max_response_bytes must be positive")
+}
+
+func TestGetStep_SelectDefinitePathMissing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"items": []}`))
+	}))
+	defer server.Close()
+
+	collector, err := NewCollector(Config{BaseURL: server.URL}, WithHttpClient(server.Client()))
+	require.NoError(t, err)
+	step, err := NewGetStep(collector.(*Collector), GetConfig{Path: "/", Select: lo.ToPtr("$.items[0].name")})
+	require.NoError(t, err)
+
+	_, err = step.Resolve(t.Context())
+	assert.EqualError(t, err, "select $.items[0].name matched nothing in the response")
+}
+
+func TestNewGetStep_SelectValidation(t *testing.T) {
+	_, err := NewGetStep(nil, GetConfig{Path: "/", Select: lo.ToPtr("$.[")})
+	assert.ErrorContains(t, err, "invalid select expression")
+
+	_, err = NewGetStep(nil, GetConfig{Path: "/", ResponseType: "raw", Select: lo.ToPtr("$.a")})
+	assert.ErrorContains(t, err, `select cannot be used with response_type "raw"`)
+}
+
+func TestNewGetStep_CSVValidation(t *testing.T) {
+	_, err := NewGetStep(nil, GetConfig{Path: "/", CSV: &CSVBlock{Delimiter: ";"}})
+	assert.ErrorContains(t, err, `csv block requires response_type "csv"`)
+
+	_, err = NewGetStep(nil, GetConfig{Path: "/", ResponseType: "csv", CSV: &CSVBlock{Delimiter: "::"}})
+	assert.ErrorContains(t, err, "csv delimiter must be a single character")
+
+	_, err = NewGetStep(nil, GetConfig{Path: "/", ResponseType: "csv", CSV: &CSVBlock{Delimiter: `"`}})
+	assert.ErrorContains(t, err, "invalid csv delimiter")
+}
+
+func TestGetStep_Revalidate(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("If-None-Match") == etag && r.URL.Query().Get("changed") == "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = w.Write([]byte(`{"version": 1}`))
+	}))
+	defer server.Close()
+
+	collector, err := NewCollector(Config{BaseURL: server.URL}, WithHttpClient(server.Client()))
+	require.NoError(t, err)
+	newStep := func(cfg GetConfig) *getStep {
+		step, err := NewGetStep(collector.(*Collector), cfg)
+		require.NoError(t, err)
+		return step.(*getStep)
+	}
+
+	previous, err := newStep(GetConfig{Path: "/"}).Resolve(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, etag, previous.Meta["etag"])
+	assert.Equal(t, lastModified, previous.Meta["last_modified"])
+
+	t.Run("not modified returns the previous result", func(t *testing.T) {
+		requests = nil
+		result, current, err := newStep(GetConfig{Path: "/", Conditional: lo.ToPtr(true)}).Revalidate(t.Context(), previous)
+		require.NoError(t, err)
+		assert.True(t, current)
+		assert.Equal(t, previous, result)
+		require.Len(t, requests, 1)
+		assert.Equal(t, etag, requests[0].Header.Get("If-None-Match"))
+		assert.Equal(t, lastModified, requests[0].Header.Get("If-Modified-Since"))
+	})
+
+	t.Run("modified resource is fetched again", func(t *testing.T) {
+		result, current, err := newStep(GetConfig{
+			Path:        "/",
+			Params:      map[string]string{"changed": "1"},
+			Conditional: lo.ToPtr(true),
+		}).Revalidate(t.Context(), previous)
+		require.NoError(t, err)
+		assert.False(t, current)
+		assert.Equal(t, map[string]any{"version": float64(1)}, result.Data)
+	})
+
+	t.Run("without conditional the validators are not sent", func(t *testing.T) {
+		requests = nil
+		result, current, err := newStep(GetConfig{Path: "/"}).Revalidate(t.Context(), previous)
+		require.NoError(t, err)
+		assert.False(t, current)
+		assert.Equal(t, map[string]any{"version": float64(1)}, result.Data)
+		require.Len(t, requests, 1)
+		assert.Empty(t, requests[0].Header.Get("If-None-Match"))
+	})
+
+	t.Run("previous result without validators", func(t *testing.T) {
+		requests = nil
+		_, current, err := newStep(GetConfig{Path: "/", Conditional: lo.ToPtr(true)}).Revalidate(t.Context(), engine.Result{})
+		require.NoError(t, err)
+		assert.False(t, current)
+		require.Len(t, requests, 1)
+		assert.Empty(t, requests[0].Header.Get("If-None-Match"))
+	})
+}
+
+func TestGetStep_StepsShareCollectorRateLimit(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	collector := newTestCollector(t, server, Config{
+		RateLimit: &RateLimitConfig{RequestsPerSecond: 20, Burst: 1},
+	})
+
+	const steps = 4
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range steps {
+		step, err := NewGetStep(collector, GetConfig{Path: fmt.Sprintf("/items/%d", i)})
+		require.NoError(t, err)
+		wg.Go(func() {
+			_, err := step.Resolve(t.Context())
+			assert.NoError(t, err)
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(steps), hits.Load())
+	// One limiter for all steps: with burst 1 at 20 rps, three of the four
+	// requests must wait ~50ms each. Per-step limiters would not wait at all.
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	xmlAttributePrefix = "@"
+	xmlTextKey         = "#text"
+)
+
+// xmlNode accumulates one element while the document is being decoded.
+type xmlNode struct {
+	name     string
+	fields   map[string]any
+	text     strings.Builder
+	children bool
+}
+
+// parseXMLResponse decodes an XML document into a nested map keyed by the
+// root element's name. Elements are mapped as follows:
+//
+//   - an element with neither attributes nor child elements becomes its
+//     (whitespace-trimmed) text as a string;
+//   - otherwise it becomes an object: attributes are stored under "@name",
+//     child elements under their name, and non-blank text under "#text";
+//   - a child element name that repeats becomes an array, in document order.
+//
+// Namespace prefixes are dropped; only local names are kept.
+func parseXMLResponse(r io.Reader) (any, error) {
+	decoder := xml.NewDecoder(r)
+
+	var (
+		stack []*xmlNode
+		root  map[string]any
+	)
+	for {
+		tok, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root != nil {
+				return nil, fmt.Errorf("multiple root elements")
+			}
+			node := &xmlNode{name: t.Name.Local, fields: make(map[string]any)}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				node.fields[xmlAttributePrefix+attr.Name.Local] = attr.Value
+			}
+			if len(stack) > 0 {
+				stack[len(stack)-1].children = true
+			}
+			stack = append(stack, node)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		case xml.EndElement:
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			value := node.value()
+			if len(stack) == 0 {
+				root = map[string]any{node.name: value}
+				continue
+			}
+			stack[len(stack)-1].addChild(node.name, value)
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("document has no root element")
+	}
+	return root, nil
+}
+
+func (n *xmlNode) value() any {
+	text := strings.TrimSpace(n.text.String())
+	if len(n.fields) == 0 && !n.children {
+		return text
+	}
+	if text != "" {
+		n.fields[xmlTextKey] = text
+	}
+	return n.fields
+}
+
+func (n *xmlNode) addChild(name string, value any) {
+	existing, ok := n.fields[name]
+	if !ok {
+		n.fields[name] = value
+		return
+	}
+	if list, ok := existing.([]any); ok {
+		n.fields[name] = append(list, value)
+		return
+	}
+	n.fields[name] = []any{existing, value}
+}
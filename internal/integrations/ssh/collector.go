@@ -0,0 +1,106 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/infracollect/infracollect/internal/sshclient"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	CollectorKind  = "ssh"
+	DefaultPort    = sshclient.DefaultPort
+	DefaultTimeout = sshclient.DefaultTimeout
+)
+
+// Config is the connection configuration; see sshclient.Config.
+type Config = sshclient.Config
+
+// Collector holds one SSH connection, opened in Start and shared by every
+// ssh_exec step bound to it; each step runs in its own session.
+type Collector struct {
+	cfg    Config
+	client *ssh.Client
+}
+
+func NewCollector(cfg Config) (engine.Collector, error) {
+	cfg, err := cfg.WithDefaults()
+	if err != nil {
+		return nil, err
+	}
+	return &Collector{cfg: cfg}, nil
+}
+
+func (c *Collector) Name() string {
+	return fmt.Sprintf("%s(%s@%s)", CollectorKind, c.cfg.User, c.cfg.Address())
+}
+
+func (c *Collector) Kind() string {
+	return CollectorKind
+}
+
+// Start dials the host and authenticates. It is idempotent.
+func (c *Collector) Start(ctx context.Context) error {
+	if c.client != nil {
+		return nil
+	}
+
+	client, err := sshclient.Dial(ctx, c.cfg)
+	if err != nil {
+		return err
+	}
+	c.client = client
+	return nil
+}
+
+// Run executes command in a new session and returns its stdout. A non-zero
+// exit status is an error carrying the command's stderr. Cancelling ctx
+// closes the session.
+func (c *Collector) Run(ctx context.Context, command string) ([]byte, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("%w: %s", engine.ErrCollectorNotStarted, c.Name())
+	}
+
+	session, err := c.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ssh session: %w", err)
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	done := make(chan error, 1)
+	go func() { done <- session.Run(command) }()
+
+	select {
+	case <-ctx.Done():
+		_ = session.Close()
+		return nil, fmt.Errorf("command interrupted: %w", ctx.Err())
+	case err := <-done:
+		if err != nil {
+			stderrStr := strings.TrimSpace(stderr.String())
+			var exitErr *ssh.ExitError
+			if errors.As(err, &exitErr) && stderrStr != "" {
+				return nil, fmt.Errorf("command failed: %w: %s", err, stderrStr)
+			}
+			return nil, fmt.Errorf("command failed: %w", err)
+		}
+	}
+	return stdout.Bytes(), nil
+}
+
+func (c *Collector) Close(context.Context) error {
+	if c.client == nil {
+		return nil
+	}
+	client := c.client
+	c.client = nil
+	return client.Close()
+}
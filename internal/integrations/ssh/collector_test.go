@@ -0,0 +1,293 @@
+package ssh
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+type cannedCommand struct {
+	stdout string
+	stderr string
+	status uint32
+}
+
+// testServer is a minimal in-process SSH server that accepts the password
+// "secret" or the generated client key and answers exec requests from a
+// table of canned commands.
+type testServer struct {
+	addr     *net.TCPAddr
+	hostKey  ssh.PublicKey
+	keyPath  string
+	commands map[string]cannedCommand
+}
+
+func newTestServer(t *testing.T, commands map[string]cannedCommand) *testServer {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	require.NoError(t, err)
+
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	authorized, err := ssh.NewPublicKey(clientPub)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600))
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	s := &testServer{
+		addr:     listener.Addr().(*net.TCPAddr),
+		hostKey:  hostSigner.PublicKey(),
+		keyPath:  keyPath,
+		commands: commands,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer channel.Close()
+			for req := range requests {
+				if req.Type != "exec" {
+					_ = req.Reply(false, nil)
+					continue
+				}
+				var payload struct{ Command string }
+				_ = ssh.Unmarshal(req.Payload, &payload)
+				_ = req.Reply(true, nil)
+
+				canned, ok := s.commands[payload.Command]
+				if !ok {
+					canned = cannedCommand{stderr: "command not found", status: 127}
+				}
+				_, _ = channel.Write([]byte(canned.stdout))
+				_, _ = channel.Stderr().Write([]byte(canned.stderr))
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{canned.status}))
+				return
+			}
+		}()
+	}
+}
+
+func (s *testServer) knownHosts(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(s.addr.String())}, s.hostKey)
+	require.NoError(t, os.WriteFile(path, []byte(line+"\n"), 0o600))
+	return path
+}
+
+func (s *testServer) config() Config {
+	return Config{
+		Host:                  s.addr.IP.String(),
+		Port:                  s.addr.Port,
+		User:                  "collector",
+		Password:              "secret",
+		InsecureIgnoreHostKey: true,
+	}
+}
+
+func startCollector(t *testing.T, cfg Config) *Collector {
+	t.Helper()
+	c, err := NewCollector(cfg)
+	require.NoError(t, err)
+	require.NoError(t, c.Start(t.Context()))
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+	return c.(*Collector)
+}
+
+func TestNewCollector_Validation(t *testing.T) {
+	cases := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "missing host", cfg: Config{User: "u", Password: "p"}, wantErr: "host is required"},
+		{name: "missing user", cfg: Config{Host: "h", Password: "p"}, wantErr: "user is required"},
+		{name: "missing auth", cfg: Config{Host: "h", User: "u"}, wantErr: "either private_key_path or password is required"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewCollector(tc.cfg)
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+
+	c, err := NewCollector(Config{Host: "h", User: "u", Password: "p"})
+	require.NoError(t, err)
+	assert.Equal(t, "ssh(u@h:22)", c.Name())
+}
+
+func TestCollector_Start(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	t.Run("private key", func(t *testing.T) {
+		cfg := server.config()
+		cfg.Password = ""
+		cfg.PrivateKeyPath = server.keyPath
+		startCollector(t, cfg)
+	})
+
+	t.Run("known host", func(t *testing.T) {
+		cfg := server.config()
+		cfg.InsecureIgnoreHostKey = false
+		cfg.KnownHostsPath = server.knownHosts(t)
+		startCollector(t, cfg)
+	})
+
+	t.Run("unknown host is rejected", func(t *testing.T) {
+		cfg := server.config()
+		cfg.InsecureIgnoreHostKey = false
+		cfg.KnownHostsPath = filepath.Join(t.TempDir(), "known_hosts")
+		require.NoError(t, os.WriteFile(cfg.KnownHostsPath, nil, 0o600))
+
+		c, err := NewCollector(cfg)
+		require.NoError(t, err)
+		err = c.Start(t.Context())
+		assert.ErrorContains(t, err, "key is unknown")
+	})
+
+	t.Run("missing known hosts file", func(t *testing.T) {
+		cfg := server.config()
+		cfg.InsecureIgnoreHostKey = false
+		cfg.KnownHostsPath = filepath.Join(t.TempDir(), "absent")
+
+		c, err := NewCollector(cfg)
+		require.NoError(t, err)
+		assert.ErrorContains(t, c.Start(t.Context()), "failed to load known hosts")
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		cfg := server.config()
+		cfg.Password = "nope"
+
+		c, err := NewCollector(cfg)
+		require.NoError(t, err)
+		assert.ErrorContains(t, c.Start(t.Context()), "failed to establish ssh connection")
+	})
+}
+
+func TestExecStep_Resolve(t *testing.T) {
+	server := newTestServer(t, map[string]cannedCommand{
+		"uptime --json": {stdout: `{"up": 42}`},
+		"hostname":      {stdout: "db1\n"},
+		"false":         {stderr: "nope", status: 1},
+	})
+	collector := startCollector(t, server.config())
+	addr := net.JoinHostPort(server.addr.IP.String(), strconv.Itoa(server.addr.Port))
+
+	t.Run("json", func(t *testing.T) {
+		step, err := NewExecStep(collector, ExecConfig{Command: "uptime --json"})
+		require.NoError(t, err)
+
+		result, err := step.Resolve(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"up": float64(42)}, result.Data)
+		assert.Equal(t, map[string]string{
+			"ssh_host":    addr,
+			"ssh_command": "uptime --json",
+			"ssh_format":  "json",
+		}, result.Meta)
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		step, err := NewExecStep(collector, ExecConfig{Command: "hostname", Format: "raw"})
+		require.NoError(t, err)
+
+		result, err := step.Resolve(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"output": base64.StdEncoding.EncodeToString([]byte("db1\n")),
+		}, result.Data)
+	})
+
+	t.Run("non-zero exit", func(t *testing.T) {
+		step, err := NewExecStep(collector, ExecConfig{Command: "false"})
+		require.NoError(t, err)
+
+		_, err = step.Resolve(t.Context())
+		assert.ErrorContains(t, err, "command failed")
+		assert.ErrorContains(t, err, "nope")
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		step, err := NewExecStep(collector, ExecConfig{Command: "hostname"})
+		require.NoError(t, err)
+
+		_, err = step.Resolve(t.Context())
+		assert.ErrorContains(t, err, "failed to parse output as JSON")
+	})
+}
+
+func TestNewExecStep_Validation(t *testing.T) {
+	_, err := NewExecStep(nil, ExecConfig{})
+	assert.ErrorContains(t, err, "command is required")
+
+	_, err = NewExecStep(nil, ExecConfig{Command: "ls", Format: "yaml"})
+	assert.ErrorContains(t, err, `unsupported format "yaml"`)
+}
+
+func TestCollector_RunBeforeStart(t *testing.T) {
+	c, err := NewCollector(Config{Host: "h", User: "u", Password: "p"})
+	require.NoError(t, err)
+
+	_, err = c.(*Collector).Run(t.Context(), "ls")
+	assert.ErrorIs(t, err, engine.ErrCollectorNotStarted)
+}
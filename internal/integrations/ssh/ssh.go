@@ -0,0 +1,86 @@
+package ssh
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+// CollectorConfig is the HCL-level shape of a `collector "ssh" "<id>" { ... }` block.
+//
+//	collector "ssh" "db1" {
+//	  host             = "db1.internal"
+//	  user             = "collector"
+//	  private_key_path = "~/.ssh/id_ed25519"
+//	}
+//
+// Host keys are checked against known_hosts_path (default
+// ~/.ssh/known_hosts); insecure_ignore_host_key disables the check.
+type CollectorConfig struct {
+	Host                  string  `hcl:"host"`
+	Port                  int     `hcl:"port,optional"`
+	User                  string  `hcl:"user"`
+	PrivateKeyPath        string  `hcl:"private_key_path,optional"`
+	Password              string  `hcl:"password,optional"`
+	KnownHostsPath        string  `hcl:"known_hosts_path,optional"`
+	InsecureIgnoreHostKey bool    `hcl:"insecure_ignore_host_key,optional"`
+	Timeout               *string `hcl:"timeout,optional"`
+}
+
+// ExecStepConfig is the HCL-level shape of a `step "ssh_exec" "<id>" { ... }` block.
+// Format is "json" (default) or "raw", matching the local exec step.
+type ExecStepConfig struct {
+	Command string  `hcl:"command"`
+	Format  *string `hcl:"format,optional"`
+}
+
+func Register(registry *engine.Registry) error {
+	if err := engine.RegisterTypedCollector(registry, CollectorKind, newCollector); err != nil {
+		return err
+	}
+
+	return registry.RegisterSteps(
+		engine.NewTypedStepDescriptor(ExecStepKind, CollectorKind, newExecStep),
+	)
+}
+
+func newCollector(
+	_ *engine.RegistryHelper,
+	_ *hcl.EvalContext,
+	cfg CollectorConfig,
+) (engine.Collector, error) {
+	c := Config{
+		Host:                  cfg.Host,
+		Port:                  cfg.Port,
+		User:                  cfg.User,
+		PrivateKeyPath:        cfg.PrivateKeyPath,
+		Password:              cfg.Password,
+		KnownHostsPath:        cfg.KnownHostsPath,
+		InsecureIgnoreHostKey: cfg.InsecureIgnoreHostKey,
+	}
+
+	if cfg.Timeout != nil {
+		timeout, err := engine.ParseDuration(*cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		c.Timeout = timeout
+	}
+
+	return NewCollector(c)
+}
+
+func newExecStep(
+	_ *engine.RegistryHelper,
+	_ string,
+	collector *Collector,
+	_ *hcl.EvalContext,
+	cfg ExecStepConfig,
+) (engine.Step, error) {
+	c := ExecConfig{Command: cfg.Command}
+	if cfg.Format != nil {
+		c.Format = *cfg.Format
+	}
+	return NewExecStep(collector, c)
+}
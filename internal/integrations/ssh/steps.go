@@ -0,0 +1,77 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+const (
+	ExecStepKind = "ssh_exec"
+
+	defaultFormat = "json"
+)
+
+type ExecConfig struct {
+	Command string
+	Format  string
+}
+
+type execStep struct {
+	collector *Collector
+	config    ExecConfig
+}
+
+func NewExecStep(collector *Collector, cfg ExecConfig) (engine.Step, error) {
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	if cfg.Format == "" {
+		cfg.Format = defaultFormat
+	}
+	if cfg.Format != "json" && cfg.Format != "raw" {
+		return nil, fmt.Errorf("unsupported format %q (known: json, raw)", cfg.Format)
+	}
+	return &execStep{collector: collector, config: cfg}, nil
+}
+
+func (s *execStep) Name() string {
+	return fmt.Sprintf("%s(%s)", ExecStepKind, s.config.Command)
+}
+
+func (s *execStep) Kind() string {
+	return ExecStepKind
+}
+
+// Resolve runs the command on the collector's host. The result mirrors the
+// local exec step: parsed JSON for format "json", or the base64-encoded
+// stdout under "output" for format "raw".
+func (s *execStep) Resolve(ctx context.Context) (engine.Result, error) {
+	stdout, err := s.collector.Run(ctx, s.config.Command)
+	if err != nil {
+		return engine.Result{}, err
+	}
+
+	meta := map[string]string{
+		"ssh_host":    s.collector.cfg.Address(),
+		"ssh_command": s.config.Command,
+		"ssh_format":  s.config.Format,
+	}
+
+	if s.config.Format == "json" {
+		var parsed any
+		if err := json.NewDecoder(bytes.NewReader(stdout)).Decode(&parsed); err != nil {
+			return engine.Result{}, fmt.Errorf("failed to parse output as JSON: %w", err)
+		}
+		return engine.Result{Data: parsed, Meta: meta}, nil
+	}
+
+	return engine.Result{
+		Data: map[string]any{"output": base64.StdEncoding.EncodeToString(stdout)},
+		Meta: meta,
+	}, nil
+}
@@ -2,12 +2,17 @@ package terraform
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
+	goversion "github.com/hashicorp/go-version"
 	tfaddr "github.com/hashicorp/terraform-registry-address"
 	"github.com/infracollect/infracollect/internal/engine"
 	tfclient "github.com/infracollect/tf-data-client"
+	"github.com/infracollect/tf-data-client/registry"
 )
 
 const (
@@ -15,22 +20,62 @@ const (
 )
 
 // Client is an interface for creating and managing Terraform providers.
+// Close stops every provider the client still runs.
 type Client interface {
 	CreateProvider(ctx context.Context, config tfclient.ProviderConfig) (tfclient.Provider, error)
 	StopProvider(ctx context.Context, config tfclient.ProviderConfig) error
+	Close() error
+}
+
+// borrowedClient keeps the pool from closing a client its caller owns.
+type borrowedClient struct{ Client }
+
+func (borrowedClient) Close() error { return nil }
+
+// VersionLister lists the published versions of a provider.
+type VersionLister interface {
+	GetVersions(ctx context.Context, namespace, name string) ([]registry.VersionInfo, error)
 }
 
 type Config struct {
 	Provider string
-	Version  string
-	Args     map[string]any
+	// Version is an exact version ("5.0.0") or a constraint ("~> 5.0"). A
+	// constraint is resolved to the newest matching version published in
+	// Versions when the collector starts.
+	Version string
+	Args    map[string]any
+	// NoCache disables the per-collector data source read cache, so every
+	// step queries the provider even when an identical read already ran.
+	NoCache bool
+	// Versions lists provider versions when resolving a constraint. Nil
+	// selects the public Terraform registry.
+	Versions VersionLister
+	// Pool shares the provider process with other collectors of the same
+	// provider, version and Args. Nil gives the collector a process of its
+	// own, launched through the client passed to NewCollector.
+	Pool *ProviderPool
+	// MaxStateBytes fails a data source read whose state is larger, measured
+	// as JSON. Zero means unlimited.
+	MaxStateBytes int64
 }
 
 type Collector struct {
 	providerConfig tfclient.ProviderConfig
 	provider       tfclient.Provider
 	args           map[string]any
-	client         Client
+	pool           *ProviderPool
+	versions       VersionLister
+
+	// constraint is the version constraint Start resolves into
+	// providerConfig.Version, nil once resolved or for an exact version.
+	constraint    goversion.Constraints
+	rawConstraint string
+
+	maxStateBytes int64
+
+	noCache bool
+	cacheMu sync.Mutex
+	cache   map[string]map[string]any // keyed by readCacheKey
 }
 
 func NewCollector(client Client, cfg Config) (engine.Collector, error) {
@@ -39,7 +84,19 @@ func NewCollector(client Client, cfg Config) (engine.Collector, error) {
 		return nil, fmt.Errorf("failed to parse provider source '%s': %w", cfg.Provider, err)
 	}
 
-	version := strings.TrimPrefix(cfg.Version, "v")
+	version, constraint, err := parseVersion(provider, cfg.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := cfg.Pool
+	if pool == nil {
+		pool = NewProviderPool(func() (Client, error) { return borrowedClient{client}, nil })
+	}
+
+	if cfg.MaxStateBytes < 0 {
+		return nil, fmt.Errorf("max_state_bytes must not be negative, got %d", cfg.MaxStateBytes)
+	}
 
 	return &Collector{
 		providerConfig: tfclient.ProviderConfig{
@@ -47,12 +104,86 @@ func NewCollector(client Client, cfg Config) (engine.Collector, error) {
 			Name:      provider.Type,
 			Version:   version,
 		},
-		args:   cfg.Args,
-		client: client,
+		args:          cfg.Args,
+		pool:          pool,
+		versions:      cfg.Versions,
+		constraint:    constraint,
+		rawConstraint: cfg.Version,
+		maxStateBytes: cfg.MaxStateBytes,
+		noCache:       cfg.NoCache,
+		cache:         make(map[string]map[string]any),
 	}, nil
 }
 
+// parseVersion splits raw into an exact version, returned as is (the empty
+// "latest" included), or a version constraint to resolve against the
+// registry later.
+func parseVersion(provider tfaddr.Provider, raw string) (string, goversion.Constraints, error) {
+	version := strings.TrimPrefix(strings.TrimSpace(raw), "v")
+	if version == "" {
+		return "", nil, nil
+	}
+	if _, err := goversion.NewVersion(version); err == nil {
+		return version, nil, nil
+	}
+
+	constraint, err := goversion.NewConstraint(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid version '%s' for provider %s: %w", raw, provider.ForDisplay(), err)
+	}
+	return "", constraint, nil
+}
+
+// resolveVersion looks up the newest published version matching the
+// collector's constraint and launches that one. It does nothing for an exact
+// version or once resolved.
+func (c *Collector) resolveVersion(ctx context.Context) error {
+	if c.constraint == nil {
+		return nil
+	}
+
+	available, err := c.versionLister().GetVersions(ctx, c.providerConfig.Namespace, c.providerConfig.Name)
+	if err != nil {
+		return fmt.Errorf("failed to resolve version constraint '%s' for provider %s: %w", c.rawConstraint, c.ProviderSource(), err)
+	}
+	version, err := c.newestMatch(available)
+	if err != nil {
+		return err
+	}
+	c.providerConfig.Version = version
+	c.constraint = nil
+	return nil
+}
+
+// newestMatch returns the newest of available matching the constraint.
+func (c *Collector) newestMatch(available []registry.VersionInfo) (string, error) {
+	var best *goversion.Version
+	for _, info := range available {
+		v, err := goversion.NewVersion(info.Version)
+		if err != nil || !c.constraint.Check(v) {
+			continue
+		}
+		if best == nil || v.GreaterThan(best) {
+			best = v
+		}
+	}
+	if best == nil {
+		return "", fmt.Errorf("no version of provider %s matches constraint '%s'", c.ProviderSource(), c.rawConstraint)
+	}
+	return best.Original(), nil
+}
+
+func (c *Collector) versionLister() VersionLister {
+	if c.versions == nil {
+		return registry.NewTerraformRegistry(nil)
+	}
+	return c.versions
+}
+
 func (c *Collector) Name() string {
+	if c.constraint != nil {
+		return fmt.Sprintf("%s(%s/%s@%s)", CollectorKind, c.providerConfig.Namespace, c.providerConfig.Name, c.rawConstraint)
+	}
 	return fmt.Sprintf("%s(%s)", CollectorKind, c.providerConfig.String())
 }
 
@@ -64,20 +195,54 @@ func (c *Collector) Start(ctx context.Context) error {
 	if c.provider != nil {
 		return nil
 	}
-
-	provider, err := c.client.CreateProvider(ctx, c.providerConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create provider: %w", err)
+	if err := c.resolveVersion(ctx); err != nil {
+		return err
 	}
 
-	if err := provider.Configure(ctx, c.args); err != nil {
-		return fmt.Errorf("failed to configure provider: %w", err)
+	provider, err := c.pool.Acquire(ctx, c.providerConfig, c.args)
+	if err != nil {
+		return err
 	}
 
 	c.provider = provider
 	return nil
 }
 
+// Validate checks that the provider, and its version when one is set, is
+// published in the registry, without downloading or launching it. A
+// constraint must match a published version.
+func (c *Collector) Validate(ctx context.Context) error {
+	available, err := c.versionLister().GetVersions(ctx, c.providerConfig.Namespace, c.providerConfig.Name)
+	if err != nil {
+		return fmt.Errorf("failed to look up provider %s: %w", c.ProviderSource(), err)
+	}
+	if len(available) == 0 {
+		return fmt.Errorf("provider %s has no published versions", c.ProviderSource())
+	}
+	if c.constraint != nil {
+		_, err := c.newestMatch(available)
+		return err
+	}
+	if c.providerConfig.Version == "" {
+		return nil
+	}
+
+	want, err := goversion.NewVersion(c.providerConfig.Version)
+	if err != nil {
+		return fmt.Errorf("invalid version '%s' for provider %s: %w", c.providerConfig.Version, c.ProviderSource(), err)
+	}
+	for _, info := range available {
+		if v, err := goversion.NewVersion(info.Version); err == nil && v.Equal(want) {
+			return nil
+		}
+	}
+	return fmt.Errorf("version %s of provider %s is not published", c.providerConfig.Version, c.ProviderSource())
+}
+
+// ReadDataSource reads a data source through the provider. Successful reads
+// are cached for the lifetime of the collector, keyed by data source name and
+// args, so identical reads from several steps query the provider once. The
+// cached State is shared between callers and must not be mutated.
 func (c *Collector) ReadDataSource(ctx context.Context, name string, args map[string]any) (map[string]any, error) {
 	if c.provider == nil {
 		return nil, fmt.Errorf("%w: %s", engine.ErrCollectorNotStarted, c.Name())
@@ -87,20 +252,95 @@ func (c *Collector) ReadDataSource(ctx context.Context, name string, args map[st
 		return nil, fmt.Errorf("provider not configured")
 	}
 
+	var key string
+	if !c.noCache {
+		k, err := readCacheKey(name, args)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+
+		c.cacheMu.Lock()
+		state, ok := c.cache[key]
+		c.cacheMu.Unlock()
+		if ok {
+			return state, nil
+		}
+	}
+
 	result, err := c.provider.ReadDataSource(ctx, name, args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read data source: %w", err)
 	}
+	if err := checkStateSize(name, result.State, c.maxStateBytes); err != nil {
+		return nil, err
+	}
+
+	if !c.noCache {
+		c.cacheMu.Lock()
+		c.cache[key] = result.State
+		c.cacheMu.Unlock()
+	}
 
 	return result.State, nil
 }
 
+// checkStateSize fails when state, encoded as JSON, is larger than max
+// bytes. The plugin protocol returns a data source's state in one message,
+// so it cannot be read in chunks; the check stops a huge state before it is
+// cached, passed to other steps and encoded again for output. Encoding stops
+// as soon as the limit is crossed. A zero max disables the check.
+func checkStateSize(name string, state map[string]any, max int64) error {
+	if max == 0 {
+		return nil
+	}
+	w := &stateSizeWriter{max: max}
+	if err := json.NewEncoder(w).Encode(state); err != nil && !errors.Is(err, errStateTooLarge) {
+		return fmt.Errorf("failed to measure data source %q state: %w", name, err)
+	}
+	if w.n > max {
+		return fmt.Errorf("data source %q returned more than max_state_bytes (%d bytes) of state; narrow its arguments to select fewer objects, or raise max_state_bytes", name, max)
+	}
+	return nil
+}
+
+var errStateTooLarge = errors.New("state too large")
+
+// stateSizeWriter counts bytes and fails once more than max are written.
+type stateSizeWriter struct {
+	n, max int64
+}
+
+func (w *stateSizeWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	if w.n > w.max {
+		return 0, errStateTooLarge
+	}
+	return len(p), nil
+}
+
+// readCacheKey canonicalizes args through encoding/json, which emits map
+// keys in sorted order, so equal args produce equal keys regardless of map
+// iteration order.
+func readCacheKey(name string, args map[string]any) (string, error) {
+	canonical, err := json.Marshal(args)
+	if err != nil {
+		return "", fmt.Errorf("failed to canonicalize data source args: %w", err)
+	}
+	return name + "\x00" + string(canonical), nil
+}
+
 func (c *Collector) Close(ctx context.Context) error {
 	if c.provider == nil {
 		return nil
 	}
 	c.provider = nil
-	return c.client.StopProvider(ctx, c.providerConfig)
+
+	c.cacheMu.Lock()
+	clear(c.cache)
+	c.cacheMu.Unlock()
+
+	return c.pool.Release(ctx, c.providerConfig, c.args)
 }
 
 func (c *Collector) ProviderSource() string {
@@ -7,6 +7,7 @@ import (
 
 	"github.com/infracollect/infracollect/internal/engine"
 	tfclient "github.com/infracollect/tf-data-client"
+	"github.com/infracollect/tf-data-client/registry"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -16,6 +17,7 @@ type mockProvider struct {
 	readDataSourceFunc func(ctx context.Context, name string, args map[string]any) (*tfclient.DataSourceResult, error)
 	isConfigured       bool
 	providerConfig     tfclient.ProviderConfig
+	dataSources        []string
 }
 
 func (m *mockProvider) Config() tfclient.ProviderConfig {
@@ -42,7 +44,7 @@ func (m *mockProvider) IsConfigured() bool {
 }
 
 func (m *mockProvider) ListDataSources() []string {
-	return nil
+	return m.dataSources
 }
 
 func (m *mockProvider) Close() error {
@@ -52,7 +54,9 @@ func (m *mockProvider) Close() error {
 type mockClient struct {
 	createProviderFunc func(ctx context.Context, config tfclient.ProviderConfig) (tfclient.Provider, error)
 	stopProviderFunc   func(ctx context.Context, config tfclient.ProviderConfig) error
+	closeFunc          func() error
 	provider           *mockProvider
+	closeCalls         int
 }
 
 func (m *mockClient) CreateProvider(ctx context.Context, config tfclient.ProviderConfig) (tfclient.Provider, error) {
@@ -72,6 +76,14 @@ func (m *mockClient) StopProvider(ctx context.Context, config tfclient.ProviderC
 	return nil
 }
 
+func (m *mockClient) Close() error {
+	m.closeCalls++
+	if m.closeFunc != nil {
+		return m.closeFunc()
+	}
+	return nil
+}
+
 func TestNewCollector(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -130,6 +142,120 @@ func TestNewCollector(t *testing.T) {
 	}
 }
 
+// staticVersions is a VersionLister over a fixed version list.
+type staticVersions struct {
+	versions []string
+	err      error
+}
+
+func (s staticVersions) GetVersions(context.Context, string, string) ([]registry.VersionInfo, error) {
+	infos := make([]registry.VersionInfo, len(s.versions))
+	for i, v := range s.versions {
+		infos[i] = registry.VersionInfo{Version: v}
+	}
+	return infos, s.err
+}
+
+func TestCollector_Start_VersionConstraint(t *testing.T) {
+	published := staticVersions{versions: []string{"4.67.0", "5.0.0", "5.31.0", "5.9.1", "6.0.0", "6.1.0-beta1"}}
+
+	tests := []struct {
+		name        string
+		version     string
+		versions    VersionLister
+		wantVersion string
+		errContains string
+	}{
+		{name: "exact version skips the registry", version: "5.0.0", versions: staticVersions{err: errors.New("unused")}, wantVersion: "5.0.0"},
+		{name: "pessimistic constraint", version: "~> 5.0", versions: published, wantVersion: "5.31.0"},
+		{name: "range constraint", version: ">= 4.0, < 5.1", versions: published, wantVersion: "5.0.0"},
+		{name: "prereleases are ignored", version: ">= 6.0", versions: published, wantVersion: "6.0.0"},
+		{
+			name:        "unmatched constraint",
+			version:     "~> 7.0",
+			versions:    published,
+			errContains: "no version of provider hashicorp/aws matches constraint '~> 7.0'",
+		},
+		{
+			name:        "registry failure",
+			version:     "~> 5.0",
+			versions:    staticVersions{err: errors.New("registry down")},
+			errContains: "failed to resolve version constraint '~> 5.0' for provider hashicorp/aws: registry down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lister := &countingVersions{VersionLister: tt.versions}
+			var launched tfclient.ProviderConfig
+			client := &mockClient{
+				createProviderFunc: func(_ context.Context, config tfclient.ProviderConfig) (tfclient.Provider, error) {
+					launched = config
+					return &mockProvider{providerConfig: config}, nil
+				},
+			}
+			collector, err := NewCollector(client, Config{
+				Provider: "hashicorp/aws",
+				Version:  tt.version,
+				Versions: lister,
+			})
+			require.NoError(t, err)
+			assert.Zero(t, lister.calls, "the registry is not queried when the collector is created")
+
+			err = collector.Start(t.Context())
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantVersion, collector.(*Collector).ProviderVersion())
+			assert.Equal(t, tt.wantVersion, launched.Version)
+		})
+	}
+}
+
+func TestNewCollector_InvalidVersion(t *testing.T) {
+	_, err := NewCollector(&mockClient{}, Config{
+		Provider: "hashicorp/aws",
+		Version:  "five",
+		Versions: staticVersions{err: errors.New("unused")},
+	})
+	assert.ErrorContains(t, err, "invalid version 'five' for provider hashicorp/aws")
+}
+
+func TestCollector_Start_VersionConstraintUsesContext(t *testing.T) {
+	collector, err := NewCollector(&mockClient{}, Config{
+		Provider: "hashicorp/aws",
+		Version:  "~> 5.0",
+		Versions: ctxVersions{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "terraform(hashicorp/aws@~> 5.0)", collector.Name())
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	err = collector.Start(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// countingVersions counts the lookups made through a VersionLister.
+type countingVersions struct {
+	VersionLister
+	calls int
+}
+
+func (c *countingVersions) GetVersions(ctx context.Context, namespace, name string) ([]registry.VersionInfo, error) {
+	c.calls++
+	return c.VersionLister.GetVersions(ctx, namespace, name)
+}
+
+// ctxVersions fails with the error of the context it is called with.
+type ctxVersions struct{}
+
+func (ctxVersions) GetVersions(ctx context.Context, _, _ string) ([]registry.VersionInfo, error) {
+	return nil, ctx.Err()
+}
+
 func TestCollector_NameAndKind(t *testing.T) {
 	client := &mockClient{}
 	collector, err := NewCollector(client, Config{
@@ -334,6 +460,61 @@ func TestCollector_ReadDataSource(t *testing.T) {
 	}
 }
 
+func TestCollector_ReadDataSource_MaxStateBytes(t *testing.T) {
+	state := map[string]any{"id": "i-12345", "name": "test-instance"}
+
+	tests := []struct {
+		name          string
+		maxStateBytes int64
+		errContains   string
+	}{
+		{name: "unlimited", maxStateBytes: 0},
+		{name: "within the limit", maxStateBytes: 1024},
+		{name: "over the limit", maxStateBytes: 10, errContains: `data source "aws_instance" returned more than max_state_bytes (10 bytes)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reads := 0
+			client := &mockClient{
+				provider: &mockProvider{
+					isConfigured: true,
+					readDataSourceFunc: func(ctx context.Context, name string, args map[string]any) (*tfclient.DataSourceResult, error) {
+						reads++
+						return &tfclient.DataSourceResult{State: state}, nil
+					},
+				},
+			}
+			collector, err := NewCollector(client, Config{Provider: "hashicorp/aws", MaxStateBytes: tt.maxStateBytes})
+			require.NoError(t, err)
+			require.NoError(t, collector.Start(t.Context()))
+
+			c := collector.(*Collector)
+			for range 2 {
+				data, err := c.ReadDataSource(t.Context(), "aws_instance", map[string]any{"id": "i-12345"})
+				if tt.errContains != "" {
+					require.ErrorContains(t, err, tt.errContains)
+					continue
+				}
+				require.NoError(t, err)
+				assert.Equal(t, state, data)
+			}
+
+			if tt.errContains != "" {
+				// A rejected state is not cached, so it is read again.
+				assert.Equal(t, 2, reads)
+			} else {
+				assert.Equal(t, 1, reads)
+			}
+		})
+	}
+}
+
+func TestNewCollector_NegativeMaxStateBytes(t *testing.T) {
+	_, err := NewCollector(&mockClient{}, Config{Provider: "hashicorp/aws", MaxStateBytes: -1})
+	require.ErrorContains(t, err, "max_state_bytes must not be negative")
+}
+
 func TestCollector_Close(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -394,6 +575,7 @@ func TestCollector_Close(t *testing.T) {
 			}
 
 			require.NoError(t, err)
+			assert.Zero(t, client.closeCalls, "a client passed to NewCollector belongs to the caller")
 		})
 	}
 }
@@ -409,3 +591,184 @@ func TestCollector_ReadDataSource_SentinelError(t *testing.T) {
 	assert.True(t, errors.Is(err, engine.ErrCollectorNotStarted),
 		"ReadDataSource should wrap engine.ErrCollectorNotStarted, got %v", err)
 }
+
+func TestCollector_ReadDataSource_Cache(t *testing.T) {
+	type read struct {
+		name string
+		args map[string]any
+	}
+	tests := []struct {
+		name      string
+		noCache   bool
+		failFirst bool
+		reads     []read
+		wantCalls int
+	}{
+		{
+			name: "identical reads hit the provider once",
+			reads: []read{
+				{"aws_vpc", map[string]any{"id": "vpc-1"}},
+				{"aws_vpc", map[string]any{"id": "vpc-1"}},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "arg key order does not matter",
+			reads: []read{
+				{"aws_vpc", map[string]any{"id": "vpc-1", "tags": map[string]any{"a": "1", "b": "2"}}},
+				{"aws_vpc", map[string]any{"tags": map[string]any{"b": "2", "a": "1"}, "id": "vpc-1"}},
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "different args are separate entries",
+			reads: []read{
+				{"aws_vpc", map[string]any{"id": "vpc-1"}},
+				{"aws_vpc", map[string]any{"id": "vpc-2"}},
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "different data sources are separate entries",
+			reads: []read{
+				{"aws_vpc", map[string]any{"id": "x"}},
+				{"aws_subnet", map[string]any{"id": "x"}},
+			},
+			wantCalls: 2,
+		},
+		{
+			name:    "no_cache bypasses the cache",
+			noCache: true,
+			reads: []read{
+				{"aws_vpc", map[string]any{"id": "vpc-1"}},
+				{"aws_vpc", map[string]any{"id": "vpc-1"}},
+			},
+			wantCalls: 2,
+		},
+		{
+			name:      "failed reads are not cached",
+			failFirst: true,
+			reads: []read{
+				{"aws_vpc", map[string]any{"id": "vpc-1"}},
+				{"aws_vpc", map[string]any{"id": "vpc-1"}},
+			},
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			client := &mockClient{
+				provider: &mockProvider{
+					isConfigured: true,
+					readDataSourceFunc: func(_ context.Context, name string, args map[string]any) (*tfclient.DataSourceResult, error) {
+						calls++
+						if tt.failFirst && calls == 1 {
+							return nil, errors.New("throttled")
+						}
+						return &tfclient.DataSourceResult{State: map[string]any{"name": name, "id": args["id"]}}, nil
+					},
+				},
+			}
+			collector, err := NewCollector(client, Config{Provider: "hashicorp/aws", NoCache: tt.noCache})
+			require.NoError(t, err)
+			require.NoError(t, collector.Start(t.Context()))
+
+			c := collector.(*Collector)
+			for i, r := range tt.reads {
+				data, err := c.ReadDataSource(t.Context(), r.name, r.args)
+				if tt.failFirst && i == 0 {
+					require.Error(t, err)
+					continue
+				}
+				require.NoError(t, err)
+				assert.Equal(t, map[string]any{"name": r.name, "id": r.args["id"]}, data)
+			}
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func TestCollector_ReadDataSource_CacheClearedOnClose(t *testing.T) {
+	calls := 0
+	client := &mockClient{
+		provider: &mockProvider{
+			isConfigured: true,
+			readDataSourceFunc: func(context.Context, string, map[string]any) (*tfclient.DataSourceResult, error) {
+				calls++
+				return &tfclient.DataSourceResult{State: map[string]any{}}, nil
+			},
+		},
+	}
+	collector, err := NewCollector(client, Config{Provider: "hashicorp/aws"})
+	require.NoError(t, err)
+	c := collector.(*Collector)
+
+	require.NoError(t, c.Start(t.Context()))
+	_, err = c.ReadDataSource(t.Context(), "aws_vpc", nil)
+	require.NoError(t, err)
+	require.NoError(t, c.Close(t.Context()))
+
+	require.NoError(t, c.Start(t.Context()))
+	_, err = c.ReadDataSource(t.Context(), "aws_vpc", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls, "a restarted collector must not serve reads cached before Close")
+}
+
+func TestCollector_Validate(t *testing.T) {
+	published := staticVersions{versions: []string{"5.0.0", "5.31.0"}}
+
+	tests := []struct {
+		name        string
+		version     string
+		versions    VersionLister
+		errContains string
+	}{
+		{name: "published version", version: "5.31.0", versions: published},
+		{name: "latest", versions: published},
+		{name: "matching constraint", version: "~> 5.0", versions: published},
+		{
+			name:        "unmatched constraint",
+			version:     "~> 7.0",
+			versions:    published,
+			errContains: "no version of provider hashicorp/aws matches constraint '~> 7.0'",
+		},
+		{
+			name:        "unpublished version",
+			version:     "5.1.0",
+			versions:    published,
+			errContains: "version 5.1.0 of provider hashicorp/aws is not published",
+		},
+		{
+			name:        "unknown provider",
+			versions:    staticVersions{},
+			errContains: "provider hashicorp/aws has no published versions",
+		},
+		{
+			name:        "registry failure",
+			versions:    staticVersions{err: errors.New("registry down")},
+			errContains: "failed to look up provider hashicorp/aws: registry down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockClient{}
+			collector, err := NewCollector(client, Config{
+				Provider: "hashicorp/aws",
+				Version:  tt.version,
+				Versions: tt.versions,
+			})
+			require.NoError(t, err)
+
+			err = collector.(engine.CollectorValidator).Validate(t.Context())
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Nil(t, client.provider, "the provider is not launched")
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	tfaddr "github.com/hashicorp/terraform-registry-address"
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+// ListDataSources launches the provider identified by source (e.g.
+// "hashicorp/kubernetes") and version, returns the names of the data sources
+// it offers in sorted order, and stops the provider again. An empty version
+// selects the latest release.
+func ListDataSources(ctx context.Context, client Client, source, version string) (names []string, err error) {
+	addr, err := tfaddr.ParseProviderSource(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse provider source '%s': %w", source, err)
+	}
+
+	provider, err := client.CreateProvider(ctx, tfclient.ProviderConfig{
+		Namespace: addr.Namespace,
+		Name:      addr.Type,
+		Version:   strings.TrimPrefix(version, "v"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	// Stop with the resolved config: when version is empty the provider is
+	// registered under the latest version, not under "".
+	defer func() {
+		if stopErr := client.StopProvider(ctx, provider.Config()); stopErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to stop provider: %w", stopErr))
+		}
+	}()
+
+	names = provider.ListDataSources()
+	slices.Sort(names)
+	return names, nil
+}
@@ -0,0 +1,96 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tfclient "github.com/infracollect/tf-data-client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDataSources(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		version     string
+		createErr   error
+		stopErr     error
+		wantNames   []string
+		wantCreate  tfclient.ProviderConfig
+		wantStopped bool
+		errContains string
+	}{
+		{
+			name:        "lists sorted names and stops the provider",
+			source:      "hashicorp/kubernetes",
+			version:     "v2.0.0",
+			wantNames:   []string{"kubernetes_namespace", "kubernetes_service"},
+			wantCreate:  tfclient.ProviderConfig{Namespace: "hashicorp", Name: "kubernetes", Version: "2.0.0"},
+			wantStopped: true,
+		},
+		{
+			name:        "invalid provider source",
+			source:      "invalid provider",
+			errContains: "failed to parse provider source",
+		},
+		{
+			name:        "create failure",
+			source:      "hashicorp/kubernetes",
+			createErr:   errors.New("download failed"),
+			wantCreate:  tfclient.ProviderConfig{Namespace: "hashicorp", Name: "kubernetes"},
+			errContains: "failed to create provider: download failed",
+		},
+		{
+			name:        "stop failure",
+			source:      "hashicorp/kubernetes",
+			stopErr:     errors.New("still running"),
+			wantCreate:  tfclient.ProviderConfig{Namespace: "hashicorp", Name: "kubernetes"},
+			wantStopped: true,
+			errContains: "failed to stop provider: still running",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved := tfclient.ProviderConfig{Namespace: "hashicorp", Name: "kubernetes", Version: "2.0.0"}
+			provider := &mockProvider{
+				providerConfig: resolved,
+				dataSources:    []string{"kubernetes_service", "kubernetes_namespace"},
+			}
+
+			var created tfclient.ProviderConfig
+			var stopped *tfclient.ProviderConfig
+			client := &mockClient{
+				createProviderFunc: func(_ context.Context, cfg tfclient.ProviderConfig) (tfclient.Provider, error) {
+					created = cfg
+					if tt.createErr != nil {
+						return nil, tt.createErr
+					}
+					return provider, nil
+				},
+				stopProviderFunc: func(_ context.Context, cfg tfclient.ProviderConfig) error {
+					stopped = &cfg
+					return tt.stopErr
+				},
+			}
+
+			names, err := ListDataSources(t.Context(), client, tt.source, tt.version)
+			assert.Equal(t, tt.wantCreate, created)
+			if tt.wantStopped {
+				require.NotNil(t, stopped)
+				assert.Equal(t, resolved, *stopped, "stops the resolved version")
+			} else {
+				assert.Nil(t, stopped)
+			}
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNames, names)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+// ProviderPoolDepKey is the registry dependency holding the *ProviderPool
+// shared by every terraform collector built from the registry.
+const ProviderPoolDepKey = "terraformProviderPool"
+
+// ProviderPool shares configured provider processes between collectors.
+// Collectors asking for the same provider, version and configure arguments
+// get the same process; it is stopped when the last of them releases it.
+//
+// Each pooled provider gets its own Client: a client runs at most one
+// process per provider version, and two collectors configuring the same
+// provider differently (two AWS regions, say) need two processes. The pool
+// closes the client along with its provider.
+type ProviderPool struct {
+	newClient func() (Client, error)
+
+	mu      sync.Mutex
+	entries map[string]*pooledProvider // keyed by providerPoolKey
+}
+
+type pooledProvider struct {
+	refs   int
+	ready  chan struct{} // closed once provider or err is set
+	client Client
+
+	provider tfclient.Provider
+	err      error
+}
+
+// NewProviderPool returns a pool that builds a client with newClient for
+// every distinct provider it launches.
+func NewProviderPool(newClient func() (Client, error)) *ProviderPool {
+	return &ProviderPool{
+		newClient: newClient,
+		entries:   make(map[string]*pooledProvider),
+	}
+}
+
+// Acquire returns a provider launched from config and configured with args,
+// starting it unless an identical one is already running. Every successful
+// Acquire must be paired with a Release.
+func (p *ProviderPool) Acquire(ctx context.Context, config tfclient.ProviderConfig, args map[string]any) (tfclient.Provider, error) {
+	key, err := providerPoolKey(config, args)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	entry, ok := p.entries[key]
+	if !ok {
+		entry = &pooledProvider{ready: make(chan struct{})}
+		p.entries[key] = entry
+	}
+	entry.refs++
+	p.mu.Unlock()
+
+	if ok {
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			p.release(key, entry)
+			return nil, ctx.Err()
+		}
+		if entry.err != nil {
+			return nil, entry.err
+		}
+		return entry.provider, nil
+	}
+
+	entry.client, entry.provider, entry.err = p.start(ctx, config, args)
+	if entry.err != nil {
+		// Waiters see the error through the entry; a later Acquire retries.
+		p.mu.Lock()
+		delete(p.entries, key)
+		p.mu.Unlock()
+	}
+	close(entry.ready)
+	return entry.provider, entry.err
+}
+
+// Release drops a reference taken by Acquire and stops the provider, and
+// closes its client, once no collector uses it.
+func (p *ProviderPool) Release(ctx context.Context, config tfclient.ProviderConfig, args map[string]any) error {
+	key, err := providerPoolKey(config, args)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	entry, ok := p.entries[key]
+	p.mu.Unlock()
+	if !ok || !p.release(key, entry) {
+		return nil
+	}
+	return errors.Join(entry.client.StopProvider(ctx, config), entry.client.Close())
+}
+
+// release drops one reference to entry and reports whether it was the last,
+// in which case the entry is removed from the pool.
+func (p *ProviderPool) release(key string, entry *pooledProvider) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry.refs--
+	if entry.refs > 0 || p.entries[key] != entry {
+		return false
+	}
+	delete(p.entries, key)
+	return true
+}
+
+func (p *ProviderPool) start(ctx context.Context, config tfclient.ProviderConfig, args map[string]any) (Client, tfclient.Provider, error) {
+	client, err := p.newClient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create terraform client: %w", err)
+	}
+
+	provider, err := client.CreateProvider(ctx, config)
+	if err != nil {
+		_ = client.Close()
+		return nil, nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+
+	if err := provider.Configure(ctx, args); err != nil {
+		_ = client.StopProvider(ctx, config)
+		_ = client.Close()
+		return nil, nil, fmt.Errorf("failed to configure provider: %w", err)
+	}
+	return client, provider, nil
+}
+
+// providerPoolKey canonicalizes args through encoding/json, like
+// readCacheKey, so equal configurations share a key.
+func providerPoolKey(config tfclient.ProviderConfig, args map[string]any) (string, error) {
+	canonical, err := json.Marshal(args)
+	if err != nil {
+		return "", fmt.Errorf("failed to canonicalize provider config: %w", err)
+	}
+	return config.String() + "\x00" + string(canonical), nil
+}
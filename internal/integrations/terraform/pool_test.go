@@ -0,0 +1,162 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	tfclient "github.com/infracollect/tf-data-client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingPool returns a pool whose clients launch a fresh mockProvider per
+// CreateProvider, along with counters of launched and stopped providers and
+// of closed clients.
+func countingPool() (*ProviderPool, *int, *int, *int) {
+	var (
+		mu      sync.Mutex
+		created int
+		stopped int
+		closed  int
+	)
+	pool := NewProviderPool(func() (Client, error) {
+		return &mockClient{
+			createProviderFunc: func(ctx context.Context, config tfclient.ProviderConfig) (tfclient.Provider, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				created++
+				return &mockProvider{providerConfig: config}, nil
+			},
+			stopProviderFunc: func(ctx context.Context, config tfclient.ProviderConfig) error {
+				mu.Lock()
+				defer mu.Unlock()
+				stopped++
+				return nil
+			},
+			closeFunc: func() error {
+				mu.Lock()
+				defer mu.Unlock()
+				closed++
+				return nil
+			},
+		}, nil
+	})
+	return pool, &created, &stopped, &closed
+}
+
+func TestProviderPool_SharesIdenticalProviders(t *testing.T) {
+	pool, created, stopped, closed := countingPool()
+
+	newCollector := func(provider string, args map[string]any) *Collector {
+		c, err := NewCollector(nil, Config{Provider: provider, Version: "5.0.0", Args: args, Pool: pool})
+		require.NoError(t, err)
+		return c.(*Collector)
+	}
+	east1 := newCollector("hashicorp/aws", map[string]any{"region": "us-east-1"})
+	east2 := newCollector("hashicorp/aws", map[string]any{"region": "us-east-1"})
+	west := newCollector("hashicorp/aws", map[string]any{"region": "us-west-2"})
+	google := newCollector("hashicorp/google", map[string]any{"region": "us-east-1"})
+
+	for _, c := range []*Collector{east1, east2, west, google} {
+		require.NoError(t, c.Start(t.Context()))
+	}
+	assert.Equal(t, 3, *created)
+	assert.Same(t, east1.provider, east2.provider)
+	assert.NotSame(t, east1.provider, west.provider)
+
+	require.NoError(t, east1.Close(t.Context()))
+	assert.Equal(t, 0, *stopped, "provider is still used by east2")
+	assert.Equal(t, 0, *closed)
+	require.NoError(t, east2.Close(t.Context()))
+	assert.Equal(t, 1, *stopped)
+	assert.Equal(t, 1, *closed, "the client is closed with its last provider")
+	require.NoError(t, west.Close(t.Context()))
+	require.NoError(t, google.Close(t.Context()))
+	assert.Equal(t, 3, *stopped)
+	assert.Equal(t, 3, *closed)
+
+	// Once released, the next collector launches a new process.
+	require.NoError(t, east1.Start(t.Context()))
+	assert.Equal(t, 4, *created)
+}
+
+func TestProviderPool_ConcurrentAcquire(t *testing.T) {
+	pool, created, _, _ := countingPool()
+	config := tfclient.ProviderConfig{Namespace: "hashicorp", Name: "aws", Version: "5.0.0"}
+
+	var wg sync.WaitGroup
+	providers := make([]tfclient.Provider, 8)
+	for i := range providers {
+		wg.Go(func() {
+			provider, err := pool.Acquire(t.Context(), config, map[string]any{"region": "us-east-1"})
+			assert.NoError(t, err)
+			providers[i] = provider
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, *created)
+	for _, provider := range providers {
+		assert.Same(t, providers[0], provider)
+	}
+}
+
+func TestProviderPool_ConfigureFailure(t *testing.T) {
+	stopped := 0
+	closed := 0
+	attempts := 0
+	pool := NewProviderPool(func() (Client, error) {
+		return &mockClient{
+			closeFunc: func() error {
+				closed++
+				return nil
+			},
+			createProviderFunc: func(ctx context.Context, config tfclient.ProviderConfig) (tfclient.Provider, error) {
+				attempts++
+				return &mockProvider{
+					configureFunc: func(ctx context.Context, args map[string]any) error {
+						return errors.New("invalid credentials")
+					},
+				}, nil
+			},
+			stopProviderFunc: func(ctx context.Context, config tfclient.ProviderConfig) error {
+				stopped++
+				return nil
+			},
+		}, nil
+	})
+	config := tfclient.ProviderConfig{Namespace: "hashicorp", Name: "aws"}
+
+	_, err := pool.Acquire(t.Context(), config, nil)
+	assert.ErrorContains(t, err, "failed to configure provider: invalid credentials")
+	assert.Equal(t, 1, stopped, "a provider that failed to configure is stopped")
+	assert.Equal(t, 1, closed, "and its client closed")
+
+	// The failure is not cached; the next Acquire tries again.
+	_, err = pool.Acquire(t.Context(), config, nil)
+	require.Error(t, err)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestProviderPool_CreateFailureClosesClient(t *testing.T) {
+	client := &mockClient{
+		createProviderFunc: func(context.Context, tfclient.ProviderConfig) (tfclient.Provider, error) {
+			return nil, errors.New("download failed")
+		},
+	}
+	pool := NewProviderPool(func() (Client, error) { return client, nil })
+
+	_, err := pool.Acquire(t.Context(), tfclient.ProviderConfig{Namespace: "hashicorp", Name: "aws"}, nil)
+	assert.ErrorContains(t, err, "failed to create provider: download failed")
+	assert.Equal(t, 1, client.closeCalls)
+}
+
+func TestProviderPool_ClientError(t *testing.T) {
+	pool := NewProviderPool(func() (Client, error) {
+		return nil, errors.New("no home directory")
+	})
+	_, err := pool.Acquire(t.Context(), tfclient.ProviderConfig{Namespace: "hashicorp", Name: "aws"}, nil)
+	assert.ErrorContains(t, err, "failed to create terraform client: no home directory")
+}
@@ -17,13 +17,21 @@ import (
 //	  config_path = env.KUBECONFIG
 //	}
 //
-// All attributes other than `provider` / `version` are forwarded to the
-// provider as its Configure() arguments, matching the behavior of Terraform's
-// `provider "kubernetes" { ... }` block.
+// All attributes other than `provider` / `version` / `no_cache` /
+// `max_state_bytes` are forwarded to the provider as its Configure()
+// arguments, matching the behavior of Terraform's `provider "kubernetes" {
+// ... }` block.
 type CollectorConfig struct {
-	Provider string   `hcl:"provider"`
-	Version  string   `hcl:"version,optional"`
-	Rest     hcl.Body `hcl:",remain"`
+	Provider string `hcl:"provider"`
+	// Provider version: an exact version such as "5.0.0" or a constraint such
+	// as "~> 5.0", resolved to the newest matching release in the registry.
+	// Defaults to the latest release.
+	Version string `hcl:"version,optional"`
+	NoCache bool   `hcl:"no_cache,optional"`
+	// Fail a data source read whose state is larger than this many bytes,
+	// measured as JSON. Unset or 0 is unlimited.
+	MaxStateBytes int64    `hcl:"max_state_bytes,optional"`
+	Rest          hcl.Body `hcl:",remain"`
 }
 
 // DataSourceStepConfig is the HCL-level shape of a
@@ -41,11 +49,23 @@ type DataSourceBlock struct {
 	Body hcl.Body `hcl:",remain"`
 }
 
+// PluginCacheDirDepKey is the optional registry dependency holding the
+// directory provider plugins are downloaded to and reused from. It must be
+// registered before Register is called; when empty or absent the client's
+// default directory is used.
+const PluginCacheDirDepKey = "terraformPluginCacheDir"
+
 func Register(registry *engine.Registry) error {
-	if err := registry.RegisterCollector(
-		CollectorKind,
-		engine.NewCollectorFactory(CollectorKind, newCollector),
-	); err != nil {
+	helper := registry.Helper()
+	opts := []tfclient.Option{tfclient.WithLogger(zapr.NewLogger(helper.Logger()))}
+	if dir, _ := engine.GetRegistryDependency[string](helper, PluginCacheDirDepKey); dir != "" {
+		opts = append(opts, tfclient.WithCacheDir(dir))
+	}
+	registry.RegisterDependency(ProviderPoolDepKey, NewProviderPool(func() (Client, error) {
+		return tfclient.New(opts...)
+	}))
+
+	if err := engine.RegisterTypedCollector(registry, CollectorKind, newCollector); err != nil {
 		return err
 	}
 
@@ -59,9 +79,9 @@ func newCollector(
 	ctx *hcl.EvalContext,
 	cfg CollectorConfig,
 ) (engine.Collector, error) {
-	client, err := tfclient.New(tfclient.WithLogger(zapr.NewLogger(helper.Logger())))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create terraform client: %w", err)
+	pool, ok := engine.GetRegistryDependency[*ProviderPool](helper, ProviderPoolDepKey)
+	if !ok {
+		return nil, fmt.Errorf("registry dependency %q is not registered", ProviderPoolDepKey)
 	}
 
 	args, err := engine.EvalBodyToMap(cfg.Rest, ctx, "terraform collector config")
@@ -69,10 +89,14 @@ func newCollector(
 		return nil, err
 	}
 
-	return NewCollector(client, Config{
+	return NewCollector(nil, Config{
 		Provider: cfg.Provider,
 		Version:  cfg.Version,
 		Args:     args,
+		NoCache:  cfg.NoCache,
+		Pool:     pool,
+
+		MaxStateBytes: cfg.MaxStateBytes,
 	})
 }
 
@@ -0,0 +1,70 @@
+package terraform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+	"go.uber.org/zap"
+)
+
+// Provider arguments are HCL expressions evaluated as a whole, so env.*
+// references resolve at any depth of nested objects and lists while
+// non-string values keep their types.
+func TestNewCollector_NestedArgsFromEnv(t *testing.T) {
+	src := `
+provider = "hashicorp/aws"
+region   = "us-east-1"
+assume_role = [{
+  role_arn     = env.ROLE_ARN
+  session_name = "infracollect-${env.STAGE}"
+  duration     = 900
+  tags = {
+    team   = "platform"
+    secret = env.SECRET
+  }
+}]
+endpoints = {
+  sts = ["https://sts.${env.STAGE}.example.com", true]
+}
+`
+	file, diags := hclsyntax.ParseConfig([]byte(src), "collector.hcl", hcl.InitialPos)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"env": cty.ObjectVal(map[string]cty.Value{
+				"ROLE_ARN": cty.StringVal("arn:aws:iam::123:role/audit"),
+				"STAGE":    cty.StringVal("prod"),
+				"SECRET":   cty.StringVal("s3cr3t"),
+			}),
+		},
+	}
+
+	registry := engine.NewRegistry(zap.NewNop())
+	require.NoError(t, Register(registry))
+
+	collector, diags := registry.CreateCollector(CollectorKind, file.Body, ctx)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	assert.Equal(t, map[string]any{
+		"region": "us-east-1",
+		"assume_role": []any{map[string]any{
+			"role_arn":     "arn:aws:iam::123:role/audit",
+			"session_name": "infracollect-prod",
+			"duration":     json.Number("900"),
+			"tags": map[string]any{
+				"team":   "platform",
+				"secret": "s3cr3t",
+			},
+		}},
+		"endpoints": map[string]any{
+			"sts": []any{"https://sts.prod.example.com", true},
+		},
+	}, collector.(*Collector).args)
+}
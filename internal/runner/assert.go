@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// checkAsserts evaluates a step's assert blocks against its resolved result,
+// bound to `self` on top of ctx. Every failing assert is reported, using its
+// error_message when set. resultCty is the {data, meta} object produced by
+// resultToCty.
+func checkAsserts(ctx *hcl.EvalContext, meta *NodeMeta, resultCty cty.Value) error {
+	if len(meta.Asserts) == 0 {
+		return nil
+	}
+
+	assertCtx := ctx.NewChild()
+	assertCtx.Variables = map[string]cty.Value{RootSelf: resultCty}
+
+	var errs []error
+	for _, a := range meta.Asserts {
+		if err := checkAssert(assertCtx, a); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkAssert(ctx *hcl.EvalContext, a *AssertBlock) error {
+	val, diags := a.Condition.Value(ctx)
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to evaluate assert condition: %s", diags.Error())
+	}
+	val, err := convert.Convert(val, cty.Bool)
+	if err != nil || val.IsNull() || !val.IsKnown() {
+		return fmt.Errorf("assert condition at %s must be a boolean", a.Condition.Range())
+	}
+	if val.True() {
+		return nil
+	}
+
+	if a.ErrorMessage == nil {
+		return fmt.Errorf("assert condition at %s is false", a.Condition.Range())
+	}
+	msg, diags := a.ErrorMessage.Value(ctx)
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to evaluate assert error_message: %s", diags.Error())
+	}
+	msg, err = convert.Convert(msg, cty.String)
+	if err != nil || msg.IsNull() || !msg.IsKnown() {
+		return fmt.Errorf("assert error_message at %s must be a string", a.ErrorMessage.Range())
+	}
+	return errors.New(msg.AsString())
+}
@@ -0,0 +1,155 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_Asserts(t *testing.T) {
+	cases := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name: "condition holds",
+			src: `
+step "stub_list" "s" {
+  items = ["a", "b"]
+  assert {
+    condition = length(self.data) > 0
+  }
+}`,
+		},
+		{
+			name: "custom error message",
+			src: `
+step "stub_list" "instances" {
+  items = []
+  assert {
+    condition     = length(self.data) > 0
+    error_message = "no instances returned; check the credentials"
+  }
+}`,
+			wantErr: "step stub_list/instances failed an assertion: no instances returned; check the credentials",
+		},
+		{
+			name: "default message names the condition",
+			src: `
+step "stub_list" "s" {
+  items = []
+  assert {
+    condition = length(self.data) > 0
+  }
+}`,
+			wantErr: "assert condition at asserts.hcl:5,17-38 is false",
+		},
+		{
+			name: "every failing assert is reported",
+			src: `
+step "stub_list" "s" {
+  items = ["a"]
+  assert {
+    condition     = length(self.data) > 1
+    error_message = "too few"
+  }
+  assert {
+    condition     = contains(self.data, "b")
+    error_message = "b is missing"
+  }
+}`,
+			wantErr: "too few\nb is missing",
+		},
+		{
+			name: "non-boolean condition",
+			src: `
+step "stub_list" "s" {
+  items = ["a"]
+  assert {
+    condition = self.data
+  }
+}`,
+			wantErr: "must be a boolean",
+		},
+		{
+			name: "checked per for_each iteration",
+			src: `
+step "stub_list" "s" {
+  for_each = { one = ["a"], two = [] }
+  items    = each.value
+  assert {
+    condition     = length(self.data) > 0
+    error_message = "${each.key} is empty"
+  }
+}`,
+			wantErr: "step stub_list/s[two] failed an assertion: two is empty",
+		},
+		{
+			name: "condition referencing another step",
+			src: `
+step "stub_nocoll" "expected" {
+  count = 2
+}
+
+step "stub_list" "s" {
+  items = ["a", "b"]
+  assert {
+    condition = length(self.data) == step.stub_nocoll.expected.data.count
+  }
+}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := newStubRegistry(t)
+			registerListStub(t, stub.reg)
+
+			_, err := runSilently(t, newRunner(t, []byte(tc.src), "asserts.hcl", stub.reg))
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestParseJobTemplate_AssertsAreRunnerOwned(t *testing.T) {
+	tmpl, diags := ParseJobTemplate([]byte(`
+step "stub_nocoll" "s" {
+  greeting = "hi"
+  assert {
+    condition     = self.data.greeting == "hi"
+    error_message = "unexpected greeting"
+  }
+  assert {
+    condition = true
+  }
+}
+`), "asserts.hcl")
+	require.False(t, diags.HasErrors(), diags.Error())
+	require.Len(t, tmpl.Steps, 1)
+
+	s := tmpl.Steps[0]
+	require.Len(t, s.Asserts, 2)
+	assert.NotNil(t, s.Asserts[0].ErrorMessage)
+	assert.Nil(t, s.Asserts[1].ErrorMessage)
+
+	attrs, diags := s.Body.JustAttributes()
+	require.False(t, diags.HasErrors(), diags.Error())
+	assert.Contains(t, attrs, "greeting")
+}
+
+func TestParseJobTemplate_AssertRequiresCondition(t *testing.T) {
+	_, diags := ParseJobTemplate([]byte(`
+step "stub_nocoll" "s" {
+  assert {
+    error_message = "missing condition"
+  }
+}
+`), "asserts.hcl")
+	require.True(t, diags.HasErrors())
+	assert.Contains(t, diags.Error(), `The argument "condition" is required`)
+}
@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+// validateItemCount enforces a step's `min_items` / `max_items` bounds
+// against its resolved data. Bounds only make sense for array results, so a
+// step declaring either one fails when its data is anything else. resultCty
+// is the {data, meta} object produced by resultToCty.
+func validateItemCount(ctx *hcl.EvalContext, meta *NodeMeta, resultCty cty.Value) error {
+	if meta.MinItems == nil && meta.MaxItems == nil {
+		return nil
+	}
+
+	minItems, err := evalItemBound(ctx, "min_items", meta.MinItems)
+	if err != nil {
+		return err
+	}
+	maxItems, err := evalItemBound(ctx, "max_items", meta.MaxItems)
+	if err != nil {
+		return err
+	}
+	if minItems != nil && maxItems != nil && *minItems > *maxItems {
+		return fmt.Errorf("min_items (%d) is greater than max_items (%d)", *minItems, *maxItems)
+	}
+
+	data := resultCty.GetAttr("data")
+	ty := data.Type()
+	if data.IsNull() || !(ty.IsListType() || ty.IsTupleType() || ty.IsSetType()) {
+		return fmt.Errorf("min_items and max_items require an array result, got %s", ty.FriendlyName())
+	}
+
+	count := data.LengthInt()
+	if minItems != nil && count < *minItems {
+		return fmt.Errorf("expected at least %d items, got %d", *minItems, count)
+	}
+	if maxItems != nil && count > *maxItems {
+		return fmt.Errorf("expected at most %d items, got %d", *maxItems, count)
+	}
+	return nil
+}
+
+func evalItemBound(ctx *hcl.EvalContext, name string, expr hcl.Expression) (*int, error) {
+	if expr == nil {
+		return nil, nil
+	}
+
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to evaluate %s: %s", name, diags.Error())
+	}
+	// Accept numeric strings too, so bounds can come straight from env.
+	val, err := convert.Convert(val, cty.Number)
+	if err != nil || val.IsNull() || !val.IsKnown() {
+		return nil, fmt.Errorf("%s must be a number", name)
+	}
+
+	var n int
+	if err := gocty.FromCtyValue(val, &n); err != nil {
+		return nil, fmt.Errorf("%s must be a whole number", name)
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("%s must not be negative", name)
+	}
+	return &n, nil
+}
@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerListStub adds a collector-less "stub_list" step whose data is the
+// value of its `items` attribute, so tests can control the result shape.
+func registerListStub(t *testing.T, reg *engine.Registry) {
+	t.Helper()
+	factory := func(_ *engine.RegistryHelper, id string, _ engine.Collector, body hcl.Body, ctx *hcl.EvalContext) (engine.Step, hcl.Diagnostics) {
+		data, diags := engine.BodyToMap(body, ctx)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		return engine.StepFunction(id, "stub_list", func(context.Context) (engine.Result, error) {
+			return engine.Result{ID: id, Data: data["items"]}, nil
+		}), nil
+	}
+	require.NoError(t, reg.RegisterStep(engine.StepDescriptor{Kind: "stub_list", Factory: factory}))
+}
+
+func TestRunner_ItemCountBounds(t *testing.T) {
+	cases := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name: "in range",
+			src: `
+step "stub_list" "s" {
+  items     = ["a", "b", "c"]
+  min_items = 1
+  max_items = 3
+}`,
+		},
+		{
+			name: "under min",
+			src: `
+step "stub_list" "s" {
+  items     = ["a"]
+  min_items = 2
+}`,
+			wantErr: "step stub_list/s returned an unexpected result: expected at least 2 items, got 1",
+		},
+		{
+			name: "over max",
+			src: `
+step "stub_list" "s" {
+  items     = ["a", "b", "c"]
+  max_items = 2
+}`,
+			wantErr: "expected at most 2 items, got 3",
+		},
+		{
+			name: "empty array under min",
+			src: `
+step "stub_list" "s" {
+  items     = []
+  min_items = 1
+}`,
+			wantErr: "expected at least 1 items, got 0",
+		},
+		{
+			name: "bound from env string",
+			src: `
+step "stub_list" "s" {
+  items     = ["a", "b"]
+  min_items = "2"
+}`,
+		},
+		{
+			name: "non-array result",
+			src: `
+step "stub_list" "s" {
+  items     = { a = 1 }
+  min_items = 1
+}`,
+			wantErr: "require an array result",
+		},
+		{
+			name: "negative bound",
+			src: `
+step "stub_list" "s" {
+  items     = []
+  max_items = -1
+}`,
+			wantErr: "max_items must not be negative",
+		},
+		{
+			name: "fractional bound",
+			src: `
+step "stub_list" "s" {
+  items     = []
+  max_items = 1.5
+}`,
+			wantErr: "max_items must be a whole number",
+		},
+		{
+			name: "min greater than max",
+			src: `
+step "stub_list" "s" {
+  items     = ["a"]
+  min_items = 3
+  max_items = 2
+}`,
+			wantErr: "min_items (3) is greater than max_items (2)",
+		},
+		{
+			name: "checked per for_each iteration",
+			src: `
+step "stub_list" "s" {
+  for_each  = { one = ["a"], two = ["a", "b"] }
+  items     = each.value
+  max_items = 1
+}`,
+			wantErr: "step stub_list/s[two] returned an unexpected result: expected at most 1 items, got 2",
+		},
+		{
+			name: "bound referencing another step",
+			src: `
+step "stub_nocoll" "limits" {
+  max = 2
+}
+
+step "stub_list" "s" {
+  items     = ["a", "b"]
+  max_items = step.stub_nocoll.limits.data.max
+}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := newStubRegistry(t)
+			registerListStub(t, stub.reg)
+
+			_, err := runSilently(t, newRunner(t, []byte(tc.src), "bounds.hcl", stub.reg))
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestParseJobTemplate_ItemBoundsAreRunnerOwned(t *testing.T) {
+	tmpl, diags := ParseJobTemplate([]byte(`
+step "stub_nocoll" "s" {
+  min_items = 1
+  max_items = 5
+  greeting  = "hi"
+}
+`), "bounds.hcl")
+	require.False(t, diags.HasErrors(), diags.Error())
+	require.Len(t, tmpl.Steps, 1)
+
+	s := tmpl.Steps[0]
+	assert.NotNil(t, s.MinItems)
+	assert.NotNil(t, s.MaxItems)
+
+	attrs, diags := s.Body.JustAttributes()
+	require.False(t, diags.HasErrors(), diags.Error())
+	assert.Contains(t, attrs, "greeting")
+	assert.NotContains(t, attrs, "min_items")
+	assert.NotContains(t, attrs, "max_items")
+}
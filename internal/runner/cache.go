@@ -0,0 +1,298 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"go.uber.org/zap"
+)
+
+// cacheKeyVersion is mixed into every cache key; bump it when the key
+// derivation or the entry format changes so old entries are ignored.
+const cacheKeyVersion = "v1"
+
+// resultCache stores step results on disk, one JSON file per key. Entries
+// older than ttl are treated as missing.
+type resultCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// cacheEntry is the on-disk form of a cached result.
+type cacheEntry struct {
+	StoredAt time.Time     `json:"stored_at"`
+	Result   engine.Result `json:"result"`
+}
+
+func (c *resultCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// get returns the result stored under key. A missing or expired entry is a
+// miss, not an error.
+func (c *resultCache) get(key string) (engine.Result, bool, error) {
+	entry, ok, err := c.load(key)
+	if err != nil || !ok || c.now().Sub(entry.StoredAt) > c.ttl {
+		return engine.Result{}, false, err
+	}
+	return entry.Result, true, nil
+}
+
+// load returns the entry stored under key, however old. Expired entries are
+// still worth revalidating with the step that produced them.
+func (c *resultCache) load(key string) (cacheEntry, bool, error) {
+	raw, err := os.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return cacheEntry{}, false, nil
+	}
+	if err != nil {
+		return cacheEntry{}, false, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	// Numbers stay json.Number so integers survive the round-trip.
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var entry cacheEntry
+	if err := dec.Decode(&entry); err != nil {
+		return cacheEntry{}, false, fmt.Errorf("failed to decode cache entry: %w", err)
+	}
+	return entry, true, nil
+}
+
+// put stores result under key. The entry is written to a temporary file and
+// renamed so concurrent runs never read a half-written entry.
+func (c *resultCache) put(key string, result engine.Result) error {
+	raw, err := json.Marshal(cacheEntry{StoredAt: c.now(), Result: result})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(raw); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+	return nil
+}
+
+// cacheKeyFor returns the key under which the step's result is cached, or ""
+// when caching is disabled for the run or the step, or the step is not
+// cacheable. A step whose key cannot be computed is simply not cached; the
+// same evaluation problem surfaces when the step is created.
+func (r *Runner) cacheKeyFor(ctx *hcl.EvalContext, node Node, meta *NodeMeta) (string, error) {
+	if r.cache == nil || !r.cacheable(node, meta) {
+		return "", nil
+	}
+	noCache, err := evalNoCache(ctx, meta.NoCache)
+	if err != nil || noCache {
+		return "", err
+	}
+	key, err := r.stepCacheKey(ctx, node, meta)
+	if err != nil {
+		r.logger.Debug("step not cached",
+			zap.String("type", node.Type),
+			zap.String("id", node.ID),
+			zap.Error(err),
+		)
+		return "", nil
+	}
+	return key, nil
+}
+
+// cachedResult looks key up in the cache. Cache errors are logged and
+// treated as a miss so a broken cache never fails a run.
+func (r *Runner) cachedResult(node Node, key string) (engine.Result, bool) {
+	if key == "" {
+		return engine.Result{}, false
+	}
+	result, ok, err := r.cache.get(key)
+	if err != nil {
+		r.logger.Warn("failed to read cached step result",
+			zap.String("type", node.Type),
+			zap.String("id", node.ID),
+			zap.Error(err),
+		)
+		return engine.Result{}, false
+	}
+	if ok {
+		r.logger.Info("step result loaded from cache",
+			zap.String("type", node.Type),
+			zap.String("id", node.ID),
+		)
+	}
+	return result, ok
+}
+
+// resolveStep resolves step. When key has an expired cache entry and the
+// step is an engine.Revalidator, the step is asked to revalidate that entry
+// instead, which may spare it the full work.
+func (r *Runner) resolveStep(ctx context.Context, node Node, key string, step engine.Step) (engine.Result, error) {
+	revalidator, ok := step.(engine.Revalidator)
+	if !ok || key == "" {
+		return step.Resolve(ctx)
+	}
+	entry, ok, err := r.cache.load(key)
+	if err != nil {
+		r.logger.Warn("failed to read cached step result",
+			zap.String("type", node.Type),
+			zap.String("id", node.ID),
+			zap.Error(err),
+		)
+	}
+	if !ok {
+		return step.Resolve(ctx)
+	}
+
+	result, current, err := revalidator.Revalidate(ctx, entry.Result)
+	if err == nil && current {
+		r.logger.Info("cached step result revalidated",
+			zap.String("type", node.Type),
+			zap.String("id", node.ID),
+		)
+	}
+	return result, err
+}
+
+// storeResult caches a freshly resolved result, logging rather than failing
+// when the cache cannot be written.
+func (r *Runner) storeResult(node Node, key string, result engine.Result) {
+	if err := r.cache.put(key, result); err != nil {
+		r.logger.Warn("failed to cache step result",
+			zap.String("type", node.Type),
+			zap.String("id", node.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// evalNoCache evaluates a step's `no_cache` attribute; unset means false.
+func evalNoCache(ctx *hcl.EvalContext, expr hcl.Expression) (bool, error) {
+	if expr == nil {
+		return false, nil
+	}
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return false, fmt.Errorf("failed to evaluate no_cache: %s", diags.Error())
+	}
+	val, err := convert.Convert(val, cty.Bool)
+	if err != nil || val.IsNull() || !val.IsKnown() {
+		return false, fmt.Errorf("no_cache must be a boolean")
+	}
+	return val.True(), nil
+}
+
+// cacheable reports whether a step's result may be served from the cache:
+// it must be a plain (non for_each) step that references nothing but env.*,
+// job.* and its collector, and the collector itself must only reference env.*
+// and job.*. Anything fed by another step's result could change between runs
+// without its spec changing, so it is always resolved.
+func (r *Runner) cacheable(node Node, meta *NodeMeta) bool {
+	if node.Kind != NodeTypeStep {
+		return false
+	}
+	for _, ref := range meta.Refs {
+		if ref.Root != RootEnv && ref.Root != RootJob && ref.Root != RootCollector {
+			return false
+		}
+	}
+	if meta.CollectorAddr == nil {
+		return true
+	}
+	collectorMeta, ok := r.pipeline.Meta(Node{
+		Kind: NodeTypeCollector,
+		Type: meta.CollectorAddr.Type,
+		ID:   meta.CollectorAddr.Name,
+	})
+	return ok && onlyStaticRefs(collectorMeta.Refs)
+}
+
+// stepCacheKey hashes a step's type and ID with its body, and its collector's
+// body, as evaluated in ctx. Keys therefore change whenever a resolved
+// attribute value does, including env values and timestamp() results.
+func (r *Runner) stepCacheKey(ctx *hcl.EvalContext, node Node, meta *NodeMeta) (string, error) {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\nstep %q %q\n", cacheKeyVersion, node.Type, node.ID)
+	if err := writeResolvedBody(h, meta.Body, ctx); err != nil {
+		return "", err
+	}
+	if addr := meta.CollectorAddr; addr != nil {
+		collectorMeta, ok := r.pipeline.Meta(Node{Kind: NodeTypeCollector, Type: addr.Type, ID: addr.Name})
+		if !ok {
+			return "", fmt.Errorf("pipeline metadata missing for collector %s/%s", addr.Type, addr.Name)
+		}
+		_, _ = fmt.Fprintf(h, "collector %q %q\n", addr.Type, addr.Name)
+		if err := writeResolvedBody(h, collectorMeta.Body, ctx); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeResolvedBody writes a canonical form of body to w: attributes sorted
+// by name with their evaluated values, then nested blocks in source order.
+func writeResolvedBody(w io.Writer, body hcl.Body, ctx *hcl.EvalContext) error {
+	syn, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return fmt.Errorf("cannot compute a cache key for body of type %T", body)
+	}
+	for _, name := range slices.Sorted(maps.Keys(syn.Attributes)) {
+		attr := syn.Attributes[name]
+		val, diags := attr.Expr.Value(ctx)
+		if diags.HasErrors() {
+			return fmt.Errorf("failed to evaluate %s: %s", name, diags.Error())
+		}
+		val, _ = val.UnmarkDeep()
+		if !val.IsWhollyKnown() {
+			return fmt.Errorf("%s is not known", name)
+		}
+		encoded := []byte("null")
+		if !val.IsNull() {
+			// Marshalling as DynamicPseudoType records the type alongside
+			// the value, so 1 and "1" hash differently.
+			var err error
+			encoded, err = ctyjson.Marshal(val, cty.DynamicPseudoType)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s: %w", name, err)
+			}
+		}
+		_, _ = fmt.Fprintf(w, "attr %q %s\n", name, encoded)
+	}
+	for _, block := range syn.Blocks {
+		_, _ = fmt.Fprintf(w, "block %q %q {\n", block.Type, block.Labels)
+		if err := writeResolvedBody(w, block.Body, ctx); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(w, "}\n")
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+	"go.uber.org/zap"
+)
+
+// countingRegistry registers a collector-less "counting" step that echoes
+// its attributes like stub_nocoll and counts how often each step resolves.
+func countingRegistry(t *testing.T) (*engine.Registry, map[string]int) {
+	t.Helper()
+	reg := newStubRegistry(t).reg
+	resolves := make(map[string]int)
+	factory := func(_ *engine.RegistryHelper, id string, _ engine.Collector, body hcl.Body, ctx *hcl.EvalContext) (engine.Step, hcl.Diagnostics) {
+		data, diags := engine.BodyToMap(body, ctx)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		return engine.StepFunction(id, "counting", func(context.Context) (engine.Result, error) {
+			resolves[id]++
+			return engine.Result{ID: id, Data: data, Meta: map[string]string{"kind": "counting"}}, nil
+		}), nil
+	}
+	require.NoError(t, reg.RegisterStep(engine.StepDescriptor{Kind: "counting", Factory: factory}))
+	return reg, resolves
+}
+
+func runCached(t *testing.T, src string, reg *engine.Registry, cache *resultCache) map[string]engine.Result {
+	t.Helper()
+	tmpl, diags := ParseJobTemplate([]byte(src), "cache.hcl")
+	require.False(t, diags.HasErrors(), "parse: %s", diags.Error())
+	r, diags := New(zap.NewNop(), tmpl, reg, nil)
+	require.False(t, diags.HasErrors(), "new: %s", diags.Error())
+	r.cache = cache
+	out, err := runSilently(t, r)
+	require.NoError(t, err)
+	return out
+}
+
+func TestRunner_ResultCache(t *testing.T) {
+	tests := []struct {
+		name string
+		// first and second are run one after the other against the same
+		// cache directory.
+		first, second string
+		// wantResolves is how often each step resolved over both runs.
+		wantResolves map[string]int
+	}{
+		{
+			name:         "unchanged step is served from the cache",
+			first:        `step "counting" "a" { val = "x" }`,
+			second:       `step "counting" "a" { val = "x" }`,
+			wantResolves: map[string]int{"a": 1},
+		},
+		{
+			name:         "changed attribute misses",
+			first:        `step "counting" "a" { val = "x" }`,
+			second:       `step "counting" "a" { val = "y" }`,
+			wantResolves: map[string]int{"a": 2},
+		},
+		{
+			name:         "attribute type is part of the key",
+			first:        `step "counting" "a" { val = 1 }`,
+			second:       `step "counting" "a" { val = "1" }`,
+			wantResolves: map[string]int{"a": 2},
+		},
+		{
+			name:         "job name is part of the resolved spec",
+			first:        "job { name = \"one\" }\nstep \"counting\" \"a\" { val = job.name }",
+			second:       "job { name = \"two\" }\nstep \"counting\" \"a\" { val = job.name }",
+			wantResolves: map[string]int{"a": 2},
+		},
+		{
+			name: "step fed by another step is never cached",
+			first: `
+step "counting" "a" { val = "x" }
+step "counting" "b" { val = step.counting.a.data.val }`,
+			second: `
+step "counting" "a" { val = "x" }
+step "counting" "b" { val = step.counting.a.data.val }`,
+			wantResolves: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name: "no_cache opts out",
+			first: `step "counting" "a" {
+  val      = "x"
+  no_cache = true
+}`,
+			second: `step "counting" "a" {
+  val      = "x"
+  no_cache = true
+}`,
+			wantResolves: map[string]int{"a": 2},
+		},
+		{
+			name:         "for_each steps are never cached",
+			first:        `step "counting" "a" { for_each = { k = "v" } }`,
+			second:       `step "counting" "a" { for_each = { k = "v" } }`,
+			wantResolves: map[string]int{"a": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, resolves := countingRegistry(t)
+			cache := &resultCache{dir: t.TempDir(), ttl: time.Hour, now: time.Now}
+
+			first := runCached(t, tt.first, reg, cache)
+			second := runCached(t, tt.second, reg, cache)
+
+			assert.Equal(t, tt.wantResolves, resolves)
+			if tt.first == tt.second {
+				firstJSON, err := json.Marshal(first)
+				require.NoError(t, err)
+				secondJSON, err := json.Marshal(second)
+				require.NoError(t, err)
+				assert.JSONEq(t, string(firstJSON), string(secondJSON), "cached results must match fresh ones")
+			}
+		})
+	}
+}
+
+func TestRunner_ResultCacheExpires(t *testing.T) {
+	reg, resolves := countingRegistry(t)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := &resultCache{dir: t.TempDir(), ttl: time.Hour, now: func() time.Time { return now }}
+	src := `step "counting" "a" { val = "x" }`
+
+	runCached(t, src, reg, cache)
+	now = now.Add(30 * time.Minute)
+	runCached(t, src, reg, cache)
+	assert.Equal(t, 1, resolves["a"], "entry within ttl is reused")
+
+	now = now.Add(time.Hour)
+	runCached(t, src, reg, cache)
+	assert.Equal(t, 2, resolves["a"], "expired entry is resolved again")
+}
+
+// revalidatingStep counts resolves and revalidations; revalidation
+// reports the previous result as current when current is set.
+type revalidatingStep struct {
+	engine.Step
+	current       bool
+	revalidations *int
+	previous      *engine.Result
+}
+
+func (s *revalidatingStep) Revalidate(ctx context.Context, previous engine.Result) (engine.Result, bool, error) {
+	*s.revalidations++
+	*s.previous = previous
+	if s.current {
+		return previous, true, nil
+	}
+	result, err := s.Resolve(ctx)
+	return result, false, err
+}
+
+func TestRunner_ResultCacheRevalidates(t *testing.T) {
+	for _, current := range []bool{true, false} {
+		t.Run(fmt.Sprintf("current=%t", current), func(t *testing.T) {
+			reg := newStubRegistry(t).reg
+			resolves, revalidations := 0, 0
+			var previous engine.Result
+			factory := func(_ *engine.RegistryHelper, id string, _ engine.Collector, _ hcl.Body, _ *hcl.EvalContext) (engine.Step, hcl.Diagnostics) {
+				inner := engine.StepFunction(id, "revalidating", func(context.Context) (engine.Result, error) {
+					resolves++
+					return engine.Result{ID: id, Data: resolves, Meta: map[string]string{"etag": fmt.Sprint(resolves)}}, nil
+				})
+				return &revalidatingStep{Step: inner, current: current, revalidations: &revalidations, previous: &previous}, nil
+			}
+			require.NoError(t, reg.RegisterStep(engine.StepDescriptor{Kind: "revalidating", Factory: factory}))
+
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			cache := &resultCache{dir: t.TempDir(), ttl: time.Hour, now: func() time.Time { return now }}
+			src := `step "revalidating" "a" {}`
+
+			runCached(t, src, reg, cache)
+			now = now.Add(2 * time.Hour)
+			out := runCached(t, src, reg, cache)
+
+			assert.Equal(t, 1, revalidations, "expired entry is revalidated")
+			assert.Equal(t, "1", previous.Meta["etag"], "revalidation gets the cached result")
+			if current {
+				assert.Equal(t, 1, resolves)
+				assert.Equal(t, "1", out["revalidating/a"].Meta["etag"])
+			} else {
+				assert.Equal(t, 2, resolves)
+				assert.Equal(t, "2", out["revalidating/a"].Meta["etag"])
+			}
+
+			now = now.Add(30 * time.Minute)
+			runCached(t, src, reg, cache)
+			assert.Equal(t, 1, revalidations, "a revalidated entry is fresh again")
+		})
+	}
+}
+
+func TestResultCache_RoundTrip(t *testing.T) {
+	cache := &resultCache{dir: t.TempDir(), ttl: time.Hour, now: time.Now}
+
+	_, ok, err := cache.get("missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	want := engine.Result{
+		ID:   "a",
+		Data: map[string]any{"big": json.Number("9007199254740993"), "list": []any{"x"}},
+		Meta: map[string]string{"kind": "counting"},
+	}
+	require.NoError(t, cache.put("key", want))
+
+	got, ok, err := cache.get("key")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, want, got)
+}
+
+func TestRunner_StepCacheKeyIncludesCollector(t *testing.T) {
+	stub := newStubRegistry(t)
+	key := func(region string) string {
+		src := `
+collector "stub" "c" {
+  region = "` + region + `"
+}
+
+step "stub_step" "s" {
+  collector = collector.stub.c
+  val       = "x"
+}`
+		r := newRunner(t, []byte(src), "key.hcl", stub.reg)
+		r.cache = &resultCache{dir: t.TempDir(), ttl: time.Hour, now: time.Now}
+		node := Node{Kind: NodeTypeStep, Type: "stub_step", ID: "s"}
+		meta, ok := r.pipeline.Meta(node)
+		require.True(t, ok)
+		r.collectorByType["stub"] = map[string]cty.Value{"c": cty.EmptyObjectVal}
+
+		k, err := r.cacheKeyFor(r.childCtxForNode(), node, meta)
+		require.NoError(t, err)
+		require.NotEmpty(t, k)
+		return k
+	}
+
+	assert.Equal(t, key("eu-west-1"), key("eu-west-1"))
+	assert.NotEqual(t, key("eu-west-1"), key("us-east-1"))
+}
@@ -2,7 +2,9 @@ package runner
 
 import (
 	"fmt"
+	"slices"
 	"sort"
+	"strings"
 )
 
 type NodeType int
@@ -41,6 +43,16 @@ func (n Node) Key() string {
 	return fmt.Sprintf("%s:%s:%s", n.Kind.String(), n.Type, n.ID)
 }
 
+// Address renders the node the way a job file references it, e.g.
+// step.http_get.users. Collections are steps as far as users are concerned.
+func (n Node) Address() string {
+	root := RootStep
+	if n.Kind == NodeTypeCollector {
+		root = RootCollector
+	}
+	return root + "." + n.Type + "." + n.ID
+}
+
 func (n Node) String() string {
 	return fmt.Sprintf("Node<%v, %v, %v>", n.Kind.String(), n.Type, n.ID)
 }
@@ -141,6 +153,18 @@ func (g *DirectedAcyclicGraph) WouldCreateCycle(from, to Node) (bool, error) {
 	return found, nil
 }
 
+// Dependents returns the nodes with an edge from node, sorted by key. A node
+// appears once per edge, matching the in-degree counted by TopologicalSort.
+func (g *DirectedAcyclicGraph) Dependents(node Node) []Node {
+	keys := append([]string(nil), g.edges[node.Key()]...)
+	sort.Strings(keys)
+	out := make([]Node, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, g.nodes[key])
+	}
+	return out
+}
+
 func (g *DirectedAcyclicGraph) TopologicalSort() ([]Node, error) {
 	return g.kahnSort()
 }
@@ -189,12 +213,77 @@ func (g *DirectedAcyclicGraph) kahnSort() ([]Node, error) {
 	}
 
 	if len(order) != len(g.nodes) {
-		return nil, fmt.Errorf("cycle detected during topological sort (nodes=%d, sorted=%d)", len(g.nodes), len(order))
+		cycle := g.findCycle(inDegree)
+		addrs := make([]string, 0, len(cycle))
+		for _, node := range cycle {
+			addrs = append(addrs, node.Address())
+		}
+		return nil, fmt.Errorf("cycle detected: %s", strings.Join(addrs, " -> "))
 	}
 
 	return order, nil
 }
 
+// findCycle returns one cycle among the nodes kahnSort could not order,
+// closed by repeating its first node. Every such node still has an unsorted
+// predecessor, so walking predecessors from any of them must revisit a node;
+// the revisited stretch of the walk is the cycle.
+func (g *DirectedAcyclicGraph) findCycle(inDegree map[string]int) []Node {
+	preds := make(map[string][]string)
+	var remaining []string
+	for key, degree := range inDegree {
+		if degree > 0 {
+			remaining = append(remaining, key)
+		}
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+	sort.Strings(remaining)
+	for from, neighbors := range g.edges {
+		if inDegree[from] == 0 {
+			continue
+		}
+		for _, to := range neighbors {
+			if inDegree[to] > 0 {
+				preds[to] = append(preds[to], from)
+			}
+		}
+	}
+
+	seen := make(map[string]int)
+	var walk []string
+	current := remaining[0]
+	for {
+		if idx, ok := seen[current]; ok {
+			walk = walk[idx:]
+			break
+		}
+		seen[current] = len(walk)
+		walk = append(walk, current)
+		candidates := preds[current]
+		sort.Strings(candidates)
+		current = candidates[0]
+	}
+
+	// Reverse into edge direction and rotate so the cycle starts at its
+	// smallest key, keeping the message stable across runs.
+	slices.Reverse(walk)
+	first := 0
+	for i, key := range walk {
+		if key < walk[first] {
+			first = i
+		}
+	}
+	walk = append(walk[first:], walk[:first]...)
+
+	cycle := make([]Node, 0, len(walk)+1)
+	for _, key := range walk {
+		cycle = append(cycle, g.nodes[key])
+	}
+	return append(cycle, cycle[0])
+}
+
 func (g *DirectedAcyclicGraph) canReach(from, to Node) (bool, error) {
 	queue := []string{from.Key()}
 	visited := map[string]bool{from.Key(): true}
@@ -88,7 +88,7 @@ func TestDirectedAcyclicGraph_TopologicalSort_ReturnsErrorOnInvariantViolation(t
 
 	_, err := g.TopologicalSort()
 	require.Error(t, err)
-	assert.EqualError(t, err, "cycle detected during topological sort (nodes=2, sorted=0)")
+	assert.EqualError(t, err, "cycle detected: step.t.a -> step.t.b -> step.t.a")
 }
 
 func TestDirectedAcyclicGraph_TopologicalSort_AfterMutation(t *testing.T) {
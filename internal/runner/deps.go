@@ -15,6 +15,7 @@ const (
 	RootCollector = "collector"
 	RootStep      = "step"
 	RootEach      = "each"
+	RootSelf      = "self"
 )
 
 // Reference is a single dependency extracted from an HCL expression. For
@@ -112,7 +113,7 @@ func classifyTraversal(t hcl.Traversal) (*Reference, hcl.Diagnostics) {
 	}
 
 	switch root.Name {
-	case RootEnv, RootJob, RootEach:
+	case RootEnv, RootJob, RootEach, RootSelf:
 		return &Reference{Root: root.Name, Traversal: t}, nil
 
 	case RootCollector, RootStep:
@@ -167,7 +168,7 @@ func classifyTraversal(t hcl.Traversal) (*Reference, hcl.Diagnostics) {
 			Severity: hcl.DiagError,
 			Summary:  "Unknown reference",
 			Detail: fmt.Sprintf(
-				"%q is not a known namespace. Use one of: env, job, collector, step, each.",
+				"%q is not a known namespace. Use one of: env, job, collector, step, each, self.",
 				root.Name,
 			),
 			Subject: t.SourceRange().Ptr(),
@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+// EmptyResults returns the addresses (step.<type>.<id>) of the results in
+// results, as returned by Run, whose data is null, an empty object or an
+// empty array, sorted. A step that collected nothing often means missing
+// credentials or permissions rather than an empty inventory.
+func EmptyResults(results map[string]engine.Result) []string {
+	var empty []string
+	for key, result := range results {
+		if !isEmptyData(result.Data) {
+			continue
+		}
+		typ, id, _ := strings.Cut(key, "/")
+		empty = append(empty, Node{Kind: NodeTypeStep, Type: typ, ID: id}.Address())
+	}
+	slices.Sort(empty)
+	return empty
+}
+
+// isEmptyData reports whether data is nil, a nil pointer or interface, or a
+// map or slice with no elements. Steps return typed values as well as
+// map[string]any and []any, so the check goes through reflection.
+func isEmptyData(data any) bool {
+	if data == nil {
+		return true
+	}
+	v := reflect.ValueOf(data)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return true
+		}
+		return isEmptyData(v.Elem().Interface())
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmptyResults(t *testing.T) {
+	var nilMap map[string]any
+	results := map[string]engine.Result{
+		"static/nil":          {Data: nil},
+		"static/empty_map":    {Data: map[string]any{}},
+		"static/empty_list":   {Data: []any{}},
+		"http_get/typed":      {Data: []map[string]string{}},
+		"http_get/nil_map":    {Data: nilMap},
+		"http_get/nil_ptr":    {Data: (*struct{})(nil)},
+		"static/object":       {Data: map[string]any{"a": 1}},
+		"static/list":         {Data: []any{nil}},
+		"static/zero":         {Data: 0},
+		"static/empty_string": {Data: ""},
+	}
+
+	assert.Equal(t, []string{
+		"step.http_get.nil_map",
+		"step.http_get.nil_ptr",
+		"step.http_get.typed",
+		"step.static.empty_list",
+		"step.static.empty_map",
+		"step.static.nil",
+	}, EmptyResults(results))
+	assert.Empty(t, EmptyResults(map[string]engine.Result{"static/a": {Data: true}}))
+}
@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// envUsage records the env.* variables a job uses. all is set when the job
+// may use any of them: it references env as a whole, indexes it with a
+// computed key, or runs an exec step that inherits every passed variable.
+type envUsage struct {
+	all   bool
+	names map[string]bool
+}
+
+func (u *envUsage) uses(name string) bool {
+	return u.all || u.names[name]
+}
+
+// jobEnvUsage walks every block of tmpl for the env.* variables it uses,
+// either through an env.NAME reference or by an exec step or hook
+// inheriting them from the environment.
+func jobEnvUsage(tmpl *JobTemplate) *envUsage {
+	usage := &envUsage{names: make(map[string]bool)}
+
+	var bodies []hcl.Body
+	if tmpl.Job != nil {
+		for _, hook := range []*HookBlock{tmpl.Job.Pre, tmpl.Job.Post} {
+			if hook != nil {
+				bodies = append(bodies, hook.Body)
+				usage.inheritedBy(hook.Body)
+			}
+		}
+	}
+	for _, c := range tmpl.Collectors {
+		bodies = append(bodies, c.Body)
+	}
+	for _, s := range tmpl.Steps {
+		bodies = append(bodies, s.Body)
+		// splitStepMeta removes the assert and encoding blocks from Body.
+		if s.Encoding != nil {
+			bodies = append(bodies, s.Encoding.Body)
+		}
+		for _, a := range s.Asserts {
+			usage.referencedInExpr(a.Condition)
+			if a.ErrorMessage != nil {
+				usage.referencedInExpr(a.ErrorMessage)
+			}
+		}
+		// Hooks are built as exec steps, so hookStepKind names them too.
+		if s.Type == hookStepKind {
+			usage.inheritedBy(s.Body)
+		}
+	}
+	if tmpl.Output != nil {
+		bodies = append(bodies, tmpl.Output.Body)
+	}
+
+	for _, body := range bodies {
+		usage.referencedIn(body)
+	}
+	return usage
+}
+
+// referencedIn adds the env.* references of body, including its nested
+// blocks and the attributes split off by splitStepMeta.
+func (u *envUsage) referencedIn(body hcl.Body) {
+	syn, ok := body.(*hclsyntax.Body)
+	if !ok {
+		// Only native syntax can be walked; assume the worst.
+		u.all = true
+		return
+	}
+	u.visit(syn)
+}
+
+// referencedInExpr adds the env.* references of expr.
+func (u *envUsage) referencedInExpr(expr hcl.Expression) {
+	syn, ok := expr.(hclsyntax.Expression)
+	if !ok {
+		u.all = true
+		return
+	}
+	u.visit(syn)
+}
+
+func (u *envUsage) visit(syn hclsyntax.Node) {
+	_ = hclsyntax.VisitAll(syn, func(node hclsyntax.Node) hcl.Diagnostics {
+		expr, ok := node.(*hclsyntax.ScopeTraversalExpr)
+		if !ok || expr.Traversal.RootName() != RootEnv {
+			return nil
+		}
+		if len(expr.Traversal) < 2 {
+			// env on its own, or the collection of an env[key] index.
+			u.all = true
+			return nil
+		}
+		switch step := expr.Traversal[1].(type) {
+		case hcl.TraverseAttr:
+			u.names[step.Name] = true
+		case hcl.TraverseIndex:
+			u.addName(step.Key)
+		}
+		return nil
+	})
+}
+
+// addName adds name when it is a known string, and otherwise assumes any
+// variable may be read.
+func (u *envUsage) addName(name cty.Value) {
+	if name.Type() != cty.String || !name.IsKnown() || name.IsNull() {
+		u.all = true
+		return
+	}
+	u.names[name.AsString()] = true
+}
+
+// inheritedBy adds the variables the exec configuration in body passes to
+// its program: the allowed_env list when set, otherwise none with clean_env
+// and every variable passed to the job without.
+func (u *envUsage) inheritedBy(body hcl.Body) {
+	syn, ok := body.(*hclsyntax.Body)
+	if !ok {
+		u.all = true
+		return
+	}
+	attr, ok := syn.Attributes["allowed_env"]
+	if !ok {
+		if attr, ok := syn.Attributes["clean_env"]; ok {
+			clean, diags := attr.Expr.Value(nil)
+			if !diags.HasErrors() && clean.Type() == cty.Bool && clean.IsKnown() && !clean.IsNull() && clean.True() {
+				return
+			}
+		}
+		u.all = true
+		return
+	}
+	list, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !list.CanIterateElements() || !list.IsWhollyKnown() {
+		u.all = true
+		return
+	}
+	for it := list.ElementIterator(); it.Next(); {
+		_, name := it.Element()
+		u.addName(name)
+	}
+}
+
+// ValidateStrictEnv reports every variable of allowedEnv that none of tmpls
+// uses: a --pass-env with no matching env.* reference in any job is usually
+// a typo. Jobs run together share the flag, so a variable only one of them
+// needs is not reported.
+func ValidateStrictEnv(tmpls []*JobTemplate, allowedEnv []string) hcl.Diagnostics {
+	usages := make([]*envUsage, len(tmpls))
+	for i, tmpl := range tmpls {
+		usages[i] = jobEnvUsage(tmpl)
+	}
+	var diags hcl.Diagnostics
+	for _, name := range allowedEnv {
+		if slices.ContainsFunc(usages, func(u *envUsage) bool { return u.uses(name) }) {
+			continue
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unused environment variable",
+			Detail:   fmt.Sprintf("%s is passed to the job but never used: no expression references env.%s and no exec step inherits it. Remove --pass-env %s or fix the reference.", name, name, name),
+		})
+	}
+	return diags
+}
@@ -0,0 +1,175 @@
+package runner
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobEnvUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		wantAll  bool
+		wantUsed []string
+	}{
+		{
+			name: "references in collectors, steps and output",
+			src: `
+collector "stub" "c" {
+  token = env.TOKEN
+}
+
+step "stub_step" "s" {
+  collector = collector.stub.c
+  for_each  = split(",", env.REGIONS)
+  url       = "https://${env["HOST"]}/"
+  nested {
+    value = env.NESTED
+  }
+}
+
+output {
+  sink "s3" {
+    bucket = env.BUCKET
+  }
+}
+`,
+			wantUsed: []string{"TOKEN", "REGIONS", "HOST", "NESTED", "BUCKET"},
+		},
+		{
+			name: "references in assert blocks",
+			src: `
+step "stub_nocoll" "s" {
+  value = 1
+  assert {
+    condition     = self.data.value > tonumber(env.MIN_VALUE)
+    error_message = "value is below ${env.MIN_LABEL}"
+  }
+}
+`,
+			wantUsed: []string{"MIN_VALUE", "MIN_LABEL"},
+		},
+		{
+			name: "references in a step encoding block",
+			src: `
+step "stub_nocoll" "s" {
+  value = 1
+  encoding "json" {
+    indent = env.INDENT
+  }
+}
+`,
+			wantUsed: []string{"INDENT"},
+		},
+		{
+			name: "exec step with allowed_env",
+			src: `
+step "exec" "e" {
+  program     = ["env"]
+  allowed_env = ["AWS_PROFILE"]
+}
+`,
+			wantUsed: []string{"AWS_PROFILE"},
+		},
+		{
+			name: "exec step with clean_env inherits nothing",
+			src: `
+step "exec" "e" {
+  program   = ["env"]
+  clean_env = true
+}
+`,
+		},
+		{
+			name: "exec step with clean_env and allowed_env",
+			src: `
+step "exec" "e" {
+  program     = ["/usr/bin/env"]
+  clean_env   = true
+  allowed_env = ["KUBECONFIG"]
+}
+`,
+			wantUsed: []string{"KUBECONFIG"},
+		},
+		{
+			name: "exec step inherits everything",
+			src: `
+step "exec" "e" {
+  program = ["env"]
+}
+`,
+			wantAll: true,
+		},
+		{
+			name: "hook inherits everything",
+			src: `
+job {
+  pre {
+    program = ["true"]
+  }
+}
+`,
+			wantAll: true,
+		},
+		{
+			name: "whole env object",
+			src: `
+step "stub_nocoll" "s" {
+  vars = env
+}
+`,
+			wantAll: true,
+		},
+		{
+			name: "computed index",
+			src: `
+step "stub_nocoll" "s" {
+  value = env[upper("home")]
+}
+`,
+			wantAll: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, diags := ParseJobTemplate([]byte(tt.src), "env.hcl")
+			require.False(t, diags.HasErrors(), diags.Error())
+
+			usage := jobEnvUsage(tmpl)
+			assert.Equal(t, tt.wantAll, usage.all)
+			assert.ElementsMatch(t, tt.wantUsed, slices.Collect(maps.Keys(usage.names)))
+		})
+	}
+}
+
+func TestValidateStrictEnv(t *testing.T) {
+	parse := func(src string) *JobTemplate {
+		tmpl, diags := ParseJobTemplate([]byte(src), "strict.hcl")
+		require.False(t, diags.HasErrors(), diags.Error())
+		return tmpl
+	}
+	first := parse(`
+step "stub_nocoll" "s" {
+  greeting = env.USED
+}
+`)
+	second := parse(`
+step "stub_nocoll" "s" {
+  region = env.OTHER_JOB
+}
+`)
+
+	diags := ValidateStrictEnv([]*JobTemplate{first}, []string{"USED", "TYPO"})
+	require.Len(t, diags, 1)
+	assert.Equal(t, "Unused environment variable", diags[0].Summary)
+	assert.Contains(t, diags[0].Detail, "TYPO is passed to the job but never used")
+
+	diags = ValidateStrictEnv([]*JobTemplate{first, second}, []string{"USED", "OTHER_JOB", "TYPO"})
+	require.Len(t, diags, 1, "a variable used by any job is not reported")
+	assert.Contains(t, diags[0].Detail, "TYPO is passed")
+}
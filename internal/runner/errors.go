@@ -0,0 +1,13 @@
+package runner
+
+// StepError is returned by Run when a step, or one iteration of a for_each
+// step, fails. It carries the step's address so callers can report it without
+// parsing the message; Error returns the wrapped message unchanged.
+type StepError struct {
+	Kind string // the step type, e.g. "http_get"
+	ID   string // the step's label
+	Err  error
+}
+
+func (e *StepError) Error() string { return e.Err.Error() }
+func (e *StepError) Unwrap() error { return e.Err }
@@ -2,7 +2,11 @@ package runner
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/infracollect/infracollect/internal/runner/hclfuncs"
@@ -13,23 +17,39 @@ import (
 // populates:
 //
 //   - env.<VAR>: one entry per variable in allowedEnv, looked up from the
-//     process environment. A missing entry is a hard error — callers must
-//     pass an explicit --pass-env list.
+//     process environment, plus one per entry of jobVars. A jobVars entry
+//     wins over the process environment and need not be set there; any other
+//     missing entry is a hard error — callers must pass an explicit
+//     --pass-env list.
 //   - job.name: the effective job name from the optional job block.
-//   - functions: timestamp, timeadd, formatdate (see hclfuncs/datetime.go).
+//   - job.started_at: the RFC3339 UTC time the run started. It is the time
+//     of this call until Run resets it, so every expression of one run, and
+//     output paths in particular, sees the same instant while each --watch
+//     cycle sees its own.
+//   - functions: timestamp, timeadd, formatdate (see hclfuncs/datetime.go),
+//     length and contains (see hclfuncs/collection.go), upper, lower,
+//     trimspace, trim, replace and coalesce (see hclfuncs/strings.go) and
+//     vault, which fails until the runner is given a secret reader (see
+//     WithSecretReader).
 //
 // It does NOT populate step.* or collector.* — those are layered in per-node
 // at execution time once predecessors have completed. It also does not
 // populate each.* — that lives only inside a for_each iteration scope.
-func BuildBaseEvalContext(tmpl *JobTemplate, allowedEnv []string) (*hcl.EvalContext, error) {
+func BuildBaseEvalContext(tmpl *JobTemplate, allowedEnv []string, jobVars map[string]string) (*hcl.EvalContext, error) {
 	envMap := map[string]cty.Value{}
 	for _, name := range allowedEnv {
+		if _, ok := jobVars[name]; ok {
+			continue
+		}
 		val, ok := os.LookupEnv(name)
 		if !ok {
 			return nil, fmt.Errorf("environment variable %q is not set", name)
 		}
 		envMap[name] = cty.StringVal(val)
 	}
+	for name, val := range jobVars {
+		envMap[name] = cty.StringVal(val)
+	}
 
 	// cty.ObjectVal panics on an empty attribute map; substitute the empty
 	// object sentinel when no env vars are in the allow-list.
@@ -38,15 +58,80 @@ func BuildBaseEvalContext(tmpl *JobTemplate, allowedEnv []string) (*hcl.EvalCont
 		envVal = cty.ObjectVal(envMap)
 	}
 
-	jobVal := cty.ObjectVal(map[string]cty.Value{
-		"name": cty.StringVal(tmpl.JobName()),
-	})
+	jobVal := jobObject(tmpl.JobName(), time.Now())
+
+	functions := hclfuncs.Datetime()
+	maps.Copy(functions, hclfuncs.Collection())
+	maps.Copy(functions, hclfuncs.Strings())
+	functions["vault"] = hclfuncs.VaultFunc(nil)
 
 	return &hcl.EvalContext{
 		Variables: map[string]cty.Value{
 			"env": envVal,
 			"job": jobVal,
 		},
-		Functions: hclfuncs.Datetime(),
+		Functions: functions,
 	}, nil
 }
+
+// jobObject builds the job.* namespace.
+func jobObject(name string, startedAt time.Time) cty.Value {
+	return cty.ObjectVal(map[string]cty.Value{
+		"name":       cty.StringVal(name),
+		"started_at": cty.StringVal(startedAt.UTC().Format(time.RFC3339)),
+	})
+}
+
+// Variable is one variable of a base evaluation context, as listed by
+// --print-vars.
+type Variable struct {
+	// Name is the reference used in expressions, e.g. env.HOME.
+	Name  string
+	Value string
+	// Builtin marks the variables infracollect defines itself (job.*), as
+	// opposed to the env.* pass-through.
+	Builtin bool
+	// Sensitive marks values whose name suggests a secret; Value is left
+	// empty for them.
+	Sensitive bool
+}
+
+// sensitiveNameParts are the substrings that mark an env var as a secret.
+var sensitiveNameParts = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "KEY", "CREDENTIAL", "AUTH", "PRIVATE"}
+
+// ListVariables flattens the variables of a context built by
+// BuildBaseEvalContext, sorted by name.
+func ListVariables(ctx *hcl.EvalContext) []Variable {
+	var vars []Variable
+	for _, ns := range slices.Sorted(maps.Keys(ctx.Variables)) {
+		val := ctx.Variables[ns]
+		if !val.Type().IsObjectType() {
+			continue
+		}
+		for _, attr := range slices.Sorted(maps.Keys(val.Type().AttributeTypes())) {
+			v := Variable{Name: ns + "." + attr, Builtin: ns != "env"}
+			if !v.Builtin && isSensitiveName(attr) {
+				v.Sensitive = true
+			} else if av := val.GetAttr(attr); av.Type() == cty.String && av.IsKnown() && !av.IsNull() {
+				v.Value = av.AsString()
+			}
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// FunctionNames returns the functions available in ctx, sorted.
+func FunctionNames(ctx *hcl.EvalContext) []string {
+	return slices.Sorted(maps.Keys(ctx.Functions))
+}
+
+func isSensitiveName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, part := range sensitiveNameParts {
+		if strings.Contains(upper, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package runner
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -13,7 +14,7 @@ func TestBuildBaseEvalContext_EnvAllowlist(t *testing.T) {
 	t.Setenv("INFRACOLLECT_TEST_BAZ", "qux")
 
 	tmpl := &JobTemplate{Job: &JobBlock{Name: "j"}}
-	ctx, err := BuildBaseEvalContext(tmpl, []string{"INFRACOLLECT_TEST_FOO", "INFRACOLLECT_TEST_BAZ"})
+	ctx, err := BuildBaseEvalContext(tmpl, []string{"INFRACOLLECT_TEST_FOO", "INFRACOLLECT_TEST_BAZ"}, nil)
 	require.NoError(t, err)
 
 	envVal := ctx.Variables["env"]
@@ -24,7 +25,7 @@ func TestBuildBaseEvalContext_EnvAllowlist(t *testing.T) {
 
 func TestBuildBaseEvalContext_MissingEnvVar(t *testing.T) {
 	tmpl := &JobTemplate{Job: &JobBlock{Name: "j"}}
-	_, err := BuildBaseEvalContext(tmpl, []string{"INFRACOLLECT_DEFINITELY_MISSING"})
+	_, err := BuildBaseEvalContext(tmpl, []string{"INFRACOLLECT_DEFINITELY_MISSING"}, nil)
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "INFRACOLLECT_DEFINITELY_MISSING")
 	assert.ErrorContains(t, err, "not set")
@@ -32,7 +33,7 @@ func TestBuildBaseEvalContext_MissingEnvVar(t *testing.T) {
 
 func TestBuildBaseEvalContext_EmptyAllowlist(t *testing.T) {
 	tmpl := &JobTemplate{Job: &JobBlock{Name: "j"}}
-	ctx, err := BuildBaseEvalContext(tmpl, nil)
+	ctx, err := BuildBaseEvalContext(tmpl, nil, nil)
 	require.NoError(t, err)
 
 	envVal := ctx.Variables["env"]
@@ -44,7 +45,7 @@ func TestBuildBaseEvalContext_EmptyAllowlist(t *testing.T) {
 
 func TestBuildBaseEvalContext_JobNameBinding(t *testing.T) {
 	tmpl := &JobTemplate{Job: &JobBlock{Name: "my-job"}}
-	ctx, err := BuildBaseEvalContext(tmpl, nil)
+	ctx, err := BuildBaseEvalContext(tmpl, nil, nil)
 	require.NoError(t, err)
 
 	jobVal := ctx.Variables["job"]
@@ -53,7 +54,7 @@ func TestBuildBaseEvalContext_JobNameBinding(t *testing.T) {
 
 func TestBuildBaseEvalContext_TimeFunctions(t *testing.T) {
 	tmpl := &JobTemplate{Job: &JobBlock{Name: "j"}}
-	ctx, err := BuildBaseEvalContext(tmpl, nil)
+	ctx, err := BuildBaseEvalContext(tmpl, nil, nil)
 	require.NoError(t, err)
 
 	// Confirm the datetime functions are wired in. We don't assert on exact
@@ -64,3 +65,51 @@ func TestBuildBaseEvalContext_TimeFunctions(t *testing.T) {
 		assert.True(t, ok, "function %q should be registered", name)
 	}
 }
+
+func TestListVariables(t *testing.T) {
+	t.Setenv("INFRACOLLECT_TEST_REGION", "eu-west-1")
+	t.Setenv("INFRACOLLECT_TEST_API_TOKEN", "hunter2")
+
+	tmpl := &JobTemplate{Job: &JobBlock{Name: "my-job"}}
+	ctx, err := BuildBaseEvalContext(tmpl, []string{"INFRACOLLECT_TEST_REGION", "INFRACOLLECT_TEST_API_TOKEN"}, nil)
+	require.NoError(t, err)
+
+	vars := ListVariables(ctx)
+	require.Len(t, vars, 4)
+	startedAt, err := time.Parse(time.RFC3339, vars[3].Value)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), startedAt, time.Minute)
+	vars[3].Value = ""
+
+	assert.Equal(t, []Variable{
+		{Name: "env.INFRACOLLECT_TEST_API_TOKEN", Sensitive: true},
+		{Name: "env.INFRACOLLECT_TEST_REGION", Value: "eu-west-1"},
+		{Name: "job.name", Value: "my-job", Builtin: true},
+		{Name: "job.started_at", Builtin: true},
+	}, vars)
+	assert.Equal(t, []string{
+		"coalesce", "contains", "formatdate", "length", "lower", "replace",
+		"timeadd", "timestamp", "trim", "trimspace", "upper", "vault",
+	}, FunctionNames(ctx))
+}
+
+func TestBuildBaseEvalContext_JobVars(t *testing.T) {
+	t.Setenv("INFRACOLLECT_TEST_REGION", "from-env")
+
+	tmpl := &JobTemplate{Job: &JobBlock{Name: "j"}}
+	ctx, err := BuildBaseEvalContext(tmpl,
+		[]string{"INFRACOLLECT_TEST_REGION", "INFRACOLLECT_TEST_UNSET"},
+		map[string]string{
+			"INFRACOLLECT_TEST_REGION": "from-var",
+			"INFRACOLLECT_TEST_UNSET":  "only-var",
+			"SINCE":                    "2026-01-01",
+		},
+	)
+	require.NoError(t, err, "a job var satisfies a --pass-env name that is not set")
+
+	envVal := ctx.Variables["env"]
+	assert.Equal(t, cty.StringVal("from-var"), envVal.GetAttr("INFRACOLLECT_TEST_REGION"), "job vars win over the environment")
+	assert.Equal(t, cty.StringVal("only-var"), envVal.GetAttr("INFRACOLLECT_TEST_UNSET"))
+	assert.Equal(t, cty.StringVal("2026-01-01"), envVal.GetAttr("SINCE"))
+	assert.Equal(t, cty.StringVal("j"), ctx.Variables["job"].GetAttr("name"), "job vars never touch job.*")
+}
@@ -0,0 +1,42 @@
+package runner
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// outputFilename returns the sink path, without extension, the result stored
+// under key (<type>/<id>) is written to. A nil expr keeps key itself;
+// otherwise expr is evaluated with step_type and step_id in scope and must
+// yield a relative path that stays inside the output.
+func outputFilename(expr hcl.Expression, baseCtx *hcl.EvalContext, key string) (string, error) {
+	if expr == nil {
+		return key, nil
+	}
+
+	stepType, stepID, _ := strings.Cut(key, "/")
+	ctx := baseCtx.NewChild()
+	ctx.Variables = map[string]cty.Value{
+		"step_type": cty.StringVal(stepType),
+		"step_id":   cty.StringVal(stepID),
+	}
+
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return "", fmt.Errorf("failed to evaluate output filename for step %s: %w", key, diags)
+	}
+	if val.IsNull() || !val.IsKnown() || val.Type() != cty.String {
+		return "", fmt.Errorf("output filename for step %s must be a string, got %s", key, val.Type().FriendlyName())
+	}
+
+	name := val.AsString()
+	cleaned := path.Clean(name)
+	if name == "" || cleaned == "." || path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("output filename %q for step %s must be a relative path inside the output", name, key)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,17 @@
+package hclfuncs
+
+import (
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// Collection returns the collection functions, taken from go-cty's stdlib:
+//
+//	length(["a", "b"])         // 2
+//	contains(["a", "b"], "b")  // true
+func Collection() map[string]function.Function {
+	return map[string]function.Function{
+		"length":   stdlib.LengthFunc,
+		"contains": stdlib.ContainsFunc,
+	}
+}
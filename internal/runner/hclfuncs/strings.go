@@ -0,0 +1,56 @@
+package hclfuncs
+
+import (
+	"errors"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// CoalesceFunc returns its first argument that is neither null nor an empty
+// string. Unlike go-cty's stdlib coalesce, which only skips nulls, it skips
+// empty strings so that an unset-but-passed env var falls through to the
+// default: coalesce(env.REGION, "eu-west-1").
+var CoalesceFunc = function.New(&function.Spec{
+	VarParam: &function.Parameter{
+		Name:             "vals",
+		Type:             cty.String,
+		AllowNull:        true,
+		AllowUnknown:     true,
+		AllowDynamicType: true,
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		for _, arg := range args {
+			if !arg.IsKnown() {
+				return cty.UnknownVal(cty.String), nil
+			}
+			if arg.IsNull() || arg.AsString() == "" {
+				continue
+			}
+			return arg, nil
+		}
+		return cty.NilVal, errors.New("coalesce: every argument is null or empty")
+	},
+})
+
+// Strings returns the string functions, taken from go-cty's stdlib except
+// for coalesce:
+//
+//	upper("eu-west-1")               // "EU-WEST-1"
+//	lower("EU-WEST-1")               // "eu-west-1"
+//	trimspace("  eu-west-1\n")       // "eu-west-1"
+//	trim("--eu-west-1--", "-")       // "eu-west-1"
+//	replace("eu-west-1", "-", "_")   // "eu_west_1"
+//	coalesce(env.REGION, "eu-west-1")
+func Strings() map[string]function.Function {
+	return map[string]function.Function{
+		"upper":     stdlib.UpperFunc,
+		"lower":     stdlib.LowerFunc,
+		"trimspace": stdlib.TrimSpaceFunc,
+		"trim":      stdlib.TrimFunc,
+		"replace":   stdlib.ReplaceFunc,
+		"coalesce":  CoalesceFunc,
+	}
+}
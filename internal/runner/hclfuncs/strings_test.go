@@ -0,0 +1,63 @@
+package hclfuncs
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestStrings(t *testing.T) {
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"env": cty.ObjectVal(map[string]cty.Value{
+				"REGION": cty.StringVal("EU-West-1"),
+				"EMPTY":  cty.StringVal(""),
+			}),
+		},
+		Functions: Strings(),
+	}
+
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{expr: `"${lower(env.REGION)}"`, want: "eu-west-1"},
+		{expr: `upper(env.REGION)`, want: "EU-WEST-1"},
+		{expr: `trimspace("  eu-west-1\n")`, want: "eu-west-1"},
+		{expr: `trim("--eu-west-1--", "-")`, want: "eu-west-1"},
+		{expr: `"prefix/${replace(lower(env.REGION), "-", "_")}"`, want: "prefix/eu_west_1"},
+		{expr: `coalesce(env.EMPTY, null, env.REGION)`, want: "EU-West-1"},
+		{expr: `coalesce(env.EMPTY, "us-east-1")`, want: "us-east-1"},
+		{expr: `coalesce(env.EMPTY, null)`, wantErr: "every argument is null or empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, diags := hclsyntax.ParseExpression([]byte(tt.expr), "test.hcl", hcl.InitialPos)
+			require.False(t, diags.HasErrors(), diags.Error())
+
+			val, diags := expr.Value(ctx)
+			if tt.wantErr != "" {
+				require.True(t, diags.HasErrors())
+				assert.Contains(t, diags.Error(), tt.wantErr)
+				return
+			}
+			require.False(t, diags.HasErrors(), diags.Error())
+			assert.Equal(t, tt.want, val.AsString())
+		})
+	}
+}
+
+func TestCoalesceFunc_Unknown(t *testing.T) {
+	val, err := CoalesceFunc.Call([]cty.Value{cty.StringVal(""), cty.UnknownVal(cty.String), cty.StringVal("b")})
+	require.NoError(t, err)
+	assert.False(t, val.IsKnown(), "an unknown argument before the first non-empty one makes the result unknown")
+
+	val, err = CoalesceFunc.Call([]cty.Value{cty.StringVal("a"), cty.UnknownVal(cty.String)})
+	require.NoError(t, err)
+	assert.Equal(t, cty.StringVal("a"), val)
+}
@@ -0,0 +1,119 @@
+package hclfuncs
+
+import (
+	"fmt"
+	"sync"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// SecretReader reads the key/value pairs stored at a secret path.
+type SecretReader interface {
+	ReadSecret(path string) (map[string]any, error)
+}
+
+// VaultFunc returns the `vault` function, which reads one string key of a
+// secret through reader:
+//
+//	vault("secret/data/app", "api_key")
+//
+// Secrets are fetched lazily, when an expression calling vault() is first
+// evaluated, and each path is read at most once. With a nil reader every
+// call fails, so jobs that use vault() without a configured Vault get a
+// clear error instead of an unknown function.
+func VaultFunc(reader SecretReader) function.Function {
+	var (
+		mu    sync.Mutex
+		cache = map[string]map[string]any{}
+	)
+
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+			{Name: "key", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			path, key := args[0].AsString(), args[1].AsString()
+			if reader == nil {
+				return cty.NilVal, fmt.Errorf("vault: cannot read %q: vault is not configured (set --vault-addr)", path)
+			}
+
+			mu.Lock()
+			data, ok := cache[path]
+			mu.Unlock()
+			if !ok {
+				var err error
+				data, err = reader.ReadSecret(path)
+				if err != nil {
+					return cty.NilVal, fmt.Errorf("vault: failed to read %q: %w", path, err)
+				}
+				mu.Lock()
+				cache[path] = data
+				mu.Unlock()
+			}
+
+			value, ok := data[key]
+			if !ok {
+				return cty.NilVal, fmt.Errorf("vault: secret %q has no key %q", path, key)
+			}
+			s, ok := value.(string)
+			if !ok {
+				return cty.NilVal, fmt.Errorf("vault: key %q of secret %q is not a string", key, path)
+			}
+			return cty.StringVal(s), nil
+		},
+	})
+}
+
+// VaultSecretReader reads secrets through the Vault HTTP API. KV version 2
+// responses are unwrapped, so callers see the secret's keys either way.
+type VaultSecretReader struct {
+	client *vault.Client
+}
+
+// NewVaultSecretReader builds a reader for the Vault server at addr,
+// authenticating with token. Other settings (TLS, namespace) come from the
+// standard VAULT_* environment variables.
+func NewVaultSecretReader(addr, token string) (*VaultSecretReader, error) {
+	cfg := vault.DefaultConfig()
+	if cfg.Error != nil {
+		return nil, fmt.Errorf("failed to load vault config: %w", cfg.Error)
+	}
+	cfg.Address = addr
+
+	client, err := vault.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault client: %w", err)
+	}
+	if token != "" {
+		client.SetToken(token)
+	}
+	return &VaultSecretReader{client: client}, nil
+}
+
+func (r *VaultSecretReader) ReadSecret(path string) (map[string]any, error) {
+	secret, err := r.client.Logical().Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("no secret found")
+	}
+	return unwrapKVv2(secret.Data), nil
+}
+
+// unwrapKVv2 returns the inner data of a KV v2 read, which nests the
+// secret's keys under "data" next to a "metadata" object.
+func unwrapKVv2(data map[string]any) map[string]any {
+	inner, ok := data["data"].(map[string]any)
+	if !ok {
+		return data
+	}
+	if _, ok := data["metadata"]; !ok {
+		return data
+	}
+	return inner
+}
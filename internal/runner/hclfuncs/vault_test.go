@@ -0,0 +1,41 @@
+package hclfuncs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultSecretReader_ReadSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "t0ken", req.Header.Get("X-Vault-Token"))
+		w.Header().Set("Content-Type", "application/json")
+		switch req.URL.Path {
+		case "/v1/secret/data/app":
+			_, _ = w.Write([]byte(`{"data":{"data":{"api_key":"k3y"},"metadata":{"version":3}}}`))
+		case "/v1/kv/app":
+			_, _ = w.Write([]byte(`{"data":{"api_key":"v1-k3y"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	reader, err := NewVaultSecretReader(server.URL, "t0ken")
+	require.NoError(t, err)
+
+	data, err := reader.ReadSecret("secret/data/app")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"api_key": "k3y"}, data, "KV v2 responses are unwrapped")
+
+	data, err = reader.ReadSecret("kv/app")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"api_key": "v1-k3y"}, data)
+
+	_, err = reader.ReadSecret("secret/data/missing")
+	assert.ErrorContains(t, err, "no secret found")
+}
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/infracollect/infracollect/internal/engine"
+	"go.uber.org/zap"
+)
+
+// hookStepKind is the step kind hooks are built with, so they share the
+// exec step's configuration, timeout and env allow-listing.
+const hookStepKind = "exec"
+
+// hookDefaults fills in hook attributes that differ from the exec step's
+// defaults: a hook's output is discarded, so it is not parsed as JSON.
+var hookDefaults = []byte(`format = "raw"`)
+
+// buildHook builds the exec step of a job hook, evaluated against ctx. name
+// is "pre" or "post". A nil block builds nothing.
+func (r *Runner) buildHook(name string, block *HookBlock, ctx *hcl.EvalContext) (engine.Step, hcl.Diagnostics) {
+	if block == nil {
+		return nil, nil
+	}
+
+	body := block.Body
+	if syn, ok := body.(*hclsyntax.Body); ok {
+		if _, set := syn.Attributes["format"]; !set {
+			defaults, diags := hclsyntax.ParseConfig(hookDefaults, name+" hook defaults", hcl.InitialPos)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+			body = hcl.MergeBodies([]hcl.Body{body, defaults.Body})
+		}
+	}
+
+	step, diags := r.registry.CreateStep(hookStepKind, name, nil, body, ctx)
+	for _, diag := range diags {
+		diag.Summary = fmt.Sprintf("Invalid %s hook: %s", name, diag.Summary)
+	}
+	return step, diags
+}
+
+// validateHooks builds the job's hooks without running them, so a broken
+// hook is reported when the runner is created rather than mid-run.
+func (r *Runner) validateHooks() hcl.Diagnostics {
+	if r.tmpl.Job == nil {
+		return nil
+	}
+	_, diags := r.buildHook("pre", r.tmpl.Job.Pre, r.baseCtx)
+	_, postDiags := r.buildHook("post", r.tmpl.Job.Post, r.baseCtx)
+	return append(diags, postDiags...)
+}
+
+// runHook builds and runs a job hook, discarding its output.
+func (r *Runner) runHook(ctx context.Context, name string, block *HookBlock) error {
+	step, diags := r.buildHook(name, block, r.baseCtx)
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to build %s hook: %w", name, diags)
+	}
+	if step == nil {
+		return nil
+	}
+
+	r.logger.Info("running hook", zap.String("hook", name))
+	if _, err := step.Resolve(ctx); err != nil {
+		return fmt.Errorf("%s hook failed: %w", name, err)
+	}
+	return nil
+}
+
+// runPostHook runs the post hook once the run is over, whatever its outcome.
+// It runs even when ctx was cancelled, bounded by the hook's own timeout,
+// and its failure is logged rather than returned so it never hides the
+// run's error.
+func (r *Runner) runPostHook(ctx context.Context) {
+	if r.tmpl.Job == nil || r.tmpl.Job.Post == nil {
+		return
+	}
+	if err := r.runHook(context.WithoutCancel(ctx), "post", r.tmpl.Job.Post); err != nil {
+		r.logger.Warn("post hook failed", zap.Error(err))
+	}
+}
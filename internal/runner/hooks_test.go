@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/infracollect/infracollect/internal/engine/steps"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newHookRegistry returns the stub registry with the builtin steps, which
+// hooks are built from, registered on top.
+func newHookRegistry(t *testing.T) *stubRegistry {
+	t.Helper()
+	stub := newStubRegistry(t)
+	stub.reg.RegisterDependency(engine.AllowedEnvVarsDepKey, []string{})
+	require.NoError(t, steps.Register(stub.reg))
+	return stub
+}
+
+func TestRunner_Hooks(t *testing.T) {
+	tests := []struct {
+		name        string
+		pre         string
+		post        string
+		errContains string
+		wantLog     string
+	}{
+		{
+			name:    "pre and post around the run",
+			pre:     `echo pre >> "$LOG"`,
+			post:    `echo post >> "$LOG"`,
+			wantLog: "pre\nstep\npost\n",
+		},
+		{
+			name:        "pre failure aborts the run but post still runs",
+			pre:         `echo nope >&2; exit 3`,
+			post:        `echo post >> "$LOG"`,
+			errContains: "pre hook failed: command failed: exit status 3: nope",
+			wantLog:     "post\n",
+		},
+		{
+			name:    "post failure is not a run failure",
+			pre:     `echo pre >> "$LOG"`,
+			post:    `exit 1`,
+			wantLog: "pre\nstep\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newHookRegistry(t)
+			log := filepath.Join(t.TempDir(), "log")
+
+			src := []byte(fmt.Sprintf(`
+job {
+  pre {
+    program = ["sh", "-c", %[2]q]
+    env     = { LOG = %[1]q }
+  }
+  post {
+    program = ["sh", "-c", %[3]q]
+    env     = { LOG = %[1]q }
+  }
+}
+
+step "exec" "work" {
+  program = ["sh", "-c", "echo step >> \"$LOG\"; echo '{}'"]
+  env     = { LOG = %[1]q }
+}
+`, log, tt.pre, tt.post))
+
+			_, err := runSilently(t, newRunner(t, src, "hooks.hcl", stub.reg))
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+
+			data, err := os.ReadFile(log)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLog, string(data))
+		})
+	}
+}
+
+func TestRunner_HookValidation(t *testing.T) {
+	stub := newHookRegistry(t)
+	tmpl, diags := ParseJobTemplate([]byte(`
+job {
+  post {
+    timeout = "5s"
+  }
+}
+`), "hooks.hcl")
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	_, diags = New(zap.NewNop(), tmpl, stub.reg, nil)
+	require.True(t, diags.HasErrors())
+	assert.Contains(t, diags.Error(), "Invalid post hook")
+	assert.Contains(t, diags.Error(), `"program" is required`)
+}
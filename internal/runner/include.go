@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ParseOption configures ParseJobTemplate.
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	includes bool
+	overlays []overlaySource
+}
+
+// WithIncludes lets the job file pull in other files with top-level
+// `include { path = "..." }` blocks. Paths are resolved relative to the
+// including file, so only enable it for job files read from local disk:
+// a job fetched from a URL or registry must not read arbitrary local files.
+func WithIncludes() ParseOption {
+	return func(o *parseOptions) {
+		o.includes = true
+	}
+}
+
+var includeSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "include"}},
+}
+
+var includeBlockSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{{Name: "path", Required: true}},
+}
+
+// resolveIncludes returns body without its include blocks, followed by the
+// bodies of the files they include, depth-first and in declaration order.
+// The result is meant for hcl.MergeBodies, so blocks from included files
+// take part in the same decoding and duplicate checks as the job's own.
+// chain holds the absolute paths of the files being included, from the job
+// file down, to reject cycles.
+func resolveIncludes(parser *hclparse.Parser, body hcl.Body, filename string, opts parseOptions, chain []string) ([]hcl.Body, hcl.Diagnostics) {
+	content, _, diags := body.PartialContent(includeSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	bodies := []hcl.Body{withoutBlocks(body, "include")}
+
+	for _, block := range content.Blocks {
+		if !opts.includes {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Include not allowed",
+				Detail:   "Only job files read from local disk may include other files.",
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+
+		path, pathDiags := includePath(block, filename)
+		diags = append(diags, pathDiags...)
+		if pathDiags.HasErrors() {
+			continue
+		}
+
+		if slices.Contains(chain, path) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Include cycle",
+				Detail:   fmt.Sprintf("%s includes itself through %s.", path, filename),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Failed to read included file",
+				Detail:   err.Error(),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+		file, fileDiags := parser.ParseHCL(data, path)
+		diags = append(diags, fileDiags...)
+		if fileDiags.HasErrors() {
+			continue
+		}
+
+		included, incDiags := resolveIncludes(parser, file.Body, path, opts, append(slices.Clone(chain), path))
+		diags = append(diags, incDiags...)
+		bodies = append(bodies, included...)
+	}
+	return bodies, diags
+}
+
+// includePath evaluates an include block's path, which must be a literal
+// string, and resolves it against the directory of the including file.
+func includePath(block *hcl.Block, filename string) (string, hcl.Diagnostics) {
+	content, diags := block.Body.Content(includeBlockSchema)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	attr := content.Attributes["path"]
+	value, valDiags := attr.Expr.Value(nil)
+	if valDiags.HasErrors() || value.IsNull() || !value.Type().Equals(cty.String) {
+		return "", hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid include path",
+			Detail:   "The include path must be a literal string; it is read before any expression can be evaluated.",
+			Subject:  attr.Expr.Range().Ptr(),
+		}}
+	}
+
+	path := value.AsString()
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(filename), path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid include path",
+			Detail:   err.Error(),
+			Subject:  attr.Expr.Range().Ptr(),
+		}}
+	}
+	return abs, nil
+}
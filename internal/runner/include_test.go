@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeJobFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(src), 0o600))
+	}
+	return dir
+}
+
+func TestParseJobTemplate_Includes(t *testing.T) {
+	dir := writeJobFiles(t, map[string]string{
+		"job.hcl": `
+include {
+  path = "common/collectors.hcl"
+}
+
+step "static" "local" {
+  value = "x"
+}
+`,
+		"common/collectors.hcl": `
+include {
+  path = "steps.hcl"
+}
+
+collector "terraform" "aws" {
+  provider = "hashicorp/aws"
+}
+`,
+		"common/steps.hcl": `
+step "static" "shared" {
+  value = "y"
+}
+`,
+	})
+	filename := filepath.Join(dir, "job.hcl")
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	tmpl, diags := ParseJobTemplate(data, filename, WithIncludes())
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	require.Len(t, tmpl.Collectors, 1)
+	assert.Equal(t, "aws", tmpl.Collectors[0].Name)
+	assert.Equal(t, filepath.Join(dir, "common", "collectors.hcl"), tmpl.Collectors[0].DefRange.Filename)
+
+	ids := make([]string, len(tmpl.Steps))
+	for i, step := range tmpl.Steps {
+		ids[i] = step.Name
+	}
+	assert.Equal(t, []string{"local", "shared"}, ids)
+}
+
+func TestParseJobTemplate_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		opts    []ParseOption
+		wantMsg string
+	}{
+		{
+			name:    "includes not enabled",
+			files:   map[string]string{"job.hcl": `include { path = "other.hcl" }`},
+			wantMsg: "Include not allowed",
+		},
+		{
+			name:    "missing file",
+			files:   map[string]string{"job.hcl": `include { path = "missing.hcl" }`},
+			opts:    []ParseOption{WithIncludes()},
+			wantMsg: "Failed to read included file",
+		},
+		{
+			name:    "expression path",
+			files:   map[string]string{"job.hcl": `include { path = env.COMMON }`},
+			opts:    []ParseOption{WithIncludes()},
+			wantMsg: "The include path must be a literal string",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"job.hcl": `include { path = "a.hcl" }`,
+				"a.hcl":   `include { path = "job.hcl" }`,
+			},
+			opts:    []ParseOption{WithIncludes()},
+			wantMsg: "Include cycle",
+		},
+		{
+			name: "duplicate step across files",
+			files: map[string]string{
+				"job.hcl": "include { path = \"a.hcl\" }\nstep \"static\" \"s\" {}",
+				"a.hcl":   `step "static" "s" {}`,
+			},
+			opts:    []ParseOption{WithIncludes()},
+			wantMsg: "Duplicate step",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeJobFiles(t, tt.files)
+			filename := filepath.Join(dir, "job.hcl")
+			_, diags := ParseJobTemplate([]byte(tt.files["job.hcl"]), filename, tt.opts...)
+			require.True(t, diags.HasErrors())
+			assert.Contains(t, diags.Error(), tt.wantMsg)
+		})
+	}
+}
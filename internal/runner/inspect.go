@@ -0,0 +1,221 @@
+package runner
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+const (
+	// RedactedValue replaces values that may hold a secret: vault() calls,
+	// anything built from an env var, and attributes or object keys with a
+	// secret-looking name.
+	RedactedValue = "(sensitive)"
+	// UnknownValue replaces values that depend on something only known
+	// once the job runs, such as another step's data.
+	UnknownValue = "(known after run)"
+)
+
+// sensitiveMark marks values derived from a secret while inspecting.
+type sensitiveMark struct{}
+
+// JobInspection is the configuration of every collector and step of a job,
+// as returned by Runner.Inspect.
+type JobInspection struct {
+	Job   string     `json:"job"`
+	Nodes []NodeSpec `json:"nodes"`
+}
+
+// NodeSpec is the configuration of one collector or step with its
+// expressions evaluated. Nested blocks appear under their type, then their
+// labels, as a list of bodies, mirroring HCL's JSON syntax.
+type NodeSpec struct {
+	// Kind is "collector" or "step".
+	Kind    string `json:"kind"`
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Address string `json:"address"`
+	// Collector is the address of the collector a step is bound to.
+	Collector string `json:"collector,omitempty"`
+	// ForEach is the evaluated for_each of a step that declares one.
+	ForEach any            `json:"for_each,omitempty"`
+	Config  map[string]any `json:"config"`
+}
+
+// Inspect evaluates the body of every collector and step, in execution
+// order, without starting collectors or resolving steps. Values that depend
+// on other nodes are reported as UnknownValue and secrets as RedactedValue;
+// vault() is never called, so inspecting needs no access to Vault.
+func (r *Runner) Inspect() (*JobInspection, error) {
+	order, err := r.pipeline.dag.TopologicalSort()
+	if err != nil {
+		return nil, fmt.Errorf("could not sort DAG: %w", err)
+	}
+
+	ectx := r.inspectEvalContext()
+	inspection := &JobInspection{Job: r.tmpl.JobName(), Nodes: []NodeSpec{}}
+	for _, node := range order {
+		meta, ok := r.pipeline.Meta(node)
+		if !ok {
+			return nil, fmt.Errorf("no metadata for node %s", node)
+		}
+
+		spec := NodeSpec{
+			Kind:    RootStep,
+			Type:    node.Type,
+			ID:      node.ID,
+			Address: node.Address(),
+		}
+		if node.Kind == NodeTypeCollector {
+			spec.Kind = RootCollector
+		}
+		if meta.CollectorAddr != nil {
+			spec.Collector = RootCollector + "." + meta.CollectorAddr.Type + "." + meta.CollectorAddr.Name
+		}
+		if meta.ForEach != nil {
+			val, diags := meta.ForEach.Value(ectx)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("%s: failed to evaluate for_each: %s", node.Address(), diags.Error())
+			}
+			if spec.ForEach, err = inspectValue(val); err != nil {
+				return nil, fmt.Errorf("%s: for_each: %w", node.Address(), err)
+			}
+		}
+
+		if spec.Config, err = inspectBody(meta.Body, ectx); err != nil {
+			return nil, fmt.Errorf("%s: %w", node.Address(), err)
+		}
+		inspection.Nodes = append(inspection.Nodes, spec)
+	}
+	return inspection, nil
+}
+
+// inspectEvalContext returns the base context with every reference to
+// another node left unknown, every env var marked sensitive, and vault()
+// replaced by a function returning a sensitive unknown string. Env vars are
+// all redacted since their names say nothing reliable about their values.
+func (r *Runner) inspectEvalContext() *hcl.EvalContext {
+	vars := maps.Clone(r.baseCtx.Variables)
+	if env, ok := vars[RootEnv]; ok && env.Type().IsObjectType() && env.LengthInt() > 0 {
+		attrs := env.AsValueMap()
+		for name, val := range attrs {
+			attrs[name] = val.Mark(sensitiveMark{})
+		}
+		vars[RootEnv] = cty.ObjectVal(attrs)
+	}
+	for _, root := range []string{RootStep, RootCollector, RootEach, RootSelf} {
+		vars[root] = cty.DynamicVal
+	}
+
+	funcs := maps.Clone(r.baseCtx.Functions)
+	funcs["vault"] = function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+			{Name: "key", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			return cty.UnknownVal(cty.String).Mark(sensitiveMark{}), nil
+		},
+	})
+
+	return &hcl.EvalContext{Variables: vars, Functions: funcs}
+}
+
+func inspectBody(body hcl.Body, ctx *hcl.EvalContext) (map[string]any, error) {
+	syn, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("cannot inspect body of type %T", body)
+	}
+
+	// JustAttributes leaves out the runner-owned attributes splitStepMeta
+	// hid; its complaint about nested blocks does not apply here.
+	attrs, _ := syn.JustAttributes()
+	config := make(map[string]any, len(attrs)+len(syn.Blocks))
+	for _, name := range slices.Sorted(maps.Keys(attrs)) {
+		if isSensitiveName(name) {
+			config[name] = RedactedValue
+			continue
+		}
+		val, diags := attrs[name].Expr.Value(ctx)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to evaluate %s: %s", name, diags.Error())
+		}
+		v, err := inspectValue(val)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		config[name] = v
+	}
+
+	for _, block := range syn.Blocks {
+		blockConfig, err := inspectBody(block.Body, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s block: %w", block.Type, err)
+		}
+		parent := config
+		key := block.Type
+		for _, label := range block.Labels {
+			child, _ := parent[key].(map[string]any)
+			if child == nil {
+				child = make(map[string]any)
+				parent[key] = child
+			}
+			parent, key = child, label
+		}
+		list, _ := parent[key].([]any)
+		parent[key] = append(list, blockConfig)
+	}
+	return config, nil
+}
+
+// inspectValue converts val for display, replacing each part that carries
+// a secret, sits under a secret-looking key, or is not known yet.
+func inspectValue(val cty.Value) (any, error) {
+	if val.IsMarked() {
+		return RedactedValue, nil
+	}
+	if !val.IsKnown() {
+		return UnknownValue, nil
+	}
+	if val.IsNull() {
+		return engine.CtyToAny(val)
+	}
+
+	ty := val.Type()
+	switch {
+	case ty.IsObjectType() || ty.IsMapType():
+		out := make(map[string]any, val.LengthInt())
+		for k, v := range val.AsValueMap() {
+			if isSensitiveName(k) {
+				out[k] = RedactedValue
+				continue
+			}
+			converted, err := inspectValue(v)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = converted
+		}
+		return out, nil
+	case ty.IsTupleType() || ty.IsListType() || ty.IsSetType():
+		out := make([]any, 0, val.LengthInt())
+		for _, v := range val.AsValueSlice() {
+			converted, err := inspectValue(v)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, converted)
+		}
+		return out, nil
+	case ty.IsPrimitiveType():
+		return engine.CtyToAny(val)
+	}
+	return nil, fmt.Errorf("cannot inspect value of type %s", ty.FriendlyName())
+}
@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingSecretReader struct{ t *testing.T }
+
+func (f failingSecretReader) ReadSecret(path string) (map[string]any, error) {
+	f.t.Errorf("inspect must not read secret %q", path)
+	return nil, nil
+}
+
+func TestRunner_Inspect(t *testing.T) {
+	src := `
+collector "stub" "c" {
+  region = env.REGION
+  token  = "literal-secret"
+
+  auth "basic" {
+    username = "ana"
+    password = vault("secret/app", "password")
+  }
+}
+
+step "stub_step" "a" {
+  collector = collector.stub.c
+  url       = "https://${env.REGION}.example.com"
+  header    = "Bearer ${env.API_TOKEN}"
+  headers   = { Accept = "application/json", Authorization = "Bearer ${env.API_TOKEN}" }
+}
+
+step "stub_step" "gh" {
+  collector = collector.stub.c
+  url       = "https://api.github.com"
+  headers   = { Authorization = "Bearer ${env.GH}", "X-Api-Key" = "k3y", Accept = "application/json" }
+  query     = { nested = { client_secret = "s3cr3t", page = 1 } }
+}
+
+step "stub_nocoll" "b" {
+  for_each = { x = "1", y = "2" }
+  name     = each.value
+  upstream = step.stub_step.a.data.url
+  urls     = ["https://a.example.com", step.stub_step.a.data.url]
+  region   = "${env.REGION}-1"
+}
+`
+	stub := newStubRegistry(t)
+	r := newRunnerWithOptions(t, []byte(src), stub.reg,
+		WithJobVars(map[string]string{"REGION": "eu", "API_TOKEN": "t0k3n", "GH": "ghp_secret"}),
+		WithSecretReader(failingSecretReader{t: t}),
+	)
+
+	inspection, err := r.Inspect()
+	require.NoError(t, err)
+
+	require.Len(t, inspection.Nodes, 4)
+	printed, err := json.Marshal(inspection)
+	require.NoError(t, err)
+	for _, secret := range []string{"ghp_secret", "t0k3n", "k3y", "s3cr3t"} {
+		assert.NotContains(t, string(printed), secret)
+	}
+	assert.Equal(t, NodeSpec{
+		Kind:    "collector",
+		Type:    "stub",
+		ID:      "c",
+		Address: "collector.stub.c",
+		Config: map[string]any{
+			"region": RedactedValue,
+			"token":  RedactedValue,
+			"auth": map[string]any{
+				"basic": []any{map[string]any{
+					"username": "ana",
+					"password": RedactedValue,
+				}},
+			},
+		},
+	}, inspectedNode(t, inspection, "collector.stub.c"))
+	assert.Equal(t, NodeSpec{
+		Kind:      "step",
+		Type:      "stub_step",
+		ID:        "a",
+		Address:   "step.stub_step.a",
+		Collector: "collector.stub.c",
+		Config: map[string]any{
+			"url":    RedactedValue,
+			"header": RedactedValue,
+			"headers": map[string]any{
+				"Accept":        "application/json",
+				"Authorization": RedactedValue,
+			},
+		},
+	}, inspectedNode(t, inspection, "step.stub_step.a"))
+	assert.Equal(t, map[string]any{
+		"url": "https://api.github.com",
+		"headers": map[string]any{
+			"Accept":        "application/json",
+			"Authorization": RedactedValue,
+			"X-Api-Key":     RedactedValue,
+		},
+		"query": map[string]any{
+			"nested": map[string]any{"client_secret": RedactedValue, "page": json.Number("1")},
+		},
+	}, inspectedNode(t, inspection, "step.stub_step.gh").Config)
+	assert.Equal(t, NodeSpec{
+		Kind:    "step",
+		Type:    "stub_nocoll",
+		ID:      "b",
+		Address: "step.stub_nocoll.b",
+		ForEach: map[string]any{"x": "1", "y": "2"},
+		Config: map[string]any{
+			"name":     UnknownValue,
+			"upstream": UnknownValue,
+			"urls":     []any{"https://a.example.com", UnknownValue},
+			"region":   RedactedValue,
+		},
+	}, inspectedNode(t, inspection, "step.stub_nocoll.b"))
+}
+
+func inspectedNode(t *testing.T, inspection *JobInspection, address string) NodeSpec {
+	t.Helper()
+	for _, node := range inspection.Nodes {
+		if node.Address == address {
+			return node
+		}
+	}
+	t.Fatalf("no node %s", address)
+	return NodeSpec{}
+}
@@ -0,0 +1,227 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	hclExpressionType = reflect.TypeFor[hcl.Expression]()
+	hclBodyType       = reflect.TypeFor[hcl.Body]()
+)
+
+// JobJSONSchema describes a collect job, in HCL's JSON syntax, as a JSON
+// Schema document. Collector and step bodies are derived from the config
+// structs recorded in the registry, so the schema tracks exactly the kinds
+// the binary supports. In JSON syntax any attribute may also be written as a
+// "${...}" template string, so every non-string attribute accepts a string
+// too.
+func JobJSONSchema(registry *engine.Registry) map[string]any {
+	collectors := make(map[string]any)
+	for _, kind := range registry.AvailableCollectors() {
+		body := map[string]any{"type": "object"}
+		if t, ok := registry.CollectorConfigType(kind); ok {
+			body = bodyJSONSchema(t)
+		}
+		collectors[kind] = labeledJSONSchema(body, 1)
+	}
+
+	// assert blocks are split off by splitStepMeta, so they have no Go
+	// struct to reflect over.
+	assertBlock := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"condition":     map[string]any{"type": []any{"boolean", "string"}},
+			"error_message": map[string]any{"type": "string"},
+		},
+		"required":             []any{"condition"},
+		"additionalProperties": false,
+	}
+	steps := make(map[string]any)
+	for _, kind := range registry.AvailableSteps() {
+		body := map[string]any{"type": "object"}
+		if desc, ok := registry.StepDescriptor(kind); ok && desc.ConfigType != nil {
+			body = bodyJSONSchema(desc.ConfigType)
+		}
+		withProperties(body, map[string]any{
+			"for_each":  map[string]any{},
+			"when":      map[string]any{"type": []any{"boolean", "string"}},
+			"min_items": map[string]any{"type": []any{"integer", "string"}},
+			"max_items": map[string]any{"type": []any{"integer", "string"}},
+			"no_cache":  map[string]any{"type": []any{"boolean", "string"}},
+			"depends_on": map[string]any{
+				"type":  []any{"array", "string"},
+				"items": map[string]any{"type": "string"},
+			},
+			"encoding": blockJSONSchema(reflect.TypeFor[*EncodingBlock]()),
+			"assert": map[string]any{
+				"anyOf": []any{assertBlock, map[string]any{"type": "array", "items": assertBlock}},
+			},
+		})
+		if desc, ok := registry.StepDescriptor(kind); ok && len(desc.AllowedCollectorKinds) > 0 {
+			withProperties(body, map[string]any{
+				"collector": map[string]any{"type": "string"},
+			})
+		}
+		steps[kind] = labeledJSONSchema(body, 1)
+	}
+
+	output := bodyJSONSchema(reflect.TypeFor[OutputBlock]())
+	withProperties(output, map[string]any{
+		"steps":    map[string]any{"type": []any{"array", "string"}, "items": map[string]any{"type": "string"}},
+		"filename": map[string]any{"type": "string"},
+	})
+	// splitOutputMeta rejects anything in the remain body besides `steps`
+	// and `filename`.
+	output["additionalProperties"] = false
+
+	return map[string]any{
+		"$schema":              jsonSchemaDialect,
+		"title":                "infracollect collect job",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"job": bodyJSONSchema(reflect.TypeFor[JobBlock]()),
+			"collector": map[string]any{
+				"type":                 "object",
+				"properties":           collectors,
+				"additionalProperties": false,
+			},
+			"step": map[string]any{
+				"type":                 "object",
+				"properties":           steps,
+				"additionalProperties": false,
+			},
+			"output": output,
+		},
+	}
+}
+
+// bodyJSONSchema reflects over the `hcl:"..."` tags of a gohcl config struct.
+// Label fields are skipped (they become object keys one level up, see
+// labeledJSONSchema) and a `,remain` field opens the body to arbitrary
+// attributes.
+func bodyJSONSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	properties := make(map[string]any)
+	var required []any
+	open := false
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("hcl")
+		if !ok {
+			continue
+		}
+		name, kind, _ := strings.Cut(tag, ",")
+
+		switch kind {
+		case "label":
+			continue
+		case "remain":
+			open = true
+		case "block":
+			properties[name] = blockJSONSchema(field.Type)
+			if field.Type.Kind() != reflect.Pointer && field.Type.Kind() != reflect.Slice {
+				required = append(required, name)
+			}
+		case "optional":
+			properties[name] = attrJSONSchema(field.Type)
+		default:
+			properties[name] = attrJSONSchema(field.Type)
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": open,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func blockJSONSchema(t reflect.Type) map[string]any {
+	repeated := t.Kind() == reflect.Slice
+	if repeated {
+		t = t.Elem()
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	labels := 0
+	for i := range t.NumField() {
+		if tag, ok := t.Field(i).Tag.Lookup("hcl"); ok && strings.HasSuffix(tag, ",label") {
+			labels++
+		}
+	}
+
+	schema := labeledJSONSchema(bodyJSONSchema(t), labels)
+	if repeated && labels == 0 {
+		return map[string]any{
+			"anyOf": []any{schema, map[string]any{"type": "array", "items": schema}},
+		}
+	}
+	return schema
+}
+
+// labeledJSONSchema nests body under one object level per block label, which
+// is how HCL's JSON syntax spells `block "label1" "label2" { ... }`.
+func labeledJSONSchema(body map[string]any, labels int) map[string]any {
+	for range labels {
+		body = map[string]any{
+			"type":                 "object",
+			"additionalProperties": body,
+		}
+	}
+	return body
+}
+
+func attrJSONSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == hclExpressionType || t == hclBodyType || t.Kind() == reflect.Interface {
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": []any{"boolean", "string"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": []any{"integer", "string"}}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": []any{"number", "string"}}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": []any{"array", "string"}, "items": attrJSONSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": []any{"object", "string"}, "additionalProperties": attrJSONSchema(t.Elem())}
+	}
+	return map[string]any{}
+}
+
+func withProperties(schema map[string]any, props map[string]any) {
+	existing, _ := schema["properties"].(map[string]any)
+	if existing == nil {
+		existing = make(map[string]any)
+		schema["properties"] = existing
+	}
+	for k, v := range props {
+		existing[k] = v
+	}
+}
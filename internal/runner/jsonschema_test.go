@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type schemaTestCollectorConfig struct {
+	BaseURL string            `hcl:"base_url"`
+	Timeout *int              `hcl:"timeout,optional"`
+	Headers map[string]string `hcl:"headers,optional"`
+	Auth    *schemaTestAuth   `hcl:"auth,block"`
+}
+
+type schemaTestAuth struct {
+	Kind     string `hcl:"kind,label"`
+	Username string `hcl:"username,optional"`
+}
+
+type schemaTestStepConfig struct {
+	Path string   `hcl:"path"`
+	Rest hcl.Body `hcl:",remain"`
+}
+
+func schemaTestRegistry(t *testing.T) *engine.Registry {
+	t.Helper()
+	reg := engine.NewRegistry(zap.NewNop())
+	require.NoError(t, engine.RegisterTypedCollector(reg, "api", func(*engine.RegistryHelper, *hcl.EvalContext, schemaTestCollectorConfig) (engine.Collector, error) {
+		return nil, nil
+	}))
+	require.NoError(t, reg.RegisterSteps(
+		engine.NewTypedStepDescriptor("api_get", "api", func(*engine.RegistryHelper, string, *stubCollector, *hcl.EvalContext, schemaTestStepConfig) (engine.Step, error) {
+			return nil, nil
+		}),
+		engine.NewTypedStepDescriptorWithoutCollector("noop", func(*engine.RegistryHelper, string, *hcl.EvalContext, struct{}) (engine.Step, error) {
+			return nil, nil
+		}),
+	))
+	return reg
+}
+
+// schemaAt walks a decoded JSON schema along the given keys.
+func schemaAt(t *testing.T, schema map[string]any, path ...string) map[string]any {
+	t.Helper()
+	cur := schema
+	for _, key := range path {
+		next, ok := cur[key].(map[string]any)
+		require.True(t, ok, "missing %q while walking %v", key, path)
+		cur = next
+	}
+	return cur
+}
+
+func TestJobJSONSchema(t *testing.T) {
+	data, err := json.Marshal(JobJSONSchema(schemaTestRegistry(t)))
+	require.NoError(t, err)
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	assert.Equal(t, jsonSchemaDialect, schema["$schema"])
+	assert.ElementsMatch(t, []any{"job", "collector", "step", "output"}, keys(schemaAt(t, schema, "properties")))
+
+	t.Run("collector body is nested per label", func(t *testing.T) {
+		body := schemaAt(t, schema, "properties", "collector", "properties", "api", "additionalProperties")
+		assert.Equal(t, []any{"base_url"}, body["required"])
+		assert.Equal(t, false, body["additionalProperties"])
+
+		props := schemaAt(t, body, "properties")
+		assert.Equal(t, map[string]any{"type": "string"}, props["base_url"])
+		assert.Equal(t, map[string]any{"type": []any{"integer", "string"}}, props["timeout"])
+		assert.Equal(t, []any{"object", "string"}, schemaAt(t, props, "headers")["type"])
+
+		auth := schemaAt(t, props, "auth", "additionalProperties", "properties")
+		assert.Contains(t, auth, "username")
+		assert.NotContains(t, auth, "kind", "labels become object keys, not attributes")
+	})
+
+	t.Run("steps carry runner-owned attributes", func(t *testing.T) {
+		apiGet := schemaAt(t, schema, "properties", "step", "properties", "api_get", "additionalProperties")
+		assert.Equal(t, true, apiGet["additionalProperties"], "remain opens the body")
+		assert.Contains(t, schemaAt(t, apiGet, "properties"), "collector")
+		assert.Contains(t, schemaAt(t, apiGet, "properties"), "for_each")
+
+		noop := schemaAt(t, schema, "properties", "step", "properties", "noop", "additionalProperties")
+		assert.NotContains(t, schemaAt(t, noop, "properties"), "collector", "collector-less steps cannot bind a collector")
+	})
+
+	t.Run("output exposes its blocks and steps filter", func(t *testing.T) {
+		props := schemaAt(t, schema, "properties", "output", "properties")
+		assert.ElementsMatch(t, []any{"encoding", "archive", "sink", "retry", "steps", "filename", "write_report", "combined", "raw", "sign"}, keys(props))
+	})
+}
+
+func keys(m map[string]any) []any {
+	out := make([]any, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
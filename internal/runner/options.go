@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/infracollect/infracollect/internal/runner/hclfuncs"
+)
+
+// Option configures optional Runner behavior.
+type Option func(*Runner)
+
+// WithStartupConcurrency bounds how many collectors the runner starts in
+// parallel before walking the DAG. Values below 1 are treated as 1, which
+// starts collectors one at a time.
+func WithStartupConcurrency(n int) Option {
+	return func(r *Runner) {
+		r.startupConcurrency = max(n, 1)
+	}
+}
+
+// WithSecretReader backs the vault() expression function with reader.
+// Without it, vault() calls fail with a "not configured" error.
+func WithSecretReader(reader hclfuncs.SecretReader) Option {
+	return func(r *Runner) {
+		r.secretReader = reader
+	}
+}
+
+// WithJobVars adds vars to the env.* namespace of job expressions. They take
+// precedence over process environment variables of the same name, which then
+// need not be set.
+func WithJobVars(vars map[string]string) Option {
+	return func(r *Runner) {
+		r.jobVars = vars
+	}
+}
+
+// WithStepConcurrency bounds how many independent steps (and collectors that
+// wait on steps) run at the same time. Values below 1 are treated as 1, which
+// runs nodes one at a time in topological order.
+func WithStepConcurrency(n int) Option {
+	return func(r *Runner) {
+		r.stepConcurrency = max(n, 1)
+	}
+}
+
+// WithPartialFlush makes an archive job that is cancelled mid-run (Ctrl-C,
+// a timeout) write the results collected so far to an archive named with
+// PartialArchiveSuffix, including the run report marked partial. Without it
+// a cancelled run writes no archive at all.
+func WithPartialFlush(enabled bool) Option {
+	return func(r *Runner) {
+		r.partialFlush = enabled
+	}
+}
+
+// WithResultCache serves step results from dir when an identical step was
+// resolved less than ttl ago, and stores new results there. Only steps whose
+// inputs are fixed before the run starts are cached; see cacheable.
+func WithResultCache(dir string, ttl time.Duration) Option {
+	return func(r *Runner) {
+		r.cache = &resultCache{dir: dir, ttl: ttl, now: time.Now}
+	}
+}
+
+// WithProgress registers fn to be called as each step starts. With step
+// concurrency above 1, fn is called from several goroutines at once.
+func WithProgress(fn ProgressFunc) Option {
+	return func(r *Runner) {
+		r.progress = fn
+	}
+}
+
+// WithStepRetries resolves a failing step again up to retries times, waiting
+// delay before each new attempt. Steps that retry on their own, as reported
+// by engine.SelfRetrying, are not retried again. Negative values are
+// treated as 0, which disables retries.
+func WithStepRetries(retries int, delay time.Duration) Option {
+	return func(r *Runner) {
+		r.stepRetries = max(retries, 0)
+		r.stepRetryDelay = max(delay, 0)
+	}
+}
+
+// WithResume skips steps whose output file is already in the sink, so a
+// job that failed partway can be run again without resolving what it
+// already wrote. See planResume for which steps qualify.
+func WithResume(enabled bool) Option {
+	return func(r *Runner) {
+		r.resume = enabled
+	}
+}
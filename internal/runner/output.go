@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
@@ -11,21 +13,32 @@ import (
 	"github.com/infracollect/infracollect/internal/engine/archivers"
 	"github.com/infracollect/infracollect/internal/engine/encoders"
 	"github.com/infracollect/infracollect/internal/engine/sinks"
+	"github.com/infracollect/infracollect/internal/sshclient"
+	"github.com/zclconf/go-cty/cty"
+	"go.uber.org/zap"
 )
 
 // buildOutputPipeline translates the parsed output {} block into an
 // (encoder, sink) pair. When output is nil the pipeline defaults to a JSON
 // encoder streaming to stdout, preserving the pre-output-block behaviour.
 // When output is present but missing a sink child, it is a user error — an
-// output block with no sink destination cannot do anything useful.
+// output block with no sink destination cannot do anything useful. Several
+// sink children fan out through a MultiSink; an archive wraps the fan-out,
+// so the archive is built once and written to every sink. A sign block sits
+// between the two, so the signature covers the archive's final bytes. A
+// partial pipeline names the archive with PartialArchiveSuffix appended.
+// Each sink is wrapped in a loggingSink, so every destination logs its own
+// writes.
 func buildOutputPipeline(
 	ctx context.Context,
+	logger *zap.Logger,
 	output *OutputBlock,
 	baseCtx *hcl.EvalContext,
 	jobName string,
+	partial bool,
 ) (engine.Encoder, engine.Sink, error) {
 	if output == nil {
-		return encoders.NewJSONEncoder("  "), sinks.NewStreamSink(os.Stdout), nil
+		return encoders.NewJSONEncoder("  "), &loggingSink{Sink: sinks.NewStreamSink(os.Stdout), logger: logger}, nil
 	}
 
 	encoder, err := buildEncoder(output.Encoding, baseCtx)
@@ -33,28 +46,67 @@ func buildOutputPipeline(
 		return nil, nil, err
 	}
 
-	if output.Sink == nil {
+	if len(output.Sinks) == 0 {
 		return nil, nil, fmt.Errorf("output block requires a sink")
 	}
-	sink, err := buildSink(ctx, output.Sink, baseCtx)
+	retry, err := buildRetryConfig(output.Retry, baseCtx)
 	if err != nil {
 		return nil, nil, err
 	}
+	built := make([]engine.Sink, 0, len(output.Sinks))
+	for _, block := range output.Sinks {
+		sink, err := buildSink(ctx, block, retry, baseCtx)
+		if err != nil {
+			return nil, nil, err
+		}
+		built = append(built, &loggingSink{Sink: sink, logger: logger})
+	}
+	sink := built[0]
+	if len(built) > 1 {
+		sink = sinks.NewMultiSink(built...)
+	}
+	if output.Sign != nil {
+		sink, err = buildSigningSink(output.Sign, sink, baseCtx)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 
 	if output.Archive != nil {
-		archiver, archiveName, err := buildArchiver(output.Archive, baseCtx, jobName)
+		archiver, archiveName, archiveOpts, err := buildArchiver(output.Archive, baseCtx, jobName)
 		if err != nil {
 			return nil, nil, err
 		}
-		sink = sinks.NewArchiveSink(sink, archiver, archiveName)
+		if partial {
+			archiveName += PartialArchiveSuffix
+		}
+		sink = sinks.NewArchiveSink(sink, archiver, archiveName, archiveOpts...)
 	}
 
 	return encoder, sink, nil
 }
 
+// jobStartedAt returns job.started_at from baseCtx, or the zero time when
+// the context does not carry it.
+func jobStartedAt(baseCtx *hcl.EvalContext) time.Time {
+	job, ok := baseCtx.Variables[RootJob]
+	if !ok || !job.Type().IsObjectType() || !job.Type().HasAttribute("started_at") {
+		return time.Time{}
+	}
+	startedAt := job.GetAttr("started_at")
+	if startedAt.Type() != cty.String || !startedAt.IsKnown() || startedAt.IsNull() {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, startedAt.AsString())
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
 // decodeBlock runs gohcl.DecodeBody into dst and wraps the diagnostics into
 // a single error labelled with the block's variant (`what` is the noun —
-// "encoding", "archive", "sink"; kind is the first-label variant).
+// "encoding", "archive", "sink", "sign"; kind is the first-label variant).
 func decodeBlock[T any](what, kind string, body hcl.Body, ctx *hcl.EvalContext, dst *T) error {
 	if diags := gohcl.DecodeBody(body, ctx, dst); diags.HasErrors() {
 		return fmt.Errorf("failed to decode %s %q: %s", what, kind, diags.Error())
@@ -63,7 +115,24 @@ func decodeBlock[T any](what, kind string, body hcl.Body, ctx *hcl.EvalContext,
 }
 
 type jsonEncodingConfig struct {
-	Indent string `hcl:"indent,optional"`
+	Indent      string `hcl:"indent,optional"`
+	IncludeMeta bool   `hcl:"include_meta,optional"`
+	SortKeys    bool   `hcl:"sort_keys,optional"`
+}
+
+// xmlEncodingConfig is `encoding "xml" {}`. The XML encoder takes no
+// attributes; decoding the empty struct still rejects unknown ones.
+type xmlEncodingConfig struct{}
+
+// msgpackEncodingConfig is `encoding "msgpack" {}`. The MessagePack encoder
+// takes no attributes either.
+type msgpackEncodingConfig struct{}
+
+// parquetEncodingConfig is `encoding "parquet" { compression = "zstd" }`.
+type parquetEncodingConfig struct {
+	// Compression codec of the file: "none", "snappy" (default), "gzip" or
+	// "zstd".
+	Compression string `hcl:"compression,optional"`
 }
 
 func buildEncoder(block *EncodingBlock, baseCtx *hcl.EvalContext) (engine.Encoder, error) {
@@ -76,30 +145,98 @@ func buildEncoder(block *EncodingBlock, baseCtx *hcl.EvalContext) (engine.Encode
 		if err := decodeBlock("encoding", block.Kind, block.Body, baseCtx, &cfg); err != nil {
 			return nil, err
 		}
-		return encoders.NewJSONEncoder(cfg.Indent), nil
+		var opts []encoders.JSONEncoderOption
+		if cfg.IncludeMeta {
+			opts = append(opts, encoders.WithIncludeMeta())
+		}
+		if cfg.SortKeys {
+			opts = append(opts, encoders.WithSortKeys())
+		}
+		return encoders.NewJSONEncoder(cfg.Indent, opts...), nil
+	case "xml":
+		var cfg xmlEncodingConfig
+		if err := decodeBlock("encoding", block.Kind, block.Body, baseCtx, &cfg); err != nil {
+			return nil, err
+		}
+		return encoders.NewXMLEncoder(), nil
+	case "msgpack":
+		var cfg msgpackEncodingConfig
+		if err := decodeBlock("encoding", block.Kind, block.Body, baseCtx, &cfg); err != nil {
+			return nil, err
+		}
+		return encoders.NewMsgpackEncoder(), nil
+	case "parquet":
+		var cfg parquetEncodingConfig
+		if err := decodeBlock("encoding", block.Kind, block.Body, baseCtx, &cfg); err != nil {
+			return nil, err
+		}
+		encoder, err := encoders.NewParquetEncoder(cfg.Compression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build parquet encoder: %w", err)
+		}
+		return encoder, nil
 	default:
-		return nil, fmt.Errorf("unknown encoding kind %q (known: json)", block.Kind)
+		return nil, fmt.Errorf("unknown encoding kind %q (known: json, xml, msgpack, parquet)", block.Kind)
 	}
 }
 
 type tarArchiveConfig struct {
 	Compression string `hcl:"compression,optional"`
+	// Level is the compression level: 1-9 for gzip, 1-22 for zstd. Unset
+	// keeps the algorithm's default.
+	Level *int `hcl:"level,optional"`
+	// Permission bits of every entry, in octal. Defaults to "0644".
+	Mode string `hcl:"mode,optional"`
+	// Modification time of every entry, as an RFC 3339 timestamp. Defaults
+	// to the time the run started; a fixed value makes identical results
+	// produce byte-identical archives.
+	Mtime string `hcl:"mtime,optional"`
+	// SplitSize splits an archive larger than it, such as "500MB", into
+	// numbered parts written next to a manifest of their checksums. Unset
+	// writes the archive whole.
+	SplitSize *string `hcl:"split_size,optional"`
 }
 
-func buildArchiver(block *ArchiveBlock, baseCtx *hcl.EvalContext, jobName string) (engine.Archiver, string, error) {
+func buildArchiver(block *ArchiveBlock, baseCtx *hcl.EvalContext, jobName string) (engine.Archiver, string, []sinks.ArchiveSinkOption, error) {
 	switch block.Kind {
 	case "tar":
 		var cfg tarArchiveConfig
 		if err := decodeBlock("archive", block.Kind, block.Body, baseCtx, &cfg); err != nil {
-			return nil, "", err
+			return nil, "", nil, err
+		}
+		var sinkOpts []sinks.ArchiveSinkOption
+		if cfg.SplitSize != nil {
+			size, err := engine.ParseSize(*cfg.SplitSize)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("invalid tar split_size: %w", err)
+			}
+			if size == 0 {
+				return nil, "", nil, fmt.Errorf("invalid tar split_size %q: must be positive", *cfg.SplitSize)
+			}
+			sinkOpts = append(sinkOpts, sinks.WithSplitSize(size))
+		}
+		opts := []archivers.TarArchiverOption{archivers.WithModTime(jobStartedAt(baseCtx))}
+		if cfg.Mode != "" {
+			mode, err := strconv.ParseInt(cfg.Mode, 8, 64)
+			if err != nil || mode < 0 || mode > 0o777 {
+				return nil, "", nil, fmt.Errorf("invalid tar mode %q: must be octal permission bits such as \"0640\"", cfg.Mode)
+			}
+			opts = append(opts, archivers.WithEntryMode(mode))
+		}
+		if cfg.Mtime != "" {
+			mtime, err := time.Parse(time.RFC3339, cfg.Mtime)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("invalid tar mtime %q: must be an RFC 3339 timestamp: %w", cfg.Mtime, err)
+			}
+			opts = append(opts, archivers.WithModTime(mtime))
 		}
-		archiver, err := archivers.NewTarArchiver(cfg.Compression)
+		archiver, err := archivers.NewTarArchiver(cfg.Compression, cfg.Level, opts...)
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to build tar archiver: %w", err)
+			return nil, "", nil, fmt.Errorf("failed to build tar archiver: %w", err)
 		}
-		return archiver, jobName + archiver.Extension(), nil
+		return archiver, jobName + archiver.Extension(), sinkOpts, nil
 	default:
-		return nil, "", fmt.Errorf("unknown archive kind %q (known: tar)", block.Kind)
+		return nil, "", nil, fmt.Errorf("unknown archive kind %q (known: tar)", block.Kind)
 	}
 }
 
@@ -115,14 +252,170 @@ type s3SinkConfig struct {
 	Endpoint       string `hcl:"endpoint,optional"`
 	Prefix         string `hcl:"prefix,optional"`
 	ForcePathStyle bool   `hcl:"force_path_style,optional"`
+	MaxAttempts    int    `hcl:"max_attempts,optional"`
+	RetryBaseDelay string `hcl:"retry_base_delay,optional"`
+	// User-defined metadata stored on every object (sent as x-amz-meta-*
+	// headers).
+	Metadata map[string]string `hcl:"metadata,optional"`
+	// Tags set on every object, e.g. for lifecycle rules or cost allocation.
+	// S3 accepts at most 10 tags per object.
+	Tags map[string]string `hcl:"tags,optional"`
+	// Presign a GET URL valid this long (e.g. "24h", at most "168h") for
+	// every uploaded object, and upload the URLs as presigned-urls.json
+	// next to the outputs.
+	PresignGet string `hcl:"presign_get,optional"`
+}
+
+// httpSinkConfig decodes `sink "http" { ... }`.
+type httpSinkConfig struct {
+	// URL every output file is POSTed to. The file's output path is sent in
+	// the X-Infracollect-Path header.
+	URL string `hcl:"url"`
+	// Extra request headers, e.g. an Authorization header.
+	Headers map[string]string `hcl:"headers,optional"`
+	// Request body compression: "none" (default), "gzip" or "deflate". The
+	// body is compressed while it is sent and Content-Encoding is set.
+	Compress string `hcl:"compress,optional"`
+	// Maximum time for each request, upload included, e.g. "30s".
+	// Defaults to 5m.
+	Timeout string `hcl:"timeout,optional"`
+}
+
+// httpCredentialsConfig decodes the `credentials { ... }` block of an http
+// sink.
+type httpCredentialsConfig struct {
+	// User name sent with HTTP basic auth on every request.
+	Username string `hcl:"username"`
+	// Password sent with HTTP basic auth.
+	Password string `hcl:"password,optional"`
+}
+
+// sftpSinkConfig decodes `sink "sftp" { ... }`. The connection settings
+// match the ssh collector.
+type sftpSinkConfig struct {
+	// SFTP server host name or address.
+	Host string `hcl:"host"`
+	// SSH port. Defaults to 22.
+	Port int `hcl:"port,optional"`
+	// User to log in as.
+	User string `hcl:"user"`
+	// Private key used to authenticate. One of private_key_path or password
+	// is required.
+	PrivateKeyPath string `hcl:"private_key_path,optional"`
+	// Password used to authenticate, e.g. env.SFTP_PASSWORD.
+	Password string `hcl:"password,optional"`
+	// Known hosts file the server's host key is checked against. Defaults to
+	// ~/.ssh/known_hosts.
+	KnownHostsPath string `hcl:"known_hosts_path,optional"`
+	// Skip host key verification. Only use this against trusted networks.
+	InsecureIgnoreHostKey bool `hcl:"insecure_ignore_host_key,optional"`
+	// Connection timeout, e.g. "30s". Defaults to 30s.
+	Timeout string `hcl:"timeout,optional"`
+	// Directory output files are written under. Relative paths start at the
+	// login directory; missing directories are created.
+	RemoteDir string `hcl:"remote_dir,optional"`
 }
 
 type s3CredentialsConfig struct {
 	AccessKeyID     string `hcl:"access_key_id,optional"`
 	SecretAccessKey string `hcl:"secret_access_key,optional"`
+	// Role to assume with an OIDC web identity token. Requires
+	// web_identity_token_file; ignored when static keys are set.
+	RoleARN string `hcl:"role_arn,optional"`
+	// Path to the OIDC token file, e.g. $AWS_WEB_IDENTITY_TOKEN_FILE.
+	WebIdentityTokenFile string `hcl:"web_identity_token_file,optional"`
+	// Session name recorded for the assumed role. Defaults to an SDK-generated
+	// name.
+	RoleSessionName string `hcl:"role_session_name,optional"`
+}
+
+// retryConfig decodes `retry { ... }` inside the output block.
+type retryConfig struct {
+	// Total tries per write, including the first. Defaults to 3.
+	MaxAttempts int `hcl:"max_attempts,optional"`
+	// Wait before the first retry, doubled after each attempt. Defaults to
+	// 1s.
+	BaseDelay string `hcl:"base_delay,optional"`
+	// Upper bound for the wait between attempts. Defaults to 30s.
+	MaxDelay string `hcl:"max_delay,optional"`
+}
+
+// buildRetryConfig decodes the optional retry block. Nil means writes are
+// not retried beyond what each sink does on its own.
+func buildRetryConfig(block *RetryBlock, baseCtx *hcl.EvalContext) (*sinks.RetryConfig, error) {
+	if block == nil {
+		return nil, nil
+	}
+	var cfg retryConfig
+	if diags := gohcl.DecodeBody(block.Body, baseCtx, &cfg); diags.HasErrors() {
+		return nil, fmt.Errorf("failed to decode retry: %s", diags.Error())
+	}
+
+	retry := &sinks.RetryConfig{MaxAttempts: cfg.MaxAttempts}
+	if cfg.BaseDelay != "" {
+		d, err := engine.ParseDuration(cfg.BaseDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry base_delay: %w", err)
+		}
+		retry.BaseDelay = d
+	}
+	if cfg.MaxDelay != "" {
+		d, err := engine.ParseDuration(cfg.MaxDelay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry max_delay: %w", err)
+		}
+		retry.MaxDelay = d
+	}
+	return retry, nil
+}
+
+// minisignSignConfig decodes `sign "minisign" { ... }` inside the output
+// block.
+type minisignSignConfig struct {
+	// Path to the minisign private key, as created by `minisign -G`.
+	PrivateKeyFile string `hcl:"private_key_file"`
+	// Password of an encrypted private key. Keys created with `minisign -G -W`
+	// are not encrypted and need none.
+	Password string `hcl:"password,optional"`
 }
 
-func buildSink(ctx context.Context, block *SinkBlock, baseCtx *hcl.EvalContext) (engine.Sink, error) {
+// buildSigningSink wraps sink so every object written to it is signed with
+// the key of the sign block.
+func buildSigningSink(block *SignBlock, sink engine.Sink, baseCtx *hcl.EvalContext) (engine.Sink, error) {
+	switch block.Algorithm {
+	case "minisign":
+		var cfg minisignSignConfig
+		if err := decodeBlock("sign", block.Algorithm, block.Body, baseCtx, &cfg); err != nil {
+			return nil, err
+		}
+		key, err := sinks.LoadMinisignKey(cfg.PrivateKeyFile, cfg.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build minisign signer: %w", err)
+		}
+		return sinks.NewSigningSink(sink, key), nil
+	default:
+		return nil, fmt.Errorf("unknown sign algorithm %q (known: minisign)", block.Algorithm)
+	}
+}
+
+// buildSink builds the sink for block and, when retry is set, wraps it in a
+// RetrySink. Stream sinks are never wrapped: a partial write to stdout
+// cannot be taken back. A wrapped S3 sink tries each upload once, so the
+// retry block is the only retry loop on top of the AWS SDK's own.
+func buildSink(ctx context.Context, block *SinkBlock, retry *sinks.RetryConfig, baseCtx *hcl.EvalContext) (engine.Sink, error) {
+	wrapped := retry != nil && block.Kind != "stdout" && block.Kind != "stderr"
+	sink, err := buildSinkKind(ctx, block, wrapped, baseCtx)
+	if err != nil || !wrapped {
+		return sink, err
+	}
+	retrying, err := sinks.NewRetrySink(sink, *retry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry: %w", err)
+	}
+	return retrying, nil
+}
+
+func buildSinkKind(ctx context.Context, block *SinkBlock, retried bool, baseCtx *hcl.EvalContext) (engine.Sink, error) {
 	switch block.Kind {
 	case "stdout":
 		return sinks.NewStreamSink(os.Stdout), nil
@@ -149,20 +442,111 @@ func buildSink(ctx context.Context, block *SinkBlock, baseCtx *hcl.EvalContext)
 				return nil, err
 			}
 		}
+		var retryBaseDelay time.Duration
+		if cfg.RetryBaseDelay != "" {
+			d, err := engine.ParseDuration(cfg.RetryBaseDelay)
+			if err != nil {
+				return nil, fmt.Errorf("invalid retry_base_delay: %w", err)
+			}
+			retryBaseDelay = d
+		}
+		if cfg.MaxAttempts < 0 {
+			return nil, fmt.Errorf("max_attempts must not be negative")
+		}
+		maxAttempts := cfg.MaxAttempts
+		if retried {
+			if cfg.MaxAttempts != 0 || cfg.RetryBaseDelay != "" {
+				return nil, fmt.Errorf("max_attempts and retry_base_delay cannot be used with an output retry block, which retries s3 uploads instead")
+			}
+			maxAttempts = 1
+		}
+		var presignGet time.Duration
+		if cfg.PresignGet != "" {
+			d, err := engine.ParseDuration(cfg.PresignGet)
+			if err != nil {
+				return nil, fmt.Errorf("invalid presign_get: %w", err)
+			}
+			presignGet = d
+		}
 		sink, err := sinks.NewS3Sink(ctx, sinks.S3Config{
-			Bucket:          cfg.Bucket,
-			Region:          cfg.Region,
-			Endpoint:        cfg.Endpoint,
-			Prefix:          cfg.Prefix,
-			ForcePathStyle:  cfg.ForcePathStyle,
-			AccessKeyID:     creds.AccessKeyID,
-			SecretAccessKey: creds.SecretAccessKey,
+			Bucket:               cfg.Bucket,
+			Region:               cfg.Region,
+			Endpoint:             cfg.Endpoint,
+			Prefix:               cfg.Prefix,
+			ForcePathStyle:       cfg.ForcePathStyle,
+			AccessKeyID:          creds.AccessKeyID,
+			SecretAccessKey:      creds.SecretAccessKey,
+			RoleARN:              creds.RoleARN,
+			WebIdentityTokenFile: creds.WebIdentityTokenFile,
+			RoleSessionName:      creds.RoleSessionName,
+			MaxAttempts:          maxAttempts,
+			RetryBaseDelay:       retryBaseDelay,
+			Metadata:             cfg.Metadata,
+			Tags:                 cfg.Tags,
+			PresignGet:           presignGet,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to build s3 sink: %w", err)
 		}
 		return sink, nil
+	case "http":
+		var cfg httpSinkConfig
+		if err := decodeBlock("sink", block.Kind, block.Body, baseCtx, &cfg); err != nil {
+			return nil, err
+		}
+		var creds httpCredentialsConfig
+		if block.Credentials != nil {
+			if err := decodeBlock("sink", block.Kind+" credentials", block.Credentials.Body, baseCtx, &creds); err != nil {
+				return nil, err
+			}
+		}
+		var timeout time.Duration
+		if cfg.Timeout != "" {
+			d, err := engine.ParseDuration(cfg.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timeout: %w", err)
+			}
+			timeout = d
+		}
+		sink, err := sinks.NewHTTPSink(sinks.HTTPConfig{
+			URL:      cfg.URL,
+			Headers:  cfg.Headers,
+			Compress: cfg.Compress,
+			Username: creds.Username,
+			Password: creds.Password,
+			Timeout:  timeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to build http sink: %w", err)
+		}
+		return sink, nil
+	case "sftp":
+		var cfg sftpSinkConfig
+		if err := decodeBlock("sink", block.Kind, block.Body, baseCtx, &cfg); err != nil {
+			return nil, err
+		}
+		sshCfg := sshclient.Config{
+			Host:                  cfg.Host,
+			Port:                  cfg.Port,
+			User:                  cfg.User,
+			PrivateKeyPath:        cfg.PrivateKeyPath,
+			Password:              cfg.Password,
+			KnownHostsPath:        cfg.KnownHostsPath,
+			InsecureIgnoreHostKey: cfg.InsecureIgnoreHostKey,
+		}
+		if cfg.Timeout != "" {
+			d, err := engine.ParseDuration(cfg.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timeout: %w", err)
+			}
+			sshCfg.Timeout = d
+		}
+		sink, err := sinks.NewSFTPSink(sinks.SFTPConfig{SSH: sshCfg, RemoteDir: cfg.RemoteDir})
+		if err != nil {
+			return nil, fmt.Errorf("failed to build sftp sink: %w", err)
+		}
+		return sink, nil
 	default:
-		return nil, fmt.Errorf("unknown sink kind %q (known: stdout, stderr, filesystem, s3)", block.Kind)
+		return nil, fmt.Errorf("unknown sink kind %q (known: stdout, stderr, filesystem, s3, http, sftp)", block.Kind)
 	}
 }
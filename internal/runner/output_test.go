@@ -3,16 +3,28 @@ package runner
 import (
 	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"aead.dev/minisign"
 	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/infracollect/infracollect/internal/engine/sinks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/zclconf/go-cty/cty"
 	"go.uber.org/zap"
 )
 
@@ -62,6 +74,254 @@ output {
 	assert.Equal(t, "hello", decoded["greeting"])
 }
 
+func TestRunner_Output_HTTPSinkGzip(t *testing.T) {
+	stub := newStubRegistry(t)
+
+	var gotPath, gotEncoding string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.Header.Get("X-Infracollect-Path")
+		gotEncoding = r.Header.Get("Content-Encoding")
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		gotBody, _ = io.ReadAll(zr)
+	}))
+	defer srv.Close()
+
+	src := []byte(fmt.Sprintf(`
+step "stub_nocoll" "only" {
+  greeting = "hello"
+}
+
+output {
+  sink "http" {
+    url      = %q
+    compress = "gzip"
+  }
+}
+`, srv.URL))
+
+	_, err := runSilently(t, newRunner(t, src, "http.hcl", stub.reg))
+	require.NoError(t, err)
+
+	assert.Equal(t, "stub_nocoll/only.json", gotPath)
+	assert.Equal(t, "gzip", gotEncoding)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(gotBody, &decoded))
+	assert.Equal(t, "hello", decoded["greeting"])
+}
+
+func TestRunner_Output_RetrySink(t *testing.T) {
+	stub := newStubRegistry(t)
+
+	var attempts int
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	src := []byte(fmt.Sprintf(`
+step "stub_nocoll" "only" {
+  greeting = "hello"
+}
+
+output {
+  retry {
+    max_attempts = 3
+    base_delay   = "1ms"
+  }
+  sink "http" {
+    url = %q
+  }
+}
+`, srv.URL))
+
+	_, err := runSilently(t, newRunner(t, src, "retry.hcl", stub.reg))
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, attempts)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(gotBody, &decoded))
+	assert.Equal(t, "hello", decoded["greeting"])
+}
+
+func TestRunner_Output_XMLEncoding(t *testing.T) {
+	stub := newStubRegistry(t)
+	dir := t.TempDir()
+
+	src := []byte(fmt.Sprintf(`
+step "stub_nocoll" "only" {
+  greeting = "hello"
+}
+
+output {
+  encoding "xml" {}
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, dir))
+
+	_, err := runSilently(t, newRunner(t, src, "xml.hcl", stub.reg))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "stub_nocoll", "only.xml"))
+	require.NoError(t, err, "expected filesystem sink to write an .xml file")
+	assert.Contains(t, string(data), "<greeting>hello</greeting>")
+}
+
+func TestRunner_Output_MsgpackEncoding(t *testing.T) {
+	stub := newStubRegistry(t)
+	dir := t.TempDir()
+
+	src := []byte(fmt.Sprintf(`
+step "stub_nocoll" "only" {
+  greeting = "hello"
+}
+
+output {
+  encoding "msgpack" {}
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, dir))
+
+	_, err := runSilently(t, newRunner(t, src, "msgpack.hcl", stub.reg))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "stub_nocoll", "only.msgpack"))
+	require.NoError(t, err, "expected filesystem sink to write a .msgpack file")
+	var decoded map[string]any
+	require.NoError(t, msgpack.Unmarshal(data, &decoded))
+	assert.Equal(t, map[string]any{"greeting": "hello"}, decoded)
+}
+
+func TestRunner_Output_JSONIncludeMeta(t *testing.T) {
+	stub := newStubRegistry(t)
+	dir := t.TempDir()
+
+	src := []byte(fmt.Sprintf(`
+collector "stub" "c" {
+}
+
+step "stub_step" "s" {
+  collector = collector.stub.c
+  greeting  = "hello"
+}
+
+output {
+  encoding "json" {
+    include_meta = true
+  }
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, dir))
+
+	_, err := runSilently(t, newRunner(t, src, "meta.hcl", stub.reg))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "stub_step", "s.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"meta":{"kind":"stub_step"},"data":{"greeting":"hello","__collector":"stub"}}`, string(data))
+
+	_, err = os.Stat(filepath.Join(dir, "stub_step", "s.meta.json"))
+	assert.True(t, os.IsNotExist(err), "meta is inlined, so no separate meta file should be written")
+}
+
+func TestRunner_Output_StepEncodingOverride(t *testing.T) {
+	stub := newStubRegistry(t)
+	dir := t.TempDir()
+
+	src := []byte(fmt.Sprintf(`
+collector "stub" "c" {
+}
+
+step "stub_nocoll" "plain" {
+  greeting = "hello"
+}
+
+step "stub_step" "custom" {
+  collector = collector.stub.c
+  greeting  = "bonjour"
+
+  encoding "xml" {}
+}
+
+output {
+  encoding "json" {}
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, dir))
+
+	_, err := runSilently(t, newRunner(t, src, "override.hcl", stub.reg))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "stub_nocoll", "plain.json"))
+	require.NoError(t, err, "steps without an encoding block use the output encoding")
+	assert.JSONEq(t, `{"greeting":"hello"}`, string(data))
+
+	data, err = os.ReadFile(filepath.Join(dir, "stub_step", "custom.xml"))
+	require.NoError(t, err, "the step encoding picks the file extension")
+	assert.Contains(t, string(data), "<greeting>bonjour</greeting>")
+
+	_, err = os.Stat(filepath.Join(dir, "stub_step", "custom.meta.xml"))
+	assert.NoError(t, err, "meta follows the step encoding too")
+}
+
+func TestParseJobTemplate_DuplicateStepEncoding(t *testing.T) {
+	_, diags := ParseJobTemplate([]byte(`
+step "stub_nocoll" "only" {
+  encoding "json" {}
+  encoding "xml" {}
+}
+`), "dup.hcl")
+	require.True(t, diags.HasErrors())
+	assert.Contains(t, diags.Error(), "Duplicate encoding block")
+}
+
+func TestRunner_Output_MultipleSinks(t *testing.T) {
+	stub := newStubRegistry(t)
+	first, second := t.TempDir(), t.TempDir()
+
+	src := []byte(fmt.Sprintf(`
+step "stub_nocoll" "only" {
+  greeting = "hello"
+}
+
+output {
+  sink "filesystem" {
+    path = %q
+  }
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, first, second))
+
+	_, err := runSilently(t, newRunner(t, src, "multi.hcl", stub.reg))
+	require.NoError(t, err)
+
+	for _, dir := range []string{first, second} {
+		data, err := os.ReadFile(filepath.Join(dir, "stub_nocoll", "only.json"))
+		require.NoError(t, err, "expected every sink to receive the result")
+		assert.Contains(t, string(data), `"greeting": "hello"`)
+	}
+}
+
 func TestRunner_Output_TarArchiveToFilesystem(t *testing.T) {
 	stub := newStubRegistry(t)
 	dir := t.TempDir()
@@ -132,6 +392,20 @@ output {
 }`,
 			wantMsg: "unknown encoding kind",
 		},
+		{
+			name: "unknown step encoding kind",
+			src: `
+step "stub_nocoll" "only" {
+  greeting = "hi"
+
+  encoding "yaml" {}
+}
+
+output {
+  sink "stdout" {}
+}`,
+			wantMsg: "failed to build encoding for step stub_nocoll/only: unknown encoding kind",
+		},
 		{
 			name: "unknown archive kind",
 			src: `
@@ -145,6 +419,37 @@ output {
 }`,
 			wantMsg: "unknown archive kind",
 		},
+		{
+			name: "parquet unsupported compression",
+			src: `
+step "stub_nocoll" "only" {
+  greeting = "hi"
+}
+
+output {
+  encoding "parquet" {
+    compression = "lz4"
+  }
+  sink "stdout" {}
+}`,
+			wantMsg: `failed to build parquet encoder: unsupported parquet compression "lz4"`,
+		},
+		{
+			name: "tar archive level out of range",
+			src: `
+step "stub_nocoll" "only" {
+  greeting = "hi"
+}
+
+output {
+  archive "tar" {
+    compression = "gzip"
+    level       = 12
+  }
+  sink "stdout" {}
+}`,
+			wantMsg: "failed to build tar archiver: gzip compression level must be between 1 and 9, got 12",
+		},
 		{
 			name: "output without sink",
 			src: `
@@ -169,6 +474,68 @@ output {
 }`,
 			wantMsg: `failed to decode sink "filesystem"`,
 		},
+		{
+			name: "http sink unknown compression",
+			src: `
+step "stub_nocoll" "only" {
+  greeting = "hi"
+}
+
+output {
+  sink "http" {
+    url      = "http://127.0.0.1:1"
+    compress = "brotli"
+  }
+}`,
+			wantMsg: `failed to build http sink: unsupported compress "brotli"`,
+		},
+		{
+			name: "retry with invalid delay",
+			src: `
+step "stub_nocoll" "only" {
+  greeting = "hi"
+}
+
+output {
+  retry {
+    base_delay = "soon"
+  }
+  sink "stdout" {}
+}`,
+			wantMsg: "invalid retry base_delay",
+		},
+		{
+			name: "retry with an s3 sink that retries itself",
+			src: `
+step "stub_nocoll" "only" {
+  greeting = "hi"
+}
+
+output {
+  retry {}
+  sink "s3" {
+    bucket       = "exports"
+    region       = "eu-west-1"
+    max_attempts = 5
+  }
+}`,
+			wantMsg: "max_attempts and retry_base_delay cannot be used with an output retry block",
+		},
+		{
+			name: "sftp sink without credentials",
+			src: `
+step "stub_nocoll" "only" {
+  greeting = "hi"
+}
+
+output {
+  sink "sftp" {
+    host = "files.example.com"
+    user = "infracollect"
+  }
+}`,
+			wantMsg: "failed to build sftp sink: either private_key_path or password is required",
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -376,7 +743,7 @@ output {
 
 func TestBuildOutputPipeline_DefaultsWhenNil(t *testing.T) {
 	baseCtx := &hcl.EvalContext{}
-	enc, sink, err := buildOutputPipeline(t.Context(), nil, baseCtx, "job")
+	enc, sink, err := buildOutputPipeline(t.Context(), zap.NewNop(), nil, baseCtx, "job", false)
 	require.NoError(t, err)
 	require.NotNil(t, enc)
 	require.NotNil(t, sink)
@@ -397,11 +764,68 @@ output {
 `), "wrap.hcl")
 	require.False(t, diags.HasErrors(), "parse: %s", diags.Error())
 
-	_, sink, err := buildOutputPipeline(t.Context(), tmpl.Output, &hcl.EvalContext{}, "job")
+	_, sink, err := buildOutputPipeline(t.Context(), zap.NewNop(), tmpl.Output, &hcl.EvalContext{}, "job", false)
 	require.NoError(t, err)
 	assert.Equal(t, "archive", sink.Kind(), "archive block should wrap the inner sink")
 }
 
+func TestBuildArchiver_TarEntries(t *testing.T) {
+	startedAt := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	baseCtx := &hcl.EvalContext{Variables: map[string]cty.Value{RootJob: jobObject("job", startedAt)}}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantMode    int64
+		wantTime    time.Time
+		errContains string
+	}{
+		{
+			name:     "defaults to the run start",
+			body:     `compression = "none"`,
+			wantMode: 0o644,
+			wantTime: startedAt,
+		},
+		{
+			name: "mode and fixed mtime",
+			body: `
+compression = "none"
+mode        = "0600"
+mtime       = "2020-01-01T00:00:00Z"
+`,
+			wantMode: 0o600,
+			wantTime: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{name: "mode not octal", body: `mode = "0999"`, errContains: `invalid tar mode "0999"`},
+		{name: "mode beyond permission bits", body: `mode = "4755"`, errContains: `invalid tar mode "4755"`},
+		{name: "mtime not a timestamp", body: `mtime = "yesterday"`, errContains: `invalid tar mtime "yesterday"`},
+		{name: "split_size not a size", body: `split_size = "big"`, errContains: `invalid tar split_size: invalid size "big"`},
+		{name: "split_size zero", body: `split_size = "0MB"`, errContains: `invalid tar split_size "0MB": must be positive`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, diags := ParseJobTemplate([]byte("output {\n  archive \"tar\" {\n"+tt.body+"\n  }\n  sink \"stdout\" {}\n}\n"), "tar.hcl")
+			require.False(t, diags.HasErrors(), diags.Error())
+
+			archiver, _, _, err := buildArchiver(tmpl.Output.Archive, baseCtx, "job")
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, archiver.AddFile(t.Context(), "a.json", strings.NewReader("{}")))
+			reader, err := archiver.Close()
+			require.NoError(t, err)
+
+			header, err := tar.NewReader(reader).Next()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMode, header.Mode)
+			assert.True(t, tt.wantTime.Equal(header.ModTime), "modtime %s, want %s", header.ModTime, tt.wantTime)
+		})
+	}
+}
+
 // tarEntries reads a plain (uncompressed) tar archive and returns its entries
 // as a filename -> bytes map. Used by the tar-archive runner test.
 func tarEntries(t *testing.T, data []byte) map[string][]byte {
@@ -421,3 +845,611 @@ func tarEntries(t *testing.T, data []byte) map[string][]byte {
 	}
 	return entries
 }
+
+func TestRunner_Output_Filename(t *testing.T) {
+	cases := []struct {
+		name      string
+		filename  string
+		extra     string
+		wantFiles []string
+		wantErr   string
+	}{
+		{
+			name:      "flat names",
+			filename:  `"${job.name}-${step_id}"`,
+			wantFiles: []string{"names-alpha.json", "names-beta.json"},
+		},
+		{
+			name:      "nested under a date",
+			filename:  `"${formatdate("2006", "2026-04-11T09:15:04Z")}/${step_type}/${step_id}"`,
+			wantFiles: []string{"2026/stub_nocoll/alpha.json", "2026/stub_nocoll/beta.json"},
+		},
+		{
+			name:     "collision between steps",
+			filename: `"${step_type}"`,
+			wantErr:  "step stub_nocoll/alpha and step stub_nocoll/beta both write to stub_nocoll.json",
+		},
+		{
+			name:     "collision with the run report",
+			filename: `"_report"`,
+			extra:    "write_report = true",
+			wantErr:  "the run report and step stub_nocoll/alpha both write to _report.json",
+		},
+		{
+			name:     "escapes the output",
+			filename: `"../${step_id}"`,
+			wantErr:  `output filename "../alpha" for step stub_nocoll/alpha must be a relative path inside the output`,
+		},
+		{
+			name:     "absolute",
+			filename: `"/tmp/${step_id}"`,
+			wantErr:  "must be a relative path inside the output",
+		},
+		{
+			name:     "unknown variable",
+			filename: `"${step.id}"`,
+			wantErr:  "failed to evaluate output filename for step stub_nocoll/alpha",
+		},
+		{
+			name:     "not a string",
+			filename: `["a"]`,
+			wantErr:  "output filename for step stub_nocoll/alpha must be a string, got tuple",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := newStubRegistry(t)
+			dir := t.TempDir()
+
+			src := []byte(fmt.Sprintf(`
+step "stub_nocoll" "alpha" {
+  greeting = "hello"
+}
+
+step "stub_nocoll" "beta" {
+  greeting = "world"
+}
+
+output {
+  filename = %s
+  %s
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, tc.filename, tc.extra, dir))
+
+			_, err := runSilently(t, newRunner(t, src, "names.hcl", stub.reg))
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tc.wantErr)
+				entries, readErr := os.ReadDir(dir)
+				require.NoError(t, readErr)
+				assert.Empty(t, entries, "nothing is written when the filenames are invalid")
+				return
+			}
+			require.NoError(t, err)
+
+			for _, name := range tc.wantFiles {
+				assert.FileExists(t, filepath.Join(dir, filepath.FromSlash(name)))
+			}
+		})
+	}
+}
+
+func TestRunner_Output_Combined(t *testing.T) {
+	stub := newStubRegistry(t)
+	dir := t.TempDir()
+
+	src := []byte(fmt.Sprintf(`
+job {
+  name = "inventory"
+}
+
+collector "stub" "c" {
+}
+
+step "stub_nocoll" "plain" {
+  greeting = "hello"
+}
+
+step "stub_step" "s" {
+  collector = collector.stub.c
+  greeting  = "bonjour"
+}
+
+output {
+  combined = true
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, dir))
+
+	runner := newRunner(t, src, "combined.hcl", stub.reg)
+	_, err := runSilently(t, runner)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "inventory.json"))
+	require.NoError(t, err, "combined output is named after the job")
+	assert.JSONEq(t, `{
+  "stub_nocoll/plain": {"id": "plain", "data": {"greeting": "hello"}},
+  "stub_step/s": {"id": "s", "data": {"greeting": "bonjour", "__collector": "stub"}, "meta": {"kind": "stub_step"}}
+}`, string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no per-step files are written")
+	assert.Equal(t, int64(len(data)), runner.Report().Bytes)
+}
+
+func TestRunner_Output_CombinedErrors(t *testing.T) {
+	for _, kind := range []string{"xml", "msgpack"} {
+		t.Run(kind+" encoder without combined support", func(t *testing.T) {
+			stub := newStubRegistry(t)
+			src := []byte(fmt.Sprintf(`
+step "stub_nocoll" "only" {
+  greeting = "hello"
+}
+
+output {
+  combined = true
+  encoding %q {}
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, kind, t.TempDir()))
+
+			tmpl, diags := ParseJobTemplate(src, "combined.hcl")
+			require.False(t, diags.HasErrors(), diags.Error())
+			_, diags = New(zap.NewNop(), tmpl, stub.reg, nil)
+			require.True(t, diags.HasErrors(), "rejected before any step runs")
+			assert.ErrorContains(t, diags, "combined output is not supported by the "+kind+" encoding")
+		})
+	}
+
+	tests := []struct {
+		name    string
+		src     string
+		wantMsg string
+	}{
+		{
+			name: "filename",
+			src: `
+output {
+  combined = true
+  filename = step_id
+  sink "stdout" {}
+}
+`,
+			wantMsg: "cannot be used with combined = true",
+		},
+		{
+			name: "step encoding",
+			src: `
+step "stub_nocoll" "only" {
+  encoding "xml" {}
+}
+
+output {
+  combined = true
+  sink "stdout" {}
+}
+`,
+			wantMsg: `Step "only" declares its own encoding`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diags := ParseJobTemplate([]byte(tt.src), "combined.hcl")
+			require.True(t, diags.HasErrors())
+			assert.Contains(t, diags.Error(), tt.wantMsg)
+		})
+	}
+}
+
+// registerHTTPStub registers an http_get step returning its body attribute
+// as data, like the real step does for the response.
+func registerHTTPStub(t *testing.T, reg *engine.Registry) {
+	t.Helper()
+	factory := func(_ *engine.RegistryHelper, id string, _ engine.Collector, body hcl.Body, ctx *hcl.EvalContext) (engine.Step, hcl.Diagnostics) {
+		data, diags := engine.BodyToMap(body, ctx)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		return engine.StepFunction(id, "http_get", func(context.Context) (engine.Result, error) {
+			return engine.Result{ID: id, Data: data["body"]}, nil
+		}), nil
+	}
+	require.NoError(t, reg.RegisterStep(engine.StepDescriptor{Kind: "http_get", Factory: factory}))
+}
+
+func TestRunner_Output_Raw(t *testing.T) {
+	const rawOutput = `
+output {
+  raw = true
+  sink "stdout" {}
+}
+`
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "string data",
+			src: `
+step "static" "doc" {
+  value = "line 1\nline 2\n"
+}
+` + rawOutput,
+			want: "line 1\nline 2\n",
+		},
+		{
+			name: "binary base64 content",
+			src: `
+step "static" "blob" {
+  base64 = "/wD+"
+}
+` + rawOutput,
+			want: "\xff\x00\xfe",
+		},
+		{
+			name: "raw exec output is decoded",
+			src: `
+step "exec" "bin" {
+  program = ["printf", "\\001\\002binary"]
+  format  = "raw"
+}
+` + rawOutput,
+			want: "\x01\x02binary",
+		},
+		{
+			name: "raw http response",
+			src: `
+step "http_get" "artifact" {
+  response_type = "raw"
+  body          = "payload"
+}
+` + rawOutput,
+			want: "payload",
+		},
+		{
+			name: "steps selects the written step",
+			src: `
+step "static" "a" {
+  value = "first"
+}
+
+step "static" "b" {
+  value = "second"
+}
+
+output {
+  raw   = true
+  steps = [step.static.b]
+  sink "stdout" {}
+}
+`,
+			want: "second",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newHookRegistry(t)
+			registerHTTPStub(t, stub.reg)
+			runner := newRunner(t, []byte(tt.src), "raw.hcl", stub.reg)
+
+			var err error
+			got := captureStdout(t, func() {
+				_, err = runner.Run(t.Context())
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, int64(len(tt.want)), runner.Report().Bytes)
+		})
+	}
+}
+
+func TestRunner_Output_RawErrors(t *testing.T) {
+	runTests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name: "several steps",
+			src: `
+step "static" "a" {
+  value = "a"
+}
+
+step "static" "b" {
+  value = "b"
+}
+
+output {
+  raw = true
+  sink "stdout" {}
+}
+`,
+			wantErr: "raw output writes exactly one step, but 2 are selected",
+		},
+		{
+			name: "object data",
+			src: `
+step "static" "doc" {
+  value    = "{\"a\": 1}"
+  parse_as = "json"
+}
+
+output {
+  raw = true
+  sink "stdout" {}
+}
+`,
+			wantErr: "failed to write step static/doc as raw output: raw output needs an exec step with format = \"raw\", a static step without parse_as or an http_get step with response_type = \"raw\", got a step of type static",
+		},
+		{
+			name: "http response shaped like exec output",
+			src: `
+step "http_get" "api" {
+  response_type = "json"
+  body          = { output = "aGk=" }
+}
+
+output {
+  raw = true
+  sink "stdout" {}
+}
+`,
+			wantErr: "got a step of type http_get",
+		},
+		{
+			name: "json exec output shaped like raw output",
+			src: `
+step "exec" "json" {
+  program = ["echo", "{\"output\": \"aGk=\"}"]
+}
+
+output {
+  raw = true
+  sink "stdout" {}
+}
+`,
+			wantErr: "got a step of type exec",
+		},
+		{
+			name: "value of another step type",
+			src: `
+step "stub_nocoll" "s" {
+  value = "not a static step"
+}
+
+output {
+  raw = true
+  sink "stdout" {}
+}
+`,
+			wantErr: "got a step of type stub_nocoll",
+		},
+	}
+	for _, tt := range runTests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newHookRegistry(t)
+			registerHTTPStub(t, stub.reg)
+			_, err := runSilently(t, newRunner(t, []byte(tt.src), "raw.hcl", stub.reg))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	parseTests := []struct {
+		name    string
+		src     string
+		wantMsg string
+	}{
+		{
+			name: "file sink",
+			src: `
+output {
+  raw = true
+  sink "filesystem" {
+    path = "out"
+  }
+}
+`,
+			wantMsg: `A "filesystem" sink cannot be used with raw = true`,
+		},
+		{
+			name: "encoding",
+			src: `
+output {
+  raw = true
+  encoding "json" {}
+  sink "stdout" {}
+}
+`,
+			wantMsg: "An encoding block cannot be used with raw = true",
+		},
+		{
+			name: "combined",
+			src: `
+output {
+  raw      = true
+  combined = true
+  sink "stdout" {}
+}
+`,
+			wantMsg: "combined = true cannot be used with raw = true",
+		},
+		{
+			name: "step encoding",
+			src: `
+step "stub_nocoll" "only" {
+  encoding "xml" {}
+}
+
+output {
+  raw = true
+  sink "stdout" {}
+}
+`,
+			wantMsg: `The encoding block of step "only" cannot be used with raw = true`,
+		},
+	}
+	for _, tt := range parseTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diags := ParseJobTemplate([]byte(tt.src), "raw.hcl")
+			require.True(t, diags.HasErrors())
+			assert.Contains(t, diags.Error(), tt.wantMsg)
+		})
+	}
+}
+
+func TestRunner_Output_SignedArchive(t *testing.T) {
+	public, private, err := minisign.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyText, err := private.MarshalText()
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "infracollect.key")
+	require.NoError(t, os.WriteFile(keyPath, keyText, 0o600))
+
+	stub := newStubRegistry(t)
+	dir := t.TempDir()
+	src := []byte(fmt.Sprintf(`
+job {
+  name = "signed-job"
+}
+
+step "stub_nocoll" "only" {
+  greeting = "hello"
+}
+
+output {
+  archive "tar" {}
+  sign "minisign" {
+    private_key_file = %q
+  }
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, keyPath, dir))
+
+	_, err = runSilently(t, newRunner(t, src, "signed.hcl", stub.reg))
+	require.NoError(t, err)
+
+	archive, err := os.ReadFile(filepath.Join(dir, "signed-job.tar.gz"))
+	require.NoError(t, err)
+	signature, err := os.ReadFile(filepath.Join(dir, "signed-job.tar.gz.minisig"))
+	require.NoError(t, err)
+	assert.True(t, minisign.Verify(public, archive, signature), "the signature covers the compressed archive")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "only the archive and its signature are written")
+}
+
+func TestRunner_Output_SplitArchive(t *testing.T) {
+	stub := newStubRegistry(t)
+	dir := t.TempDir()
+	src := []byte(fmt.Sprintf(`
+job {
+  name = "split-job"
+}
+
+step "stub_nocoll" "only" {
+  greeting = "hello"
+}
+
+output {
+  archive "tar" {
+    compression = "none"
+    split_size  = "1KiB"
+  }
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, dir))
+
+	_, err := runSilently(t, newRunner(t, src, "split.hcl", stub.reg))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "split-job.tar"+sinks.ArchivePartsManifestSuffix))
+	require.NoError(t, err)
+	var manifest sinks.ArchiveParts
+	require.NoError(t, json.Unmarshal(data, &manifest))
+	assert.Equal(t, "split-job.tar", manifest.Archive)
+	require.Len(t, manifest.Parts, 2, "a one-entry tar takes a header block, a data block and two end blocks")
+
+	var whole []byte
+	for _, part := range manifest.Parts {
+		data, err := os.ReadFile(filepath.Join(dir, part.Name))
+		require.NoError(t, err)
+		whole = append(whole, data...)
+	}
+	header, err := tar.NewReader(bytes.NewReader(whole)).Next()
+	require.NoError(t, err)
+	assert.Equal(t, "stub_nocoll/only.json", header.Name)
+
+	_, err = os.Stat(filepath.Join(dir, "split-job.tar"))
+	assert.ErrorIs(t, err, os.ErrNotExist, "a split archive is not also written whole")
+}
+
+func TestRunner_Output_SignErrors(t *testing.T) {
+	runTests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name: "unknown algorithm",
+			src: `
+output {
+  sign "gpg" {}
+  sink "filesystem" {
+    path = "out"
+  }
+}
+`,
+			wantErr: `unknown sign algorithm "gpg" (known: minisign)`,
+		},
+		{
+			name: "missing key file",
+			src: `
+output {
+  sign "minisign" {
+    private_key_file = "does-not-exist.key"
+  }
+  sink "filesystem" {
+    path = "out"
+  }
+}
+`,
+			wantErr: "failed to build minisign signer: failed to read private key",
+		},
+	}
+	for _, tt := range runTests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStubRegistry(t)
+			_, err := runSilently(t, newRunner(t, []byte(tt.src), "sign.hcl", stub.reg))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("stream sink", func(t *testing.T) {
+		_, diags := ParseJobTemplate([]byte(`
+output {
+  sign "minisign" {
+    private_key_file = "infracollect.key"
+  }
+  sink "stdout" {}
+}
+`), "sign.hcl")
+		require.True(t, diags.HasErrors())
+		assert.Contains(t, diags.Error(), `A "stdout" sink cannot be used with a sign block`)
+	})
+}
@@ -0,0 +1,163 @@
+package runner
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// overlaySource is an overlay file given to WithOverlay.
+type overlaySource struct {
+	data     []byte
+	filename string
+}
+
+// WithOverlay merges the HCL in data onto the job before it is validated,
+// so one base job can serve several environments. Overlays given in several
+// options are applied in order, each one winning over the job and the
+// overlays before it. See applyOverlay for the merge rules.
+func WithOverlay(data []byte, filename string) ParseOption {
+	return func(o *parseOptions) {
+		o.overlays = append(o.overlays, overlaySource{data: data, filename: filename})
+	}
+}
+
+// applyOverlays parses each overlay and merges it onto bodies, the job file
+// and the files it includes as returned by resolveIncludes.
+func applyOverlays(parser *hclparse.Parser, bodies []hcl.Body, overlays []overlaySource) ([]hcl.Body, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	for _, overlay := range overlays {
+		file, fileDiags := parser.ParseHCL(overlay.data, overlay.filename)
+		diags = append(diags, fileDiags...)
+		if fileDiags.HasErrors() {
+			continue
+		}
+		var overlayDiags hcl.Diagnostics
+		bodies, overlayDiags = applyOverlay(bodies, file.Body.(*hclsyntax.Body))
+		diags = append(diags, overlayDiags...)
+	}
+	return bodies, diags
+}
+
+// applyOverlay merges overlay onto bodies. A top-level overlay block is
+// merged into the block of the same type and labels, in whichever file
+// declares it, and added to the job file when there is none. Within a
+// merged block, attributes of the overlay replace those of the base whole,
+// lists and objects included, and nested blocks are matched and merged the
+// same way. Nothing can be removed.
+func applyOverlay(bodies []hcl.Body, overlay *hclsyntax.Body) ([]hcl.Body, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	bodies = slices.Clone(bodies)
+
+	for _, block := range overlay.Blocks {
+		if block.Type == "include" {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Include not allowed",
+				Detail:   "An overlay cannot include other files.",
+				Subject:  block.DefRange().Ptr(),
+			})
+			continue
+		}
+
+		type match struct{ body, block int }
+		var matches []match
+		for i, body := range bodies {
+			syn, ok := body.(*hclsyntax.Body)
+			if !ok {
+				continue
+			}
+			for _, j := range matchingBlocks(syn, block) {
+				matches = append(matches, match{body: i, block: j})
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			base := *bodies[0].(*hclsyntax.Body)
+			base.Blocks = append(slices.Clone(base.Blocks), block)
+			bodies[0] = &base
+		case 1:
+			m := matches[0]
+			base := *bodies[m.body].(*hclsyntax.Body)
+			base.Blocks = slices.Clone(base.Blocks)
+			merged, mergeDiags := mergeBlock(base.Blocks[m.block], block)
+			diags = append(diags, mergeDiags...)
+			base.Blocks[m.block] = merged
+			bodies[m.body] = &base
+		default:
+			diags = append(diags, ambiguousOverlayBlock(block, len(matches)))
+		}
+	}
+
+	if len(overlay.Attributes) > 0 {
+		// The job file has no top-level attributes, so decoding reports
+		// these as unsupported.
+		bodies[0], _ = mergeBody(bodies[0].(*hclsyntax.Body), &hclsyntax.Body{Attributes: overlay.Attributes})
+	}
+	return bodies, diags
+}
+
+// mergeBody returns a copy of base with the attributes of overlay set on it
+// and the blocks of overlay merged into it.
+func mergeBody(base, overlay *hclsyntax.Body) (*hclsyntax.Body, hcl.Diagnostics) {
+	merged := *base
+	merged.Attributes = maps.Clone(base.Attributes)
+	if merged.Attributes == nil {
+		merged.Attributes = make(hclsyntax.Attributes, len(overlay.Attributes))
+	}
+	maps.Copy(merged.Attributes, overlay.Attributes)
+	merged.Blocks = slices.Clone(base.Blocks)
+
+	var diags hcl.Diagnostics
+	for _, block := range overlay.Blocks {
+		matches := matchingBlocks(&merged, block)
+		switch len(matches) {
+		case 0:
+			merged.Blocks = append(merged.Blocks, block)
+		case 1:
+			nested, nestedDiags := mergeBlock(merged.Blocks[matches[0]], block)
+			diags = append(diags, nestedDiags...)
+			merged.Blocks[matches[0]] = nested
+		default:
+			diags = append(diags, ambiguousOverlayBlock(block, len(matches)))
+		}
+	}
+	return &merged, diags
+}
+
+func mergeBlock(base, overlay *hclsyntax.Block) (*hclsyntax.Block, hcl.Diagnostics) {
+	body, diags := mergeBody(base.Body, overlay.Body)
+	merged := *base
+	merged.Body = body
+	return &merged, diags
+}
+
+// matchingBlocks returns the indexes of the blocks of body with the type and
+// labels of block.
+func matchingBlocks(body *hclsyntax.Body, block *hclsyntax.Block) []int {
+	var matches []int
+	for i, candidate := range body.Blocks {
+		if candidate.Type == block.Type && slices.Equal(candidate.Labels, block.Labels) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+func ambiguousOverlayBlock(block *hclsyntax.Block, n int) *hcl.Diagnostic {
+	header := block.Type
+	for _, label := range block.Labels {
+		header += fmt.Sprintf(" %q", label)
+	}
+	return &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Ambiguous overlay block",
+		Detail:   fmt.Sprintf("The overlay block %s matches %d blocks of the job, so it is unclear which one to change. An overlay can only change blocks whose type and labels are unique.", header, n),
+		Subject:  block.DefRange().Ptr(),
+	}
+}
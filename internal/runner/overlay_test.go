@@ -0,0 +1,176 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// literalAttr evaluates the literal attribute name of body.
+func literalAttr(t *testing.T, body hcl.Body, name string) cty.Value {
+	t.Helper()
+	attrs, diags := body.JustAttributes()
+	require.False(t, diags.HasErrors(), diags.Error())
+	require.Contains(t, attrs, name)
+	value, diags := attrs[name].Expr.Value(nil)
+	require.False(t, diags.HasErrors(), diags.Error())
+	return value
+}
+
+func TestParseJobTemplate_Overlay(t *testing.T) {
+	dir := writeJobFiles(t, map[string]string{
+		"job.hcl": `
+include {
+  path = "steps.hcl"
+}
+
+job {
+  name = "inventory"
+}
+
+step "static" "region" {
+  value = "eu-west-1"
+}
+
+output {
+  steps = [step.static.region, step.static.shared]
+  sink "filesystem" {
+    path = "out/dev"
+  }
+}
+`,
+		"steps.hcl": `
+step "static" "shared" {
+  value = "dev"
+}
+`,
+	})
+	filename := filepath.Join(dir, "job.hcl")
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+
+	prod := []byte(`
+step "static" "region" {
+  value = "us-east-1"
+}
+
+step "static" "shared" {
+  value = "prod"
+}
+
+step "static" "extra" {
+  value = "added"
+}
+
+output {
+  steps = [step.static.extra]
+  sink "filesystem" {
+    path = "out/prod"
+  }
+}
+`)
+	audit := []byte(`
+job {
+  name = "inventory-audit"
+}
+
+output {
+  sink "filesystem" {
+    path = "out/audit"
+  }
+}
+`)
+
+	tmpl, diags := ParseJobTemplate(data, filename, WithIncludes(),
+		WithOverlay(prod, "prod.hcl"),
+		WithOverlay(audit, "audit.hcl"),
+	)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	assert.Equal(t, "inventory-audit", tmpl.JobName(), "later overlays win")
+
+	values := map[string]string{}
+	for _, s := range tmpl.Steps {
+		values[s.Type+"."+s.Name] = literalAttr(t, s.Body, "value").AsString()
+	}
+	assert.Equal(t, map[string]string{
+		"static.region": "us-east-1",
+		"static.shared": "prod",
+		"static.extra":  "added",
+	}, values, "blocks from included files are overlaid too, and new blocks are added")
+
+	items, diags := hcl.ExprList(tmpl.Output.Steps)
+	require.False(t, diags.HasErrors(), diags.Error())
+	require.Len(t, items, 1, "lists are replaced, not appended to")
+	traversal, diags := hcl.AbsTraversalForExpr(items[0])
+	require.False(t, diags.HasErrors(), diags.Error())
+	assert.Equal(t, "extra", traversal[2].(hcl.TraverseAttr).Name)
+
+	require.Len(t, tmpl.Output.Sinks, 1, "the sink block is merged, not duplicated")
+	assert.Equal(t, "out/audit", literalAttr(t, tmpl.Output.Sinks[0].Body, "path").AsString())
+}
+
+func TestParseJobTemplate_OverlayErrors(t *testing.T) {
+	base := []byte(`
+step "static" "a" {
+  value = "a"
+}
+
+output {
+  sink "filesystem" {
+    path = "one"
+  }
+  sink "filesystem" {
+    path = "two"
+  }
+}
+`)
+	tests := []struct {
+		name    string
+		overlay string
+		wantMsg string
+	}{
+		{
+			name: "ambiguous block",
+			overlay: `
+output {
+  sink "filesystem" {
+    path = "three"
+  }
+}
+`,
+			wantMsg: `The overlay block sink "filesystem" matches 2 blocks of the job`,
+		},
+		{
+			name: "include",
+			overlay: `
+include {
+  path = "other.hcl"
+}
+`,
+			wantMsg: "An overlay cannot include other files.",
+		},
+		{
+			name:    "syntax error",
+			overlay: `step "static" {`,
+			wantMsg: "overlay.hcl:1",
+		},
+		{
+			name:    "top-level attribute",
+			overlay: `region = "us-east-1"`,
+			wantMsg: `An argument named "region" is not expected here.`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diags := ParseJobTemplate(base, "job.hcl", WithOverlay([]byte(tt.overlay), "overlay.hcl"))
+			require.True(t, diags.HasErrors())
+			assert.Contains(t, diags.Error(), tt.wantMsg)
+		})
+	}
+}
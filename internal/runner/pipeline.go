@@ -18,7 +18,9 @@ type CollectorAddr struct {
 }
 
 // Pipeline is the resolvable shape of a collect job: a DAG of collector and
-// step nodes with per-node metadata attached for execution.
+// step nodes with per-node metadata attached for execution. It is never
+// modified once BuildPipeline returns, so the runner's node goroutines read
+// it without locking; everything they produce goes through Runner.mu.
 type Pipeline struct {
 	dag  *DirectedAcyclicGraph
 	meta map[Node]*NodeMeta
@@ -34,6 +36,12 @@ type NodeMeta struct {
 	Refs          []Reference
 	ForEach       hcl.Expression // nil unless this is a Collection node
 	CollectorAddr *CollectorAddr // step-only; parsed collector binding
+	When          hcl.Expression // step-only; nil when not declared
+	MinItems      hcl.Expression // step-only; nil when not declared
+	MaxItems      hcl.Expression // step-only; nil when not declared
+	NoCache       hcl.Expression // step-only; nil when not declared
+	Encoding      *EncodingBlock // step-only; nil uses the output encoding
+	Asserts       []*AssertBlock // step-only; checked after resolve
 	DefRange      hcl.Range
 }
 
@@ -46,6 +54,17 @@ func (p *Pipeline) Meta(n Node) (*NodeMeta, bool) {
 
 func (p *Pipeline) OutputSteps() map[string]struct{} { return p.outputSteps }
 
+// StepCount returns the number of step nodes, for_each steps included.
+func (p *Pipeline) StepCount() int {
+	n := 0
+	for node := range p.meta {
+		if node.Kind != NodeTypeCollector {
+			n++
+		}
+	}
+	return n
+}
+
 // BuildPipeline extracts references via HCL's native Variables() walk and
 // builds a DAG with one node per collector and one per step. Steps that
 // declared a for_each become NodeTypeCollection. Structural errors
@@ -137,6 +156,32 @@ func BuildPipeline(logger *zap.Logger, tmpl *JobTemplate, registry *engine.Regis
 			diags = append(diags, fd...)
 			refs = append(refs, forEachRefs...)
 		}
+		for _, expr := range []hcl.Expression{s.When, s.MinItems, s.MaxItems, s.NoCache} {
+			if expr == nil {
+				continue
+			}
+			boundRefs, bd := ReferencesInExpression(expr)
+			diags = append(diags, bd...)
+			refs = append(refs, boundRefs...)
+		}
+		for _, a := range s.Asserts {
+			for _, expr := range []hcl.Expression{a.Condition, a.ErrorMessage} {
+				if expr == nil {
+					continue
+				}
+				assertRefs, ad := ReferencesInExpression(expr)
+				diags = append(diags, ad...)
+				// self is the step's own result, not a dependency.
+				refs = append(refs, slices.DeleteFunc(assertRefs, func(ref Reference) bool {
+					return ref.Root == RootSelf
+				})...)
+			}
+		}
+		if s.DependsOn != nil {
+			depRefs, dd := parseDependsOn(s.DependsOn)
+			diags = append(diags, dd...)
+			refs = append(refs, depRefs...)
+		}
 
 		var collectorAddr *CollectorAddr
 		switch {
@@ -186,11 +231,21 @@ func BuildPipeline(logger *zap.Logger, tmpl *JobTemplate, registry *engine.Regis
 			}
 		}
 
+		if desc.Validate != nil {
+			diags = append(diags, desc.Validate(registry.Helper(), s.Body)...)
+		}
+
 		p.meta[node] = &NodeMeta{
 			Body:          s.Body,
 			Refs:          refs,
 			ForEach:       s.ForEach,
 			CollectorAddr: collectorAddr,
+			When:          s.When,
+			MinItems:      s.MinItems,
+			MaxItems:      s.MaxItems,
+			NoCache:       s.NoCache,
+			Encoding:      s.Encoding,
+			Asserts:       s.Asserts,
 			DefRange:      s.DefRange,
 		}
 		nodes = append(nodes, node)
@@ -254,6 +309,17 @@ func (p *Pipeline) addEdgeForRef(to Node, ref Reference) hcl.Diagnostics {
 		}
 		return nil
 
+	case RootSelf:
+		return hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "self.* used outside an assert block",
+			Detail: fmt.Sprintf(
+				"%s %q references self.*, which is only available in a step's assert blocks.",
+				to.Kind.String(), to.ID,
+			),
+			Subject: ref.Traversal.SourceRange().Ptr(),
+		}}
+
 	case RootCollector, RootStep:
 		from, ok := p.resolveRefNode(ref)
 		if !ok {
@@ -365,6 +431,37 @@ func parseCollectorBinding(expr hcl.Expression) (CollectorAddr, hcl.Traversal, h
 	return CollectorAddr{Type: typeName, Name: idName}, trav, nil
 }
 
+// parseDependsOn validates a step's `depends_on` list. Each element must be a
+// direct traversal of the form step.<type>.<id> or collector.<type>.<id>;
+// whether the target exists is checked when the references become edges.
+func parseDependsOn(expr hcl.Expression) ([]Reference, hcl.Diagnostics) {
+	tuple, ok := expr.(*hclsyntax.TupleConsExpr)
+	if !ok {
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid depends_on",
+			Detail:   "The `depends_on` attribute must be a list of step or collector references (step.<type>.<id>, collector.<type>.<id>).",
+			Subject:  expr.Range().Ptr(),
+		}}
+	}
+
+	var refs []Reference
+	var diags hcl.Diagnostics
+	for _, elem := range tuple.Exprs {
+		root := RootStep
+		if trav, ok := elem.(*hclsyntax.ScopeTraversalExpr); ok && trav.Traversal.RootName() == RootCollector {
+			root = RootCollector
+		}
+		typeName, idName, trav, ed := parseTraversalRef(elem, root, "Invalid dependency")
+		if ed.HasErrors() {
+			diags = append(diags, ed...)
+			continue
+		}
+		refs = append(refs, Reference{Root: root, Type: typeName, Name: idName, Traversal: trav})
+	}
+	return refs, diags
+}
+
 // validateOutputSteps parses and validates the output.steps expression
 // against the known step nodes in the pipeline. Each element must be a
 // direct traversal of the form step.<type>.<id>. An empty list is
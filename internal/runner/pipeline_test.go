@@ -162,6 +162,14 @@ step "static" "bad" {
 }`,
 			wantMsg: "each.* used outside a for_each step",
 		},
+		{
+			name: "self.* outside assert",
+			src: `
+step "static" "bad" {
+  value = self.data
+}`,
+			wantMsg: "self.* used outside an assert block",
+		},
 		{
 			name: "unknown collector type",
 			src: `
@@ -572,3 +580,35 @@ step "http_get" "fetch" {
 	assert.Less(t, indexOf(keys, tfColl.Key()), indexOf(keys, tfStep.Key()))
 	assert.Less(t, indexOf(keys, httpColl.Key()), indexOf(keys, httpStep.Key()))
 }
+
+func TestBuildPipeline_StepValidate(t *testing.T) {
+	reg := testRegistry()
+	require.NoError(t, reg.RegisterStep(engine.StepDescriptor{
+		Kind: "checked",
+		Factory: func(*engine.RegistryHelper, string, engine.Collector, hcl.Body, *hcl.EvalContext) (engine.Step, hcl.Diagnostics) {
+			return nil, nil
+		},
+		Validate: func(_ *engine.RegistryHelper, body hcl.Body) hcl.Diagnostics {
+			attrs, diags := body.JustAttributes()
+			if _, ok := attrs["bad"]; ok {
+				diags = append(diags, &hcl.Diagnostic{Severity: hcl.DiagError, Summary: "Bad attribute"})
+			}
+			return diags
+		},
+	}))
+
+	tmpl, diags := ParseJobTemplate([]byte(`
+step "checked" "ok" {
+  good = true
+}
+
+step "checked" "ko" {
+  bad = true
+}
+`), "validate.hcl")
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	_, diags = BuildPipeline(zap.NewNop(), tmpl, reg)
+	require.Len(t, diags, 1)
+	assert.Equal(t, "Bad attribute", diags[0].Summary)
+}
@@ -0,0 +1,27 @@
+package runner
+
+// ProgressEvent describes a step the runner is about to resolve. Index
+// counts started steps from 1; Total is the number of steps in the job.
+// Collectors are not counted.
+type ProgressEvent struct {
+	Step  Node
+	Index int
+	Total int
+}
+
+// ProgressFunc observes a run as it advances, so callers can render
+// progress without the runner knowing about terminals.
+type ProgressFunc func(ProgressEvent)
+
+// reportProgress notifies the progress observer, if any, that node is
+// starting.
+func (r *Runner) reportProgress(node Node) {
+	if r.progress == nil {
+		return
+	}
+	r.mu.Lock()
+	r.stepsStarted++
+	event := ProgressEvent{Step: node, Index: r.stepsStarted, Total: r.pipeline.StepCount()}
+	r.mu.Unlock()
+	r.progress(event)
+}
@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_Progress(t *testing.T) {
+	stub := newStubRegistry(t)
+
+	var events []ProgressEvent
+	r := newRunnerWithOptions(t, []byte(`
+collector "stub" "c" {
+}
+
+step "stub_nocoll" "first" {
+  greeting = "hello"
+}
+
+step "stub_step" "second" {
+  collector = collector.stub.c
+  after     = step.stub_nocoll.first.data
+}
+
+step "stub_nocoll" "each" {
+  for_each = { a = "a", b = "b" }
+  greeting = each.value
+}
+`), stub.reg, WithProgress(func(ev ProgressEvent) {
+		events = append(events, ev)
+	}))
+	_, err := runSilently(t, r)
+	require.NoError(t, err)
+
+	require.Len(t, events, 3, "one event per step, collections once, collectors not at all")
+	for i, ev := range events {
+		assert.Equal(t, i+1, ev.Index)
+		assert.Equal(t, 3, ev.Total)
+		assert.NotEqual(t, NodeTypeCollector, ev.Step.Kind)
+	}
+}
@@ -0,0 +1,172 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"go.uber.org/zap"
+)
+
+// ReportFileName is the sink path the run report is written to when the
+// output block sets `write_report = true`.
+const ReportFileName = "_report.json"
+
+const (
+	ReportStatusSucceeded = "succeeded"
+	ReportStatusFailed    = "failed"
+	ReportStatusSkipped   = "skipped"
+)
+
+// RunReport is the telemetry of a single Run: overall timing and status plus
+// one entry per step that was attempted, in execution order. Steps that never
+// ran because an earlier node failed have no entry. Bytes totals the steps'
+// Bytes; Destination names the sink results were written to and stays empty
+// when the run failed before writing. Partial marks a run that was
+// interrupted and flushed the results collected so far (see
+// WithPartialFlush).
+type RunReport struct {
+	JobName     string       `json:"job_name"`
+	Status      string       `json:"status"`
+	Error       string       `json:"error,omitempty"`
+	StartedAt   time.Time    `json:"started_at"`
+	FinishedAt  time.Time    `json:"finished_at"`
+	DurationMs  int64        `json:"duration_ms"`
+	Bytes       int64        `json:"bytes"`
+	Destination string       `json:"destination,omitempty"`
+	Partial     bool         `json:"partial,omitempty"`
+	Steps       []StepReport `json:"steps"`
+}
+
+// StepReport describes one step. Bytes is the encoded size of the step's
+// result and meta files as handed to the sink; it stays zero for steps that
+// failed, were skipped by `when` or --resume, or were excluded by the output
+// `steps` filter.
+type StepReport struct {
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Bytes      int64  `json:"bytes"`
+}
+
+func newRunReport(jobName string) *RunReport {
+	return &RunReport{
+		JobName: jobName,
+		Steps:   []StepReport{},
+	}
+}
+
+func (rep *RunReport) start(now time.Time) {
+	rep.StartedAt = now
+}
+
+func (rep *RunReport) finish(now time.Time, err error) {
+	rep.FinishedAt = now
+	rep.DurationMs = now.Sub(rep.StartedAt).Milliseconds()
+	rep.Status = ReportStatusSucceeded
+	if err != nil {
+		rep.Status = ReportStatusFailed
+		rep.Error = err.Error()
+	}
+}
+
+func (rep *RunReport) recordStep(node Node, elapsed time.Duration, skipped bool, err error) {
+	entry := StepReport{
+		Type:       node.Type,
+		ID:         node.ID,
+		Status:     ReportStatusSucceeded,
+		DurationMs: elapsed.Milliseconds(),
+	}
+	if skipped {
+		entry.Status = ReportStatusSkipped
+	}
+	if err != nil {
+		entry.Status = ReportStatusFailed
+		entry.Error = err.Error()
+	}
+	rep.Steps = append(rep.Steps, entry)
+}
+
+func (rep *RunReport) addBytes(key string, n int64) {
+	rep.Bytes += n
+	for i := range rep.Steps {
+		if nodeKey(rep.Steps[i].Type, rep.Steps[i].ID) == key {
+			rep.Steps[i].Bytes += n
+			return
+		}
+	}
+}
+
+// write encodes the report as indented JSON and hands it to the sink. The
+// report is always JSON, regardless of the output encoding, so tooling can
+// read it without knowing how the job was configured.
+func (rep *RunReport) write(ctx context.Context, sink engine.Sink) error {
+	var buff bytes.Buffer
+	encoder := json.NewEncoder(&buff)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(rep); err != nil {
+		return fmt.Errorf("failed to encode run report: %w", err)
+	}
+	if err := sink.Write(ctx, ReportFileName, &buff); err != nil {
+		return fmt.Errorf("failed to write run report: %w", err)
+	}
+	return nil
+}
+
+// countingReader tallies the bytes read through it so writeResults can
+// attribute encoded sizes to steps without buffering twice.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// loggingSink logs every object written through it with its size and how
+// long the write took, so operators can audit what a run produced and where.
+type loggingSink struct {
+	engine.Sink
+	logger *zap.Logger
+}
+
+func (s *loggingSink) Write(ctx context.Context, path string, data io.Reader) error {
+	counted := &countingReader{r: data}
+	start := time.Now()
+	if err := s.Sink.Write(ctx, path, counted); err != nil {
+		return err
+	}
+	s.logger.Info("output written",
+		zap.String("sink", s.Kind()),
+		zap.String("destination", s.Name()),
+		zap.String("path", path),
+		zap.Int64("bytes", counted.n),
+		zap.Duration("duration", time.Since(start)),
+	)
+	return nil
+}
+
+// ChecksExistence reports whether the wrapped sink can answer existence
+// checks.
+func (s *loggingSink) ChecksExistence() bool {
+	_, ok := engine.AsExistenceChecker(s.Sink)
+	return ok
+}
+
+// Exists asks the wrapped sink.
+func (s *loggingSink) Exists(ctx context.Context, path string) (bool, error) {
+	checker, ok := engine.AsExistenceChecker(s.Sink)
+	if !ok {
+		return false, fmt.Errorf("%s: existence checks are not supported", s.Sink.Name())
+	}
+	return checker.Exists(ctx, path)
+}
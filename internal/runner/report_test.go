@@ -0,0 +1,357 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/infracollect/infracollect/internal/engine/sinks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func readReport(t *testing.T, data []byte) RunReport {
+	t.Helper()
+	var report RunReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	return report
+}
+
+func TestRunner_WriteReport(t *testing.T) {
+	stub := newStubRegistry(t)
+	dir := t.TempDir()
+
+	src := []byte(fmt.Sprintf(`
+job {
+  name = "report-job"
+}
+
+step "stub_nocoll" "alpha" {
+  greeting = "hello"
+}
+
+step "stub_nocoll" "beta" {
+  greeting = step.stub_nocoll.alpha.data.greeting
+}
+
+output {
+  write_report = true
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, dir))
+
+	_, err := runSilently(t, newRunner(t, src, "report.hcl", stub.reg))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, ReportFileName))
+	require.NoError(t, err, "expected run report to be written")
+	report := readReport(t, data)
+
+	assert.Equal(t, "report-job", report.JobName)
+	assert.Equal(t, ReportStatusSucceeded, report.Status)
+	assert.Empty(t, report.Error)
+	assert.False(t, report.FinishedAt.Before(report.StartedAt))
+	assert.Equal(t, "filesystem("+filepath.Clean(dir)+")", report.Destination)
+
+	require.Len(t, report.Steps, 2)
+	assert.Equal(t, report.Steps[0].Bytes+report.Steps[1].Bytes, report.Bytes)
+	for i, id := range []string{"alpha", "beta"} {
+		step := report.Steps[i]
+		assert.Equal(t, "stub_nocoll", step.Type)
+		assert.Equal(t, id, step.ID)
+		assert.Equal(t, ReportStatusSucceeded, step.Status)
+
+		written, err := os.ReadFile(filepath.Join(dir, "stub_nocoll", id+".json"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(written)), step.Bytes)
+	}
+}
+
+func TestRunner_WriteReport_Disabled(t *testing.T) {
+	stub := newStubRegistry(t)
+	dir := t.TempDir()
+
+	src := []byte(fmt.Sprintf(`
+step "stub_nocoll" "only" {
+  greeting = "hello"
+}
+
+output {
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, dir))
+
+	r := newRunner(t, src, "noreport.hcl", stub.reg)
+	_, err := runSilently(t, r)
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, ReportFileName))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	require.Len(t, r.Report().Steps, 1, "the report is collected even when not written")
+}
+
+func TestRunner_WriteReport_FailedRun(t *testing.T) {
+	stub := newStubRegistry(t)
+	registerListStub(t, stub.reg)
+	dir := t.TempDir()
+
+	src := []byte(fmt.Sprintf(`
+step "stub_nocoll" "ok" {
+  greeting = "hello"
+}
+
+step "stub_list" "short" {
+  items     = [step.stub_nocoll.ok.data.greeting]
+  min_items = 2
+}
+
+output {
+  write_report = true
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, dir))
+
+	_, err := runSilently(t, newRunner(t, src, "failed.hcl", stub.reg))
+	require.Error(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, ReportFileName))
+	require.NoError(t, err, "expected run report to be written for a failed run")
+	report := readReport(t, data)
+
+	assert.Equal(t, ReportStatusFailed, report.Status)
+	assert.Contains(t, report.Error, "expected at least 2 items")
+	require.Len(t, report.Steps, 2)
+	assert.Equal(t, ReportStatusSucceeded, report.Steps[0].Status)
+	assert.Equal(t, "short", report.Steps[1].ID)
+	assert.Equal(t, ReportStatusFailed, report.Steps[1].Status)
+	assert.Contains(t, report.Steps[1].Error, "expected at least 2 items")
+}
+
+func TestRunner_WriteReport_IntoArchive(t *testing.T) {
+	stub := newStubRegistry(t)
+	dir := t.TempDir()
+
+	src := []byte(fmt.Sprintf(`
+job {
+  name = "archived"
+}
+
+step "stub_nocoll" "only" {
+  greeting = "hello"
+}
+
+output {
+  write_report = true
+  archive "tar" {
+    compression = "none"
+  }
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, dir))
+
+	_, err := runSilently(t, newRunner(t, src, "archived.hcl", stub.reg))
+	require.NoError(t, err)
+
+	archiveBytes, err := os.ReadFile(filepath.Join(dir, "archived.tar"))
+	require.NoError(t, err)
+	entries := tarEntries(t, archiveBytes)
+	require.Contains(t, entries, ReportFileName)
+
+	report := readReport(t, entries[ReportFileName])
+	require.Len(t, report.Steps, 1)
+	assert.Equal(t, int64(len(entries["stub_nocoll/only.json"])), report.Steps[0].Bytes)
+}
+
+func TestRunner_PartialFlush(t *testing.T) {
+	cases := []struct {
+		name        string
+		flush       bool
+		wantArchive bool
+	}{
+		{name: "flushes what was collected", flush: true, wantArchive: true},
+		{name: "disabled keeps all-or-nothing", flush: false, wantArchive: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := newStubRegistry(t)
+			dir := t.TempDir()
+
+			// stub_interrupt cancels the run from inside a step, as Ctrl-C
+			// would, once the step it depends on has finished.
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			require.NoError(t, stub.reg.RegisterStep(engine.StepDescriptor{
+				Kind: "stub_interrupt",
+				Factory: func(_ *engine.RegistryHelper, id string, _ engine.Collector, _ hcl.Body, _ *hcl.EvalContext) (engine.Step, hcl.Diagnostics) {
+					return engine.StepFunction(id, "stub_interrupt", func(context.Context) (engine.Result, error) {
+						cancel()
+						return engine.Result{}, context.Canceled
+					}), nil
+				},
+			}))
+
+			src := []byte(fmt.Sprintf(`
+job {
+  name = "interrupted"
+}
+
+step "stub_nocoll" "first" {
+  greeting = "hello"
+}
+
+step "stub_interrupt" "second" {
+  depends_on = [step.stub_nocoll.first]
+}
+
+output {
+  archive "tar" {
+    compression = "none"
+  }
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, dir))
+
+			r := newRunnerWithOptions(t, src, stub.reg, WithPartialFlush(tc.flush))
+			_, err := r.Run(ctx)
+			require.ErrorIs(t, err, context.Canceled)
+
+			files, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			if !tc.wantArchive {
+				assert.Empty(t, files)
+				return
+			}
+
+			archiveBytes, err := os.ReadFile(filepath.Join(dir, "interrupted.tar"+PartialArchiveSuffix))
+			require.NoError(t, err)
+			entries := tarEntries(t, archiveBytes)
+			assert.Contains(t, entries, "stub_nocoll/first.json")
+			require.Contains(t, entries, ReportFileName, "the report is included even without write_report")
+
+			report := readReport(t, entries[ReportFileName])
+			assert.True(t, report.Partial)
+			assert.Equal(t, ReportStatusFailed, report.Status)
+			assert.Contains(t, report.Error, "context canceled")
+			assert.True(t, r.Report().Partial)
+		})
+	}
+}
+
+// job.started_at is the run's start time, shared by every expression of the
+// run, so an output path built from it matches the report's started_at.
+func TestRunner_JobStartedAt(t *testing.T) {
+	stub := newStubRegistry(t)
+	dir := t.TempDir()
+
+	src := []byte(fmt.Sprintf(`
+step "stub_nocoll" "alpha" {
+  greeting = job.started_at
+}
+
+output {
+  write_report = true
+  sink "filesystem" {
+    path = "%s/${formatdate("20060102T150405Z", job.started_at)}"
+  }
+}
+`, dir))
+
+	r := newRunner(t, src, "started.hcl", stub.reg)
+	time.Sleep(1100 * time.Millisecond) // job.started_at is reset when Run starts
+	_, err := runSilently(t, r)
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name(), ReportFileName))
+	require.NoError(t, err)
+	startedAt := readReport(t, data).StartedAt.UTC()
+	assert.Equal(t, startedAt.Format("20060102T150405Z"), entries[0].Name())
+
+	result, err := os.ReadFile(filepath.Join(dir, entries[0].Name(), "stub_nocoll", "alpha.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(result), startedAt.Format(time.RFC3339))
+}
+
+func TestLoggingSink(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	dir := t.TempDir()
+	fsSink, err := sinks.NewFilesystemSinkFromPath(dir)
+	require.NoError(t, err)
+	sink := &loggingSink{Sink: fsSink, logger: zap.New(core)}
+
+	require.NoError(t, sink.Write(t.Context(), "static/a.json", strings.NewReader(`{"a":1}`)))
+
+	entries := logs.FilterMessage("output written").All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "filesystem", fields["sink"])
+	assert.Equal(t, sink.Name(), fields["destination"])
+	assert.Equal(t, "static/a.json", fields["path"])
+	assert.Equal(t, int64(7), fields["bytes"])
+	assert.Contains(t, fields, "duration")
+
+	// A failed write is returned to the caller rather than logged.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), nil, 0o600))
+	require.Error(t, sink.Write(t.Context(), "file/b.json", strings.NewReader("{}")))
+	assert.Equal(t, 1, logs.FilterMessage("output written").Len())
+}
+
+func TestRunner_LogsEverySinkWrite(t *testing.T) {
+	stub := newStubRegistry(t)
+	first, second := t.TempDir(), t.TempDir()
+	src := []byte(fmt.Sprintf(`
+step "stub_nocoll" "alpha" {
+  greeting = "hello"
+}
+
+output {
+  sink "filesystem" {
+    path = %q
+  }
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, first, second))
+	tmpl, diags := ParseJobTemplate(src, "multi.hcl")
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	core, logs := observer.New(zap.InfoLevel)
+	r, diags := New(zap.New(core), tmpl, stub.reg, nil)
+	require.False(t, diags.HasErrors(), diags.Error())
+	_, err := r.Run(t.Context())
+	require.NoError(t, err)
+
+	entries := logs.FilterMessage("output written").All()
+	require.Len(t, entries, 2, "one line per destination")
+	var destinations []any
+	for _, entry := range entries {
+		fields := entry.ContextMap()
+		assert.Equal(t, "filesystem", fields["sink"])
+		assert.Equal(t, "stub_nocoll/alpha.json", fields["path"])
+		destinations = append(destinations, fields["destination"])
+	}
+	assert.ElementsMatch(t, []any{"filesystem(" + first + ")", "filesystem(" + second + ")"}, destinations)
+}
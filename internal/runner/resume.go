@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"go.uber.org/zap"
+)
+
+// planResume returns the keys of the steps whose output file the sink
+// already holds, which runNode then skips. Resuming assumes a step writes
+// the same file on every run, so a file at its path means the step already
+// ran; only the main file is looked up, not its .meta sidecar.
+//
+// A step qualifies only when it is written on its own and nothing depends
+// on it: a skipped step has no result, so anything referencing it would
+// see none. Resume is a no-op, logged, when the output goes to stdout, to
+// an archive or to a single combined or raw document, or when the sink
+// does not support it (see engine.ExistenceChecker).
+func (r *Runner) planResume(ctx context.Context, order []Node) (_ map[string]struct{}, err error) {
+	output := r.tmpl.Output
+	switch {
+	case output == nil:
+		r.logger.Info("resume has no effect when writing to stdout")
+		return nil, nil
+	case output.Archive != nil || output.Combined || output.Raw:
+		r.logger.Info("resume has no effect when steps are not written to files of their own")
+		return nil, nil
+	}
+
+	encoder, sink, err := buildOutputPipeline(ctx, r.logger, output, r.baseCtx, r.tmpl.JobName(), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build output pipeline: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, sink.Close(ctx))
+	}()
+
+	checker, ok := engine.AsExistenceChecker(sink)
+	if !ok {
+		r.logger.Info("resume has no effect: the sink does not support resuming", zap.String("sink", sink.Name()))
+		return nil, nil
+	}
+
+	allowed := r.pipeline.OutputSteps()
+	stepEncodings := r.stepEncodings()
+	resumed := make(map[string]struct{})
+	for _, node := range order {
+		if node.Kind == NodeTypeCollector || len(r.pipeline.dag.Dependents(node)) > 0 {
+			continue
+		}
+		key := nodeKey(node.Type, node.ID)
+		if allowed != nil {
+			if _, ok := allowed[key]; !ok {
+				continue
+			}
+		}
+
+		enc, name, err := r.stepOutput(key, output.Filename, encoder, stepEncodings)
+		if err != nil {
+			return nil, err
+		}
+		path := name + "." + enc.FileExtension()
+		exists, err := checker.Exists(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check for existing output %s: %w", path, err)
+		}
+		if exists {
+			resumed[key] = struct{}{}
+		}
+	}
+	return resumed, nil
+}
@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_Resume(t *testing.T) {
+	stub := newStubRegistry(t)
+	dir := t.TempDir()
+	src := []byte(fmt.Sprintf(`
+step "stub_nocoll" "done" {
+  greeting = "fresh"
+}
+
+step "stub_nocoll" "missing" {
+  greeting = "fresh"
+}
+
+step "stub_nocoll" "base" {
+  greeting = "fresh"
+}
+
+step "stub_nocoll" "derived" {
+  greeting = step.stub_nocoll.base.data.greeting
+}
+
+output {
+  write_report = true
+  sink "filesystem" {
+    path = %q
+  }
+}
+`, dir))
+
+	stale := []byte(`{"greeting":"stale"}`)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "stub_nocoll"), 0o755))
+	for _, id := range []string{"done", "base"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "stub_nocoll", id+".json"), stale, 0o644))
+	}
+
+	results, err := runSilently(t, newRunnerWithOptions(t, src, stub.reg, WithResume(true)))
+	require.NoError(t, err)
+
+	assert.NotContains(t, results, "stub_nocoll/done")
+	data, err := os.ReadFile(filepath.Join(dir, "stub_nocoll", "done.json"))
+	require.NoError(t, err)
+	assert.Equal(t, stale, data, "an already written step is not written again")
+
+	for _, id := range []string{"missing", "base", "derived"} {
+		assert.Contains(t, results, "stub_nocoll/"+id)
+		data, err := os.ReadFile(filepath.Join(dir, "stub_nocoll", id+".json"))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"fresh"`, "step %s must be resolved", id)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, ReportFileName))
+	require.NoError(t, err)
+	statuses := make(map[string]string)
+	for _, step := range readReport(t, data).Steps {
+		statuses[step.ID] = step.Status
+	}
+	assert.Equal(t, map[string]string{
+		"done":    ReportStatusSkipped,
+		"missing": ReportStatusSucceeded,
+		"base":    ReportStatusSucceeded,
+		"derived": ReportStatusSucceeded,
+	}, statuses)
+}
+
+func TestRunner_ResumeNoop(t *testing.T) {
+	cases := []struct {
+		name   string
+		output string
+	}{
+		{name: "stdout", output: ""},
+		{name: "archive", output: `
+output {
+  archive "tar" {
+    compression = "none"
+  }
+  sink "filesystem" {
+    path = %q
+  }
+}`},
+		{name: "combined", output: `
+output {
+  combined = true
+  sink "filesystem" {
+    path = %q
+  }
+}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := newStubRegistry(t)
+			dir := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "stub_nocoll"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "stub_nocoll", "only.json"), []byte("{}"), 0o644))
+
+			output := tc.output
+			if output != "" {
+				output = fmt.Sprintf(output, dir)
+			}
+			src := []byte(`
+step "stub_nocoll" "only" {
+  greeting = "hello"
+}
+` + output)
+
+			results, err := runSilently(t, newRunnerWithOptions(t, src, stub.reg, WithResume(true)))
+			require.NoError(t, err)
+			assert.Contains(t, results, "stub_nocoll/only", "resume must not skip steps it cannot check")
+		})
+	}
+}
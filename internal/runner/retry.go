@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"go.uber.org/zap"
+)
+
+// resolveWithRetries calls resolve for step and, while it fails, calls it
+// again up to r.stepRetries times, r.stepRetryDelay apart. key names the
+// for_each iteration, or is empty for a single step. Cancelling ctx stops
+// the retries and returns the last failure.
+func (r *Runner) resolveWithRetries(
+	ctx context.Context,
+	node Node,
+	key string,
+	step engine.Step,
+	resolve func(ctx context.Context) (engine.Result, error),
+) (engine.Result, error) {
+	retries := r.stepRetries
+	if self, ok := step.(engine.SelfRetrying); ok && self.RetriesFailures() {
+		retries = 0
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := resolve(ctx)
+		if err == nil || attempt > retries || ctx.Err() != nil {
+			return result, err
+		}
+
+		fields := []zap.Field{
+			zap.String("type", node.Type),
+			zap.String("id", node.ID),
+			zap.Int("attempt", attempt),
+			zap.Int("max_attempts", retries+1),
+			zap.Duration("delay", r.stepRetryDelay),
+			zap.Error(err),
+		}
+		if key != "" {
+			fields = append(fields, zap.String("key", key))
+		}
+		r.logger.Warn("step failed, retrying", fields...)
+
+		select {
+		case <-ctx.Done():
+			return result, errors.Join(err, context.Cause(ctx))
+		case <-time.After(r.stepRetryDelay):
+		}
+	}
+}
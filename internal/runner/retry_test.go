@@ -0,0 +1,173 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// selfRetryingStep is a step that reports retrying its own failures.
+type selfRetryingStep struct {
+	engine.Step
+}
+
+func (selfRetryingStep) RetriesFailures() bool { return true }
+
+// newFlakyRegistry registers "flaky" and "flaky_self_retrying" steps that
+// fail their first fail_times resolutions, counted per step and for_each
+// key in attempts.
+func newFlakyRegistry(t *testing.T) (*engine.Registry, map[string]int) {
+	t.Helper()
+	reg := engine.NewRegistry(zap.NewNop())
+	var mu sync.Mutex
+	attempts := map[string]int{}
+
+	factory := func(selfRetrying bool) engine.StepFactory {
+		return func(_ *engine.RegistryHelper, id string, _ engine.Collector, body hcl.Body, ctx *hcl.EvalContext) (engine.Step, hcl.Diagnostics) {
+			data, diags := engine.BodyToMap(body, ctx)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+			name := id
+			if each, ok := ctx.Variables["each"]; ok {
+				name += "[" + each.GetAttr("key").AsString() + "]"
+			}
+			failTimes, err := data["fail_times"].(json.Number).Int64()
+			if err != nil {
+				return nil, hcl.Diagnostics{{Severity: hcl.DiagError, Summary: err.Error()}}
+			}
+			var step engine.Step = engine.StepFunction(id, "flaky", func(context.Context) (engine.Result, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				attempts[name]++
+				if int64(attempts[name]) <= failTimes {
+					return engine.Result{}, errors.New("flaky failure")
+				}
+				return engine.Result{ID: id, Data: map[string]any{"attempts": attempts[name]}}, nil
+			})
+			if selfRetrying {
+				step = selfRetryingStep{Step: step}
+			}
+			return step, nil
+		}
+	}
+	require.NoError(t, reg.RegisterStep(engine.StepDescriptor{Kind: "flaky", Factory: factory(false)}))
+	require.NoError(t, reg.RegisterStep(engine.StepDescriptor{Kind: "flaky_self_retrying", Factory: factory(true)}))
+	return reg, attempts
+}
+
+func TestRunner_StepRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		src          string
+		retries      int
+		wantAttempts map[string]int
+		wantErr      string
+	}{
+		{
+			name: "succeeds within the retries",
+			src: `
+step "flaky" "a" {
+  fail_times = 2
+}
+`,
+			retries:      2,
+			wantAttempts: map[string]int{"a": 3},
+		},
+		{
+			name: "gives up after the retries",
+			src: `
+step "flaky" "a" {
+  fail_times = 3
+}
+`,
+			retries:      2,
+			wantAttempts: map[string]int{"a": 3},
+			wantErr:      "failed to resolve step flaky/a: flaky failure",
+		},
+		{
+			name: "disabled by default",
+			src: `
+step "flaky" "a" {
+  fail_times = 1
+}
+`,
+			wantAttempts: map[string]int{"a": 1},
+			wantErr:      "flaky failure",
+		},
+		{
+			name: "self-retrying steps are not retried again",
+			src: `
+step "flaky_self_retrying" "a" {
+  fail_times = 1
+}
+`,
+			retries:      2,
+			wantAttempts: map[string]int{"a": 1},
+			wantErr:      "flaky failure",
+		},
+		{
+			name: "for_each iterations are retried on their own",
+			src: `
+step "flaky" "a" {
+  for_each   = { x = 1, y = 2 }
+  fail_times = 1
+}
+`,
+			retries:      1,
+			wantAttempts: map[string]int{"a[x]": 2, "a[y]": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, attempts := newFlakyRegistry(t)
+			r := newRunnerWithOptions(t, []byte(tt.src), reg, WithStepRetries(tt.retries, time.Millisecond))
+
+			_, err := runSilently(t, r)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantAttempts, attempts)
+		})
+	}
+}
+
+func TestRunner_StepRetriesStopOnCancel(t *testing.T) {
+	reg, attempts := newFlakyRegistry(t)
+	r := newRunnerWithOptions(t, []byte(`
+step "flaky" "a" {
+  fail_times = 5
+}
+`), reg, WithStepRetries(5, time.Hour))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	var err error
+	silenceStdout(t, func() {
+		_, err = r.Run(ctx)
+	})
+	assert.ErrorContains(t, err, "flaky failure")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 10*time.Second, "the wait between attempts is cut short")
+	assert.Equal(t, map[string]int{"a": 1}, attempts)
+}
+
+func TestWithStepRetries_ClampsNegativeValues(t *testing.T) {
+	r := &Runner{}
+	WithStepRetries(-1, -time.Second)(r)
+	assert.Equal(t, 0, r.stepRetries)
+	assert.Equal(t, time.Duration(0), r.stepRetryDelay)
+}
@@ -1,18 +1,33 @@
 package runner
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/infracollect/infracollect/internal/runner/hclfuncs"
 	"github.com/zclconf/go-cty/cty"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
-const collectorCloseTimeout = 30 * time.Second
+const (
+	collectorCloseTimeout = 30 * time.Second
+
+	// partialFlushTimeout bounds writing a partial archive after the run's
+	// context is done.
+	partialFlushTimeout = 30 * time.Second
+
+	// PartialArchiveSuffix is appended to the archive name of a run that was
+	// cancelled and flushed what it had collected.
+	PartialArchiveSuffix = ".partial"
+)
 
 type Runner struct {
 	logger   *zap.Logger
@@ -20,7 +35,26 @@ type Runner struct {
 	pipeline *Pipeline
 	baseCtx  *hcl.EvalContext
 	registry *engine.Registry
+	report   *RunReport
+
+	startupConcurrency int
+	stepConcurrency    int
+	progress           ProgressFunc
+	stepsStarted       int
+	partialFlush       bool
+	stepRetries        int
+	stepRetryDelay     time.Duration
+	cache              *resultCache // nil disables result caching
+	jobVars            map[string]string
+	secretReader       hclfuncs.SecretReader
+	resume             bool
+	// resumed holds the keys of the steps planResume found already
+	// written; runNode skips them.
+	resumed map[string]struct{}
 
+	// mu guards collectors, raw, the by-type namespaces and the report
+	// while runNodes has several nodes in flight.
+	mu         sync.Mutex
 	collectors map[string]engine.Collector // keyed by "<type>/<id>"
 	raw        map[string]engine.Result    // keyed by "<type>/<id>"
 
@@ -36,10 +70,27 @@ func New(
 	tmpl *JobTemplate,
 	registry *engine.Registry,
 	allowedEnv []string,
+	opts ...Option,
 ) (*Runner, hcl.Diagnostics) {
 	logger.Info("creating runner", zap.String("job_name", tmpl.JobName()))
 
-	baseCtx, err := BuildBaseEvalContext(tmpl, allowedEnv)
+	r := &Runner{
+		logger:             logger,
+		tmpl:               tmpl,
+		registry:           registry,
+		report:             newRunReport(tmpl.JobName()),
+		startupConcurrency: 1,
+		stepConcurrency:    1,
+		collectors:         make(map[string]engine.Collector),
+		raw:                make(map[string]engine.Result),
+		stepByType:         make(map[string]map[string]cty.Value),
+		collectorByType:    make(map[string]map[string]cty.Value),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	baseCtx, err := BuildBaseEvalContext(tmpl, allowedEnv, r.jobVars)
 	if err != nil {
 		return nil, hcl.Diagnostics{&hcl.Diagnostic{
 			Severity: hcl.DiagError,
@@ -47,75 +98,135 @@ func New(
 			Detail:   err.Error(),
 		}}
 	}
+	if r.secretReader != nil {
+		baseCtx.Functions["vault"] = hclfuncs.VaultFunc(r.secretReader)
+	}
+	r.baseCtx = baseCtx
 
 	pipeline, diags := BuildPipeline(logger.Named("pipeline"), tmpl, registry)
 	if diags.HasErrors() {
 		return nil, diags
 	}
+	r.pipeline = pipeline
 
-	return &Runner{
-		logger:          logger,
-		tmpl:            tmpl,
-		pipeline:        pipeline,
-		baseCtx:         baseCtx,
-		registry:        registry,
-		collectors:      make(map[string]engine.Collector),
-		raw:             make(map[string]engine.Result),
-		stepByType:      make(map[string]map[string]cty.Value),
-		collectorByType: make(map[string]map[string]cty.Value),
-	}, diags
+	diags = append(diags, r.validateHooks()...)
+	diags = append(diags, r.validateCombinedEncoder()...)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return r, diags
 }
 
-// Run walks the DAG in topological order and executes each node, then
+// Run executes every DAG node once its dependencies have finished, then
 // streams the collected results through the encoder + sink pair described
 // by the template's output {} block (defaulting to json + stdout when the
-// block is absent).
+// block is absent). Every attempted step is recorded in the run report,
+// which is written alongside the results when the output block sets
+// `write_report = true` — including, best-effort, when the run fails.
 func (r *Runner) Run(ctx context.Context) (map[string]engine.Result, error) {
+	now := time.Now()
+	r.report.start(now)
+	r.baseCtx.Variables[RootJob] = jobObject(r.tmpl.JobName(), now)
+
+	defer r.runPostHook(ctx)
+	if r.tmpl.Job != nil {
+		if err := r.runHook(ctx, "pre", r.tmpl.Job.Pre); err != nil {
+			return nil, r.failRun(ctx, err)
+		}
+	}
+
 	order, err := r.pipeline.dag.TopologicalSort()
 	if err != nil {
-		return nil, fmt.Errorf("could not sort DAG: %w", err)
+		return nil, r.failRun(ctx, fmt.Errorf("could not sort DAG: %w", err))
+	}
+
+	if r.resume {
+		if r.resumed, err = r.planResume(ctx, order); err != nil {
+			return nil, r.failRun(ctx, err)
+		}
 	}
 
 	defer r.closeCollectors()
 
-	for _, node := range order {
-		meta, ok := r.pipeline.Meta(node)
-		if !ok {
-			return nil, fmt.Errorf("pipeline metadata missing for node %s", node.Key())
-		}
+	if err := r.startIndependentCollectors(ctx, order); err != nil {
+		return nil, r.failRun(ctx, err)
+	}
 
-		switch node.Kind {
-		case NodeTypeCollector:
-			if err := r.runCollector(ctx, node, meta); err != nil {
-				return nil, err
-			}
-		case NodeTypeStep:
-			if err := r.runStep(ctx, node, meta); err != nil {
-				return nil, err
-			}
-		case NodeTypeCollection:
-			if err := r.runCollection(ctx, node, meta); err != nil {
-				return nil, err
-			}
-		default:
-			return nil, fmt.Errorf("unknown node kind %q", node.Kind.String())
+	if err := r.runNodes(ctx, order); err != nil {
+		if r.partialFlush && ctx.Err() != nil && r.tmpl.Output != nil && r.tmpl.Output.Archive != nil {
+			return nil, r.flushPartial(ctx, err)
 		}
+		return nil, r.failRun(ctx, err)
 	}
 
-	if err := r.writeResults(ctx); err != nil {
+	if err := r.writeResults(ctx, nil); err != nil {
 		return nil, err
 	}
 
 	return r.raw, nil
 }
 
+// Report returns the run report. It is complete once Run has returned.
+func (r *Runner) Report() *RunReport { return r.report }
+
+// failRun finalizes the report with err and, when the job asked for it,
+// writes the report on its own through the configured sink so failed runs
+// leave telemetry behind too. Report write problems are logged rather than
+// returned: err is what the caller needs to see.
+func (r *Runner) failRun(ctx context.Context, err error) error {
+	r.report.finish(time.Now(), err)
+	if r.tmpl.Output == nil || !r.tmpl.Output.WriteReport {
+		return err
+	}
+
+	_, sink, buildErr := buildOutputPipeline(ctx, r.logger, r.tmpl.Output, r.baseCtx, r.tmpl.JobName(), false)
+	if buildErr != nil {
+		r.logger.Warn("failed to build output pipeline for run report", zap.Error(buildErr))
+		return err
+	}
+	if writeErr := r.report.write(ctx, sink); writeErr != nil {
+		r.logger.Warn("failed to write run report", zap.Error(writeErr))
+	}
+	if closeErr := sink.Close(ctx); closeErr != nil {
+		r.logger.Warn("failed to close sink", zap.Error(closeErr))
+	}
+	return err
+}
+
+// flushPartial writes the results of the steps that finished before ctx was
+// cancelled to a partial archive, with the run report marked partial, so an
+// interrupted job keeps what it collected. ctx is already done, so the flush
+// runs on a detached context with its own deadline. runErr is returned
+// either way; flush problems are logged.
+func (r *Runner) flushPartial(ctx context.Context, runErr error) error {
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), partialFlushTimeout)
+	defer cancel()
+
+	r.report.Partial = true
+	if err := r.writeResults(flushCtx, runErr); err != nil {
+		r.logger.Warn("failed to write partial archive", zap.Error(err))
+		return runErr
+	}
+	r.logger.Warn("run interrupted, wrote partial archive",
+		zap.String("destination", r.report.Destination),
+		zap.Int("results", len(r.raw)),
+	)
+	return runErr
+}
+
 // writeResults encodes every collected result through the configured
 // encoder and streams it to the configured sink. Keys are sorted so
 // concatenated output is reproducible despite Go's randomized map
 // iteration. When the output block declares a `steps` filter, only
-// the referenced steps are written.
-func (r *Runner) writeResults(ctx context.Context) error {
-	encoder, sink, err := buildOutputPipeline(ctx, r.tmpl.Output, r.baseCtx, r.tmpl.JobName())
+// the referenced steps are written. A step with its own `encoding` block
+// is encoded (and named) by that encoder instead. With `combined = true`
+// every result goes into one document named after the job, and with
+// `raw = true` the single result's data is written unencoded. A non-nil runErr means
+// the run was interrupted: the results so far go to a partial archive and
+// the report, recording runErr, is always included.
+func (r *Runner) writeResults(ctx context.Context, runErr error) error {
+	partial := runErr != nil
+	encoder, sink, err := buildOutputPipeline(ctx, r.logger, r.tmpl.Output, r.baseCtx, r.tmpl.JobName(), partial)
 	if err != nil {
 		return fmt.Errorf("failed to build output pipeline: %w", err)
 	}
@@ -124,10 +235,10 @@ func (r *Runner) writeResults(ctx context.Context) error {
 			r.logger.Warn("failed to close sink", zap.Error(err))
 		}
 	}()
+	r.report.Destination = sink.Name()
 
 	allowed := r.pipeline.OutputSteps()
 
-	ext := encoder.FileExtension()
 	keys := make([]string, 0, len(r.raw))
 	for k := range r.raw {
 		if allowed != nil {
@@ -139,41 +250,499 @@ func (r *Runner) writeResults(ctx context.Context) error {
 	}
 	sort.Strings(keys)
 
+	stepEncodings := r.stepEncodings()
+
+	writeReport := partial || (r.tmpl.Output != nil && r.tmpl.Output.WriteReport)
+	switch {
+	case r.tmpl.Output != nil && r.tmpl.Output.Raw:
+		if err := r.writeRaw(ctx, keys, sink); err != nil {
+			return err
+		}
+	case r.tmpl.Output != nil && r.tmpl.Output.Combined:
+		if err := r.writeCombined(ctx, keys, encoder, sink); err != nil {
+			return err
+		}
+	default:
+		if err := r.writePerStep(ctx, keys, encoder, sink, stepEncodings, writeReport); err != nil {
+			return err
+		}
+	}
+
+	r.report.finish(time.Now(), runErr)
+	if writeReport {
+		if err := r.report.write(ctx, sink); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateCombinedEncoder rejects combined = true with an encoding that
+// cannot encode every result into one document, before any step runs.
+func (r *Runner) validateCombinedEncoder() hcl.Diagnostics {
+	output := r.tmpl.Output
+	if output == nil || !output.Combined || output.Encoding == nil {
+		return nil
+	}
+	encoder, err := buildEncoder(output.Encoding, r.baseCtx)
+	if err != nil {
+		return hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid output encoding",
+			Detail:   err.Error(),
+			Subject:  output.Encoding.Body.MissingItemRange().Ptr(),
+		}}
+	}
+	if _, ok := encoder.(engine.ResultsEncoder); !ok {
+		return hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid combined output",
+			Detail:   fmt.Sprintf("combined output is not supported by the %s encoding; use the json encoding.", output.Encoding.Kind),
+			Subject:  output.Encoding.Body.MissingItemRange().Ptr(),
+		}}
+	}
+	return nil
+}
+
+// writeCombined encodes the results under keys into a single document named
+// after the job. The encoder must implement engine.ResultsEncoder.
+func (r *Runner) writeCombined(ctx context.Context, keys []string, encoder engine.Encoder, sink engine.Sink) error {
+	resultsEncoder, ok := encoder.(engine.ResultsEncoder)
+	if !ok {
+		return fmt.Errorf("combined output is not supported by the %s encoding", encoder.FileExtension())
+	}
+
+	results := make(map[string]engine.Result, len(keys))
 	for _, key := range keys {
-		result := r.raw[key]
-		reader, err := encoder.EncodeResult(ctx, result)
+		results[key] = r.raw[key]
+	}
+	reader, err := resultsEncoder.EncodeResults(ctx, results)
+	if err != nil {
+		return fmt.Errorf("failed to encode combined results: %w", err)
+	}
+
+	path := r.tmpl.JobName() + "." + encoder.FileExtension()
+	if path == ReportFileName {
+		return fmt.Errorf("combined output %s would overwrite the run report; rename the job", path)
+	}
+	counted := &countingReader{r: reader}
+	if err := sink.Write(ctx, path, counted); err != nil {
+		return fmt.Errorf("failed to write combined results: %w", err)
+	}
+	// The document is not attributable to a single step, so only the run
+	// total grows.
+	r.report.addBytes("", counted.n)
+	return nil
+}
+
+// writeRaw writes the data of the single result under keys to sink without
+// encoding it. Which steps qualify, and how their data is turned into bytes,
+// is decided by the step type and configuration (see rawBytes), never by
+// the shape of the data alone.
+func (r *Runner) writeRaw(ctx context.Context, keys []string, sink engine.Sink) error {
+	if len(keys) != 1 {
+		return fmt.Errorf("raw output writes exactly one step, but %d are selected; choose one with the output steps attribute", len(keys))
+	}
+	key := keys[0]
+
+	data, err := r.rawBytes(key)
+	if err != nil {
+		return fmt.Errorf("failed to write step %s as raw output: %w", key, err)
+	}
+	counted := &countingReader{r: bytes.NewReader(data)}
+	if err := sink.Write(ctx, key, counted); err != nil {
+		return fmt.Errorf("failed to write result %s: %w", key, err)
+	}
+	r.report.addBytes(key, counted.n)
+	return nil
+}
+
+// rawBytes returns the bytes raw output writes for the step under key:
+//   - an exec step with format = "raw": the program's output, decoded from
+//     base64;
+//   - a static step without parse_as: its value or file content;
+//   - an http_get step with response_type = "raw": the response body.
+//
+// Any other step is rejected, even when its data happens to look like one
+// of these.
+func (r *Runner) rawBytes(key string) ([]byte, error) {
+	node, meta, ok := r.stepNode(key)
+	if !ok {
+		return nil, fmt.Errorf("step %s not found", key)
+	}
+	data := r.raw[key].Data
+	ectx := r.childCtxForNode()
+
+	switch node.Type {
+	case "exec":
+		format, err := stringAttr(meta.Body, "format", ectx)
+		if err != nil {
+			return nil, err
+		}
+		if format != "raw" {
+			break
+		}
+		encoded, ok := singleString(data, "output")
+		if !ok {
+			return nil, fmt.Errorf("exec output must be a string, got %s", describeRawData(data))
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode base64 output: %w", err)
+		}
+		return decoded, nil
+	case "static":
+		parseAs, err := stringAttr(meta.Body, "parse_as", ectx)
+		if err != nil {
+			return nil, err
+		}
+		if parseAs != "" {
+			break
+		}
+		// A value is returned under "value" and a file under its base
+		// name; a .json file is parsed even without parse_as.
+		m, ok := data.(map[string]any)
+		if !ok || len(m) != 1 {
+			return nil, fmt.Errorf("static data must be a single string, got %s", describeRawData(data))
+		}
+		for _, value := range m {
+			switch v := value.(type) {
+			case string:
+				return []byte(v), nil
+			case []byte:
+				// Decoded base64 content that is not UTF-8.
+				return v, nil
+			}
+			return nil, fmt.Errorf("static data must be a single string, got %s", describeRawData(value))
+		}
+	case "http_get":
+		responseType, err := stringAttr(meta.Body, "response_type", ectx)
+		if err != nil {
+			return nil, err
+		}
+		if responseType != "raw" {
+			break
+		}
+		body, ok := data.(string)
+		if !ok {
+			return nil, fmt.Errorf("response body must be a string, got %s", describeRawData(data))
+		}
+		return []byte(body), nil
+	}
+	return nil, fmt.Errorf(`raw output needs an exec step with format = "raw", a static step without parse_as or an http_get step with response_type = "raw", got a step of type %s`, node.Type)
+}
+
+// stepNode returns the step node written under key, with its meta.
+func (r *Runner) stepNode(key string) (Node, *NodeMeta, bool) {
+	for node, meta := range r.pipeline.meta {
+		if node.Kind != NodeTypeCollector && nodeKey(node.Type, node.ID) == key {
+			return node, meta, true
+		}
+	}
+	return Node{}, nil, false
+}
+
+// stringAttr evaluates the optional string attribute name of body, returning
+// "" when it is not set.
+func stringAttr(body hcl.Body, name string, ectx *hcl.EvalContext) (string, error) {
+	content, _, diags := body.PartialContent(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: name}},
+	})
+	if diags.HasErrors() {
+		return "", diags
+	}
+	attr, ok := content.Attributes[name]
+	if !ok {
+		return "", nil
+	}
+	value, diags := attr.Expr.Value(ectx)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	if value.IsNull() {
+		return "", nil
+	}
+	if value.Type() != cty.String || !value.IsKnown() {
+		return "", fmt.Errorf("%s must be a string", name)
+	}
+	return value.AsString(), nil
+}
+
+// singleString returns data[name] when data is an object holding only that
+// string.
+func singleString(data any, name string) (string, bool) {
+	m, ok := data.(map[string]any)
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+	value, ok := m[name].(string)
+	return value, ok
+}
+
+// describeRawData names the JSON type of data for rawBytes' errors.
+func describeRawData(data any) string {
+	switch data.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case bool:
+		return "a bool"
+	case float64, int64, int:
+		return "a number"
+	default:
+		return fmt.Sprintf("%T", data)
+	}
+}
+
+// writePerStep writes every result under keys to its own file, with its meta
+// alongside when the encoder does not inline it.
+func (r *Runner) writePerStep(ctx context.Context, keys []string, encoder engine.Encoder, sink engine.Sink, stepEncodings map[string]*EncodingBlock, writeReport bool) error {
+	writes, err := r.planWrites(keys, encoder, stepEncodings, writeReport)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range writes {
+		result := r.raw[w.key]
+		reader, err := w.enc.EncodeResult(ctx, result)
 		if err != nil {
-			return fmt.Errorf("failed to encode result %s: %w", key, err)
+			return fmt.Errorf("failed to encode result %s: %w", w.key, err)
 		}
-		if err := sink.Write(ctx, key+"."+ext, reader); err != nil {
-			return fmt.Errorf("failed to write result %s: %w", key, err)
+		counted := &countingReader{r: reader}
+		if err := sink.Write(ctx, w.path, counted); err != nil {
+			return fmt.Errorf("failed to write result %s: %w", w.key, err)
 		}
+		r.report.addBytes(w.key, counted.n)
 
-		if len(result.Meta) > 0 {
-			metaReader, err := encoder.EncodeMeta(ctx, result.Meta)
+		if w.metaPath != "" {
+			metaReader, err := w.enc.EncodeMeta(ctx, result.Meta)
 			if err != nil {
-				return fmt.Errorf("failed to encode meta %s: %w", key, err)
+				return fmt.Errorf("failed to encode meta %s: %w", w.key, err)
 			}
-			if err := sink.Write(ctx, key+".meta."+ext, metaReader); err != nil {
-				return fmt.Errorf("failed to write meta %s: %w", key, err)
+			counted := &countingReader{r: metaReader}
+			if err := sink.Write(ctx, w.metaPath, counted); err != nil {
+				return fmt.Errorf("failed to write meta %s: %w", w.key, err)
 			}
+			r.report.addBytes(w.key, counted.n)
 		}
 	}
 	return nil
 }
 
+// plannedWrite is one result to write: its encoder and the sink paths of the
+// result and, when the meta is written separately, of its meta.
+type plannedWrite struct {
+	key      string
+	enc      engine.Encoder
+	path     string
+	metaPath string
+}
+
+// planWrites resolves the encoder and sink paths of every result before
+// anything is written, so a bad output filename or two steps writing to the
+// same path fail the run without leaving partial output behind.
+func (r *Runner) planWrites(keys []string, encoder engine.Encoder, stepEncodings map[string]*EncodingBlock, writeReport bool) ([]plannedWrite, error) {
+	var filenameExpr hcl.Expression
+	if r.tmpl.Output != nil {
+		filenameExpr = r.tmpl.Output.Filename
+	}
+
+	owners := make(map[string]string)
+	claim := func(path, key string) error {
+		if other, ok := owners[path]; ok {
+			return fmt.Errorf("%s and step %s both write to %s; make the output filename unique per step", other, key, path)
+		}
+		owners[path] = "step " + key
+		return nil
+	}
+	if writeReport {
+		owners[ReportFileName] = "the run report"
+	}
+
+	writes := make([]plannedWrite, 0, len(keys))
+	for _, key := range keys {
+		enc, name, err := r.stepOutput(key, filenameExpr, encoder, stepEncodings)
+		if err != nil {
+			return nil, err
+		}
+
+		ext := enc.FileExtension()
+		w := plannedWrite{key: key, enc: enc, path: name + "." + ext}
+		if err := claim(w.path, key); err != nil {
+			return nil, err
+		}
+
+		inliner, ok := enc.(engine.MetaInliner)
+		inlinesMeta := ok && inliner.InlinesMeta()
+		if len(r.raw[key].Meta) > 0 && !inlinesMeta {
+			w.metaPath = name + ".meta." + ext
+			if err := claim(w.metaPath, key); err != nil {
+				return nil, err
+			}
+		}
+		writes = append(writes, w)
+	}
+	return writes, nil
+}
+
+// stepEncodings returns the encoding blocks of the steps that override the
+// output encoding, keyed by "<type>/<id>".
+func (r *Runner) stepEncodings() map[string]*EncodingBlock {
+	encodings := make(map[string]*EncodingBlock)
+	for node, meta := range r.pipeline.meta {
+		if meta.Encoding != nil {
+			encodings[nodeKey(node.Type, node.ID)] = meta.Encoding
+		}
+	}
+	return encodings
+}
+
+// stepOutput returns the encoder the result under key is written with and
+// its sink path without extension.
+func (r *Runner) stepOutput(key string, filenameExpr hcl.Expression, encoder engine.Encoder, stepEncodings map[string]*EncodingBlock) (engine.Encoder, string, error) {
+	enc := encoder
+	if block, ok := stepEncodings[key]; ok {
+		var err error
+		enc, err = buildEncoder(block, r.baseCtx)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to build encoding for step %s: %w", key, err)
+		}
+	}
+	name, err := outputFilename(filenameExpr, r.baseCtx, key)
+	if err != nil {
+		return nil, "", err
+	}
+	return enc, name, nil
+}
+
 func (r *Runner) runCollector(ctx context.Context, node Node, meta *NodeMeta) error {
+	collector, err := r.createCollector(node, meta, r.childCtxForNode())
+	if err != nil {
+		return err
+	}
+	if err := r.startCollector(ctx, node, collector); err != nil {
+		return err
+	}
+	r.publishCollector(node, collector)
+	return nil
+}
+
+// startIndependentCollectors starts, up to startupConcurrency at a time,
+// every collector whose body references nothing but env.* and job.* — those
+// can never depend on another node's output, so they need not wait for their
+// turn in the DAG walk. Collectors that reference steps are left for Run's
+// ordered walk. Every collector that started is published (and therefore
+// closed by closeCollectors) even when another one fails mid-flight, and
+// one whose start was aborted by that failure is closed here.
+func (r *Runner) startIndependentCollectors(ctx context.Context, order []Node) error {
+	var (
+		nodes []Node
+		metas []*NodeMeta
+	)
+	for _, node := range order {
+		if node.Kind != NodeTypeCollector {
+			continue
+		}
+		meta, ok := r.pipeline.Meta(node)
+		if !ok || !onlyStaticRefs(meta.Refs) {
+			continue
+		}
+		nodes = append(nodes, node)
+		metas = append(metas, meta)
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	// No step or collector has resolved yet, so one eval context serves
+	// every factory; it is only read from here on.
 	ectx := r.childCtxForNode()
+	started := make([]engine.Collector, len(nodes))
+	aborted := make([]engine.Collector, len(nodes))
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(r.startupConcurrency)
+	for i := range nodes {
+		g.Go(func() error {
+			collector, err := r.createCollector(nodes[i], metas[i], ectx)
+			if err != nil {
+				return err
+			}
+			if err := r.startCollector(gctx, nodes[i], collector); err != nil {
+				// gctx is only canceled once a failing start returned, so a
+				// done gctx means this start was aborted rather than failed.
+				if gctx.Err() != nil {
+					aborted[i] = collector
+				}
+				return err
+			}
+			started[i] = collector
+			return nil
+		})
+	}
+	err := g.Wait()
+
+	for i, collector := range started {
+		if collector != nil {
+			r.publishCollector(nodes[i], collector)
+		}
+	}
+	r.closeAborted(aborted)
+	return err
+}
 
+// closeAborted closes the collectors whose start was aborted. Start may have
+// acquired resources before it noticed the cancellation.
+func (r *Runner) closeAborted(collectors []engine.Collector) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectorCloseTimeout)
+	defer cancel()
+	for _, c := range collectors {
+		if c == nil {
+			continue
+		}
+		if err := c.Close(ctx); err != nil {
+			r.logger.Warn("failed to close collector",
+				zap.String("collector", c.Name()),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+func onlyStaticRefs(refs []Reference) bool {
+	for _, ref := range refs {
+		if ref.Root != RootEnv && ref.Root != RootJob {
+			return false
+		}
+	}
+	return true
+}
+
+// createCollector and startCollector touch no Runner state, so
+// startIndependentCollectors can call them concurrently.
+func (r *Runner) createCollector(node Node, meta *NodeMeta, ectx *hcl.EvalContext) (engine.Collector, error) {
 	collector, diags := r.registry.CreateCollector(node.Type, meta.Body, ectx)
 	if diags.HasErrors() {
-		return fmt.Errorf("failed to create collector %s/%s: %s", node.Type, node.ID, diags.Error())
+		return nil, fmt.Errorf("failed to create collector %s/%s: %s", node.Type, node.ID, diags.Error())
 	}
+	return collector, nil
+}
 
+func (r *Runner) startCollector(ctx context.Context, node Node, collector engine.Collector) error {
 	if err := collector.Start(ctx); err != nil {
-		return fmt.Errorf("failed to start collector %s/%s: %w", node.Type, node.ID, err)
+		return fmt.Errorf("failed to start collector %s/%s (%s): %w", node.Type, node.ID, collector.Name(), err)
 	}
+	return nil
+}
 
+func (r *Runner) publishCollector(node Node, collector engine.Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.collectors[nodeKey(node.Type, node.ID)] = collector
 	if r.collectorByType[node.Type] == nil {
 		r.collectorByType[node.Type] = make(map[string]cty.Value)
@@ -186,42 +755,71 @@ func (r *Runner) runCollector(ctx context.Context, node Node, meta *NodeMeta) er
 		zap.String("type", node.Type),
 		zap.String("id", node.ID),
 	)
-	return nil
 }
 
-func (r *Runner) runStep(ctx context.Context, node Node, meta *NodeMeta) error {
+// runStep resolves a single step. It reports skipped when the step's `when`
+// condition is false; a skipped step produces no output and its references
+// resolve to null data.
+func (r *Runner) runStep(ctx context.Context, node Node, meta *NodeMeta) (bool, error) {
 	ectx := r.childCtxForNode()
 
-	collector, err := r.resolveStepCollector(node, meta)
+	run, err := evalWhen(ectx, meta.When)
 	if err != nil {
-		return err
+		return false, fmt.Errorf("step %s/%s: %w", node.Type, node.ID, err)
+	}
+	if !run {
+		r.publishStep(node, skippedStepValue, nil)
+		r.logger.Info("step skipped",
+			zap.String("type", node.Type),
+			zap.String("id", node.ID),
+		)
+		return true, nil
 	}
 
-	step, diags := r.registry.CreateStep(node.Type, node.ID, collector, meta.Body, ectx)
-	if diags.HasErrors() {
-		return fmt.Errorf("failed to create step %s/%s: %s", node.Type, node.ID, diags.Error())
+	collector, err := r.resolveStepCollector(node, meta)
+	if err != nil {
+		return false, err
 	}
 
-	result, err := step.Resolve(ctx)
+	cacheKey, err := r.cacheKeyFor(ectx, node, meta)
 	if err != nil {
-		return fmt.Errorf("failed to resolve step %s/%s: %w", node.Type, node.ID, err)
+		return false, fmt.Errorf("step %s/%s: %w", node.Type, node.ID, err)
+	}
+	result, cached := r.cachedResult(node, cacheKey)
+	if !cached {
+		step, diags := r.registry.CreateStep(node.Type, node.ID, collector, meta.Body, ectx)
+		if diags.HasErrors() {
+			return false, fmt.Errorf("failed to create step %s/%s: %s", node.Type, node.ID, diags.Error())
+		}
+
+		result, err = r.resolveWithRetries(ctx, node, "", step, func(ctx context.Context) (engine.Result, error) {
+			return r.resolveStep(ctx, node, cacheKey, step)
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to resolve step %s/%s: %w", node.Type, node.ID, err)
+		}
 	}
 
 	resultCty, err := resultToCty(result)
 	if err != nil {
-		return fmt.Errorf("failed to convert result for %s/%s: %w", node.Type, node.ID, err)
+		return false, fmt.Errorf("failed to convert result for %s/%s: %w", node.Type, node.ID, err)
 	}
-	if r.stepByType[node.Type] == nil {
-		r.stepByType[node.Type] = make(map[string]cty.Value)
+	if err := validateItemCount(ectx, meta, resultCty); err != nil {
+		return false, fmt.Errorf("step %s/%s returned an unexpected result: %w", node.Type, node.ID, err)
+	}
+	if err := checkAsserts(ectx, meta, resultCty); err != nil {
+		return false, fmt.Errorf("step %s/%s failed an assertion: %w", node.Type, node.ID, err)
 	}
-	r.stepByType[node.Type][node.ID] = resultCty
-	r.raw[nodeKey(node.Type, node.ID)] = result
+	if cacheKey != "" && !cached {
+		r.storeResult(node, cacheKey, result)
+	}
+	r.publishStep(node, resultCty, &result)
 
 	r.logger.Info("step resolved",
 		zap.String("type", node.Type),
 		zap.String("id", node.ID),
 	)
-	return nil
+	return false, nil
 }
 
 func (r *Runner) runCollection(ctx context.Context, node Node, meta *NodeMeta) error {
@@ -262,12 +860,20 @@ func (r *Runner) runCollection(ctx context.Context, node Node, meta *NodeMeta) e
 			}),
 		}
 
+		run, err := evalWhen(iterCtx, meta.When)
+		if err != nil {
+			return fmt.Errorf("step %s/%s[%s]: %w", node.Type, node.ID, keyStr, err)
+		}
+		if !run {
+			continue
+		}
+
 		step, diags := r.registry.CreateStep(node.Type, node.ID, collector, meta.Body, iterCtx)
 		if diags.HasErrors() {
 			return fmt.Errorf("failed to create step %s/%s[%s]: %s", node.Type, node.ID, keyStr, diags.Error())
 		}
 
-		result, err := step.Resolve(ctx)
+		result, err := r.resolveWithRetries(ctx, node, keyStr, step, step.Resolve)
 		if err != nil {
 			return fmt.Errorf("failed to resolve step %s/%s[%s]: %w", node.Type, node.ID, keyStr, err)
 		}
@@ -276,6 +882,12 @@ func (r *Runner) runCollection(ctx context.Context, node Node, meta *NodeMeta) e
 		if err != nil {
 			return fmt.Errorf("failed to convert result for %s/%s[%s]: %w", node.Type, node.ID, keyStr, err)
 		}
+		if err := validateItemCount(iterCtx, meta, resultCty); err != nil {
+			return fmt.Errorf("step %s/%s[%s] returned an unexpected result: %w", node.Type, node.ID, keyStr, err)
+		}
+		if err := checkAsserts(iterCtx, meta, resultCty); err != nil {
+			return fmt.Errorf("step %s/%s[%s] failed an assertion: %w", node.Type, node.ID, keyStr, err)
+		}
 
 		iterResults[keyStr] = resultCty
 		iterRaw[keyStr] = result
@@ -289,11 +901,7 @@ func (r *Runner) runCollection(ctx context.Context, node Node, meta *NodeMeta) e
 	} else {
 		aggregated = cty.ObjectVal(iterResults)
 	}
-	if r.stepByType[node.Type] == nil {
-		r.stepByType[node.Type] = make(map[string]cty.Value)
-	}
-	r.stepByType[node.Type][node.ID] = aggregated
-	r.raw[nodeKey(node.Type, node.ID)] = engine.Result{Data: iterRaw}
+	r.publishStep(node, aggregated, &engine.Result{Data: iterRaw})
 
 	r.logger.Info("collection resolved",
 		zap.String("type", node.Type),
@@ -303,13 +911,29 @@ func (r *Runner) runCollection(ctx context.Context, node Node, meta *NodeMeta) e
 	return nil
 }
 
+// publishStep makes a step's value visible to later nodes as step.<type>.<id>
+// and, unless result is nil (skipped steps), queues it for output.
+func (r *Runner) publishStep(node Node, value cty.Value, result *engine.Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stepByType[node.Type] == nil {
+		r.stepByType[node.Type] = make(map[string]cty.Value)
+	}
+	r.stepByType[node.Type][node.ID] = value
+	if result != nil {
+		r.raw[nodeKey(node.Type, node.ID)] = *result
+	}
+}
+
 func (r *Runner) resolveStepCollector(node Node, meta *NodeMeta) (engine.Collector, error) {
 	if meta.CollectorAddr == nil {
 		// Collector-less step kinds (static, exec).
 		return nil, nil
 	}
 	key := nodeKey(meta.CollectorAddr.Type, meta.CollectorAddr.Name)
+	r.mu.Lock()
 	c, ok := r.collectors[key]
+	r.mu.Unlock()
 	if !ok {
 		return nil, fmt.Errorf("step %s/%s references unknown collector %s", node.Type, node.ID, key)
 	}
@@ -317,6 +941,8 @@ func (r *Runner) resolveStepCollector(node Node, meta *NodeMeta) (engine.Collect
 }
 
 func (r *Runner) childCtxForNode() *hcl.EvalContext {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	child := r.baseCtx.NewChild()
 	child.Variables = map[string]cty.Value{
 		"step":      r.stepNamespace(),
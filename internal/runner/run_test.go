@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"io"
@@ -152,6 +153,28 @@ func newRunner(t *testing.T, src []byte, filename string, reg *engine.Registry)
 	return r
 }
 
+// captureStdout returns what fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	orig := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(&buf, r)
+		close(done)
+	}()
+
+	defer func() { os.Stdout = orig }()
+	fn()
+	_ = w.Close()
+	<-done
+	return buf.String()
+}
+
 func runSilently(t *testing.T, r *Runner) (map[string]engine.Result, error) {
 	t.Helper()
 	var (
@@ -271,6 +294,35 @@ step "stub_nocoll" "fan" {
 	assert.ErrorContains(t, err, "map, object, or set of strings")
 }
 
+func TestRunner_StepFailureIsStepError(t *testing.T) {
+	stub := newStubRegistry(t)
+
+	src := []byte(`
+step "stub_nocoll" "ok" {
+  val = "x"
+}
+
+step "stub_nocoll" "fan" {
+  for_each = ["a", "b"]
+  val      = each.value
+}
+`)
+
+	_, err := runSilently(t, newRunner(t, src, "fan.hcl", stub.reg))
+	var stepErr *StepError
+	require.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, "stub_nocoll", stepErr.Kind)
+	assert.Equal(t, "fan", stepErr.ID)
+	assert.Equal(t, stepErr.Err.Error(), err.Error(), "wrapping must not change the message")
+
+	_, err = runSilently(t, newRunner(t, []byte(`
+collector "stub_failing" "bad" {
+}
+`), "fail.hcl", stub.reg))
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &stepErr), "collector failures are not step errors")
+}
+
 func TestRunner_CollectorStartErrorClosesStartedCollectors(t *testing.T) {
 	stub := newStubRegistry(t)
 
@@ -289,6 +341,44 @@ collector "stub_failing" "bad" {
 	}
 }
 
+func TestRunner_StopsWhenContextExpires(t *testing.T) {
+	stub := newStubRegistry(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	// stub_cancel cancels the run context from inside its own resolution,
+	// standing in for a deadline that fires mid-run.
+	factory := func(_ *engine.RegistryHelper, id string, _ engine.Collector, _ hcl.Body, _ *hcl.EvalContext) (engine.Step, hcl.Diagnostics) {
+		return engine.StepFunction(id, "stub_cancel", func(context.Context) (engine.Result, error) {
+			cancel()
+			return engine.Result{ID: id, Data: map[string]any{}}, nil
+		}), nil
+	}
+	require.NoError(t, stub.reg.RegisterStep(engine.StepDescriptor{Kind: "stub_cancel", Factory: factory}))
+
+	r := newRunner(t, []byte(`
+collector "stub" "c" {
+}
+
+step "stub_cancel" "first" {
+}
+
+step "stub_step" "second" {
+  collector = collector.stub.c
+  after     = step.stub_cancel.first.data
+}
+`), "cancel.hcl", stub.reg)
+
+	var err error
+	silenceStdout(t, func() {
+		_, err = r.Run(ctx)
+	})
+	assert.ErrorContains(t, err, "run aborted before stub_step/second")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, stub.collectors["stub"].closed, "collectors are closed after an aborted run")
+}
+
 func TestRunner_CloseCollectorsUsesIndependentContext(t *testing.T) {
 	stub := newStubRegistry(t)
 
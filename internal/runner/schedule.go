@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// nodeOutcome is what a node goroutine reports back to runNodes.
+type nodeOutcome struct {
+	node Node
+	err  error
+}
+
+// runNodes executes the DAG, starting each node as soon as every node it
+// depends on has finished, with at most stepConcurrency nodes in flight.
+// Ready nodes start in topological order, so a concurrency of 1 reproduces a
+// plain walk over order. After the first failure (or once ctx is done) no
+// new node starts; nodes already in flight are waited for, and the first
+// error is returned.
+func (r *Runner) runNodes(ctx context.Context, order []Node) error {
+	dag := r.pipeline.dag
+
+	position := make(map[Node]int, len(order))
+	pending := make(map[Node]int, len(order))
+	for i, node := range order {
+		position[node] = i
+		for _, next := range dag.Dependents(node) {
+			pending[next]++
+		}
+	}
+
+	var ready []Node
+	for _, node := range order {
+		if pending[node] == 0 {
+			ready = append(ready, node)
+		}
+	}
+
+	outcomes := make(chan nodeOutcome)
+	running := 0
+	var firstErr error
+	for {
+		for firstErr == nil && running < r.stepConcurrency && len(ready) > 0 {
+			node := ready[0]
+			if err := ctx.Err(); err != nil {
+				firstErr = fmt.Errorf("run aborted before %s/%s: %w", node.Type, node.ID, err)
+				break
+			}
+			ready = ready[1:]
+			running++
+			go func() {
+				outcomes <- nodeOutcome{node: node, err: r.runNode(ctx, node)}
+			}()
+		}
+		if running == 0 {
+			return firstErr
+		}
+
+		outcome := <-outcomes
+		running--
+		if outcome.err != nil {
+			if firstErr == nil {
+				firstErr = outcome.err
+			}
+			continue
+		}
+		for _, next := range dag.Dependents(outcome.node) {
+			pending[next]--
+			if pending[next] == 0 {
+				ready = insertByPosition(ready, next, position)
+			}
+		}
+	}
+}
+
+// insertByPosition inserts node into ready, which is kept sorted by
+// topological position.
+func insertByPosition(ready []Node, node Node, position map[Node]int) []Node {
+	idx := len(ready)
+	for i, other := range ready {
+		if position[other] > position[node] {
+			idx = i
+			break
+		}
+	}
+	ready = append(ready, Node{})
+	copy(ready[idx+1:], ready[idx:])
+	ready[idx] = node
+	return ready
+}
+
+// runNode executes a single node and records steps in the run report.
+func (r *Runner) runNode(ctx context.Context, node Node) error {
+	meta, ok := r.pipeline.Meta(node)
+	if !ok {
+		return fmt.Errorf("pipeline metadata missing for node %s", node.Key())
+	}
+
+	if _, ok := r.resumed[nodeKey(node.Type, node.ID)]; ok && node.Kind != NodeTypeCollector {
+		r.logger.Info("step output already written, skipping", zap.String("step", node.Address()))
+		r.publishStep(node, skippedStepValue, nil)
+		r.recordStep(node, 0, true, nil)
+		return nil
+	}
+
+	switch node.Kind {
+	case NodeTypeCollector:
+		r.mu.Lock()
+		_, started := r.collectors[nodeKey(node.Type, node.ID)]
+		r.mu.Unlock()
+		if started {
+			return nil
+		}
+		return r.runCollector(ctx, node, meta)
+	case NodeTypeStep:
+		r.reportProgress(node)
+		start := time.Now()
+		skipped, err := r.runStep(ctx, node, meta)
+		r.recordStep(node, time.Since(start), skipped, err)
+		return stepError(node, err)
+	case NodeTypeCollection:
+		r.reportProgress(node)
+		start := time.Now()
+		err := r.runCollection(ctx, node, meta)
+		r.recordStep(node, time.Since(start), false, err)
+		return stepError(node, err)
+	default:
+		return fmt.Errorf("unknown node kind %q", node.Kind.String())
+	}
+}
+
+// stepError wraps a failure of node in a StepError; nil stays nil.
+func stepError(node Node, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &StepError{Kind: node.Type, ID: node.ID, Err: err}
+}
+
+func (r *Runner) recordStep(node Node, elapsed time.Duration, skipped bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.recordStep(node, elapsed, skipped, err)
+}
@@ -0,0 +1,219 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stepTracker records how many tracked steps overlap and the order in which
+// they finish.
+type stepTracker struct {
+	delay time.Duration
+
+	mu        sync.Mutex
+	inFlight  int
+	maxFlight int
+	finished  []string
+}
+
+// registerTrackedStep registers the collector-less "tracked" step kind. A
+// step whose id is "boom" fails.
+func registerTrackedStep(t *testing.T, reg *engine.Registry, tr *stepTracker) {
+	t.Helper()
+	factory := func(_ *engine.RegistryHelper, id string, _ engine.Collector, _ hcl.Body, _ *hcl.EvalContext) (engine.Step, hcl.Diagnostics) {
+		return engine.StepFunction(id, "tracked", func(context.Context) (engine.Result, error) {
+			tr.mu.Lock()
+			tr.inFlight++
+			tr.maxFlight = max(tr.maxFlight, tr.inFlight)
+			tr.mu.Unlock()
+
+			time.Sleep(tr.delay)
+
+			tr.mu.Lock()
+			tr.inFlight--
+			tr.finished = append(tr.finished, id)
+			tr.mu.Unlock()
+
+			if id == "boom" {
+				return engine.Result{}, errors.New("boom")
+			}
+			return engine.Result{ID: id, Data: map[string]any{}}, nil
+		}), nil
+	}
+	require.NoError(t, reg.RegisterStep(engine.StepDescriptor{Kind: "tracked", Factory: factory}))
+}
+
+func TestRunner_StepConcurrency(t *testing.T) {
+	src := []byte(`
+step "tracked" "a" {}
+step "tracked" "b" {}
+step "tracked" "c" {}
+step "tracked" "d" {}
+`)
+	cases := []struct {
+		name        string
+		concurrency int
+		wantMax     int
+	}{
+		{name: "sequential by default", concurrency: 0, wantMax: 1},
+		{name: "bounded", concurrency: 2, wantMax: 2},
+		{name: "all at once", concurrency: 8, wantMax: 4},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := newStubRegistry(t)
+			tr := &stepTracker{delay: 30 * time.Millisecond}
+			registerTrackedStep(t, stub.reg, tr)
+
+			var opts []Option
+			if tc.concurrency > 0 {
+				opts = append(opts, WithStepConcurrency(tc.concurrency))
+			}
+			r := newRunnerWithOptions(t, src, stub.reg, opts...)
+			results, err := runSilently(t, r)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.wantMax, tr.maxFlight)
+			assert.Len(t, results, 4)
+			assert.Len(t, r.Report().Steps, 4)
+		})
+	}
+}
+
+func TestRunner_DependsOnOrdersSteps(t *testing.T) {
+	stub := newStubRegistry(t)
+	tr := &stepTracker{delay: 20 * time.Millisecond}
+	registerTrackedStep(t, stub.reg, tr)
+
+	r := newRunnerWithOptions(t, []byte(`
+step "tracked" "last" {
+  depends_on = [step.tracked.first, step.tracked.second]
+}
+step "tracked" "first" {}
+step "tracked" "second" {}
+`), stub.reg, WithStepConcurrency(4))
+	_, err := runSilently(t, r)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, tr.maxFlight, "first and second are independent")
+	require.Len(t, tr.finished, 3)
+	assert.Equal(t, "last", tr.finished[2])
+}
+
+func TestRunner_StepConcurrency_FailureStopsDependents(t *testing.T) {
+	stub := newStubRegistry(t)
+	tr := &stepTracker{delay: 20 * time.Millisecond}
+	registerTrackedStep(t, stub.reg, tr)
+
+	r := newRunnerWithOptions(t, []byte(`
+step "tracked" "boom" {}
+step "tracked" "sibling" {}
+step "tracked" "after" {
+  depends_on = [step.tracked.boom]
+}
+`), stub.reg, WithStepConcurrency(2))
+	_, err := runSilently(t, r)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "failed to resolve step tracked/boom")
+
+	assert.ElementsMatch(t, []string{"boom", "sibling"}, tr.finished,
+		"the in-flight sibling finishes, the dependent never starts")
+}
+
+func TestBuildPipeline_DependsOn(t *testing.T) {
+	src := []byte(`
+collector "terraform" "k8s" {
+  provider = "hashicorp/kubernetes"
+}
+
+step "static" "b" {
+  value      = "b"
+  depends_on = [step.static.a, collector.terraform.k8s]
+}
+
+step "static" "a" {
+  value = "a"
+}
+`)
+	tmpl, diags := ParseJobTemplate(src, "deps.hcl")
+	require.False(t, diags.HasErrors(), "parse diags: %s", diags.Error())
+	require.NotNil(t, tmpl.Steps[0].DependsOn)
+
+	p, diags := BuildPipeline(zap.NewNop(), tmpl, testRegistry())
+	require.False(t, diags.HasErrors(), "build diags: %s", diags.Error())
+
+	b := Node{Kind: NodeTypeStep, Type: "static", ID: "b"}
+	assert.ElementsMatch(t,
+		[]Node{b},
+		p.Dag().Dependents(Node{Kind: NodeTypeStep, Type: "static", ID: "a"}))
+	assert.ElementsMatch(t,
+		[]Node{b},
+		p.Dag().Dependents(Node{Kind: NodeTypeCollector, Type: "terraform", ID: "k8s"}))
+}
+
+func TestBuildPipeline_DependsOnErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		src     string
+		wantMsg string
+	}{
+		{
+			name: "not a list",
+			src: `
+step "static" "a" {
+  depends_on = step.static.b
+}
+step "static" "b" {}`,
+			wantMsg: "Invalid depends_on",
+		},
+		{
+			name: "not a traversal",
+			src: `
+step "static" "a" {
+  depends_on = ["step.static.b"]
+}`,
+			wantMsg: "Invalid dependency",
+		},
+		{
+			name: "unknown step",
+			src: `
+step "static" "a" {
+  depends_on = [step.static.missing]
+}`,
+			wantMsg: "step.static.missing is not declared in this job.",
+		},
+		{
+			name: "cycle lists its members",
+			src: `
+step "static" "a" {
+  depends_on = [step.static.c]
+}
+step "static" "b" {
+  depends_on = [step.static.a]
+}
+step "static" "c" {
+  value = step.static.b.data
+}`,
+			wantMsg: "cycle detected: step.static.a -> step.static.b -> step.static.c -> step.static.a",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, diags := ParseJobTemplate([]byte(tc.src), "case.hcl")
+			require.False(t, diags.HasErrors(), "parse diags: %s", diags.Error())
+
+			_, diags = BuildPipeline(zap.NewNop(), tmpl, testRegistry())
+			require.True(t, diags.HasErrors())
+			assert.Contains(t, diags.Error(), tc.wantMsg)
+		})
+	}
+}
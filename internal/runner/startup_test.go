@@ -0,0 +1,207 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// slowCollector sleeps in Start so tests can observe overlapping starts.
+// Collectors whose name is listed in failNames fail to start, and those in
+// blockNames wait in Start until it is canceled.
+type slowCollector struct {
+	name    string
+	tracker *startTracker
+	closed  atomic.Bool
+}
+
+type startTracker struct {
+	delay      time.Duration
+	failNames  map[string]bool
+	blockNames map[string]bool
+
+	mu         sync.Mutex
+	inFlight   int
+	maxFlight  int
+	collectors []*slowCollector
+}
+
+func (c *slowCollector) Name() string { return c.name }
+func (c *slowCollector) Kind() string { return "slow" }
+
+func (c *slowCollector) Start(ctx context.Context) error {
+	tr := c.tracker
+	tr.mu.Lock()
+	tr.inFlight++
+	tr.maxFlight = max(tr.maxFlight, tr.inFlight)
+	tr.mu.Unlock()
+
+	defer func() {
+		tr.mu.Lock()
+		tr.inFlight--
+		tr.mu.Unlock()
+	}()
+
+	if tr.blockNames[c.name] {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	time.Sleep(tr.delay)
+	if tr.failNames[c.name] {
+		return errors.New("dial failed")
+	}
+	return nil
+}
+
+func (c *slowCollector) Close(context.Context) error {
+	c.closed.Store(true)
+	return nil
+}
+
+func registerSlowCollector(t *testing.T, reg *engine.Registry, tr *startTracker) {
+	t.Helper()
+	require.NoError(t, reg.RegisterCollector("slow", func(_ *engine.RegistryHelper, body hcl.Body, ctx *hcl.EvalContext) (engine.Collector, hcl.Diagnostics) {
+		data, diags := engine.BodyToMap(body, ctx)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		c := &slowCollector{name: fmt.Sprint(data["endpoint"]), tracker: tr}
+		tr.mu.Lock()
+		tr.collectors = append(tr.collectors, c)
+		tr.mu.Unlock()
+		return c, nil
+	}))
+}
+
+func slowCollectorsJob(n int) []byte {
+	src := ""
+	for i := range n {
+		src += fmt.Sprintf("collector \"slow\" \"c%d\" {\n  endpoint = \"endpoint-%d\"\n}\n\n", i, i)
+	}
+	return []byte(src)
+}
+
+func newRunnerWithOptions(t *testing.T, src []byte, reg *engine.Registry, opts ...Option) *Runner {
+	t.Helper()
+	tmpl, diags := ParseJobTemplate(src, "startup.hcl")
+	require.False(t, diags.HasErrors(), "parse: %s", diags.Error())
+	r, diags := New(zap.NewNop(), tmpl, reg, nil, opts...)
+	require.False(t, diags.HasErrors(), "new: %s", diags.Error())
+	return r
+}
+
+func TestRunner_StartupConcurrency(t *testing.T) {
+	cases := []struct {
+		name        string
+		concurrency int
+		wantMax     int
+	}{
+		{name: "sequential by default", concurrency: 0, wantMax: 1},
+		{name: "bounded", concurrency: 2, wantMax: 2},
+		{name: "all at once", concurrency: 8, wantMax: 4},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := newStubRegistry(t)
+			tr := &startTracker{delay: 30 * time.Millisecond}
+			registerSlowCollector(t, stub.reg, tr)
+
+			var opts []Option
+			if tc.concurrency > 0 {
+				opts = append(opts, WithStartupConcurrency(tc.concurrency))
+			}
+			r := newRunnerWithOptions(t, slowCollectorsJob(4), stub.reg, opts...)
+			_, err := runSilently(t, r)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.wantMax, tr.maxFlight)
+			require.Len(t, tr.collectors, 4)
+			for _, c := range tr.collectors {
+				assert.True(t, c.closed.Load(), "collector %s must be closed", c.name)
+			}
+		})
+	}
+}
+
+func TestRunner_StartupConcurrency_FailureClosesStarted(t *testing.T) {
+	stub := newStubRegistry(t)
+	tr := &startTracker{
+		delay:     10 * time.Millisecond,
+		failNames: map[string]bool{"endpoint-1": true},
+	}
+	registerSlowCollector(t, stub.reg, tr)
+
+	r := newRunnerWithOptions(t, slowCollectorsJob(4), stub.reg, WithStartupConcurrency(4))
+	_, err := runSilently(t, r)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "failed to start collector slow/c1 (endpoint-1): dial failed")
+
+	require.Len(t, tr.collectors, 4)
+	for _, c := range tr.collectors {
+		if c.name == "endpoint-1" {
+			assert.False(t, c.closed.Load(), "failed-to-start collector must not be closed")
+			continue
+		}
+		assert.True(t, c.closed.Load(), "started collector %s must be closed", c.name)
+	}
+}
+
+func TestRunner_StartupConcurrency_FailureClosesAborted(t *testing.T) {
+	stub := newStubRegistry(t)
+	tr := &startTracker{
+		delay:      10 * time.Millisecond,
+		failNames:  map[string]bool{"endpoint-1": true},
+		blockNames: map[string]bool{"endpoint-2": true},
+	}
+	registerSlowCollector(t, stub.reg, tr)
+
+	r := newRunnerWithOptions(t, slowCollectorsJob(4), stub.reg, WithStartupConcurrency(4))
+	_, err := runSilently(t, r)
+	assert.ErrorContains(t, err, "failed to start collector slow/c1 (endpoint-1): dial failed")
+
+	require.Len(t, tr.collectors, 4)
+	for _, c := range tr.collectors {
+		if c.name == "endpoint-1" {
+			assert.False(t, c.closed.Load(), "failed-to-start collector must not be closed")
+			continue
+		}
+		assert.True(t, c.closed.Load(), "collector %s must be closed, started or aborted", c.name)
+	}
+}
+
+func TestRunner_StartupConcurrency_StepDependentCollectorWaits(t *testing.T) {
+	stub := newStubRegistry(t)
+	tr := &startTracker{}
+	registerSlowCollector(t, stub.reg, tr)
+
+	src := []byte(`
+step "stub_nocoll" "discover" {
+  endpoint = "discovered"
+}
+
+collector "slow" "late" {
+  endpoint = step.stub_nocoll.discover.data.endpoint
+}
+
+collector "slow" "early" {
+  endpoint = "static"
+}
+`)
+	r := newRunnerWithOptions(t, src, stub.reg, WithStartupConcurrency(4))
+	_, err := runSilently(t, r)
+	require.NoError(t, err)
+
+	require.Len(t, tr.collectors, 2)
+	assert.Equal(t, "static", tr.collectors[0].name, "independent collectors start before the DAG walk")
+	assert.Equal(t, "discovered", tr.collectors[1].name)
+}
@@ -11,11 +11,13 @@ package runner
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 )
 
 // JobTemplate is the parse-time shape of a collect job. It describes a
@@ -36,6 +38,17 @@ type JobTemplate struct {
 // pipeline generates a default name.
 type JobBlock struct {
 	Name string `hcl:"name,optional"`
+	// Pre and Post are commands run before the collectors start and after
+	// the run, successful or not. See hooks.go.
+	Pre  *HookBlock `hcl:"pre,block"`
+	Post *HookBlock `hcl:"post,block"`
+}
+
+// HookBlock is a `pre { ... }` or `post { ... }` block of the job block. Its
+// body is an exec step configuration, decoded by the registry's exec step
+// when the hook runs.
+type HookBlock struct {
+	Body hcl.Body `hcl:",remain"`
 }
 
 // CollectorBlock is the outer shape of a collector. The inner body stays as
@@ -69,14 +82,34 @@ type StepBlock struct {
 	// attributes. Nil otherwise.
 	ForEach   hcl.Expression
 	Collector hcl.Expression
+	MinItems  hcl.Expression
+	MaxItems  hcl.Expression
+	When      hcl.Expression
+	DependsOn hcl.Expression
+	// NoCache keeps the step's result out of the result cache.
+	NoCache hcl.Expression
+	// Encoding overrides the output encoding for this step's result.
+	Encoding *EncodingBlock
+	// Asserts are checked against the step's result after it resolves.
+	Asserts []*AssertBlock
 
 	// Untagged so gohcl ignores it.
 	DefRange hcl.Range
 }
 
+// AssertBlock is a step's `assert { condition = ..., error_message = ... }`
+// sub-block. Condition is evaluated with `self` bound to the step's result
+// ({data, meta}); the step fails when it is false.
+type AssertBlock struct {
+	Condition    hcl.Expression
+	ErrorMessage hcl.Expression // nil when not declared
+	DefRange     hcl.Range
+}
+
 // OutputBlock wraps the output configuration. Its children are labeled
 // sub-blocks whose first label selects the variant (json encoding, tar
-// archive, s3 sink, ...). The inner bodies stay unevaluated for the
+// archive, s3 sink, ...). Several sink blocks may be declared; every result
+// is then written to each of them. The inner bodies stay unevaluated for the
 // respective integration factories to decode; runner execution does not
 // consume them yet — the runner returns collected results to the caller
 // and the CLI is responsible for writing output until per-integration
@@ -84,12 +117,31 @@ type StepBlock struct {
 type OutputBlock struct {
 	Encoding *EncodingBlock `hcl:"encoding,block"`
 	Archive  *ArchiveBlock  `hcl:"archive,block"`
-	Sink     *SinkBlock     `hcl:"sink,block"`
+	Sinks    []*SinkBlock   `hcl:"sink,block"`
+	Retry    *RetryBlock    `hcl:"retry,block"`
+	Sign     *SignBlock     `hcl:"sign,block"`
 	Body     hcl.Body       `hcl:",remain"`
 
+	// WriteReport persists the run report as _report.json through the sink
+	// (and therefore into the archive when archiving).
+	WriteReport bool `hcl:"write_report,optional"`
+
+	// Combined writes every selected step into a single <job name>.<ext>
+	// document keyed by step instead of one file per step.
+	Combined bool `hcl:"combined,optional"`
+
+	// Raw writes the single selected step's data as-is, without an
+	// encoder, so a download can be piped straight to a file.
+	Raw bool `hcl:"raw,optional"`
+
 	// Populated by splitOutputMeta when the output body contains a `steps`
 	// attribute. Nil means "include all steps in the output".
 	Steps hcl.Expression
+
+	// Populated by splitOutputMeta when the output body contains a
+	// `filename` attribute. It is evaluated once per written step, with
+	// step_type and step_id in scope. Nil keeps the <type>/<id> layout.
+	Filename hcl.Expression
 }
 
 // EncodingBlock is `encoding "<kind>" { ... }`.
@@ -120,6 +172,21 @@ type CredentialsBlock struct {
 	Body hcl.Body `hcl:",remain"`
 }
 
+// RetryBlock is the `retry { ... }` sub-block of output. When present, every
+// sink's writes are retried with backoff. Its body is evaluated when the
+// sinks are built.
+type RetryBlock struct {
+	Body hcl.Body `hcl:",remain"`
+}
+
+// SignBlock is `sign "<algorithm>" { ... }` inside output. Every object
+// written to the sinks, the archive when archiving, gets a detached
+// signature written next to it.
+type SignBlock struct {
+	Algorithm string   `hcl:"algorithm,label"`
+	Body      hcl.Body `hcl:",remain"`
+}
+
 // JobName returns the effective job name, generating a default when the
 // optional job block is absent or the name is empty.
 func (t *JobTemplate) JobName() string {
@@ -138,16 +205,39 @@ func (t *JobTemplate) JobName() string {
 // ParseJobTemplate parses raw HCL bytes into a JobTemplate and runs the
 // semantic checks HCL cannot do on its own (unknown block types, duplicate
 // second-labels). Every error is an hcl.Diagnostic with a source range
-// pointing at the offending bytes.
-func ParseJobTemplate(data []byte, filename string) (*JobTemplate, hcl.Diagnostics) {
+// pointing at the offending bytes. Top-level include blocks are rejected
+// unless WithIncludes is given. Overlays given with WithOverlay are merged
+// onto the job before any of the checks run.
+func ParseJobTemplate(data []byte, filename string, opts ...ParseOption) (*JobTemplate, hcl.Diagnostics) {
+	var options parseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	parser := hclparse.NewParser()
 	file, diags := parser.ParseHCL(data, filename)
 	if diags.HasErrors() || file == nil {
 		return nil, diags
 	}
 
+	var chain []string
+	if abs, err := filepath.Abs(filename); err == nil {
+		chain = append(chain, abs)
+	}
+	bodies, incDiags := resolveIncludes(parser, file.Body, filename, options, chain)
+	diags = append(diags, incDiags...)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	bodies, ovDiags := applyOverlays(parser, bodies, options.overlays)
+	diags = append(diags, ovDiags...)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body := hcl.MergeBodies(bodies)
+
 	var tmpl JobTemplate
-	diags = append(diags, gohcl.DecodeBody(file.Body, nil, &tmpl)...)
+	diags = append(diags, gohcl.DecodeBody(body, nil, &tmpl)...)
 	if diags.HasErrors() {
 		return nil, diags
 	}
@@ -155,13 +245,16 @@ func ParseJobTemplate(data []byte, filename string) (*JobTemplate, hcl.Diagnosti
 
 	// gohcl does not populate DefRange on ,remain-bearing structs; pull it
 	// from the raw body content so diagnostics can point at the block header.
-	diags = append(diags, populateDefRanges(file.Body, &tmpl)...)
+	diags = append(diags, populateDefRanges(body, &tmpl)...)
 
 	// for_each and collector are extracted manually because gohcl cannot
 	// distinguish an absent optional hcl.Expression from a present null
 	// expression.
 	diags = append(diags, splitStepMeta(&tmpl)...)
 	diags = append(diags, splitOutputMeta(&tmpl)...)
+	diags = append(diags, validateCombinedOutput(&tmpl)...)
+	diags = append(diags, validateRawOutput(&tmpl)...)
+	diags = append(diags, validateSignedOutput(&tmpl)...)
 
 	diags = append(diags, validateUniqueLabels(&tmpl)...)
 
@@ -204,9 +297,10 @@ func populateDefRanges(body hcl.Body, tmpl *JobTemplate) hcl.Diagnostics {
 	return diags
 }
 
-// splitStepMeta walks the decoded steps and extracts the `for_each` and
-// `collector` attributes from each step's Body into dedicated fields. The
-// remaining body (everything other than those two attributes) replaces
+// splitStepMeta walks the decoded steps and extracts the runner-owned
+// `for_each`, `collector`, `when`, `min_items`, `max_items` and `depends_on`
+// attributes and the `encoding` and `assert` blocks from each step's Body
+// into dedicated fields. The remaining body replaces
 // step.Body so integration-local gohcl decode never sees runner-owned
 // attributes, and so downstream reference extraction does not double-count
 // dependencies.
@@ -216,6 +310,21 @@ func splitStepMeta(tmpl *JobTemplate) hcl.Diagnostics {
 		Attributes: []hcl.AttributeSchema{
 			{Name: "for_each", Required: false},
 			{Name: "collector", Required: false},
+			{Name: "when", Required: false},
+			{Name: "min_items", Required: false},
+			{Name: "max_items", Required: false},
+			{Name: "depends_on", Required: false},
+			{Name: "no_cache", Required: false},
+		},
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "encoding", LabelNames: []string{"kind"}},
+			{Type: "assert"},
+		},
+	}
+	assertSchema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "condition", Required: true},
+			{Name: "error_message", Required: false},
 		},
 	}
 	for _, s := range tmpl.Steps {
@@ -230,11 +339,70 @@ func splitStepMeta(tmpl *JobTemplate) hcl.Diagnostics {
 		if attr, ok := content.Attributes["collector"]; ok {
 			s.Collector = attr.Expr
 		}
+		if attr, ok := content.Attributes["when"]; ok {
+			s.When = attr.Expr
+		}
+		if attr, ok := content.Attributes["min_items"]; ok {
+			s.MinItems = attr.Expr
+		}
+		if attr, ok := content.Attributes["max_items"]; ok {
+			s.MaxItems = attr.Expr
+		}
+		if attr, ok := content.Attributes["depends_on"]; ok {
+			s.DependsOn = attr.Expr
+		}
+		if attr, ok := content.Attributes["no_cache"]; ok {
+			s.NoCache = attr.Expr
+		}
+		for _, block := range content.Blocks {
+			if block.Type == "assert" {
+				ac, ad := block.Body.Content(assertSchema)
+				diags = append(diags, ad...)
+				if ad.HasErrors() {
+					continue
+				}
+				a := &AssertBlock{Condition: ac.Attributes["condition"].Expr, DefRange: block.DefRange}
+				if attr, ok := ac.Attributes["error_message"]; ok {
+					a.ErrorMessage = attr.Expr
+				}
+				s.Asserts = append(s.Asserts, a)
+				continue
+			}
+			if s.Encoding != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate encoding block",
+					Detail:   fmt.Sprintf("Step %q %q declares more than one encoding block.", s.Type, s.Name),
+					Subject:  block.DefRange.Ptr(),
+				})
+				continue
+			}
+			s.Encoding = &EncodingBlock{Kind: block.Labels[0], Body: block.Body}
+		}
+		if len(content.Blocks) > 0 {
+			remain = withoutBlocks(remain, "encoding", "assert")
+		}
 		s.Body = remain
 	}
 	return diags
 }
 
+// withoutBlocks drops blocks of the given types from a native-syntax body.
+// PartialContent only hides extracted blocks, and hclsyntax's
+// JustAttributes rejects any block, hidden or not, which would break
+// integrations that decode their body as plain attributes.
+func withoutBlocks(body hcl.Body, blockTypes ...string) hcl.Body {
+	syn, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return body
+	}
+	stripped := *syn
+	stripped.Blocks = slices.DeleteFunc(slices.Clone(syn.Blocks), func(b *hclsyntax.Block) bool {
+		return slices.Contains(blockTypes, b.Type)
+	})
+	return &stripped
+}
+
 // splitOutputMeta extracts the `steps` attribute from the output block's
 // remaining body into a dedicated field. Unknown attributes left in the
 // body after extraction are diagnosed as errors.
@@ -245,18 +413,118 @@ func splitOutputMeta(tmpl *JobTemplate) hcl.Diagnostics {
 	schema := &hcl.BodySchema{
 		Attributes: []hcl.AttributeSchema{
 			{Name: "steps", Required: false},
+			{Name: "filename", Required: false},
 		},
 	}
 	content, remain, diags := tmpl.Output.Body.PartialContent(schema)
 	if attr, ok := content.Attributes["steps"]; ok {
 		tmpl.Output.Steps = attr.Expr
 	}
+	if attr, ok := content.Attributes["filename"]; ok {
+		tmpl.Output.Filename = attr.Expr
+	}
 	// Diagnose any remaining unknown attributes.
 	_, rd := remain.Content(&hcl.BodySchema{})
 	diags = append(diags, rd...)
 	return diags
 }
 
+// validateCombinedOutput rejects the per-step output settings that have no
+// meaning once every step is written into one document.
+func validateCombinedOutput(tmpl *JobTemplate) hcl.Diagnostics {
+	if tmpl.Output == nil || !tmpl.Output.Combined {
+		return nil
+	}
+	var diags hcl.Diagnostics
+	if tmpl.Output.Filename != nil {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid output filename",
+			Detail:   "The filename attribute names per-step files and cannot be used with combined = true; the combined file is named after the job.",
+			Subject:  tmpl.Output.Filename.Range().Ptr(),
+		})
+	}
+	for _, s := range tmpl.Steps {
+		if s.Encoding == nil {
+			continue
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid step encoding",
+			Detail:   fmt.Sprintf("Step %q declares its own encoding, which cannot be used when the output block sets combined = true.", s.Name),
+			Subject:  s.DefRange.Ptr(),
+		})
+	}
+	return diags
+}
+
+// validateRawOutput rejects the output settings that shape or name encoded
+// files, which raw output does not produce, and sinks other than stdout and
+// stderr: raw output has no file name to write to.
+func validateRawOutput(tmpl *JobTemplate) hcl.Diagnostics {
+	output := tmpl.Output
+	if output == nil || !output.Raw {
+		return nil
+	}
+	var diags hcl.Diagnostics
+	reject := func(setting string, subject hcl.Range) {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid raw output",
+			Detail:   fmt.Sprintf("%s cannot be used with raw = true, which writes the step's data without encoding it.", setting),
+			Subject:  subject.Ptr(),
+		})
+	}
+	if output.Combined {
+		reject("combined = true", output.Body.MissingItemRange())
+	}
+	if output.WriteReport {
+		reject("write_report = true", output.Body.MissingItemRange())
+	}
+	if output.Filename != nil {
+		reject("The filename attribute", output.Filename.Range())
+	}
+	if output.Encoding != nil {
+		reject("An encoding block", output.Encoding.Body.MissingItemRange())
+	}
+	if output.Archive != nil {
+		reject("An archive block", output.Archive.Body.MissingItemRange())
+	}
+	for _, sink := range output.Sinks {
+		if sink.Kind != "stdout" && sink.Kind != "stderr" {
+			reject(fmt.Sprintf("A %q sink", sink.Kind), sink.Body.MissingItemRange())
+		}
+	}
+	for _, s := range tmpl.Steps {
+		if s.Encoding != nil {
+			reject(fmt.Sprintf("The encoding block of step %q", s.Name), s.DefRange)
+		}
+	}
+	return diags
+}
+
+// validateSignedOutput rejects stdout and stderr sinks when the output is
+// signed: a stream has no file name to put the signature next to, so it
+// would be appended to the data itself.
+func validateSignedOutput(tmpl *JobTemplate) hcl.Diagnostics {
+	output := tmpl.Output
+	if output == nil || output.Sign == nil {
+		return nil
+	}
+	var diags hcl.Diagnostics
+	for _, sink := range output.Sinks {
+		if sink.Kind == "stdout" || sink.Kind == "stderr" {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid signed output",
+				Detail:   fmt.Sprintf("A %q sink cannot be used with a sign block: the signature is written as a separate file next to each output file.", sink.Kind),
+				Subject:  sink.Body.MissingItemRange().Ptr(),
+			})
+		}
+	}
+	return diags
+}
+
 func validateUniqueLabels(tmpl *JobTemplate) hcl.Diagnostics {
 	var diags hcl.Diagnostics
 
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+	"go.uber.org/zap"
+)
+
+// ValidateCollectors creates every collector whose body references nothing
+// but env.* and job.*, without starting it, and calls Validate on those that
+// implement engine.CollectorValidator. Collectors that reference steps cannot
+// be created before those steps run and are skipped. Every collector created
+// is closed again.
+func (r *Runner) ValidateCollectors(ctx context.Context) hcl.Diagnostics {
+	order, err := r.pipeline.dag.TopologicalSort()
+	if err != nil {
+		return hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Could not sort DAG",
+			Detail:   err.Error(),
+		}}
+	}
+
+	var diags hcl.Diagnostics
+	ectx := r.childCtxForNode()
+	for _, node := range order {
+		if node.Kind != NodeTypeCollector {
+			continue
+		}
+		meta, ok := r.pipeline.Meta(node)
+		if !ok {
+			continue
+		}
+		if !onlyStaticRefs(meta.Refs) {
+			r.logger.Debug("skipping validation of collector that references steps",
+				zap.String("type", node.Type),
+				zap.String("id", node.ID),
+			)
+			continue
+		}
+
+		collector, createDiags := r.registry.CreateCollector(node.Type, meta.Body, ectx)
+		diags = append(diags, createDiags...)
+		if createDiags.HasErrors() {
+			continue
+		}
+		if validator, ok := collector.(engine.CollectorValidator); ok {
+			if err := validator.Validate(ctx); err != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Collector validation failed",
+					Detail:   fmt.Sprintf("Collector %s/%s (%s): %s", node.Type, node.ID, collector.Name(), err),
+					Subject:  meta.DefRange.Ptr(),
+				})
+			}
+		}
+		// Creating a collector can already hold clients or processes.
+		if err := collector.Close(ctx); err != nil {
+			r.logger.Warn("failed to close collector after validation",
+				zap.String("collector", collector.Name()),
+				zap.Error(err),
+			)
+		}
+	}
+	return diags
+}
@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validatingCollector fails Validate when its body sets reachable = false.
+type validatingCollector struct {
+	stubCollector
+	reachable bool
+	validated bool
+}
+
+func (c *validatingCollector) Validate(context.Context) error {
+	c.validated = true
+	if !c.reachable {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestRunner_ValidateCollectors(t *testing.T) {
+	sr := newStubRegistry(t)
+	validating := map[string]*validatingCollector{}
+	require.NoError(t, sr.reg.RegisterCollector("checked", func(_ *engine.RegistryHelper, body hcl.Body, ctx *hcl.EvalContext) (engine.Collector, hcl.Diagnostics) {
+		data, diags := engine.BodyToMap(body, ctx)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		c := &validatingCollector{
+			stubCollector: stubCollector{name: data["name"].(string)},
+			reachable:     data["reachable"].(bool),
+		}
+		validating[c.name] = c
+		return c, nil
+	}))
+
+	r := newRunner(t, []byte(`
+collector "checked" "up" {
+  name      = "up"
+  reachable = true
+}
+
+collector "checked" "down" {
+  name      = "down"
+  reachable = false
+}
+
+collector "checked" "later" {
+  name      = step.stub_nocoll.name.name
+  reachable = false
+}
+
+collector "stub" "plain" {}
+
+step "stub_nocoll" "name" {
+  name = "later"
+}
+`), "validate.hcl", sr.reg)
+
+	diags := r.ValidateCollectors(t.Context())
+	require.Len(t, diags, 1)
+	assert.Equal(t, "Collector validation failed", diags[0].Summary)
+	assert.Equal(t, "Collector checked/down (down): connection refused", diags[0].Detail)
+	assert.Equal(t, "validate.hcl", diags[0].Subject.Filename)
+
+	require.Contains(t, validating, "up")
+	assert.True(t, validating["up"].validated)
+	assert.False(t, validating["up"].started, "collectors are validated without being started")
+	assert.True(t, validating["up"].closed, "validated collectors are closed")
+	assert.True(t, validating["down"].closed, "collectors failing validation are closed")
+	assert.NotContains(t, validating, "later", "collectors referencing steps are skipped")
+	require.Contains(t, sr.collectors, "stub")
+	assert.False(t, sr.collectors["stub"].started)
+	assert.True(t, sr.collectors["stub"].closed, "collectors without Validate are closed too")
+}
+
+func TestRunner_ValidateCollectors_CreateErrors(t *testing.T) {
+	sr := newStubRegistry(t)
+	require.NoError(t, sr.reg.RegisterCollector("invalid", func(*engine.RegistryHelper, hcl.Body, *hcl.EvalContext) (engine.Collector, hcl.Diagnostics) {
+		return nil, hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "Invalid collector"}}
+	}))
+
+	r := newRunner(t, []byte(`
+collector "invalid" "a" {}
+`), "validate.hcl", sr.reg)
+
+	diags := r.ValidateCollectors(t.Context())
+	require.True(t, diags.HasErrors())
+	assert.Equal(t, "Invalid collector", diags[0].Summary)
+}
@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeSecrets serves secrets from memory and counts reads per path.
+type fakeSecrets struct {
+	secrets map[string]map[string]any
+	reads   map[string]int
+}
+
+func (f *fakeSecrets) ReadSecret(path string) (map[string]any, error) {
+	f.reads[path]++
+	data, ok := f.secrets[path]
+	if !ok {
+		return nil, errors.New("permission denied")
+	}
+	return data, nil
+}
+
+func runWithSecrets(t *testing.T, src string, secrets *fakeSecrets) (*Runner, error) {
+	t.Helper()
+	stub := newStubRegistry(t)
+	tmpl, diags := ParseJobTemplate([]byte(src), "vault.hcl")
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	var opts []Option
+	if secrets != nil {
+		opts = append(opts, WithSecretReader(secrets))
+	}
+	r, diags := New(zap.NewNop(), tmpl, stub.reg, nil, opts...)
+	require.False(t, diags.HasErrors(), diags.Error())
+	_, err := runSilently(t, r)
+	return r, err
+}
+
+func TestRunner_VaultFunction(t *testing.T) {
+	secrets := &fakeSecrets{
+		secrets: map[string]map[string]any{
+			"secret/data/app":    {"api_key": "k3y", "user": "svc"},
+			"secret/data/unused": {"x": "y"},
+		},
+		reads: map[string]int{},
+	}
+
+	r, err := runWithSecrets(t, `
+step "stub_nocoll" "s" {
+  key  = vault("secret/data/app", "api_key")
+  user = vault("secret/data/app", "user")
+}
+`, secrets)
+	require.NoError(t, err)
+
+	data := r.raw["stub_nocoll/s"].Data.(map[string]any)
+	assert.Equal(t, "k3y", data["key"])
+	assert.Equal(t, "svc", data["user"])
+	assert.Equal(t, map[string]int{"secret/data/app": 1}, secrets.reads,
+		"only referenced paths are read, and each at most once")
+}
+
+func TestRunner_VaultFunctionErrors(t *testing.T) {
+	secrets := &fakeSecrets{
+		secrets: map[string]map[string]any{
+			"secret/data/app": {"api_key": "k3y", "port": 8200},
+		},
+		reads: map[string]int{},
+	}
+
+	cases := []struct {
+		name    string
+		expr    string
+		secrets *fakeSecrets
+		wantErr string
+	}{
+		{
+			name:    "read failure names the path",
+			expr:    `vault("secret/data/missing", "api_key")`,
+			secrets: secrets,
+			wantErr: `vault: failed to read "secret/data/missing": permission denied`,
+		},
+		{
+			name:    "missing key",
+			expr:    `vault("secret/data/app", "nope")`,
+			secrets: secrets,
+			wantErr: `vault: secret "secret/data/app" has no key "nope"`,
+		},
+		{
+			name:    "non-string value",
+			expr:    `vault("secret/data/app", "port")`,
+			secrets: secrets,
+			wantErr: `vault: key "port" of secret "secret/data/app" is not a string`,
+		},
+		{
+			name:    "vault not configured",
+			expr:    `vault("secret/data/app", "api_key")`,
+			wantErr: "vault is not configured",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := runWithSecrets(t, `
+step "stub_nocoll" "s" {
+  key = `+tc.expr+`
+}
+`, tc.secrets)
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
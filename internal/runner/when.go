@@ -0,0 +1,35 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// evalWhen evaluates a step's `when` condition. A step without one always
+// runs. The condition must resolve to a known boolean; "true"/"false"
+// strings are accepted so conditions can compare against env directly.
+func evalWhen(ctx *hcl.EvalContext, expr hcl.Expression) (bool, error) {
+	if expr == nil {
+		return true, nil
+	}
+
+	val, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return false, fmt.Errorf("failed to evaluate when: %s", diags.Error())
+	}
+	val, err := convert.Convert(val, cty.Bool)
+	if err != nil || val.IsNull() || !val.IsKnown() {
+		return false, fmt.Errorf("when must be a boolean")
+	}
+	return val.True(), nil
+}
+
+// skippedStepValue stands in for a skipped step's result so downstream
+// references to step.<type>.<id>.data still resolve — to null.
+var skippedStepValue = cty.ObjectVal(map[string]cty.Value{
+	"data": cty.NullVal(cty.DynamicPseudoType),
+	"meta": cty.EmptyObjectVal,
+})
@@ -0,0 +1,163 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/infracollect/infracollect/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_When(t *testing.T) {
+	cases := []struct {
+		name    string
+		src     string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "true runs the step",
+			src: `
+step "stub_nocoll" "s" {
+  when = true
+}`,
+			want: []string{"stub_nocoll/s"},
+		},
+		{
+			name: "false skips the step",
+			src: `
+step "stub_nocoll" "s" {
+  when = false
+}`,
+			want: []string{},
+		},
+		{
+			name: "string condition",
+			src: `
+step "stub_nocoll" "s" {
+  when = "true"
+}`,
+			want: []string{"stub_nocoll/s"},
+		},
+		{
+			name: "condition referencing another step",
+			src: `
+step "stub_nocoll" "flags" {
+  enabled = false
+}
+
+step "stub_nocoll" "s" {
+  when = step.stub_nocoll.flags.data.enabled
+}`,
+			want: []string{"stub_nocoll/flags"},
+		},
+		{
+			name: "non-boolean condition",
+			src: `
+step "stub_nocoll" "s" {
+  when = "maybe"
+}`,
+			wantErr: "step stub_nocoll/s: when must be a boolean",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := newStubRegistry(t)
+
+			out, err := runSilently(t, newRunner(t, []byte(tc.src), "when.hcl", stub.reg))
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			keys := make([]string, 0, len(out))
+			for k := range out {
+				keys = append(keys, k)
+			}
+			assert.ElementsMatch(t, tc.want, keys)
+		})
+	}
+}
+
+func TestRunner_When_DownstreamSeesNull(t *testing.T) {
+	stub := newStubRegistry(t)
+
+	out := runOrFail(t, []byte(`
+step "stub_nocoll" "s" {
+  when = false
+}
+
+step "stub_nocoll" "after" {
+  missing = step.stub_nocoll.s.data == null
+}
+`), "when.hcl", stub.reg)
+
+	after := out["stub_nocoll/after"].Data.(map[string]any)
+	assert.Equal(t, true, after["missing"])
+}
+
+func TestRunner_When_ForEachFiltersIterations(t *testing.T) {
+	stub := newStubRegistry(t)
+
+	out := runOrFail(t, []byte(`
+step "stub_nocoll" "fan" {
+  for_each = { alpha = "one", beta = "two" }
+  when     = each.key != "beta"
+  val      = each.value
+}
+`), "when.hcl", stub.reg)
+
+	fan := out["stub_nocoll/fan"].Data.(map[string]engine.Result)
+	require.Len(t, fan, 1)
+	assert.Contains(t, fan, "alpha")
+}
+
+func TestRunner_When_RecordedInReport(t *testing.T) {
+	stub := newStubRegistry(t)
+
+	r := newRunner(t, []byte(`
+step "stub_nocoll" "skipped" {
+  when = false
+}
+
+step "stub_nocoll" "ran" {
+}
+`), "when.hcl", stub.reg)
+	_, err := runSilently(t, r)
+	require.NoError(t, err)
+
+	statuses := make(map[string]string)
+	for _, s := range r.Report().Steps {
+		statuses[s.ID] = s.Status
+	}
+	assert.Equal(t, map[string]string{
+		"skipped": ReportStatusSkipped,
+		"ran":     ReportStatusSucceeded,
+	}, statuses)
+}
+
+func TestParseJobTemplate_WhenIsRunnerOwned(t *testing.T) {
+	tmpl, diags := ParseJobTemplate([]byte(`
+step "stub_nocoll" "s" {
+  when     = true
+  greeting = "hi"
+}
+`), "when.hcl")
+	require.False(t, diags.HasErrors(), diags.Error())
+	require.Len(t, tmpl.Steps, 1)
+	assert.NotNil(t, tmpl.Steps[0].When)
+
+	attrs, diags := tmpl.Steps[0].Body.JustAttributes()
+	require.False(t, diags.HasErrors(), diags.Error())
+	assert.Contains(t, attrs, "greeting")
+	assert.NotContains(t, attrs, "when")
+}
+
+func TestParseJobTemplate_MalformedWhen(t *testing.T) {
+	_, diags := ParseJobTemplate([]byte(`
+step "stub_nocoll" "s" {
+  when = env.ENV ==
+}
+`), "when.hcl")
+	assert.True(t, diags.HasErrors())
+}
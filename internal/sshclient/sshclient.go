@@ -0,0 +1,176 @@
+// Package sshclient dials authenticated SSH connections. It is shared by the
+// ssh collector and the SFTP sink so both enforce the same host key checking.
+package sshclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	DefaultPort    = 22
+	DefaultTimeout = 30 * time.Second
+
+	defaultKnownHostsPath = "~/.ssh/known_hosts"
+)
+
+type Config struct {
+	Host string
+	Port int
+	User string
+
+	// PrivateKeyPath and Password are the supported authentication methods;
+	// at least one is required. Both may be set, in which case the key is
+	// offered first.
+	PrivateKeyPath string
+	Password       string
+
+	// KnownHostsPath is checked strictly: an unknown or mismatched host key
+	// fails the connection. InsecureIgnoreHostKey skips the check and must
+	// be set explicitly.
+	KnownHostsPath        string
+	InsecureIgnoreHostKey bool
+
+	// Timeout bounds establishing the connection, including the SSH
+	// handshake.
+	Timeout time.Duration
+}
+
+// WithDefaults validates cfg and fills in the default port and timeout.
+func (cfg Config) WithDefaults() (Config, error) {
+	if cfg.Host == "" {
+		return cfg, fmt.Errorf("host is required")
+	}
+	if cfg.User == "" {
+		return cfg, fmt.Errorf("user is required")
+	}
+	if cfg.PrivateKeyPath == "" && cfg.Password == "" {
+		return cfg, fmt.Errorf("either private_key_path or password is required")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = DefaultPort
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return cfg, nil
+}
+
+// Address returns host:port.
+func (cfg Config) Address() string {
+	return net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+}
+
+// Dial connects to cfg.Address and authenticates.
+func Dial(ctx context.Context, cfg Config) (*ssh.Client, error) {
+	auth, err := authMethods(cfg)
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := hostKeyCallback(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConfig := &ssh.ClientConfig{
+		User:            cfg.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         cfg.Timeout,
+	}
+
+	dialer := net.Dialer{Timeout: cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", cfg.Address())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", cfg.Address(), err)
+	}
+
+	// ClientConfig.Timeout only covers the TCP dial, so bound the handshake
+	// with a deadline and abort it when ctx is done. A server that accepts
+	// the connection and then stalls would otherwise hang forever.
+	if err := conn.SetDeadline(time.Now().Add(cfg.Timeout)); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to set handshake deadline for %s: %w", cfg.Address(), err)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, cfg.Address(), clientConfig)
+	if !stop() {
+		if err == nil {
+			_ = sshConn.Close()
+		}
+		return nil, fmt.Errorf("failed to establish ssh connection to %s: %w", cfg.Address(), context.Cause(ctx))
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to establish ssh connection to %s: %w", cfg.Address(), err)
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		_ = sshConn.Close()
+		return nil, fmt.Errorf("failed to clear handshake deadline for %s: %w", cfg.Address(), err)
+	}
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+func authMethods(cfg Config) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+	if cfg.PrivateKeyPath != "" {
+		path, err := expandHome(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key %s: %w", path, err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		methods = append(methods, ssh.Password(cfg.Password))
+	}
+	return methods, nil
+}
+
+func hostKeyCallback(cfg Config) (ssh.HostKeyCallback, error) {
+	if cfg.InsecureIgnoreHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	path := cfg.KnownHostsPath
+	if path == "" {
+		path = defaultKnownHostsPath
+	}
+	path, err := expandHome(path)
+	if err != nil {
+		return nil, err
+	}
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known hosts (set insecure_ignore_host_key to skip host key checking): %w", err)
+	}
+	return callback, nil
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to expand %s: %w", path, err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
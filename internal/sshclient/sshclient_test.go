@@ -0,0 +1,78 @@
+package sshclient
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stallingListener accepts connections and never speaks SSH, like a server
+// that hangs after the TCP handshake.
+func stallingListener(t *testing.T) *net.TCPAddr {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var (
+		mu    sync.Mutex
+		conns []net.Conn
+	)
+	t.Cleanup(func() {
+		_ = listener.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr)
+}
+
+func TestDial_StalledHandshake(t *testing.T) {
+	addr := stallingListener(t)
+	cfg := Config{
+		Host:                  addr.IP.String(),
+		Port:                  addr.Port,
+		User:                  "collector",
+		Password:              "secret",
+		InsecureIgnoreHostKey: true,
+	}
+
+	t.Run("times out", func(t *testing.T) {
+		cfg := cfg
+		cfg.Timeout = 50 * time.Millisecond
+
+		start := time.Now()
+		_, err := Dial(t.Context(), cfg)
+		assert.ErrorContains(t, err, "failed to establish ssh connection")
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		cfg := cfg
+		cfg.Timeout = time.Minute
+
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := Dial(ctx, cfg)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
@@ -28,6 +28,10 @@ var commands = []command{
 		name: "infracollect",
 		args: []string{"--help"},
 	},
+	{
+		name: "init",
+		args: []string{"init", "--help"},
+	},
 	{
 		name: "collect",
 		args: []string{"collect", "--help"},
@@ -36,6 +40,14 @@ var commands = []command{
 		name: "validate",
 		args: []string{"validate", "--help"},
 	},
+	{
+		name: "schema",
+		args: []string{"schema", "--help"},
+	},
+	{
+		name: "terraform datasources",
+		args: []string{"terraform", "datasources", "--help"},
+	},
 	{
 		name: "version",
 		args: []string{"version", "--help"},
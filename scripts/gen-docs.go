@@ -3,7 +3,8 @@
 // gen-docs generates v2 JSON schema documentation from Go structs with
 // hcl:"..." tags. It reads the target registry from docs/registry.yaml,
 // parses the listed Go packages via AST, and emits JSON files to
-// website/src/data/schemas/.
+// website/src/data/schemas/: one per target, plus index.json combining them
+// all under the job file target with "$ref" links between nested blocks.
 //
 // Usage:
 //
@@ -19,6 +20,10 @@ import (
 	"github.com/infracollect/infracollect/scripts/gendocs"
 )
 
+// rootTarget is the registry target describing a whole job file, the root
+// of the combined schema.
+const rootTarget = "job-file"
+
 func main() {
 	root, err := findProjectRoot()
 	if err != nil {
@@ -49,9 +54,10 @@ func main() {
 		os.Exit(1)
 	}
 
-	idx := gendocs.BuildBlockHeaderIndex(reg)
+	idx := gendocs.BuildRefIndex(reg)
 
 	var warnings []string
+	schemas := make([]*gendocs.DocSchema, 0, len(reg.Targets))
 	for _, target := range reg.Targets {
 		schema, err := gendocs.ExtractSchema(loaded, reg, idx, target)
 		if err != nil {
@@ -59,12 +65,20 @@ func main() {
 			os.Exit(1)
 		}
 
-		// Warn on missing field descriptions.
+		// Warn on missing field descriptions and unresolved blocks.
 		for _, attr := range schema.Attributes {
 			if attr.Description == "" {
 				warnings = append(warnings, fmt.Sprintf("  %s: attribute %q has no description", target.ID, attr.Name))
 			}
 		}
+		if schema.Kind != gendocs.SchemaKindPage {
+			for _, block := range schema.Blocks {
+				if block.Ref == "" {
+					warnings = append(warnings, fmt.Sprintf("  %s: block %q has no schema", target.ID, block.Name))
+				}
+			}
+		}
+		schemas = append(schemas, schema)
 
 		if err := gendocs.EmitJSON(schema, *outputDir); err != nil {
 			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", target.ID, err)
@@ -74,8 +88,19 @@ func main() {
 		fmt.Printf("  %-30s → %s.json\n", target.ID, target.ID)
 	}
 
+	combined, err := gendocs.Combine(schemas, rootTarget)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error combining schemas: %v\n", err)
+		os.Exit(1)
+	}
+	if err := gendocs.EmitCombined(combined, *outputDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing combined schema: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("  %-30s → %s\n", "(combined)", gendocs.CombinedFilename)
+
 	if len(warnings) > 0 {
-		fmt.Printf("\nWarnings (%d fields missing descriptions):\n", len(warnings))
+		fmt.Printf("\nWarnings (%d fields missing descriptions or schemas):\n", len(warnings))
 		for _, w := range warnings {
 			fmt.Println(w)
 		}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 )
 
 // EnsureOutputDir creates the output directory if it doesn't exist.
@@ -12,14 +13,75 @@ func EnsureOutputDir(dir string) error {
 	return os.MkdirAll(dir, 0o755)
 }
 
+// CombinedFilename is the file EmitCombined writes the combined schema to.
+const CombinedFilename = "index.json"
+
 // EmitJSON writes a DocSchema as a formatted JSON file.
 func EmitJSON(schema *DocSchema, outputDir string) error {
-	filename := schema.ID + ".json"
-	path := filepath.Join(outputDir, filename)
+	return writeJSON(schema, schema.ID, filepath.Join(outputDir, schema.ID+".json"))
+}
+
+// EmitCombined writes the combined schema to CombinedFilename.
+func EmitCombined(combined *CombinedSchema, outputDir string) error {
+	return writeJSON(combined, "combined schema", filepath.Join(outputDir, CombinedFilename))
+}
+
+// Combine gathers schemas into one CombinedSchema rooted at rootID, setting
+// the "$ref" pointer of every resolved block ref and variant. Page schemas
+// describe website pages rather than blocks and are left out. The schemas
+// passed in are not modified.
+func Combine(schemas []*DocSchema, rootID string) (*CombinedSchema, error) {
+	combined := &CombinedSchema{
+		SchemaVersion: 2,
+		Root:          definitionPointer(rootID),
+		Definitions:   make(map[string]*DocSchema, len(schemas)),
+	}
+	for _, schema := range schemas {
+		if schema.Kind == SchemaKindPage {
+			continue
+		}
+		combined.Definitions[schema.ID] = schema
+	}
+	if _, ok := combined.Definitions[rootID]; !ok {
+		return nil, fmt.Errorf("root schema %q not found", rootID)
+	}
+
+	for id, schema := range combined.Definitions {
+		linked := *schema
+		linked.Blocks = slices.Clone(schema.Blocks)
+		for i, block := range linked.Blocks {
+			if block.Ref == "" {
+				continue
+			}
+			if _, ok := combined.Definitions[block.Ref]; !ok {
+				return nil, fmt.Errorf("%s: block %q refers to unknown schema %q", id, block.Name, block.Ref)
+			}
+			linked.Blocks[i].Pointer = definitionPointer(block.Ref)
+		}
+		linked.Variants = slices.Clone(schema.Variants)
+		for i, variant := range linked.Variants {
+			if variant.Ref == "" {
+				continue
+			}
+			if _, ok := combined.Definitions[variant.Ref]; !ok {
+				return nil, fmt.Errorf("%s: variant %q refers to unknown schema %q", id, variant.Label, variant.Ref)
+			}
+			linked.Variants[i].Pointer = definitionPointer(variant.Ref)
+		}
+		combined.Definitions[id] = &linked
+	}
+
+	return combined, nil
+}
+
+func definitionPointer(id string) string {
+	return "#/definitions/" + id
+}
 
-	data, err := json.MarshalIndent(schema, "", "  ")
+func writeJSON(v any, name, path string) error {
+	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
-		return fmt.Errorf("marshaling %s: %w", schema.ID, err)
+		return fmt.Errorf("marshaling %s: %w", name, err)
 	}
 
 	// Ensure trailing newline.
@@ -7,8 +7,8 @@ import (
 )
 
 // ExtractSchema builds a DocSchema for a single registry target.
-// The idx is a pre-built block header index for fast block-ref lookups.
-func ExtractSchema(lp *LoadedPackages, reg *Registry, idx BlockHeaderIndex, target Target) (*DocSchema, error) {
+// The idx is a pre-built reference index for fast block-ref lookups.
+func ExtractSchema(lp *LoadedPackages, reg *Registry, idx *RefIndex, target Target) (*DocSchema, error) {
 	switch target.Kind {
 	case KindPage:
 		return extractPage(lp, target)
@@ -20,7 +20,7 @@ func ExtractSchema(lp *LoadedPackages, reg *Registry, idx BlockHeaderIndex, targ
 }
 
 // extractBlock handles rootBlock, stepBlock, variant, and freeform targets.
-func extractBlock(lp *LoadedPackages, idx BlockHeaderIndex, target Target) (*DocSchema, error) {
+func extractBlock(lp *LoadedPackages, idx *RefIndex, target Target) (*DocSchema, error) {
 	info, err := lp.FindStruct(target.Package, target.Type)
 	if err != nil {
 		return nil, fmt.Errorf("target %s: %w", target.ID, err)
@@ -58,7 +58,7 @@ func extractBlock(lp *LoadedPackages, idx BlockHeaderIndex, target Target) (*Doc
 		// In gohcl, blocks are optional when the Go field is a pointer or
 		// slice — the ,optional modifier is for attributes only.
 		if tag.Block {
-			ref := findBlockRef(idx, tag.Name)
+			ref := idx.Resolve(target.Package, f.GoType, tag.Name)
 			required := !strings.HasPrefix(f.GoType, "*") && !strings.HasPrefix(f.GoType, "[]")
 			schema.Blocks = append(schema.Blocks, DocBlockRef{
 				Name:        tag.Name,
@@ -158,27 +158,53 @@ func extractPage(lp *LoadedPackages, target Target) (*DocSchema, error) {
 	return schema, nil
 }
 
-// BlockHeaderIndex maps block header base names (e.g. "auth", "sink") to
-// target IDs for O(1) lookups. Build once with BuildBlockHeaderIndex.
-type BlockHeaderIndex map[string]string
+// RefIndex resolves nested blocks to the registry targets documenting
+// them. Build once with BuildRefIndex.
+type RefIndex struct {
+	// byType maps "<package>.<type>" to the first target declared for
+	// that Go type, so a union wins over its variants sharing the struct.
+	byType map[string]string
+	// byHeader maps block header base names (e.g. "auth", "sink") to
+	// target IDs, for blocks whose Go type has no target of its own.
+	byHeader map[string]string
+}
 
-// BuildBlockHeaderIndex builds an index from block header base names to
-// target IDs for fast lookups in findBlockRef.
-func BuildBlockHeaderIndex(reg *Registry) BlockHeaderIndex {
-	idx := make(BlockHeaderIndex, len(reg.Targets))
+// BuildRefIndex indexes the registry targets by Go type and by block
+// header base name.
+func BuildRefIndex(reg *Registry) *RefIndex {
+	idx := &RefIndex{
+		byType:   make(map[string]string, len(reg.Targets)),
+		byHeader: make(map[string]string, len(reg.Targets)),
+	}
 	for _, t := range reg.Targets {
-		if t.BlockHeader == "" {
+		if t.Kind == KindPage {
 			continue
 		}
-		base := strings.SplitN(t.BlockHeader, " ", 2)[0]
-		idx[base] = t.ID
+		key := t.Package + "." + t.Type
+		if _, ok := idx.byType[key]; !ok {
+			idx.byType[key] = t.ID
+		}
+		if t.BlockHeader != "" {
+			base := strings.SplitN(t.BlockHeader, " ", 2)[0]
+			idx.byHeader[base] = t.ID
+		}
 	}
 	return idx
 }
 
-// findBlockRef looks up the registry target ID for a nested block name.
-func findBlockRef(idx BlockHeaderIndex, blockName string) string {
-	return idx[blockName]
+// Resolve returns the target ID for a block field of Go type goType,
+// declared in pkgPath under the block name blockName. The Go type wins over
+// the block name, which is ambiguous when several structs share it. Types
+// from other packages are only resolved by block name, since the AST alone
+// does not tell which package an import alias refers to.
+func (idx *RefIndex) Resolve(pkgPath, goType, blockName string) string {
+	elem := strings.TrimLeft(goType, "*[]")
+	if !strings.Contains(elem, ".") {
+		if id, ok := idx.byType[pkgPath+"."+elem]; ok {
+			return id
+		}
+	}
+	return idx.byHeader[blockName]
 }
 
 // cleanGoType simplifies a Go type for display in docs.
@@ -188,7 +214,7 @@ func cleanGoType(goType string) string {
 
 	// Map well-known types to friendlier names.
 	switch goType {
-	case "int", "int64", "int32":
+	case "int", "int64", "int32", "float64", "float32":
 		return "number"
 	case "bool":
 		return "bool"
@@ -198,8 +224,12 @@ func cleanGoType(goType string) string {
 		return "list(string)"
 	case "map[string]string":
 		return "map(string)"
+	case "map[string][]string":
+		return "map(list(string))"
 	case "map[string]any", "map[string]interface{}":
 		return "map(any)"
+	case "hcl.Expression":
+		return "any"
 	}
 
 	return goType
@@ -1,6 +1,7 @@
 package gendocs
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -46,13 +47,19 @@ func TestExtractSchema_Fixtures(t *testing.T) {
 				Type:    "FormatConfig",
 				Kind:    KindRootBlock,
 			},
+			{
+				ID:      "nested",
+				Package: "github.com/infracollect/infracollect/scripts/gendocs/testdata",
+				Type:    "NestedConfig",
+				Kind:    KindRootBlock,
+			},
 		},
 	}
 
 	loaded, err := LoadPackages(".", reg)
 	require.NoError(t, err)
 
-	idx := BuildBlockHeaderIndex(reg)
+	idx := BuildRefIndex(reg)
 
 	t.Run("simple config attributes", func(t *testing.T) {
 		schema, err := ExtractSchema(loaded, reg, idx, reg.Targets[0])
@@ -126,6 +133,64 @@ func TestExtractSchema_Fixtures(t *testing.T) {
 		require.Len(t, schema.Attributes, 1)
 		assert.Equal(t, []string{"json", "yaml", "toml"}, schema.Attributes[0].Enum)
 	})
+
+	t.Run("block refs resolve by type, then by block name", func(t *testing.T) {
+		schema, err := ExtractSchema(loaded, reg, idx, reg.Targets[5])
+		require.NoError(t, err)
+
+		require.Len(t, schema.Blocks, 2)
+		assert.Equal(t, "login", schema.Blocks[0].Name)
+		assert.Equal(t, "auth-fixture", schema.Blocks[0].Ref)
+		assert.Equal(t, "block", schema.Blocks[1].Name)
+		assert.Equal(t, "block-config", schema.Blocks[1].Ref)
+	})
+}
+
+func TestCombine(t *testing.T) {
+	root := &DocSchema{
+		SchemaVersion: 2,
+		ID:            "root",
+		Blocks: []DocBlockRef{
+			{Name: "auth", Ref: "auth"},
+			{Name: "opaque"},
+		},
+	}
+	auth := &DocSchema{
+		SchemaVersion: 2,
+		ID:            "auth",
+		Kind:          SchemaKindUnion,
+		Variants: []DocVariant{
+			{Label: "basic", Ref: "auth-basic"},
+			{Label: "none"},
+		},
+	}
+	basic := &DocSchema{SchemaVersion: 2, ID: "auth-basic"}
+	page := &DocSchema{SchemaVersion: 2, ID: "page", Kind: SchemaKindPage}
+
+	combined, err := Combine([]*DocSchema{root, auth, basic, page}, "root")
+	require.NoError(t, err)
+
+	assert.Equal(t, "#/definitions/root", combined.Root)
+	assert.Len(t, combined.Definitions, 3, "page schemas are left out")
+	assert.Equal(t, "#/definitions/auth", combined.Definitions["root"].Blocks[0].Pointer)
+	assert.Empty(t, combined.Definitions["root"].Blocks[1].Pointer)
+	assert.Equal(t, "#/definitions/auth-basic", combined.Definitions["auth"].Variants[0].Pointer)
+	assert.Empty(t, combined.Definitions["auth"].Variants[1].Pointer)
+	assert.Empty(t, root.Blocks[0].Pointer, "the input schemas are not modified")
+
+	data, err := json.Marshal(combined)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"$ref":"#/definitions/root"`)
+
+	t.Run("unknown root", func(t *testing.T) {
+		_, err := Combine([]*DocSchema{basic}, "root")
+		assert.ErrorContains(t, err, `root schema "root" not found`)
+	})
+
+	t.Run("dangling ref", func(t *testing.T) {
+		_, err := Combine([]*DocSchema{root}, "root")
+		assert.ErrorContains(t, err, `root: block "auth" refers to unknown schema "auth"`)
+	})
 }
 
 func strPtr(s string) *string { return &s }
@@ -35,18 +35,30 @@ type DocAttribute struct {
 // DocBlockRef references a nested block within a schema.
 type DocBlockRef struct {
 	Name        string `json:"name"`
-	Ref         string `json:"ref,omitempty"` // target id of the nested block's schema
+	Ref         string `json:"ref,omitempty"`  // target id of the nested block's schema
+	Pointer     string `json:"$ref,omitempty"` // JSON pointer to Ref, set in the combined schema only
 	Required    bool   `json:"required"`
 	Description string `json:"description,omitempty"`
 }
 
 // DocVariant is one arm of a labeled union.
 type DocVariant struct {
-	Label string `json:"label"`
-	Ref   string `json:"ref,omitempty"` // target id, empty for label-only variants
+	Label   string `json:"label"`
+	Ref     string `json:"ref,omitempty"`  // target id, empty for label-only variants
+	Pointer string `json:"$ref,omitempty"` // JSON pointer to Ref, set in the combined schema only
 }
 
 // DocRemain documents a ",remain" body on the struct.
 type DocRemain struct {
 	Description string `json:"description,omitempty"`
 }
+
+// CombinedSchema holds every target's schema in one document. Root points at
+// the schema of the job file itself, and block refs and variants carry
+// "$ref" JSON pointers into Definitions, so the whole nesting of a job can
+// be walked from one file.
+type CombinedSchema struct {
+	SchemaVersion int                   `json:"schemaVersion"`
+	Root          string                `json:"$ref"`
+	Definitions   map[string]*DocSchema `json:"definitions"`
+}
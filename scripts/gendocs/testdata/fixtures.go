@@ -42,3 +42,17 @@ type FormatConfig struct {
 	// Format for the output. One of: json, yaml, toml.
 	Format string `hcl:"format"`
 }
+
+// NestedConfig nests blocks whose names differ from their targets' block
+// headers.
+type NestedConfig struct {
+	// Login is resolved by its Go type, not its block name.
+	Login *AuthBlockFixture `hcl:"login,block"`
+	// Blocks are resolved by their block name when the type has no target.
+	Children []*BlockFixtureChild `hcl:"block,block"`
+}
+
+// BlockFixtureChild has no registry target of its own.
+type BlockFixtureChild struct {
+	Body hcl.Body `hcl:",remain"`
+}